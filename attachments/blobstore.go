@@ -0,0 +1,93 @@
+package attachments
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ErrBlobNotFound — в хранилище нет объекта с таким ключом
+var ErrBlobNotFound = errors.New("файл не найден в хранилище")
+
+// BlobStore хранит содержимое вложений. Ключи — пути через "/", их выбирает вызывающий код.
+type BlobStore interface {
+	// Put сохраняет содержимое r под ключом key. size равен -1, если размер заранее неизвестен.
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Open открывает объект на чтение. Возвращаемый поток поддерживает Seek, что нужно для Range-запросов.
+	Open(ctx context.Context, key string) (io.ReadSeekCloser, error)
+	// Delete удаляет объект; отсутствие объекта ошибкой не считается
+	Delete(ctx context.Context, key string) error
+}
+
+// FSBlobStore хранит объекты файлами в локальном каталоге
+type FSBlobStore struct {
+	Root string
+}
+
+func NewFSBlobStore(root string) (*FSBlobStore, error) {
+	if err := os.MkdirAll(root, 0o750); err != nil {
+		return nil, err
+	}
+	return &FSBlobStore{Root: root}, nil
+}
+
+func (s *FSBlobStore) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+
+	// Пишем во временный файл и переименовываем, чтобы не оставить обрезанный объект
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *FSBlobStore) Open(ctx context.Context, key string) (io.ReadSeekCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrBlobNotFound
+	}
+	return f, err
+}
+
+func (s *FSBlobStore) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// path переводит ключ в путь внутри Root. Ключ чистится как абсолютный путь,
+// поэтому ".." не выводит за пределы Root.
+func (s *FSBlobStore) path(key string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash("/" + key))
+	if clean == string(filepath.Separator) {
+		return "", errors.New("недопустимый ключ объекта")
+	}
+	return filepath.Join(s.Root, clean), nil
+}
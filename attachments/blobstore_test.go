@@ -0,0 +1,83 @@
+package attachments
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFSBlobStoreKeysStayUnderRoot(t *testing.T) {
+	ctx := context.Background()
+	root := filepath.Join(t.TempDir(), "blobs")
+	s, err := NewFSBlobStore(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		key  string
+		path string
+	}{
+		{"users/1/a", "users/1/a"},
+		{"../escape", "escape"},
+		{"users/../../../escape2", "escape2"},
+		{"/abs/key", "abs/key"},
+		{"users/1/./b/../c", "users/1/c"},
+	}
+	for _, tt := range tests {
+		if err := s.Put(ctx, tt.key, strings.NewReader(tt.key), -1, "text/plain"); err != nil {
+			t.Fatalf("Put(%q): %v", tt.key, err)
+		}
+		data, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(tt.path)))
+		if err != nil || string(data) != tt.key {
+			t.Errorf("ключ %q записан не в %s: %q, %v", tt.key, tt.path, data, err)
+		}
+	}
+
+	// Ничего не записано рядом с Root
+	entries, err := os.ReadDir(filepath.Dir(root))
+	if err != nil || len(entries) != 1 {
+		t.Errorf("за пределами Root появились файлы: %v, %v", entries, err)
+	}
+
+	for _, key := range []string{"", "/", "..", "a/.."} {
+		if err := s.Put(ctx, key, strings.NewReader("x"), -1, "text/plain"); err == nil {
+			t.Errorf("ключ %q, указывающий на сам Root, принят", key)
+		}
+	}
+}
+
+func TestFSBlobStoreOpenDelete(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewFSBlobStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Put(ctx, "a/b", strings.NewReader("содержимое"), -1, "text/plain"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	f, err := s.Open(ctx, "a/b")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	data, _ := io.ReadAll(f)
+	f.Close()
+	if string(data) != "содержимое" {
+		t.Errorf("прочитано %q", data)
+	}
+
+	if err := s.Delete(ctx, "a/b"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := s.Open(ctx, "a/b"); !errors.Is(err, ErrBlobNotFound) {
+		t.Errorf("после удаления Open: %v", err)
+	}
+	if err := s.Delete(ctx, "a/b"); err != nil {
+		t.Errorf("повторное удаление: %v", err)
+	}
+}
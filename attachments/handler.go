@@ -0,0 +1,262 @@
+package attachments
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"notes-api/httpx"
+	"notes-api/logger"
+	"notes-api/problem"
+	"strconv"
+)
+
+const (
+	// multipartOverhead — запас на заголовки и границы multipart сверх размера файла
+	multipartOverhead = 1 << 20
+
+	defaultThumbnailSize = 256
+)
+
+type AttachmentHandler struct {
+	Service *AttachmentService
+}
+
+// Upload godoc
+// @Summary Прикрепить файл к заметке
+// @Description Файл передаётся в поле file и сохраняется потоково, без буферизации в памяти. Тип определяется по содержимому, а не по заголовку Content-Type; из JPEG и PNG удаляются метаданные (EXIF и т. п.). Для изображений JPEG, PNG и GIF в фоне строятся превью
+// @Tags attachments
+// @Security ApiKeyAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path int true "ID заметки"
+// @Param file formData file true "Файл"
+// @Success 201 {object} model.Attachment "Созданное вложение"
+// @Failure 400 {object} problem.Problem "Неверный запрос"
+// @Failure 403 {object} problem.Problem "Доступ запрещен"
+// @Failure 404 {object} problem.Problem "Заметка не найдена"
+// @Failure 413 {object} problem.Problem "Файл слишком большой"
+// @Failure 415 {object} problem.Problem "Недопустимый тип файла"
+// @Router /notes/{id}/attachments [post]
+func (h *AttachmentHandler) Upload(w http.ResponseWriter, r *http.Request) {
+	userID, ok := httpx.UserID(w, r)
+	if !ok {
+		return
+	}
+	noteID, ok := httpx.PathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, h.Service.Limits.MaxSize+multipartOverhead)
+	reader, err := r.MultipartReader()
+	if err != nil {
+		logger.FromContext(r.Context()).WithError(err).Warn("Неверный запрос: ожидается multipart/form-data")
+		problem.Error(w, r, problem.Invalid(problem.Field("file", problem.FieldRequired)))
+		return
+	}
+
+	// Идём по частям формы до поля file и передаём его в хранилище, не читая целиком
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			logger.FromContext(r.Context()).WithError(err).Warn("Ошибка при чтении multipart")
+			problem.Write(w, r, problem.BadRequest)
+			return
+		}
+		if part.FormName() != "file" {
+			part.Close()
+			continue
+		}
+
+		attachment, err := h.Service.Upload(r.Context(), userID, noteID, part.FileName(), part.Header.Get("Content-Type"), part)
+		part.Close()
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				err = ErrTooLarge
+			}
+			problem.Error(w, r, err)
+			return
+		}
+
+		httpx.WriteJSON(w, http.StatusCreated, attachment)
+		logger.FromContext(r.Context()).WithFields(logger.Fields{
+			"user_id":       userID,
+			"note_id":       noteID,
+			"attachment_id": attachment.ID,
+			"size":          attachment.Size,
+		}).Info("Вложение загружено")
+		return
+	}
+
+	problem.Error(w, r, problem.Invalid(problem.Field("file", problem.FieldRequired)))
+}
+
+// List godoc
+// @Summary Получить вложения заметки
+// @Tags attachments
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path int true "ID заметки"
+// @Success 200 {array} model.Attachment "Вложения"
+// @Failure 403 {object} problem.Problem "Доступ запрещен"
+// @Failure 404 {object} problem.Problem "Заметка не найдена"
+// @Router /notes/{id}/attachments [get]
+func (h *AttachmentHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := httpx.UserID(w, r)
+	if !ok {
+		return
+	}
+	noteID, ok := httpx.PathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	attachments, err := h.Service.List(userID, noteID)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, attachments)
+}
+
+// Download godoc
+// @Summary Скачать вложение
+// @Description Поддерживает Range-запросы и условные запросы по ETag
+// @Tags attachments
+// @Security ApiKeyAuth
+// @Produce octet-stream
+// @Param id path int true "ID заметки"
+// @Param attachment_id path int true "ID вложения"
+// @Param Range header string false "Диапазон байт, например bytes=0-1023"
+// @Success 200 {file} file "Содержимое файла"
+// @Success 206 {file} file "Запрошенный диапазон"
+// @Failure 403 {object} problem.Problem "Доступ запрещен"
+// @Failure 404 {object} problem.Problem "Заметка или вложение не найдены"
+// @Router /notes/{id}/attachments/{attachment_id} [get]
+func (h *AttachmentHandler) Download(w http.ResponseWriter, r *http.Request) {
+	userID, ok := httpx.UserID(w, r)
+	if !ok {
+		return
+	}
+	noteID, ok := httpx.PathID(w, r, "id")
+	if !ok {
+		return
+	}
+	id, ok := httpx.PathID(w, r, "attachment_id")
+	if !ok {
+		return
+	}
+
+	attachment, content, err := h.Service.Open(r.Context(), userID, noteID, id)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+	defer content.Close()
+
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.FileName}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("ETag", `"`+attachment.SHA256+`"`)
+	w.Header().Set("Cache-Control", "private, max-age=0, must-revalidate")
+
+	// ServeContent сам разбирает Range, If-Range и If-None-Match
+	http.ServeContent(w, r, attachment.FileName, attachment.CreatedAt, content)
+}
+
+// Thumbnail godoc
+// @Summary Превью изображения
+// @Description Превью строятся фоновой задачей после загрузки (64, 256 и 1024 пикселя по длинной стороне); возвращается наименьшее не меньше size. Пока превью строятся, thumbnail_status вложения равен pending, а запрос возвращает 404 с заголовком Retry-After
+// @Tags attachments
+// @Security ApiKeyAuth
+// @Produce image/jpeg,image/png
+// @Param id path int true "ID заметки"
+// @Param attachment_id path int true "ID вложения"
+// @Param size query int false "Желаемый размер в пикселях (по умолчанию 256)"
+// @Success 200 {file} file "Превью"
+// @Success 304 {string} string "Превью не изменилось"
+// @Failure 403 {object} problem.Problem "Доступ запрещен"
+// @Failure 404 {object} problem.Problem "Заметка, вложение или превью не найдены"
+// @Router /notes/{id}/attachments/{attachment_id}/thumbnail [get]
+func (h *AttachmentHandler) Thumbnail(w http.ResponseWriter, r *http.Request) {
+	userID, ok := httpx.UserID(w, r)
+	if !ok {
+		return
+	}
+	noteID, ok := httpx.PathID(w, r, "id")
+	if !ok {
+		return
+	}
+	id, ok := httpx.PathID(w, r, "attachment_id")
+	if !ok {
+		return
+	}
+
+	size := defaultThumbnailSize
+	if n, err := strconv.Atoi(r.URL.Query().Get("size")); err == nil && n > 0 {
+		size = n
+	}
+
+	attachment, chosen, content, err := h.Service.OpenThumbnail(r.Context(), userID, noteID, id, size)
+	if errors.Is(err, ErrThumbnailNotReady) {
+		w.Header().Set("Retry-After", "2")
+	}
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+	defer content.Close()
+
+	// Вложения не меняются, поэтому превью можно кешировать надолго
+	w.Header().Set("Content-Type", thumbnailContentType(attachment.ContentType))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("ETag", fmt.Sprintf(`"%s-%d"`, attachment.SHA256, chosen))
+	w.Header().Set("Cache-Control", "private, max-age=31536000, immutable")
+
+	http.ServeContent(w, r, "", attachment.CreatedAt, content)
+}
+
+// Delete godoc
+// @Summary Удалить вложение
+// @Tags attachments
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path int true "ID заметки"
+// @Param attachment_id path int true "ID вложения"
+// @Success 200 {object} map[string]string "Сообщение об удалении"
+// @Failure 403 {object} problem.Problem "Доступ запрещен"
+// @Failure 404 {object} problem.Problem "Заметка или вложение не найдены"
+// @Router /notes/{id}/attachments/{attachment_id} [delete]
+func (h *AttachmentHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	userID, ok := httpx.UserID(w, r)
+	if !ok {
+		return
+	}
+	noteID, ok := httpx.PathID(w, r, "id")
+	if !ok {
+		return
+	}
+	id, ok := httpx.PathID(w, r, "attachment_id")
+	if !ok {
+		return
+	}
+
+	if err := h.Service.Delete(r.Context(), userID, noteID, id); err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, map[string]string{"message": "Вложение удалено"})
+	logger.FromContext(r.Context()).WithFields(logger.Fields{
+		"user_id":       userID,
+		"note_id":       noteID,
+		"attachment_id": id,
+	}).Info("Вложение удалено")
+}
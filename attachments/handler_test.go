@@ -0,0 +1,194 @@
+package attachments
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"notes-api/db/dbtest"
+	_ "notes-api/encryption"
+	"notes-api/jobs"
+	"notes-api/middleware"
+	"notes-api/model"
+	storage "notes-api/repo"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+const testContent = "0123456789abcdef"
+
+type attachmentFixture struct {
+	service    *AttachmentService
+	owner      uint
+	other      uint
+	note       model.Note
+	attachment model.Attachment
+}
+
+func newAttachmentFixture(t *testing.T) *attachmentFixture {
+	t.Helper()
+	db := dbtest.Open(t, &model.User{}, &model.Note{}, &model.Attachment{}, &model.Job{})
+	owner := model.User{Email: "owner@example.com"}
+	other := model.User{Email: "other@example.com"}
+	for _, user := range []*model.User{&owner, &other} {
+		if err := db.Create(user).Error; err != nil {
+			t.Fatalf("создать пользователя: %v", err)
+		}
+	}
+	note := model.Note{UserID: owner.ID, Title: "заметка"}
+	if err := db.Create(&note).Error; err != nil {
+		t.Fatalf("создать заметку: %v", err)
+	}
+
+	blobs, err := NewFSBlobStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewAttachmentService(db, blobs, jobs.NewQueue(db), DefaultLimits)
+	attachment, err := s.Upload(context.Background(), owner.ID, note.ID, "файл.txt", "text/plain", strings.NewReader(testContent))
+	if err != nil {
+		t.Fatalf("Upload: %v", err)
+	}
+	return &attachmentFixture{service: s, owner: owner.ID, other: other.ID, note: note, attachment: attachment}
+}
+
+// download выполняет GET вложения от имени userID с заголовком Range
+func (f *attachmentFixture) download(userID uint, rangeHeader string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	if rangeHeader != "" {
+		r.Header.Set("Range", rangeHeader)
+	}
+	r = mux.SetURLVars(r, map[string]string{
+		"id":            strconv.Itoa(int(f.note.ID)),
+		"attachment_id": strconv.Itoa(int(f.attachment.ID)),
+	})
+	r = r.WithContext(context.WithValue(r.Context(), middleware.UserIDKey, userID))
+
+	w := httptest.NewRecorder()
+	(&AttachmentHandler{Service: f.service}).Download(w, r)
+	return w
+}
+
+func TestDownloadRanges(t *testing.T) {
+	f := newAttachmentFixture(t)
+
+	tests := []struct {
+		name   string
+		header string
+		status int
+		// body — ожидаемое тело; для 416 — ожидаемый Content-Range
+		body string
+	}{
+		{"целиком", "", http.StatusOK, testContent},
+		{"начало", "bytes=0-3", http.StatusPartialContent, "0123"},
+		{"середина", "bytes=10-12", http.StatusPartialContent, "abc"},
+		{"хвост", "bytes=-4", http.StatusPartialContent, "cdef"},
+		{"до конца", "bytes=12-", http.StatusPartialContent, "cdef"},
+		{"за концом файла", "bytes=100-200", http.StatusRequestedRangeNotSatisfiable, "bytes */16"},
+		{"начало после конца", "bytes=5-2", http.StatusRequestedRangeNotSatisfiable, ""},
+	}
+	for _, tt := range tests {
+		w := f.download(f.owner, tt.header)
+		if w.Code != tt.status {
+			t.Errorf("%s: статус %d, ожидался %d", tt.name, w.Code, tt.status)
+			continue
+		}
+		if tt.status == http.StatusRequestedRangeNotSatisfiable {
+			if strings.Contains(w.Body.String(), testContent[:4]) {
+				t.Errorf("%s: в ответе 416 есть содержимое файла: %q", tt.name, w.Body.String())
+			}
+			if got := w.Header().Get("Content-Range"); got != tt.body {
+				t.Errorf("%s: Content-Range %q, ожидался %q", tt.name, got, tt.body)
+			}
+			continue
+		}
+		if w.Body.String() != tt.body {
+			t.Errorf("%s: тело %q, ожидалось %q", tt.name, w.Body.String(), tt.body)
+		}
+		if w.Header().Get("ETag") != `"`+f.attachment.SHA256+`"` {
+			t.Errorf("%s: ETag %q", tt.name, w.Header().Get("ETag"))
+		}
+	}
+}
+
+func TestDownloadMultipleRanges(t *testing.T) {
+	f := newAttachmentFixture(t)
+
+	w := f.download(f.owner, "bytes=0-1,4-5,-2")
+	if w.Code != http.StatusPartialContent {
+		t.Fatalf("статус %d", w.Code)
+	}
+	mediaType, params, err := mime.ParseMediaType(w.Header().Get("Content-Type"))
+	if err != nil || mediaType != "multipart/byteranges" {
+		t.Fatalf("Content-Type %q: %v", w.Header().Get("Content-Type"), err)
+	}
+
+	want := []struct{ contentRange, body string }{
+		{"bytes 0-1/16", "01"},
+		{"bytes 4-5/16", "45"},
+		{"bytes 14-15/16", "ef"},
+	}
+	mr := multipart.NewReader(w.Body, params["boundary"])
+	for i := 0; ; i++ {
+		part, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			if i != len(want) {
+				t.Errorf("%d частей, ожидалось %d", i, len(want))
+			}
+			break
+		}
+		if err != nil {
+			t.Fatalf("часть %d: %v", i, err)
+		}
+		if i >= len(want) {
+			t.Fatalf("лишняя часть %q", part.Header.Get("Content-Range"))
+		}
+		body, _ := io.ReadAll(part)
+		if part.Header.Get("Content-Range") != want[i].contentRange || string(body) != want[i].body {
+			t.Errorf("часть %d: %q %q, ожидалась %q %q", i, part.Header.Get("Content-Range"), body, want[i].contentRange, want[i].body)
+		}
+	}
+}
+
+func TestAttachmentOwnerCheck(t *testing.T) {
+	ctx := context.Background()
+	f := newAttachmentFixture(t)
+	noteID, id := f.note.ID, f.attachment.ID
+
+	if _, _, err := f.service.Open(ctx, f.other, noteID, id); !errors.Is(err, storage.ErrForbidden) {
+		t.Errorf("Open чужого вложения: %v", err)
+	}
+	if err := f.service.Delete(ctx, f.other, noteID, id); !errors.Is(err, storage.ErrForbidden) {
+		t.Errorf("Delete чужого вложения: %v", err)
+	}
+	if _, err := f.service.Upload(ctx, f.other, noteID, "x.txt", "text/plain", strings.NewReader("x")); !errors.Is(err, storage.ErrForbidden) {
+		t.Errorf("Upload в чужую заметку: %v", err)
+	}
+
+	// Вложение другой заметки по ID не находится, даже если заметка своя
+	own := model.Note{UserID: f.other, Title: "своя"}
+	if err := f.service.DB.Create(&own).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := f.service.Open(ctx, f.other, own.ID, id); !errors.Is(err, ErrAttachmentNotFound) {
+		t.Errorf("Open вложения через свою заметку: %v", err)
+	}
+
+	// Отказ ничего не удалил: владелец по-прежнему скачивает файл
+	if w := f.download(f.owner, ""); w.Code != http.StatusOK || w.Body.String() != testContent {
+		t.Errorf("после чужих попыток: статус %d, тело %q", w.Code, w.Body.String())
+	}
+
+	if err := f.service.Delete(ctx, f.owner, noteID, id); err != nil {
+		t.Fatalf("Delete владельцем: %v", err)
+	}
+	if _, _, err := f.service.Open(ctx, f.owner, noteID, id); !errors.Is(err, ErrAttachmentNotFound) {
+		t.Errorf("после удаления Open: %v", err)
+	}
+}
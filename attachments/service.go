@@ -0,0 +1,318 @@
+package attachments
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"notes-api/events"
+	"notes-api/jobs"
+	"notes-api/logger"
+	"notes-api/model"
+	storage "notes-api/repo"
+	"path"
+	"strings"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// DeleteNoteJobType — задача удаления вложений удалённой заметки
+	DeleteNoteJobType = "attachments.delete_note"
+
+	maxFileNameLength = 255
+	defaultMaxSize    = 25 << 20
+)
+
+var (
+	ErrAttachmentNotFound = errors.New("вложение не найдено")
+	ErrTooLarge           = errors.New("файл слишком большой")
+	ErrTypeNotAllowed     = errors.New("недопустимый тип файла")
+)
+
+// Limits — ограничения на загружаемые файлы
+type Limits struct {
+	// MaxSize — наибольший размер файла в байтах
+	MaxSize int64
+	// AllowedTypes — разрешённые MIME-типы; "image/*" разрешает все подтипы
+	AllowedTypes []string
+}
+
+// DefaultLimits — ограничения по умолчанию
+var DefaultLimits = Limits{
+	MaxSize: defaultMaxSize,
+	AllowedTypes: []string{
+		"image/*",
+		"text/plain",
+		"text/markdown",
+		"text/csv",
+		"application/pdf",
+		"application/zip",
+		"application/json",
+		"application/octet-stream",
+	},
+}
+
+// Allowed сообщает, разрешён ли тип contentType
+func (l Limits) Allowed(contentType string) bool {
+	for _, allowed := range l.AllowedTypes {
+		if allowed == contentType {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(allowed, "/*"); ok && strings.HasPrefix(contentType, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+type AttachmentService struct {
+	DB     *gorm.DB
+	Blobs  BlobStore
+	Queue  *jobs.Queue
+	Limits Limits
+}
+
+func NewAttachmentService(db *gorm.DB, blobs BlobStore, q *jobs.Queue, limits Limits) *AttachmentService {
+	return &AttachmentService{DB: db, Blobs: blobs, Queue: q, Limits: limits}
+}
+
+type deleteNotePayload struct {
+	NoteID uint `json:"note_id"`
+}
+
+// Upload потоково сохраняет файл в хранилище и записывает метаданные вложения.
+// Тип файла определяется по содержимому, у JPEG и PNG удаляются метаданные,
+// а превью изображений строятся фоновой задачей.
+func (s *AttachmentService) Upload(ctx context.Context, userID, noteID uint, fileName, contentType string, r io.Reader) (model.Attachment, error) {
+	if err := storage.CheckNoteOwner(s.DB, userID, noteID); err != nil {
+		return model.Attachment{}, err
+	}
+
+	input := bufio.NewReaderSize(&limitedReader{r: r, remaining: s.Limits.MaxSize}, sniffLength)
+	head, err := input.Peek(sniffLength)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return model.Attachment{}, err
+	}
+	contentType = sniffContentType(head, normalizeContentType(contentType))
+	if !s.Limits.Allowed(contentType) {
+		return model.Attachment{}, fmt.Errorf("%w: %s", ErrTypeNotAllowed, contentType)
+	}
+
+	body, err := stripMetadata(contentType, input)
+	if err != nil {
+		return model.Attachment{}, err
+	}
+
+	attachment := model.Attachment{
+		NoteID:      noteID,
+		UserID:      userID,
+		FileName:    cleanFileName(fileName),
+		ContentType: contentType,
+		StorageKey:  fmt.Sprintf("users/%d/notes/%d/%s", userID, noteID, randomHex(16)),
+	}
+	if thumbnailable(contentType) {
+		attachment.ThumbnailStatus = model.ThumbnailsPending
+	}
+
+	hash := sha256.New()
+	counter := &countingReader{r: io.TeeReader(body, hash)}
+	if err := s.Blobs.Put(ctx, attachment.StorageKey, counter, -1, contentType); err != nil {
+		// Хранилище может успеть сохранить часть объекта
+		s.deleteBlob(attachment.StorageKey)
+		if errors.Is(err, ErrTooLarge) {
+			return model.Attachment{}, ErrTooLarge
+		}
+		return model.Attachment{}, err
+	}
+	attachment.Size = counter.read
+	attachment.SHA256 = hex.EncodeToString(hash.Sum(nil))
+
+	if err := s.DB.WithContext(ctx).Create(&attachment).Error; err != nil {
+		s.deleteBlob(attachment.StorageKey)
+		return model.Attachment{}, err
+	}
+
+	if attachment.ThumbnailStatus == model.ThumbnailsPending {
+		s.enqueueThumbnails(ctx, attachment)
+	}
+	return attachment, nil
+}
+
+// List возвращает вложения заметки
+func (s *AttachmentService) List(userID, noteID uint) ([]model.Attachment, error) {
+	if err := storage.CheckNoteOwner(s.DB, userID, noteID); err != nil {
+		return nil, err
+	}
+
+	var attachments []model.Attachment
+	err := s.DB.Where("note_id = ?", noteID).Order("id").Find(&attachments).Error
+	return attachments, err
+}
+
+func (s *AttachmentService) Get(userID, noteID, id uint) (model.Attachment, error) {
+	if err := storage.CheckNoteOwner(s.DB, userID, noteID); err != nil {
+		return model.Attachment{}, err
+	}
+
+	var attachment model.Attachment
+	if err := s.DB.Where("id = ? AND note_id = ?", id, noteID).First(&attachment).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.Attachment{}, ErrAttachmentNotFound
+		}
+		return model.Attachment{}, err
+	}
+	return attachment, nil
+}
+
+// Open возвращает вложение и поток его содержимого; поток нужно закрыть
+func (s *AttachmentService) Open(ctx context.Context, userID, noteID, id uint) (model.Attachment, io.ReadSeekCloser, error) {
+	attachment, err := s.Get(userID, noteID, id)
+	if err != nil {
+		return model.Attachment{}, nil, err
+	}
+
+	content, err := s.Blobs.Open(ctx, attachment.StorageKey)
+	if errors.Is(err, ErrBlobNotFound) {
+		return model.Attachment{}, nil, ErrAttachmentNotFound
+	}
+	if err != nil {
+		return model.Attachment{}, nil, err
+	}
+	return attachment, content, nil
+}
+
+func (s *AttachmentService) Delete(ctx context.Context, userID, noteID, id uint) error {
+	attachment, err := s.Get(userID, noteID, id)
+	if err != nil {
+		return err
+	}
+	if err := s.DB.WithContext(ctx).Delete(&attachment).Error; err != nil {
+		return err
+	}
+	// Если удалить файл не вышло, в хранилище останется сирота, но вложение уже недоступно
+	for _, key := range blobKeys(attachment) {
+		s.deleteBlob(key)
+	}
+	return nil
+}
+
+// Publish реализует events.Publisher: после удаления заметки её вложения удаляются фоновой задачей
+func (s *AttachmentService) Publish(e events.Event) {
+	if e.Type != events.NoteDeleted {
+		return
+	}
+
+	_, err := s.Queue.Enqueue(context.Background(), DeleteNoteJobType, deleteNotePayload{NoteID: e.Note.ID}, jobs.Options{
+		UserID:    e.UserID,
+		UniqueKey: fmt.Sprintf("%s:%d", DeleteNoteJobType, e.Note.ID),
+	})
+	if err != nil {
+		logger.Log.WithError(err).WithField("note_id", e.Note.ID).Error("Не удалось поставить удаление вложений в очередь")
+	}
+}
+
+// HandleDeleteNoteJob удаляет файлы и метаданные вложений удалённой заметки.
+// Метаданные вложения удаляются только после файла, поэтому повтор задачи доделает начатое.
+func (s *AttachmentService) HandleDeleteNoteJob(ctx context.Context, job *model.Job) error {
+	var p deleteNotePayload
+	if err := json.Unmarshal(job.Payload, &p); err != nil {
+		return jobs.Permanent(err)
+	}
+
+	var attachments []model.Attachment
+	if err := s.DB.WithContext(ctx).Where("note_id = ?", p.NoteID).Find(&attachments).Error; err != nil {
+		return err
+	}
+
+	for _, attachment := range attachments {
+		for _, key := range blobKeys(attachment) {
+			if err := s.Blobs.Delete(ctx, key); err != nil {
+				return err
+			}
+		}
+		if err := s.DB.WithContext(ctx).Delete(&attachment).Error; err != nil {
+			return err
+		}
+	}
+
+	logger.Log.WithFields(logger.Fields{
+		"note_id": p.NoteID,
+		"count":   len(attachments),
+	}).Info("Вложения удалённой заметки удалены")
+	return nil
+}
+
+func (s *AttachmentService) deleteBlob(key string) {
+	if err := s.Blobs.Delete(context.Background(), key); err != nil {
+		logger.Log.WithError(err).WithField("key", key).Warn("Не удалось удалить файл вложения из хранилища")
+	}
+}
+
+// countingReader считает прочитанные байты
+type countingReader struct {
+	r    io.Reader
+	read int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.read += int64(n)
+	return n, err
+}
+
+// limitedReader обрывает чтение ошибкой ErrTooLarge при превышении лимита
+type limitedReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.remaining < 0 {
+		return 0, ErrTooLarge
+	}
+	// Читаем на байт больше лимита, чтобы отличить файл ровно в лимит от превышения
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		return n, ErrTooLarge
+	}
+	return n, err
+}
+
+func normalizeContentType(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType == "" {
+		return "application/octet-stream"
+	}
+	return mediaType
+}
+
+// cleanFileName оставляет от имени файла только последний компонент пути
+func cleanFileName(name string) string {
+	name = strings.TrimSpace(path.Base(strings.ReplaceAll(name, "\\", "/")))
+	if name == "" || name == "." || name == "/" {
+		return "file"
+	}
+	for len(name) > maxFileNameLength {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	return name
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,293 @@
+package attachments
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// sniffLength — сколько первых байт смотрит http.DetectContentType
+const sniffLength = 512
+
+// maxMetadataHeader — сколько байт заголовка изображения (до начала данных) разбирается при
+// удалении метаданных; в настоящих файлах заголовок без EXIF занимает несколько килобайт
+const maxMetadataHeader = 4 << 20
+
+// ErrBadImage — файл заявлен изображением, но не разбирается как изображение
+var ErrBadImage = errors.New("повреждённый файл изображения")
+
+// textRefinements — уточнения для text/plain: по содержимому их не отличить,
+// поэтому заявленный клиентом тип принимается, если содержимое текстовое
+var textRefinements = map[string]bool{
+	"text/markdown":    true,
+	"text/csv":         true,
+	"application/json": true,
+}
+
+// sniffContentType определяет тип по содержимому. Заголовку клиента доверяем,
+// только когда он уточняет текстовый тип.
+func sniffContentType(head []byte, declared string) string {
+	sniffed := normalizeContentType(http.DetectContentType(head))
+	if sniffed == "text/plain" && textRefinements[declared] {
+		return declared
+	}
+	return sniffed
+}
+
+// stripMetadata убирает из JPEG и PNG метаданные (EXIF с координатами съёмки,
+// моделью камеры и т. п.). Изображение не перекодируется: выбрасываются только
+// служебные блоки до начала графических данных, остальное передаётся как есть.
+func stripMetadata(contentType string, r *bufio.Reader) (io.Reader, error) {
+	switch contentType {
+	case "image/jpeg":
+		return stripJPEGMetadata(r)
+	case "image/png":
+		return stripPNGMetadata(r)
+	default:
+		return r, nil
+	}
+}
+
+// JPEG-маркеры сегментов, которые выбрасываются: APP1 (EXIF, XMP), APP13 (IPTC) и комментарий.
+// APP0 (JFIF), APP2 (ICC-профиль) и APP14 (Adobe) нужны для правильного отображения цветов.
+// Из EXIF сохраняется только ориентация: без неё снимки с телефонов показываются повёрнутыми.
+var jpegDroppedMarkers = map[byte]bool{
+	jpegAPP1: true,
+	0xED:     true,
+	0xFE:     true,
+}
+
+const (
+	jpegAPP1 = 0xE1
+	jpegSOS  = 0xDA
+)
+
+func stripJPEGMetadata(r *bufio.Reader) (io.Reader, error) {
+	var header bytes.Buffer
+	soi := make([]byte, 2)
+	if _, err := io.ReadFull(r, soi); err != nil || soi[0] != 0xFF || soi[1] != 0xD8 {
+		return nil, ErrBadImage
+	}
+	header.Write(soi)
+
+	scanned, orientationKept := 0, false
+	for scanned < maxMetadataHeader {
+		marker := make([]byte, 2)
+		if _, err := io.ReadFull(r, marker); err != nil {
+			return nil, badImage(err)
+		}
+		if marker[0] != 0xFF {
+			return nil, ErrBadImage
+		}
+		// Маркеры могут предваряться заполнителем 0xFF
+		for marker[1] == 0xFF {
+			b, err := r.ReadByte()
+			if err != nil {
+				return nil, badImage(err)
+			}
+			marker[1] = b
+		}
+
+		// После SOS идут сжатые данные — дальше копируем без разбора
+		if marker[1] == jpegSOS {
+			header.Write(marker)
+			return io.MultiReader(&header, r), nil
+		}
+
+		lengthBytes := make([]byte, 2)
+		if _, err := io.ReadFull(r, lengthBytes); err != nil {
+			return nil, badImage(err)
+		}
+		length := int(binary.BigEndian.Uint16(lengthBytes))
+		if length < 2 {
+			return nil, ErrBadImage
+		}
+		scanned += length + 2
+
+		if marker[1] == jpegAPP1 && !orientationKept {
+			segment := make([]byte, length-2)
+			if _, err := io.ReadFull(r, segment); err != nil {
+				return nil, badImage(err)
+			}
+			if orientation := exifOrientation(segment); orientation > 1 {
+				header.Write(orientationSegment(orientation))
+				orientationKept = true
+			}
+			continue
+		}
+		if jpegDroppedMarkers[marker[1]] {
+			if _, err := r.Discard(length - 2); err != nil {
+				return nil, badImage(err)
+			}
+			continue
+		}
+		header.Write(marker)
+		header.Write(lengthBytes)
+		if _, err := io.CopyN(&header, r, int64(length-2)); err != nil {
+			return nil, badImage(err)
+		}
+	}
+	return nil, ErrBadImage
+}
+
+var exifHeader = []byte("Exif\x00\x00")
+
+// exifOrientationTag — тег Orientation (0x0112) в IFD0
+const exifOrientationTag = 0x0112
+
+// exifOrientation возвращает ориентацию (1–8) из содержимого сегмента APP1
+// или 0, если это не EXIF или тега нет. Разбирается только IFD0.
+func exifOrientation(segment []byte) int {
+	tiff, ok := bytes.CutPrefix(segment, exifHeader)
+	if !ok || len(tiff) < 8 {
+		return 0
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:4]) {
+	case "II*\x00":
+		order = binary.LittleEndian
+	case "MM\x00*":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+
+	ifd := int64(order.Uint32(tiff[4:8]))
+	if ifd < 8 || ifd+2 > int64(len(tiff)) {
+		return 0
+	}
+	count := int64(order.Uint16(tiff[ifd:]))
+	for i := int64(0); i < count; i++ {
+		// Запись IFD: тег (2 байта), тип (2), число значений (4), значение или смещение (4)
+		entry := ifd + 2 + i*12
+		if entry+12 > int64(len(tiff)) {
+			return 0
+		}
+		if order.Uint16(tiff[entry:]) != exifOrientationTag {
+			continue
+		}
+		// Ориентация — одно значение SHORT, оно лежит прямо в записи
+		if order.Uint16(tiff[entry+2:]) != 3 || order.Uint32(tiff[entry+4:]) != 1 {
+			return 0
+		}
+		if v := int(order.Uint16(tiff[entry+8:])); v >= 1 && v <= 8 {
+			return v
+		}
+		return 0
+	}
+	return 0
+}
+
+// orientationSegment собирает сегмент APP1 с EXIF, в котором есть только тег ориентации
+func orientationSegment(orientation int) []byte {
+	tiff := []byte{
+		'M', 'M', 0x00, 0x2A, // порядок байт big-endian и магическое число TIFF
+		0x00, 0x00, 0x00, 0x08, // смещение IFD0
+		0x00, 0x01, // одна запись
+		0x01, 0x12, 0x00, 0x03, 0x00, 0x00, 0x00, 0x01, // Orientation, SHORT, одно значение
+		0x00, byte(orientation), 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, // следующего IFD нет
+	}
+	segment := []byte{0xFF, jpegAPP1, 0, 0}
+	segment = append(segment, exifHeader...)
+	segment = append(segment, tiff...)
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(segment)-2))
+	return segment
+}
+
+// jpegOrientation читает ориентацию из заголовка JPEG, не дальше начала сжатых данных.
+// Ошибки разбора означают ориентацию по умолчанию: превью всё равно строится.
+func jpegOrientation(r io.Reader) int {
+	br := bufio.NewReader(io.LimitReader(r, maxMetadataHeader))
+	soi := make([]byte, 2)
+	if _, err := io.ReadFull(br, soi); err != nil || soi[0] != 0xFF || soi[1] != 0xD8 {
+		return 0
+	}
+	for {
+		marker := make([]byte, 4)
+		if _, err := io.ReadFull(br, marker); err != nil || marker[0] != 0xFF || marker[1] == jpegSOS {
+			return 0
+		}
+		length := int(binary.BigEndian.Uint16(marker[2:]))
+		if length < 2 {
+			return 0
+		}
+		if marker[1] != jpegAPP1 {
+			if _, err := br.Discard(length - 2); err != nil {
+				return 0
+			}
+			continue
+		}
+		segment := make([]byte, length-2)
+		if _, err := io.ReadFull(br, segment); err != nil {
+			return 0
+		}
+		if orientation := exifOrientation(segment); orientation > 0 {
+			return orientation
+		}
+	}
+}
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// Служебные блоки PNG с метаданными: EXIF, текстовые поля и время изменения
+var pngDroppedChunks = map[string]bool{
+	"eXIf": true,
+	"tEXt": true,
+	"iTXt": true,
+	"zTXt": true,
+	"tIME": true,
+}
+
+func stripPNGMetadata(r *bufio.Reader) (io.Reader, error) {
+	var header bytes.Buffer
+	signature := make([]byte, len(pngSignature))
+	if _, err := io.ReadFull(r, signature); err != nil || !bytes.Equal(signature, pngSignature) {
+		return nil, ErrBadImage
+	}
+	header.Write(signature)
+
+	scanned := 0
+	for scanned < maxMetadataHeader {
+		// Блок: длина данных (4 байта), тип (4 байта), данные, CRC (4 байта)
+		chunkHeader := make([]byte, 8)
+		if _, err := io.ReadFull(r, chunkHeader); err != nil {
+			return nil, badImage(err)
+		}
+		length := int64(binary.BigEndian.Uint32(chunkHeader[:4]))
+		chunkType := string(chunkHeader[4:])
+
+		if chunkType == "IDAT" {
+			header.Write(chunkHeader)
+			return io.MultiReader(&header, r), nil
+		}
+		if length > maxMetadataHeader {
+			return nil, ErrBadImage
+		}
+		scanned += int(length) + 12
+
+		if pngDroppedChunks[chunkType] {
+			if _, err := r.Discard(int(length) + 4); err != nil {
+				return nil, badImage(err)
+			}
+			continue
+		}
+		header.Write(chunkHeader)
+		if _, err := io.CopyN(&header, r, length+4); err != nil {
+			return nil, badImage(err)
+		}
+	}
+	return nil, ErrBadImage
+}
+
+// badImage сохраняет ErrTooLarge от ограничителя размера, остальные ошибки чтения означают битый файл
+func badImage(err error) error {
+	if errors.Is(err, ErrTooLarge) {
+		return err
+	}
+	return ErrBadImage
+}
@@ -0,0 +1,300 @@
+package attachments
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"testing"
+)
+
+func testImage(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.SetRGBA(x, y, color.RGBA{R: uint8(x * 40), G: uint8(y * 40), B: 100, A: 255})
+		}
+	}
+	return img
+}
+
+func jpegSegment(marker byte, data []byte) []byte {
+	segment := []byte{0xFF, marker, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(data)+2))
+	return append(segment, data...)
+}
+
+// exifSegment — EXIF в порядке байт little-endian с моделью камеры и ориентацией
+func exifSegment(orientation uint16) []byte {
+	tiff := []byte("II*\x00")
+	tiff = binary.LittleEndian.AppendUint32(tiff, 8)
+	tiff = binary.LittleEndian.AppendUint16(tiff, 2)
+	// Make, ASCII, 4 байта "GPS\0" прямо в записи
+	tiff = binary.LittleEndian.AppendUint16(tiff, 0x010F)
+	tiff = binary.LittleEndian.AppendUint16(tiff, 2)
+	tiff = binary.LittleEndian.AppendUint32(tiff, 4)
+	tiff = append(tiff, "GPS\x00"...)
+	// Orientation, SHORT
+	tiff = binary.LittleEndian.AppendUint16(tiff, exifOrientationTag)
+	tiff = binary.LittleEndian.AppendUint16(tiff, 3)
+	tiff = binary.LittleEndian.AppendUint32(tiff, 1)
+	tiff = binary.LittleEndian.AppendUint16(tiff, orientation)
+	tiff = binary.LittleEndian.AppendUint16(tiff, 0)
+	tiff = binary.LittleEndian.AppendUint32(tiff, 0)
+	return jpegSegment(jpegAPP1, append(append([]byte{}, exifHeader...), tiff...))
+}
+
+// testJPEG возвращает JPEG, в который после SOI вставлены сегменты extra
+func testJPEG(t *testing.T, extra ...[]byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, testImage(6, 4), nil); err != nil {
+		t.Fatal(err)
+	}
+	raw := buf.Bytes()
+	out := append([]byte{}, raw[:2]...)
+	for _, segment := range extra {
+		out = append(out, segment...)
+	}
+	return append(out, raw[2:]...)
+}
+
+func strip(t *testing.T, contentType string, data []byte) ([]byte, error) {
+	t.Helper()
+	r, err := stripMetadata(contentType, bufio.NewReader(bytes.NewReader(data)))
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+func TestStripJPEGMetadata(t *testing.T) {
+	xmp := jpegSegment(jpegAPP1, []byte("http://ns.adobe.com/xap/1.0/\x00<x:xmpmeta>secret</x:xmpmeta>"))
+	comment := jpegSegment(0xFE, []byte("secret comment"))
+	iptc := jpegSegment(0xED, []byte("Photoshop 3.0\x00secret"))
+	input := testJPEG(t, xmp, exifSegment(6), comment, iptc)
+
+	out, err := strip(t, "image/jpeg", input)
+	if err != nil {
+		t.Fatalf("stripMetadata: %v", err)
+	}
+	for _, secret := range []string{"secret", "GPS"} {
+		if bytes.Contains(out, []byte(secret)) {
+			t.Errorf("после очистки осталось %q", secret)
+		}
+	}
+	if got := jpegOrientation(bytes.NewReader(out)); got != 6 {
+		t.Errorf("ориентация после очистки = %d, ожидалось 6", got)
+	}
+	if !bytes.Contains(out, orientationSegment(6)) {
+		t.Error("нет сегмента с ориентацией")
+	}
+	if _, err := jpeg.Decode(bytes.NewReader(out)); err != nil {
+		t.Errorf("очищенный файл не декодируется: %v", err)
+	}
+}
+
+func TestStripJPEGDefaultOrientation(t *testing.T) {
+	out, err := strip(t, "image/jpeg", testJPEG(t, exifSegment(1)))
+	if err != nil {
+		t.Fatalf("stripMetadata: %v", err)
+	}
+	if bytes.Contains(out, exifHeader) {
+		t.Error("EXIF с ориентацией по умолчанию не нужно сохранять")
+	}
+	if got := jpegOrientation(bytes.NewReader(out)); got != 0 {
+		t.Errorf("ориентация = %d", got)
+	}
+}
+
+func TestStripJPEGMalformed(t *testing.T) {
+	valid := testJPEG(t, exifSegment(3))
+	sos := bytes.Index(valid, []byte{0xFF, jpegSOS})
+
+	tests := map[string][]byte{
+		"пусто":                      {},
+		"не JPEG":                    []byte("GIF89a......"),
+		"только SOI":                 {0xFF, 0xD8},
+		"обрыв в маркере":            {0xFF, 0xD8, 0xFF},
+		"обрыв в длине":              {0xFF, 0xD8, 0xFF, 0xE0, 0x00},
+		"длина меньше 2":             {0xFF, 0xD8, 0xFF, 0xE0, 0x00, 0x01},
+		"мусор вместо маркера":       {0xFF, 0xD8, 0x12, 0x34},
+		"обрыв в сегменте":           {0xFF, 0xD8, 0xFF, 0xE0, 0x00, 0x10, 'J', 'F'},
+		"обрыв в EXIF":               valid[:len(exifSegment(3))-4],
+		"обрыв в удаляемом сегменте": {0xFF, 0xD8, 0xFF, 0xFE, 0x00, 0x10, 'x'},
+		"нет SOS":                    valid[:sos],
+	}
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := strip(t, "image/jpeg", input); !errors.Is(err, ErrBadImage) {
+				t.Errorf("ожидалась ErrBadImage, получено %v", err)
+			}
+		})
+	}
+}
+
+func TestExifOrientation(t *testing.T) {
+	le := exifSegment(8)[4:]
+	tests := map[string]struct {
+		segment []byte
+		want    int
+	}{
+		"little-endian": {le, 8},
+		"big-endian":    {orientationSegment(5)[4:], 5},
+		"не EXIF":       {[]byte("http://ns.adobe.com/xap/1.0/\x00"), 0},
+		"обрезанный заголовок":   {le[:10], 0},
+		"обрезанная запись":      {le[:len(le)-10], 0},
+		"смещение IFD за концом": {append(append([]byte{}, exifHeader...), 'I', 'I', '*', 0, 0xFF, 0xFF, 0, 0), 0},
+		"недопустимое значение":  {exifSegment(9)[4:], 0},
+		"неизвестный порядок":    {append(append([]byte{}, exifHeader...), "XX*\x00\x08\x00\x00\x00"...), 0},
+	}
+	for name, tt := range tests {
+		if got := exifOrientation(tt.segment); got != tt.want {
+			t.Errorf("%s: exifOrientation = %d, ожидалось %d", name, got, tt.want)
+		}
+	}
+}
+
+func pngChunk(chunkType string, data []byte) []byte {
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+	chunk = append(chunk, chunkType...)
+	chunk = append(chunk, data...)
+	return binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+}
+
+// testPNG возвращает PNG, в который после IHDR вставлены блоки extra
+func testPNG(t *testing.T, extra ...[]byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, testImage(3, 3)); err != nil {
+		t.Fatal(err)
+	}
+	raw := buf.Bytes()
+	// Сигнатура (8 байт) и IHDR (12 + 13 байт)
+	ihdrEnd := len(pngSignature) + 25
+	out := append([]byte{}, raw[:ihdrEnd]...)
+	for _, chunk := range extra {
+		out = append(out, chunk...)
+	}
+	return append(out, raw[ihdrEnd:]...)
+}
+
+func TestStripPNGMetadata(t *testing.T) {
+	input := testPNG(t,
+		pngChunk("tEXt", []byte("Author\x00secret")),
+		pngChunk("eXIf", []byte("MM\x00*secret")),
+		pngChunk("gAMA", []byte{0, 0, 0xB1, 0x8F}),
+	)
+
+	out, err := strip(t, "image/png", input)
+	if err != nil {
+		t.Fatalf("stripMetadata: %v", err)
+	}
+	if bytes.Contains(out, []byte("secret")) {
+		t.Error("после очистки остались метаданные")
+	}
+	if !bytes.Contains(out, []byte("gAMA")) {
+		t.Error("удалён блок, нужный для отображения")
+	}
+	if _, err := png.Decode(bytes.NewReader(out)); err != nil {
+		t.Errorf("очищенный файл не декодируется: %v", err)
+	}
+}
+
+func TestStripPNGMalformed(t *testing.T) {
+	valid := testPNG(t, pngChunk("tEXt", []byte("k\x00v")))
+	idat := bytes.Index(valid, []byte("IDAT")) - 4
+
+	tests := map[string][]byte{
+		"пусто":                   {},
+		"не PNG":                  []byte("\x89PNX\r\n\x1a\n"),
+		"только сигнатура":        pngSignature,
+		"обрыв в заголовке":       valid[:len(pngSignature)+5],
+		"обрыв в блоке":           valid[:len(pngSignature)+15],
+		"обрыв в удаляемом блоке": valid[:len(pngSignature)+25+10],
+		"огромная длина":          append(append([]byte{}, pngSignature...), 0x7F, 0xFF, 0xFF, 0xFF, 't', 'E', 'X', 't'),
+		"нет IDAT":                valid[:idat],
+	}
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := strip(t, "image/png", input); !errors.Is(err, ErrBadImage) {
+				t.Errorf("ожидалась ErrBadImage, получено %v", err)
+			}
+		})
+	}
+}
+
+func TestStripOtherTypesUnchanged(t *testing.T) {
+	input := []byte("plain text with Exif\x00\x00 inside")
+	out, err := strip(t, "text/plain", input)
+	if err != nil || !bytes.Equal(out, input) {
+		t.Errorf("содержимое изменено: %q, %v", out, err)
+	}
+}
+
+func TestSniffContentType(t *testing.T) {
+	tests := []struct {
+		head     []byte
+		declared string
+		want     string
+	}{
+		{testJPEG(t), "image/png", "image/jpeg"},
+		{testPNG(t), "application/pdf", "image/png"},
+		{[]byte("# Заголовок\n"), "text/markdown", "text/markdown"},
+		{[]byte(`{"a":1}`), "application/json", "application/json"},
+		// Текстовый тип не выдаёт бинарное содержимое за текст
+		{[]byte{0x00, 0x01, 0x02, 0x03}, "text/markdown", "application/octet-stream"},
+		{[]byte("<html><script>"), "text/markdown", "text/html"},
+		{[]byte("просто текст"), "image/jpeg", "text/plain"},
+	}
+	for _, tt := range tests {
+		if got := sniffContentType(tt.head, tt.declared); got != tt.want {
+			t.Errorf("sniffContentType(%.12q, %q) = %q, ожидалось %q", tt.head, tt.declared, got, tt.want)
+		}
+	}
+}
+
+func TestOrient(t *testing.T) {
+	src := testImage(3, 2)
+	at := func(img *image.RGBA, x, y int) color.RGBA { return img.RGBAAt(x, y) }
+
+	tests := []struct {
+		orientation   int
+		width, height int
+		// check — пиксель результата (x, y), в который должен попасть пиксель исходника (0, 0)
+		x, y int
+	}{
+		{1, 3, 2, 0, 0},
+		{2, 3, 2, 2, 0},
+		{3, 3, 2, 2, 1},
+		{4, 3, 2, 0, 1},
+		{5, 2, 3, 0, 0},
+		{6, 2, 3, 1, 0},
+		{7, 2, 3, 1, 2},
+		{8, 2, 3, 0, 2},
+	}
+	for _, tt := range tests {
+		dst := orient(src, tt.orientation)
+		if dst.Bounds().Dx() != tt.width || dst.Bounds().Dy() != tt.height {
+			t.Errorf("ориентация %d: размер %v, ожидалось %dx%d", tt.orientation, dst.Bounds().Size(), tt.width, tt.height)
+			continue
+		}
+		if at(dst, tt.x, tt.y) != at(src, 0, 0) {
+			t.Errorf("ориентация %d: угловой пиксель не в (%d, %d)", tt.orientation, tt.x, tt.y)
+		}
+	}
+
+	// Поворот на 90° по часовой: верхняя строка исходника становится правым столбцом
+	dst := orient(src, 6)
+	for x := 0; x < 3; x++ {
+		if at(dst, 1, x) != at(src, x, 0) {
+			t.Errorf("поворот на 90°: пиксель (%d, 0) не в (1, %d)", x, x)
+		}
+	}
+}
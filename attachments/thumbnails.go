@@ -0,0 +1,292 @@
+package attachments
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"notes-api/jobs"
+	"notes-api/logger"
+	"notes-api/model"
+	"slices"
+
+	"golang.org/x/image/draw"
+	"gorm.io/gorm"
+)
+
+const (
+	// ThumbnailJobType — задача построения превью изображения
+	ThumbnailJobType = "attachments.thumbnails"
+
+	// maxImagePixels защищает воркер от «бомб» — маленьких файлов с огромными размерами
+	maxImagePixels       = 50_000_000
+	thumbnailJPEGQuality = 85
+)
+
+// ThumbnailSizes — размеры превью по длинной стороне
+var ThumbnailSizes = []int{64, 256, 1024}
+
+var ErrThumbnailNotReady = errors.New("превью ещё не готово")
+
+type thumbnailPayload struct {
+	AttachmentID uint `json:"attachment_id"`
+}
+
+// thumbnailable сообщает, строятся ли превью для типа. Декодеры только из стандартной библиотеки.
+func thumbnailable(contentType string) bool {
+	switch contentType {
+	case "image/jpeg", "image/png", "image/gif":
+		return true
+	}
+	return false
+}
+
+// thumbnailContentType — превью фотографий кодируются в JPEG, остальные в PNG, чтобы сохранить прозрачность
+func thumbnailContentType(contentType string) string {
+	if contentType == "image/jpeg" {
+		return "image/jpeg"
+	}
+	return "image/png"
+}
+
+func thumbnailKey(attachment model.Attachment, size int) string {
+	return fmt.Sprintf("%s.thumb-%d", attachment.StorageKey, size)
+}
+
+// blobKeys — все объекты вложения в хранилище, включая превью, которые могли не достроиться
+func blobKeys(attachment model.Attachment) []string {
+	keys := []string{attachment.StorageKey}
+	if attachment.ThumbnailStatus != "" {
+		for _, size := range ThumbnailSizes {
+			keys = append(keys, thumbnailKey(attachment, size))
+		}
+	}
+	return keys
+}
+
+func (s *AttachmentService) enqueueThumbnails(ctx context.Context, attachment model.Attachment) {
+	_, err := s.Queue.Enqueue(ctx, ThumbnailJobType, thumbnailPayload{AttachmentID: attachment.ID}, jobs.Options{
+		UserID: attachment.UserID,
+	})
+	if err != nil {
+		logger.Log.WithError(err).WithField("attachment_id", attachment.ID).Error("Не удалось поставить построение превью в очередь")
+	}
+}
+
+// HandleThumbnailJob строит превью всех размеров и отмечает вложение готовым
+func (s *AttachmentService) HandleThumbnailJob(ctx context.Context, job *model.Job) error {
+	var p thumbnailPayload
+	if err := json.Unmarshal(job.Payload, &p); err != nil {
+		return jobs.Permanent(err)
+	}
+
+	var attachment model.Attachment
+	if err := s.DB.WithContext(ctx).First(&attachment, p.AttachmentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Вложение удалили раньше, чем до него дошла очередь
+			return nil
+		}
+		return err
+	}
+
+	img, orientation, err := s.decodeImage(ctx, attachment)
+	if err != nil {
+		if errors.Is(err, ErrBlobNotFound) || errors.Is(err, ErrBadImage) {
+			s.setThumbnailStatus(ctx, attachment.ID, model.ThumbnailsFailed, nil)
+			return jobs.Permanent(err)
+		}
+		return err
+	}
+
+	contentType := thumbnailContentType(attachment.ContentType)
+	for _, size := range ThumbnailSizes {
+		var buf bytes.Buffer
+		if err := encodeThumbnail(&buf, orient(resize(img, size), orientation), contentType); err != nil {
+			return err
+		}
+		if err := s.Blobs.Put(ctx, thumbnailKey(attachment, size), &buf, int64(buf.Len()), contentType); err != nil {
+			return err
+		}
+	}
+
+	if !s.setThumbnailStatus(ctx, attachment.ID, model.ThumbnailsReady, ThumbnailSizes) {
+		// Вложение удалили, пока строились превью: убираем то, что успели записать
+		for _, key := range blobKeys(attachment)[1:] {
+			s.deleteBlob(key)
+		}
+		return nil
+	}
+
+	logger.Log.WithField("attachment_id", attachment.ID).Info("Превью вложения построены")
+	return nil
+}
+
+// HandleThumbnailDead отмечает, что превью не будет: без этого вложение навсегда
+// осталось бы в состоянии pending, если задача исчерпала попытки или роняла воркер
+func (s *AttachmentService) HandleThumbnailDead(ctx context.Context, job *model.Job) {
+	var p thumbnailPayload
+	if err := json.Unmarshal(job.Payload, &p); err != nil {
+		return
+	}
+	err := s.DB.WithContext(ctx).Model(&model.Attachment{}).
+		Where("id = ? AND thumbnail_status = ?", p.AttachmentID, model.ThumbnailsPending).
+		Update("thumbnail_status", model.ThumbnailsFailed).Error
+	if err != nil {
+		logger.Log.WithError(err).WithField("attachment_id", p.AttachmentID).Error("Не удалось обновить состояние превью")
+	}
+}
+
+// decodeImage декодирует изображение и возвращает его ориентацию из EXIF (0, если её нет)
+func (s *AttachmentService) decodeImage(ctx context.Context, attachment model.Attachment) (image.Image, int, error) {
+	content, err := s.Blobs.Open(ctx, attachment.StorageKey)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer content.Close()
+
+	config, _, err := image.DecodeConfig(bufio.NewReader(content))
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: %v", ErrBadImage, err)
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > maxImagePixels {
+		return nil, 0, fmt.Errorf("%w: размер %dx%d", ErrBadImage, config.Width, config.Height)
+	}
+
+	orientation := 0
+	if attachment.ContentType == "image/jpeg" {
+		if _, err := content.Seek(0, io.SeekStart); err != nil {
+			return nil, 0, err
+		}
+		orientation = jpegOrientation(content)
+	}
+
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return nil, 0, err
+	}
+	// У GIF берётся первый кадр
+	img, _, err := image.Decode(bufio.NewReader(content))
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: %v", ErrBadImage, err)
+	}
+	return img, orientation, nil
+}
+
+// setThumbnailStatus возвращает false, если вложения уже нет
+func (s *AttachmentService) setThumbnailStatus(ctx context.Context, id uint, status string, sizes []int) bool {
+	res := s.DB.WithContext(ctx).Model(&model.Attachment{}).Where("id = ?", id).Updates(&model.Attachment{
+		ThumbnailStatus: status,
+		ThumbnailSizes:  sizes,
+	})
+	if res.Error != nil {
+		logger.Log.WithError(res.Error).WithField("attachment_id", id).Error("Не удалось обновить состояние превью")
+		return true
+	}
+	return res.RowsAffected > 0
+}
+
+// OpenThumbnail возвращает превью наименьшего размера не меньше size (или наибольшее, если size больше всех)
+func (s *AttachmentService) OpenThumbnail(ctx context.Context, userID, noteID, id uint, size int) (model.Attachment, int, io.ReadSeekCloser, error) {
+	attachment, err := s.Get(userID, noteID, id)
+	if err != nil {
+		return model.Attachment{}, 0, nil, err
+	}
+	switch attachment.ThumbnailStatus {
+	case model.ThumbnailsReady:
+	case model.ThumbnailsPending:
+		return model.Attachment{}, 0, nil, ErrThumbnailNotReady
+	default:
+		return model.Attachment{}, 0, nil, ErrAttachmentNotFound
+	}
+
+	sizes := slices.Sorted(slices.Values(attachment.ThumbnailSizes))
+	chosen := sizes[len(sizes)-1]
+	for _, candidate := range sizes {
+		if candidate >= size {
+			chosen = candidate
+			break
+		}
+	}
+
+	content, err := s.Blobs.Open(ctx, thumbnailKey(attachment, chosen))
+	if errors.Is(err, ErrBlobNotFound) {
+		return model.Attachment{}, 0, nil, ErrAttachmentNotFound
+	}
+	if err != nil {
+		return model.Attachment{}, 0, nil, err
+	}
+	return attachment, chosen, content, nil
+}
+
+// resize вписывает изображение в квадрат size×size с сохранением пропорций. Маленькие изображения не растягиваются.
+func resize(img image.Image, size int) *image.RGBA {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width > size || height > size {
+		if width >= height {
+			height = max(1, height*size/width)
+			width = size
+		} else {
+			width = max(1, width*size/height)
+			height = size
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Src, nil)
+	return dst
+}
+
+// orient поворачивает и отражает изображение так, как предписывает ориентация EXIF (1–8),
+// чтобы превью показывалось правильно и там, где EXIF не учитывается
+func orient(img *image.RGBA, orientation int) *image.RGBA {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	// При ориентациях 5–8 изображение повёрнуто на 90°: ширина и высота меняются местами
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2: // отражение по горизонтали
+				sx, sy = w-1-x, y
+			case 3: // поворот на 180°
+				sx, sy = w-1-x, h-1-y
+			case 4: // отражение по вертикали
+				sx, sy = x, h-1-y
+			case 5: // транспонирование
+				sx, sy = y, x
+			case 6: // поворот на 90° по часовой
+				sx, sy = y, h-1-x
+			case 7: // транспонирование по побочной диагонали
+				sx, sy = w-1-y, h-1-x
+			case 8: // поворот на 90° против часовой
+				sx, sy = w-1-y, x
+			}
+			dst.SetRGBA(x, y, img.RGBAAt(b.Min.X+sx, b.Min.Y+sy))
+		}
+	}
+	return dst
+}
+
+func encodeThumbnail(w io.Writer, img image.Image, contentType string) error {
+	if contentType == "image/jpeg" {
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: thumbnailJPEGQuality})
+	}
+	return png.Encode(w, img)
+}
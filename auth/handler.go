@@ -4,7 +4,9 @@ import (
 	"encoding/json"
 	"net/http"
 	"notes-api/logger"
+	"notes-api/metrics"
 	"notes-api/middleware"
+	"notes-api/problem"
 )
 
 type AuthHandler struct {
@@ -23,28 +25,24 @@ type AuthInput struct {
 // @Produce json
 // @Param input body auth.AuthInput true "Данные пользователя"
 // @Success 200 {object} map[string]string
-// @Failure 400 {string} string "Неверный запрос"
+// @Failure 400 {object} problem.Problem "Неверный запрос"
 // @Router /register [post]
 func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 	var input AuthInput
 
 	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		logger.Log.WithError(err).Warn("Ошибка декодирования запроса на регистрацию")
-		http.Error(w, "Неверный формат запроса", http.StatusBadRequest)
+		logger.FromContext(r.Context()).WithError(err).Warn("Ошибка декодирования запроса на регистрацию")
+		problem.Write(w, r, problem.BadRequest)
 		return
 	}
 
 	if err := h.Service.Register(input.Email, input.Password); err != nil {
-		logger.Log.WithFields(logger.Fields{
-			"email": input.Email,
-		}).WithError(err).Warn("Ошибка регистрации")
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		logger.FromContext(r.Context()).WithError(err).Warn("Ошибка регистрации")
+		problem.Error(w, r, err)
 		return
 	}
 
-	logger.Log.WithFields(logger.Fields{
-		"email": input.Email,
-	}).Info("Пользователь успешно зарегистрирован")
+	logger.FromContext(r.Context()).Info("Пользователь успешно зарегистрирован")
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{
@@ -59,29 +57,26 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Param input body auth.AuthInput true "Данные пользователя"
 // @Success 200 {object} map[string]string
-// @Failure 401 {string} string "Неверные данные"
+// @Failure 401 {object} problem.Problem "Неверные данные"
 // @Router /login [post]
 func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	var input AuthInput
 
 	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		logger.Log.WithError(err).Warn("Ошибка декодирования запроса на вход")
-		http.Error(w, "Неверный формат", http.StatusBadRequest)
+		logger.FromContext(r.Context()).WithError(err).Warn("Ошибка декодирования запроса на вход")
+		problem.Write(w, r, problem.BadRequest)
 		return
 	}
 
 	accessToken, refreshToken, err := h.Service.Login(input.Email, input.Password)
+	metrics.Login(err == nil)
 	if err != nil {
-		logger.Log.WithFields(logger.Fields{
-			"email": input.Email,
-		}).WithError(err).Warn("Ошибка входа")
-		http.Error(w, err.Error(), http.StatusUnauthorized)
+		logger.FromContext(r.Context()).WithError(err).Warn("Ошибка входа")
+		problem.Error(w, r, err)
 		return
 	}
 
-	logger.Log.WithFields(logger.Fields{
-		"email": input.Email,
-	}).Info("Успешный вход пользователя")
+	logger.FromContext(r.Context()).Info("Успешный вход пользователя")
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{
@@ -97,7 +92,7 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Param input body map[string]string true "Refresh токен"
 // @Success 200 {object} map[string]string
-// @Failure 400 {string} string "Ошибка токена"
+// @Failure 400 {object} problem.Problem "Ошибка токена"
 // @Router /refresh [post]
 func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
 	var input struct {
@@ -105,19 +100,20 @@ func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		logger.Log.WithError(err).Warn("Ошибка декодирования refresh токена")
-		http.Error(w, "Неверный формат запроса", http.StatusBadRequest)
+		logger.FromContext(r.Context()).WithError(err).Warn("Ошибка декодирования refresh токена")
+		problem.Write(w, r, problem.BadRequest)
 		return
 	}
 
 	newAccessToken, err := h.Service.RefreshToken(input.RefreshToken)
+	metrics.TokenRefresh(err == nil)
 	if err != nil {
-		logger.Log.WithError(err).Warn("Ошибка при обновлении токена")
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		logger.FromContext(r.Context()).WithError(err).Warn("Ошибка при обновлении токена")
+		problem.Error(w, r, err)
 		return
 	}
 
-	logger.Log.Info("Access token успешно обновлён")
+	logger.FromContext(r.Context()).Info("Access token успешно обновлён")
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{
 		"access_token": newAccessToken,
@@ -128,12 +124,12 @@ func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
 	rawUserID := r.Context().Value(middleware.UserIDKey)
 	userID, ok := rawUserID.(uint)
 	if !ok {
-		http.Error(w, "Пользователь не аутентифицирован", http.StatusUnauthorized)
+		problem.Write(w, r, problem.Unauthorized)
 		return
 	}
 
 	if err := h.Service.Logout(userID); err != nil {
-		http.Error(w, "Ошибка при выходе", http.StatusInternalServerError)
+		problem.Write(w, r, problem.Internal)
 		return
 	}
 
@@ -2,9 +2,9 @@ package auth
 
 import (
 	"errors"
-	"notes-api/logger"
+	"fmt"
 	"notes-api/model"
-	"os"
+	"notes-api/problem"
 	"regexp"
 	"time"
 
@@ -13,23 +13,48 @@ import (
 	"gorm.io/gorm"
 )
 
+var (
+	ErrUserNotFound = errors.New("пользователь не найден")
+	ErrUserDisabled = errors.New("учётная запись отключена")
+	ErrUserExists   = errors.New("пользователь с таким email уже существует")
+	// ErrInvalidCredentials не различает неизвестный email и неверный пароль,
+	// чтобы по ответу нельзя было проверить, зарегистрирован ли адрес
+	ErrInvalidCredentials = errors.New("неверный email или пароль")
+	ErrInvalidToken       = errors.New("некорректный или просроченный токен")
+)
+
+const (
+	minPasswordLength = 6
+	// bcrypt учитывает только первые 72 байта пароля
+	maxPasswordBytes = 72
+)
+
+// TokenConfig — ключ подписи и сроки действия выдаваемых токенов
+type TokenConfig struct {
+	Secret     []byte
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
+}
+
 type AuthService struct {
-	DB *gorm.DB
+	DB     *gorm.DB
+	Tokens TokenConfig
+
+	status *statusCache
 }
 
-func NewAuthService(db *gorm.DB) *AuthService {
-	return &AuthService{DB: db}
+func NewAuthService(db *gorm.DB, tokens TokenConfig) *AuthService {
+	return &AuthService{DB: db, Tokens: tokens, status: newStatusCache()}
 }
 
 func (s *AuthService) Register(email, password string) error {
-	logger.Log.Infof("Попытка регистрации: %s", email)
 	if err := validateCredentials(email, password); err != nil {
 		return err
 	}
 
 	var existing model.User
 	if err := s.DB.Where("email = ?", email).First(&existing).Error; err == nil {
-		return errors.New("пользователь с таким email уже существует")
+		return ErrUserExists
 	}
 
 	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
@@ -46,39 +71,36 @@ func (s *AuthService) Register(email, password string) error {
 }
 
 func (s *AuthService) Login(email, password string) (string, string, error) {
-	logger.Log.Infof("Попытка входа: %s", email)
 	var user model.User
 	if err := s.DB.Where("email = ?", email).First(&user).Error; err != nil {
-		return "", "", errors.New("пользователь не найден")
+		return "", "", ErrInvalidCredentials
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Hash), []byte(password)); err != nil {
-		return "", "", errors.New("неверный email или пароль")
+		return "", "", ErrInvalidCredentials
+	}
+	if user.DisabledAt != nil {
+		return "", "", ErrUserDisabled
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 		"user_id": user.ID,
 		"email":   user.Email,
-		"exp":     time.Now().Add(2 * time.Hour).Unix(),
+		"exp":     time.Now().Add(s.Tokens.AccessTTL).Unix(),
 	})
 
 	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 		"user_id": user.ID,
 		"email":   user.Email,
-		"exp":     time.Now().Add(24 * time.Hour).Unix(),
+		"exp":     time.Now().Add(s.Tokens.RefreshTTL).Unix(),
 	})
 
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		secret = "default_secret"
-	}
-
-	tokenString, err := token.SignedString([]byte(secret))
+	tokenString, err := token.SignedString(s.Tokens.Secret)
 	if err != nil {
 		return "", "", err
 	}
 
-	refreshTokenString, err := refreshToken.SignedString([]byte(secret))
+	refreshTokenString, err := refreshToken.SignedString(s.Tokens.Secret)
 	if err != nil {
 		return "", "", err
 	}
@@ -92,44 +114,42 @@ func (s *AuthService) Login(email, password string) (string, string, error) {
 }
 
 func (s *AuthService) RefreshToken(refreshTokenString string) (string, error) {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		secret = "default_secret"
-	}
-
 	token, err := jwt.Parse(refreshTokenString, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, errors.New("неподдерживаемый алгоритм подписи")
 		}
-		return []byte(secret), nil
+		return s.Tokens.Secret, nil
 	})
 	if err != nil || !token.Valid {
-		return "", errors.New("некорректный или просроченный токен")
+		return "", ErrInvalidToken
 	}
 
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok || claims["user_id"] == nil {
-		return "", errors.New("невалидный payload токена")
+		return "", fmt.Errorf("%w: в токене нет user_id", ErrInvalidToken)
 	}
 
 	userID := uint(claims["user_id"].(float64))
 
 	var user model.User
 	if err := s.DB.First(&user, userID).Error; err != nil {
-		return "", errors.New("пользователь не найден")
+		return "", fmt.Errorf("%w: %w", ErrInvalidToken, ErrUserNotFound)
+	}
+	if user.DisabledAt != nil {
+		return "", ErrUserDisabled
 	}
 
 	if user.RefreshTokenHash != refreshTokenString {
-		return "", errors.New("refresh токен недействителен")
+		return "", fmt.Errorf("%w: refresh-токен отозван", ErrInvalidToken)
 	}
 
 	newToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 		"user_id": user.ID,
 		"email":   user.Email,
-		"exp":     time.Now().Add(2 * time.Hour).Unix(),
+		"exp":     time.Now().Add(s.Tokens.AccessTTL).Unix(),
 	})
 
-	newTokenString, err := newToken.SignedString([]byte(secret))
+	newTokenString, err := newToken.SignedString(s.Tokens.Secret)
 	if err != nil {
 		return "", err
 	}
@@ -141,23 +161,72 @@ func (s *AuthService) Logout(userID uint) error {
 	return s.DB.Model(&model.User{}).Where("id = ?", userID).Update("refresh_token_hash", "").Error
 }
 
-func validateCredentials(email, password string) error {
-	if email == "" || password == "" {
-		return errors.New("email и пароль обязательны")
+// Disable отключает учётную запись и отзывает её refresh-токен. Уже выданные access-токены
+// перестают приниматься не позже чем через userStatusTTL (см. CheckUser).
+func (s *AuthService) Disable(email string) error {
+	var user model.User
+	err := s.DB.Select("id").Where("email = ?", email).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return err
 	}
 
-	regex := regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
-	if !regex.MatchString(email) {
-		return errors.New("неверный формат email")
+	err = s.DB.Model(&model.User{}).
+		Where("id = ? AND disabled_at IS NULL", user.ID).
+		Updates(map[string]any{"disabled_at": time.Now(), "refresh_token_hash": ""}).Error
+	if err != nil {
+		return err
 	}
+	s.status.forget(user.ID)
+	return nil
+}
 
-	if len(password) < 6 {
-		return errors.New("пароль должен содержать не менее 6 символов")
+// ResetPassword задаёт пользователю новый пароль и отзывает его refresh-токен
+func (s *AuthService) ResetPassword(email, password string) error {
+	if err := validateCredentials(email, password); err != nil {
+		return err
 	}
 
-	if len([]byte(password)) > 72 {
-		return errors.New("пароль не должен превышать 72 байта")
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
 	}
 
+	res := s.DB.Model(&model.User{}).
+		Where("email = ?", email).
+		Updates(map[string]any{"hash": string(hash), "refresh_token_hash": ""})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+var emailPattern = regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
+
+// validateCredentials проверяет email и пароль и возвращает ошибки по всем полям сразу
+func validateCredentials(email, password string) error {
+	var fields []problem.FieldError
+	switch {
+	case email == "":
+		fields = append(fields, problem.Field("email", problem.FieldRequired))
+	case !emailPattern.MatchString(email):
+		fields = append(fields, problem.Field("email", problem.FieldInvalidEmail))
+	}
+	switch {
+	case password == "":
+		fields = append(fields, problem.Field("password", problem.FieldRequired))
+	case len(password) < minPasswordLength:
+		fields = append(fields, problem.FieldLimit("password", problem.FieldTooShort, minPasswordLength))
+	case len(password) > maxPasswordBytes:
+		fields = append(fields, problem.FieldLimit("password", problem.FieldTooLong, maxPasswordBytes))
+	}
+	if len(fields) > 0 {
+		return problem.Invalid(fields...)
+	}
 	return nil
 }
@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"notes-api/model"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// userStatusTTL — сколько помнить состояние учётной записи. Отключают её из CLI, то есть
+	// из другого процесса, поэтому сервер замечает отключение не позже чем через это время.
+	userStatusTTL = 30 * time.Second
+	// userStatusCacheSize — после скольких записей из кеша выбрасываются устаревшие
+	userStatusCacheSize = 10_000
+)
+
+type userStatus struct {
+	err     error
+	expires time.Time
+}
+
+// statusCache — недавно проверенные учётные записи
+type statusCache struct {
+	mu      sync.Mutex
+	entries map[uint]userStatus
+}
+
+func newStatusCache() *statusCache {
+	return &statusCache{entries: make(map[uint]userStatus)}
+}
+
+func (c *statusCache) get(userID uint) (error, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[userID]
+	if !ok || time.Now().After(entry.expires) {
+		return nil, false
+	}
+	return entry.err, true
+}
+
+func (c *statusCache) put(userID uint, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if len(c.entries) >= userStatusCacheSize {
+		for id, entry := range c.entries {
+			if now.After(entry.expires) {
+				delete(c.entries, id)
+			}
+		}
+	}
+	c.entries[userID] = userStatus{err: err, expires: now.Add(userStatusTTL)}
+}
+
+func (c *statusCache) forget(userID uint) {
+	c.mu.Lock()
+	delete(c.entries, userID)
+	c.mu.Unlock()
+}
+
+// CheckUser реализует middleware.UserStatus: access-токен действует до конца срока,
+// поэтому при каждом запросе проверяется, что учётная запись существует и не отключена.
+// Возвращает ErrUserDisabled или ErrInvalidToken, если пользователя больше нет.
+func (s *AuthService) CheckUser(ctx context.Context, userID uint) error {
+	if err, ok := s.status.get(userID); ok {
+		return err
+	}
+
+	var user model.User
+	err := s.DB.WithContext(ctx).Select("id", "disabled_at").First(&user, userID).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		err = ErrInvalidToken
+	case err != nil:
+		// Сбой базы не кешируется: следующий запрос проверит заново
+		return err
+	case user.DisabledAt != nil:
+		err = ErrUserDisabled
+	}
+	s.status.put(userID, err)
+	return err
+}
@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"notes-api/db/dbtest"
+	"notes-api/middleware"
+	"notes-api/model"
+	"notes-api/problem"
+	"testing"
+	"time"
+)
+
+func init() {
+	problem.Register(ErrInvalidToken, problem.InvalidToken)
+	problem.Register(ErrUserDisabled, problem.UserDisabled)
+}
+
+func newTestService(t *testing.T) *AuthService {
+	t.Helper()
+	db := dbtest.Open(t, &model.User{})
+	return NewAuthService(db, TokenConfig{
+		Secret:     []byte("secret"),
+		AccessTTL:  time.Hour,
+		RefreshTTL: time.Hour,
+	})
+}
+
+func TestDisabledUserTokenRejected(t *testing.T) {
+	s := newTestService(t)
+	if err := s.Register("user@example.com", "password"); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	access, _, err := s.Login("user@example.com", "password")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+
+	var checked error
+	handler := middleware.JWTQueryAuthMiddleware(s.Tokens.Secret, s)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		checked = middleware.CheckUser(r.Context())
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	request := func() int {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/notes/stream?access_token="+access, nil))
+		return rec.Code
+	}
+
+	if code := request(); code != http.StatusNoContent {
+		t.Fatalf("до отключения: статус %d, ожидался %d", code, http.StatusNoContent)
+	}
+	if checked != nil {
+		t.Errorf("CheckUser до отключения: %v", checked)
+	}
+
+	if err := s.Disable("user@example.com"); err != nil {
+		t.Fatalf("Disable: %v", err)
+	}
+	if code := request(); code != http.StatusForbidden {
+		t.Errorf("после отключения: статус %d, ожидался %d", code, http.StatusForbidden)
+	}
+}
+
+func TestCheckUser(t *testing.T) {
+	s := newTestService(t)
+	if err := s.Register("user@example.com", "password"); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	var user model.User
+	if err := s.DB.Where("email = ?", "user@example.com").First(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.CheckUser(context.Background(), user.ID); err != nil {
+		t.Errorf("активный пользователь: %v", err)
+	}
+	if err := s.CheckUser(context.Background(), user.ID+1); err != ErrInvalidToken {
+		t.Errorf("несуществующий пользователь: %v, ожидалась ErrInvalidToken", err)
+	}
+
+	// Отключение из другого процесса сервер видит только после истечения кеша
+	if err := s.DB.Model(&user).Update("disabled_at", time.Now()).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CheckUser(context.Background(), user.ID); err != nil {
+		t.Errorf("до истечения кеша: %v", err)
+	}
+	s.status.forget(user.ID)
+	if err := s.CheckUser(context.Background(), user.ID); err != ErrUserDisabled {
+		t.Errorf("отключённый пользователь: %v, ожидалась ErrUserDisabled", err)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"notes-api/config"
+	"notes-api/db"
+	"notes-api/encryption"
+	"notes-api/logger"
+	"os"
+	"time"
+
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// connect подключается к базе, проверяет версию схемы и включает шифрование полей.
+// Возвращает связку ключей или nil, если шифрование выключено.
+func connect(ctx context.Context, cfg *config.Config) *encryption.Keyring {
+	db.ConnectDB(cfg.DB)
+
+	// Схема меняется только командой migrate up: со схемой другой версии сервис не работает
+	migrator, err := db.SchemaMigrator()
+	if err != nil {
+		log.Fatal("Failed to load migrations:", err)
+	}
+	if err := migrator.Check(ctx); err != nil {
+		log.Fatal("Database schema check failed:", err)
+	}
+
+	// Ключи подключаются до первых запросов к заметкам: без них зашифрованные поля не прочитать
+	keyProvider, err := encryptionKeyProvider(cfg.Encryption)
+	if err != nil {
+		log.Fatal("Failed to init encryption keys:", err)
+	}
+	if keyProvider == nil {
+		return nil
+	}
+	keyring := encryption.NewKeyring(db.DB, keyProvider)
+	encryption.Enable(keyring)
+	return keyring
+}
+
+// encryptionKeyProvider выбирает источник мастер-ключей шифрования заметок в базе:
+// env (master_keys) или file (master_key_file). Без провайдера шифрование выключено.
+func encryptionKeyProvider(cfg config.Encryption) (encryption.KeyProvider, error) {
+	switch cfg.KeyProvider {
+	case "":
+		return nil, nil
+	case "env":
+		return encryption.NewEnvKeyProvider(string(cfg.MasterKeys))
+	case "file":
+		return encryption.NewFileKeyProvider(cfg.MasterKeyFile)
+	default:
+		return nil, fmt.Errorf("неизвестный провайдер ключей шифрования %q", cfg.KeyProvider)
+	}
+}
+
+// cliOutput переводит журналы сервиса и GORM в stderr, чтобы stdout административных
+// команд можно было перенаправить в файл или передать следующей команде
+func cliOutput() {
+	logger.Log.SetOutput(os.Stderr)
+	gormlogger.Default = gormlogger.New(log.New(os.Stderr, "\r\n", log.LstdFlags), gormlogger.Config{
+		SlowThreshold: 200 * time.Millisecond,
+		LogLevel:      gormlogger.Warn,
+		Colorful:      false,
+	})
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"notes-api/config"
+	"os"
+)
+
+// runConfig выполняет подкоманду config print: печатает действующие настройки в YAML без секретов.
+// Ошибки проверки выводятся после настроек, чтобы по ним было видно, какое значение исправить.
+func runConfig(cfg *config.Config, args []string) {
+	if len(args) != 1 || args[0] != "print" {
+		fmt.Fprintln(os.Stderr, "использование: notes-api config print")
+		os.Exit(2)
+	}
+	if err := config.Print(os.Stdout, cfg); err != nil {
+		log.Fatal("Failed to print config:", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "некорректные настройки:\n%v\n", err)
+		os.Exit(1)
+	}
+}
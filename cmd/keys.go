@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"notes-api/config"
+	"notes-api/encryption"
+	"os"
+)
+
+// runKeys выполняет подкоманду keys rotate: переоборачивает ключи данных текущим мастер-ключом
+// и перешифровывает заметки и их копии сразу, не дожидаясь фоновой задачи сервера
+func runKeys(cfg *config.Config, args []string) {
+	if len(args) == 0 || args[0] != "rotate" {
+		keysUsage()
+	}
+
+	flags := flag.NewFlagSet("keys rotate", flag.ExitOnError)
+	var opts encryption.RotateOptions
+	flags.BoolVar(&opts.NewDataKeys, "new-data-keys", false, "вывести из оборота действующие ключи данных и перешифровать заметки новыми")
+	flags.Parse(args[1:])
+	if flags.NArg() > 0 {
+		keysUsage()
+	}
+
+	ctx := context.Background()
+	keyring := connect(ctx, cfg)
+	if keyring == nil {
+		log.Fatal("Encryption is not configured: set ENCRYPTION_KEY_PROVIDER")
+	}
+	if err := keyring.Rotate(ctx, opts); err != nil {
+		log.Fatal("Key rotation failed:", err)
+	}
+	fmt.Println("ротация ключей завершена")
+}
+
+func keysUsage() {
+	fmt.Fprintln(os.Stderr, "использование: notes-api keys rotate [--new-data-keys]")
+	os.Exit(2)
+}
@@ -11,52 +11,99 @@
 package main
 
 import (
+	"errors"
+	"flag"
+	"fmt"
 	"log"
-	"net/http"
-	"notes-api/auth"
-	"notes-api/db"
-	"notes-api/handler"
+	"notes-api/config"
 	"notes-api/logger"
-	"notes-api/middleware"
-	storage "notes-api/repo"
-	"notes-api/service"
+	"os"
+)
 
-	_ "notes-api/docs"
+const usage = `использование: notes-api [флаги] <команда> [аргументы]
 
-	"github.com/gorilla/mux"
-	httpSwagger "github.com/swaggo/http-swagger"
-)
+команды:
+  serve                                      запустить HTTP-сервер (по умолчанию)
+  migrate up | down [N] | status | create [--dir каталог] <имя>
+                                             управлять схемой базы
+  user create --email E [--password P]       создать пользователя
+  user disable --email E                     отключить учётную запись
+  user reset-password --email E [--password P]
+                                             задать новый пароль
+  notes export --user ID|EMAIL [--format json|markdown|html] [--output файл]
+                                             выгрузить заметки пользователя
+  keys rotate [--new-data-keys]              перевести данные на текущие ключи шифрования
+  config print                               вывести действующие настройки без секретов
+
+Настройки читаются по возрастанию приоритета: значения по умолчанию, YAML-файл --config,
+переменные окружения (и файл .env), флаги.
+
+флаги:
+`
 
 func main() {
+	flags := flag.NewFlagSet("notes-api", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), usage)
+		flags.PrintDefaults()
+	}
+	cfg, err := config.Load(flags, os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "некорректные настройки:\n%v\n", err)
+		os.Exit(2)
+	}
+
+	command, args := "serve", flags.Args()
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
+
 	logger.Init()
-	logger.Log.Info("Логгер инициализирован")
-
-	db.ConnectDB()
-	newStore := storage.NewPostgresStore(db.DB)
-	service := service.NewNoteService(newStore)
-	authService := auth.NewAuthService(db.DB)
-	h := &handler.NoteHandler{Store: service}
-	authHandler := &auth.AuthHandler{Service: authService}
-
-	r := mux.NewRouter()
-
-	r.PathPrefix("/swagger").Handler(httpSwagger.WrapHandler)
-	r.HandleFunc("/register", authHandler.Register).Methods("POST")
-	r.HandleFunc("/login", authHandler.Login).Methods("POST")
-	r.HandleFunc("/refresh", authHandler.Refresh).Methods("POST")
-
-	authRoutes := r.PathPrefix("/notes").Subrouter()
-	authRoutes.Use(middleware.JWTAuthMiddleware)
-	authRoutes.HandleFunc("", h.GetAll).Methods("GET")
-	authRoutes.HandleFunc("", h.Create).Methods("POST")
-	authRoutes.HandleFunc("/{id}", h.GetByID).Methods("GET")
-	authRoutes.HandleFunc("/{id}", h.Update).Methods("PUT")
-	authRoutes.HandleFunc("/{id}", h.Delete).Methods("DELETE")
-
-	authProtected := r.NewRoute().Subrouter()
-	authProtected.Use(middleware.JWTAuthMiddleware)
-	authProtected.HandleFunc("/logout", authHandler.Logout).Methods("POST")
-
-	log.Println("Server started on :8080")
-	log.Fatal(http.ListenAndServe(":8080", r))
+	if command != "serve" {
+		cliOutput()
+	}
+
+	switch command {
+	case "config":
+		runConfig(cfg, args)
+		return
+	case "help":
+		flags.SetOutput(os.Stdout)
+		flags.Usage()
+		return
+	}
+
+	// Все ошибки настроек выводятся разом, а не по одной за запуск
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "некорректные настройки:\n%v\n", err)
+		os.Exit(2)
+	}
+	if err := logger.SetLevel(cfg.Log.Level); err != nil {
+		log.Fatal(err)
+	}
+	if err := logger.SetFormat(cfg.Log.Format); err != nil {
+		log.Fatal(err)
+	}
+	if command == "serve" {
+		logger.Log.Info("Логгер инициализирован")
+	}
+
+	switch command {
+	case "serve":
+		runServe(cfg, args)
+	case "migrate":
+		runMigrate(cfg, args)
+	case "user":
+		runUser(cfg, args)
+	case "notes":
+		runNotes(cfg, args)
+	case "keys":
+		runKeys(cfg, args)
+	default:
+		flags.Usage()
+		os.Exit(2)
+	}
 }
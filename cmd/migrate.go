@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"notes-api/config"
+	"notes-api/db"
+	"os"
+	"strconv"
+	"time"
+)
+
+// runMigrate выполняет подкоманду migrate: up, down [N], status или create [--dir каталог] <имя>
+func runMigrate(cfg *config.Config, args []string) {
+	if len(args) == 0 {
+		migrateUsage()
+	}
+
+	if args[0] == "create" {
+		flags := flag.NewFlagSet("migrate create", flag.ExitOnError)
+		dir := flags.String("dir", db.MigrationsDir, "каталог миграций в исходниках")
+		flags.Parse(args[1:])
+		if flags.NArg() != 1 {
+			migrateUsage()
+		}
+		up, down, err := db.CreateMigration(*dir, flags.Arg(0))
+		if err != nil {
+			log.Fatal("Failed to create migration:", err)
+		}
+		fmt.Println(up)
+		fmt.Println(down)
+		return
+	}
+
+	switch args[0] {
+	case "up", "down", "status":
+	default:
+		migrateUsage()
+	}
+
+	ctx := context.Background()
+	db.ConnectDB(cfg.DB)
+	migrator, err := db.SchemaMigrator()
+	if err != nil {
+		log.Fatal("Failed to load migrations:", err)
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, m := range applied {
+			fmt.Printf("применена %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatal("Migration failed:", err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("схема в актуальном состоянии")
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps <= 0 {
+				migrateUsage()
+			}
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		for _, m := range reverted {
+			fmt.Printf("откачена %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatal("Migration rollback failed:", err)
+		}
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			log.Fatal("Failed to read migration status:", err)
+		}
+		for _, s := range statuses {
+			state := "не применена"
+			if s.AppliedAt != nil {
+				state = "применена " + s.AppliedAt.Format(time.RFC3339)
+			}
+			name := s.Name
+			if name == "" {
+				name = "(нет в приложении)"
+			}
+			fmt.Printf("%04d  %-40s %s\n", s.Version, name, state)
+		}
+	}
+}
+
+func migrateUsage() {
+	fmt.Fprintln(os.Stderr, "использование: notes-api migrate up | down [N] | status | create [--dir каталог] <имя>")
+	os.Exit(2)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"notes-api/config"
+	"notes-api/db"
+	"notes-api/model"
+	storage "notes-api/repo"
+	"notes-api/service"
+	"os"
+	"strconv"
+)
+
+// runNotes выполняет подкоманду notes export: выгрузку заметок пользователя в файл или stdout
+func runNotes(cfg *config.Config, args []string) {
+	if len(args) == 0 || args[0] != "export" {
+		notesUsage()
+	}
+
+	flags := flag.NewFlagSet("notes export", flag.ExitOnError)
+	userRef := flags.String("user", "", "ID или email пользователя")
+	rawFormat := flags.String("format", "json", "формат выгрузки: json, markdown или html")
+	output := flags.String("output", "-", "файл выгрузки; - — stdout")
+	flags.Parse(args[1:])
+	if *userRef == "" || flags.NArg() > 0 {
+		notesUsage()
+	}
+	format, err := service.ParseExportFormat(*rawFormat)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	connect(context.Background(), cfg)
+	user, err := findUser(*userRef)
+	if err != nil {
+		log.Fatal("Failed to find user:", err)
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "-" {
+		f, err := os.Create(*output)
+		if err != nil {
+			log.Fatal("Failed to create export file:", err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	noteService := service.NewNoteService(storage.NewPostgresStore(db.DB))
+	if err := noteService.ExportNotes(user.ID, format, w); err != nil {
+		if *output != "-" {
+			os.Remove(*output)
+		}
+		log.Fatal("Export failed:", err)
+	}
+}
+
+// findUser ищет пользователя по ID или email
+func findUser(ref string) (model.User, error) {
+	var user model.User
+	q := db.DB.Where("email = ?", ref)
+	if id, err := strconv.ParseUint(ref, 10, 64); err == nil {
+		q = db.DB.Where("id = ?", id)
+	}
+	if err := q.First(&user).Error; err != nil {
+		return model.User{}, fmt.Errorf("пользователь %s: %w", ref, err)
+	}
+	return user, nil
+}
+
+func notesUsage() {
+	fmt.Fprintln(os.Stderr, "использование: notes-api notes export --user ID|EMAIL [--format json|markdown|html] [--output файл]")
+	os.Exit(2)
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"notes-api/attachments"
+	"notes-api/auth"
+	"notes-api/collab"
+	"notes-api/e2e"
+	"notes-api/jobs"
+	"notes-api/problem"
+	"notes-api/reminders"
+	storage "notes-api/repo"
+	"notes-api/service"
+	"notes-api/templates"
+	"notes-api/todos"
+	"notes-api/webhooks"
+)
+
+// registerProblems сопоставляет ошибки пакетов с кодами ответов API. Ошибки валидации
+// (problem.ErrValidation и problem.Invalid) сопоставляются сами; всё, чего здесь нет,
+// отдаётся клиенту как internal_error. Порядок важен: ошибка, оборачивающая несколько
+// перечисленных, получает код первой из них.
+func registerProblems() {
+	for _, m := range []struct {
+		err  error
+		code problem.Code
+	}{
+		{auth.ErrUserExists, problem.UserExists},
+		{auth.ErrInvalidCredentials, problem.InvalidCredentials},
+		{auth.ErrInvalidToken, problem.InvalidToken},
+		{auth.ErrUserDisabled, problem.UserDisabled},
+		{auth.ErrUserNotFound, problem.NotFound},
+
+		{service.ErrUnsupportedFormat, problem.UnsupportedFormat},
+		{service.ErrEncryptedNote, problem.NoteEncrypted},
+		{service.ErrInvalidSyncToken, problem.InvalidSyncToken},
+		{storage.ErrConflict, problem.NoteConflict},
+		{storage.ErrNoteArchived, problem.NoteArchived},
+		{storage.ErrNoteNotFound, problem.NoteNotFound},
+		{storage.ErrForbidden, problem.Forbidden},
+		{e2e.ErrInvalidEnvelope, problem.InvalidEnvelope},
+		{e2e.ErrKeyNotFound, problem.KeyNotFound},
+		{e2e.ErrKeyExists, problem.KeyExists},
+		{e2e.ErrTooManyKeys, problem.KeyLimit},
+
+		{attachments.ErrAttachmentNotFound, problem.AttachmentNotFound},
+		{attachments.ErrThumbnailNotReady, problem.ThumbnailNotReady},
+		{attachments.ErrTooLarge, problem.AttachmentTooLarge},
+		{attachments.ErrTypeNotAllowed, problem.AttachmentTypeDenied},
+		{attachments.ErrBadImage, problem.InvalidImage},
+
+		{collab.ErrEncrypted, problem.NoteEncrypted},
+		{collab.ErrTooLarge, problem.NoteTooLarge},
+
+		{reminders.ErrReminderNotFound, problem.ReminderNotFound},
+		{reminders.ErrNotificationNotFound, problem.NotificationNotFound},
+		{reminders.ErrTooManyReminders, problem.ReminderLimit},
+
+		{templates.ErrTemplateNotFound, problem.TemplateNotFound},
+		{templates.ErrForbidden, problem.TemplateReadOnly},
+
+		{todos.ErrTodoNotFound, problem.TodoNotFound},
+		{todos.ErrTodoChanged, problem.TodoChanged},
+		{todos.ErrInvalidQuery, problem.ValidationFailed},
+
+		{webhooks.ErrWebhookNotFound, problem.WebhookNotFound},
+		{webhooks.ErrDeliveryNotFound, problem.DeliveryNotFound},
+
+		{jobs.ErrJobNotFound, problem.JobNotFound},
+	} {
+		problem.Register(m.err, m.code)
+	}
+}
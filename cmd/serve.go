@@ -0,0 +1,403 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"notes-api/attachments"
+	"notes-api/auth"
+	"notes-api/collab"
+	"notes-api/config"
+	"notes-api/db"
+	"notes-api/e2e"
+	"notes-api/encryption"
+	"notes-api/events"
+	"notes-api/handler"
+	"notes-api/health"
+	"notes-api/jobs"
+	"notes-api/links"
+	"notes-api/logger"
+	"notes-api/metrics"
+	"notes-api/middleware"
+	"notes-api/model"
+	"notes-api/problem"
+	"notes-api/reminders"
+	storage "notes-api/repo"
+	"notes-api/service"
+	"notes-api/templates"
+	"notes-api/todos"
+	"notes-api/tracing"
+	"notes-api/webhooks"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	_ "notes-api/docs"
+
+	"github.com/gorilla/mux"
+	httpSwagger "github.com/swaggo/http-swagger"
+)
+
+// runServe запускает HTTP-сервер, фоновые задачи и планировщик
+func runServe(cfg *config.Config, args []string) {
+	if len(args) > 0 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	// ctx живёт до остановки сервера: по его отмене завершаются воркеры, планировщик и сеансы
+	ctx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+	var background sync.WaitGroup
+	goBackground := func(run func(ctx context.Context)) {
+		background.Add(1)
+		go func() {
+			defer background.Done()
+			run(ctx)
+		}()
+	}
+	signals, stopSignals := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stopSignals()
+
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing)
+	if err != nil {
+		log.Fatal("Failed to init tracing:", err)
+	}
+	logger.Log.AddHook(tracing.LogHook{})
+
+	registerProblems()
+	keyring := connect(ctx, cfg)
+	sqlDB, err := db.DB.DB()
+	if err != nil {
+		log.Fatal("Failed to connect to database:", err)
+	}
+	if err := db.DB.Use(metrics.GormPlugin{}); err != nil {
+		log.Fatal("Failed to register database metrics:", err)
+	}
+	if err := db.DB.Use(tracing.GormPlugin{}); err != nil {
+		log.Fatal("Failed to register database tracing:", err)
+	}
+	metrics.RegisterDB(sqlDB)
+	migrator, err := db.SchemaMigrator()
+	if err != nil {
+		log.Fatal("Failed to load migrations:", err)
+	}
+	healthHandler := &health.HealthHandler{Checks: map[string]health.Check{
+		"db":         sqlDB.PingContext,
+		"migrations": migrator.Check,
+	}}
+
+	newStore := storage.NewPostgresStore(db.DB)
+	noteService := service.NewNoteService(newStore)
+	authService := auth.NewAuthService(db.DB, tokenConfig(cfg.JWT))
+	h := &handler.NoteHandler{Store: noteService}
+	authHandler := &auth.AuthHandler{Service: authService}
+	queue := jobs.NewQueue(db.DB)
+	importService := service.NewImportService(noteService, newStore, queue)
+	importHandler := &handler.ImportHandler{Service: importService, MaxUploadSize: cfg.Limits.ImportMaxSize}
+	syncService := service.NewSyncService(noteService, newStore, newStore)
+	syncHandler := &handler.SyncHandler{Service: syncService}
+	jobHandler := &jobs.JobHandler{Queue: queue}
+	webhookService := webhooks.NewWebhookService(db.DB, queue, cfg.Webhooks.AllowPrivateNetworks)
+	webhookHandler := &webhooks.WebhookHandler{Service: webhookService}
+
+	blobs, err := attachmentBlobStore(ctx, cfg.Attachments)
+	if err != nil {
+		log.Fatal("Failed to init attachment storage:", err)
+	}
+	attachmentService := attachments.NewAttachmentService(db.DB, blobs, queue, attachmentLimits(cfg.Limits))
+	attachmentHandler := &attachments.AttachmentHandler{Service: attachmentService}
+
+	eventStore := events.NewStore(db.DB)
+	var pubsub events.PubSub = events.NewLocalPubSub()
+	if cfg.Events.PubSub == "postgres" {
+		pgPubSub := events.NewPostgresPubSub(db.DB, eventStore, cfg.DB.DSN())
+		goBackground(pgPubSub.Run)
+		pubsub = pgPubSub
+	}
+	eventStream := events.NewStream(eventStore, pubsub)
+	streamHandler := &handler.StreamHandler{Stream: eventStream}
+	collabHub := collab.NewHub(db.DB, noteService)
+	collabHandler := &collab.CollabHandler{Hub: collabHub}
+	todoService := todos.NewTodoService(db.DB, noteService)
+	todoHandler := &todos.TodoHandler{Service: todoService}
+	linkService := links.NewLinkService(db.DB, noteService, queue)
+	linkHandler := &links.LinkHandler{Service: linkService}
+	templateService := templates.NewTemplateService(db.DB, noteService)
+	templateHandler := &templates.TemplateHandler{Service: templateService}
+	if err := templateService.SeedGlobal(); err != nil {
+		log.Fatal("Failed to seed note templates:", err)
+	}
+	reminderService := reminders.NewReminderService(db.DB, queue, map[string]reminders.Notifier{
+		model.ChannelInApp:   &reminders.InAppNotifier{DB: db.DB},
+		model.ChannelEmail:   &reminders.EmailNotifier{DB: db.DB, Mailer: mailer(cfg.SMTP)},
+		model.ChannelWebhook: &reminders.WebhookNotifier{Webhooks: webhookService},
+	})
+	reminderHandler := &reminders.ReminderHandler{Service: reminderService}
+	keyHandler := &e2e.KeyHandler{Service: e2e.NewKeyService(db.DB)}
+	noteService.Events = events.Publishers{todoService, linkService, reminderService, eventStream, webhookService, collabHub, attachmentService}
+
+	worker := jobs.NewWorker(queue, cfg.Jobs.Concurrency)
+	worker.Register(jobs.CleanupJobType, queue.HandleCleanup)
+	worker.Register(service.ImportJobType, importService.HandleImportJob)
+	worker.Register(webhooks.DeliverJobType, webhookService.HandleDeliverJob)
+	worker.Register(events.CleanupJobType, eventStore.HandleCleanup)
+	worker.Register(service.SyncCleanupJobType, syncService.HandleCleanup)
+	worker.Register(attachments.DeleteNoteJobType, attachmentService.HandleDeleteNoteJob)
+	worker.Register(attachments.ThumbnailJobType, attachmentService.HandleThumbnailJob)
+	worker.OnDead(attachments.ThumbnailJobType, attachmentService.HandleThumbnailDead)
+	worker.Register(todos.ReindexJobType, todoService.HandleReindexJob)
+	worker.Register(links.ReindexJobType, linkService.HandleReindexJob)
+	worker.Register(links.RewriteJobType, linkService.HandleRewriteJob)
+	worker.Register(reminders.DispatchJobType, reminderService.HandleDispatchJob)
+	worker.Register(reminders.FireJobType, reminderService.HandleFireJob)
+	if keyring != nil {
+		worker.Register(encryption.RotateJobType, keyring.HandleRotateJob)
+	}
+
+	// Заметки, сохранённые до появления списка задач и графа ссылок, индексируются один раз в фоне
+	for _, jobType := range []string{todos.ReindexJobType, links.ReindexJobType} {
+		if _, err := queue.Enqueue(ctx, jobType, nil, jobs.Options{UniqueKey: jobType}); err != nil {
+			logger.Log.WithError(err).WithField("type", jobType).Error("Не удалось поставить индексацию заметок в очередь")
+		}
+	}
+	// Заметки, записанные открытым текстом, и ключи под прежним мастер-ключом переводятся на текущие ключи.
+	// Ключ уникальности включает текущий мастер-ключ, чтобы после его смены задача запустилась снова.
+	if keyring != nil {
+		uniqueKey := encryption.RotateJobType + ":" + keyring.Provider.CurrentKeyID()
+		if _, err := queue.Enqueue(ctx, encryption.RotateJobType, nil, jobs.Options{UniqueKey: uniqueKey}); err != nil {
+			logger.Log.WithError(err).Error("Не удалось поставить ротацию ключей шифрования в очередь")
+		}
+	}
+
+	scheduler := jobs.NewScheduler(queue)
+	if err := scheduler.Add("@daily", jobs.CleanupJobType, nil); err != nil {
+		log.Fatal(err)
+	}
+	if err := scheduler.Add("@daily", events.CleanupJobType, nil); err != nil {
+		log.Fatal(err)
+	}
+	if err := scheduler.Add("@daily", service.SyncCleanupJobType, nil); err != nil {
+		log.Fatal(err)
+	}
+	if err := scheduler.Add("@every 1m", reminders.DispatchJobType, nil); err != nil {
+		log.Fatal(err)
+	}
+
+	goBackground(worker.Run)
+	goBackground(scheduler.Run)
+	goBackground(collabHub.Run)
+
+	r := mux.NewRouter()
+	r.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		problem.Write(w, r, problem.NotFound)
+	})
+	r.MethodNotAllowedHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		problem.Write(w, r, problem.MethodNotAllowed)
+	})
+	r.Use(middleware.Route, tracing.Middleware(cfg.Tracing.ServiceName), metrics.Middleware)
+
+	r.PathPrefix("/swagger").Handler(httpSwagger.WrapHandler)
+	r.HandleFunc("/healthz", healthHandler.Live).Methods("GET")
+	r.HandleFunc("/readyz", healthHandler.Ready).Methods("GET")
+	r.HandleFunc("/register", authHandler.Register).Methods("POST")
+	r.HandleFunc("/login", authHandler.Login).Methods("POST")
+	r.HandleFunc("/refresh", authHandler.Refresh).Methods("POST")
+
+	// Потоки событий и совместное редактирование регистрируются до подроутера /notes: им нужна авторизация через access_token
+	jwtAuth := middleware.JWTAuthMiddleware([]byte(cfg.JWT.Secret), authService)
+	jwtQueryAuth := middleware.JWTQueryAuthMiddleware([]byte(cfg.JWT.Secret), authService)
+	stopStreams := make(chan struct{})
+	stream := middleware.Stream(stopStreams)
+	transfer := middleware.Transfer(cfg.HTTP.TransferTimeout)
+	r.Handle("/notes/stream", stream(jwtQueryAuth(http.HandlerFunc(streamHandler.SSE)))).Methods("GET")
+	r.Handle("/notes/ws", stream(jwtQueryAuth(http.HandlerFunc(streamHandler.WebSocket)))).Methods("GET")
+	r.Handle("/notes/{id}/collab", stream(jwtQueryAuth(http.HandlerFunc(collabHandler.Connect)))).Methods("GET")
+
+	authRoutes := r.PathPrefix("/notes").Subrouter()
+	authRoutes.Use(jwtAuth)
+	authRoutes.HandleFunc("", h.GetAll).Methods("GET")
+	authRoutes.HandleFunc("", templateHandler.Instantiate).Methods("POST").Queries("template_id", "{template_id}")
+	authRoutes.HandleFunc("", h.Create).Methods("POST")
+	authRoutes.Handle("/export", transfer(http.HandlerFunc(h.Export))).Methods("GET")
+	authRoutes.Handle("/import", transfer(http.HandlerFunc(importHandler.Import))).Methods("POST")
+	authRoutes.HandleFunc("/{id}", h.GetByID).Methods("GET")
+	authRoutes.HandleFunc("/{id}", h.Update).Methods("PUT")
+	authRoutes.HandleFunc("/{id}", h.Delete).Methods("DELETE")
+	authRoutes.HandleFunc("/{id}/render", h.Render).Methods("GET")
+	authRoutes.HandleFunc("/{id}/due", h.SetDue).Methods("PUT")
+	authRoutes.HandleFunc("/{id}/pin", h.Pin).Methods("POST")
+	authRoutes.HandleFunc("/{id}/unpin", h.Unpin).Methods("POST")
+	authRoutes.HandleFunc("/{id}/archive", h.Archive).Methods("POST")
+	authRoutes.HandleFunc("/{id}/unarchive", h.Unarchive).Methods("POST")
+	authRoutes.HandleFunc("/{id}/star", h.Star).Methods("POST")
+	authRoutes.HandleFunc("/{id}/unstar", h.Unstar).Methods("POST")
+	authRoutes.HandleFunc("/{id}/reminders", reminderHandler.List).Methods("GET")
+	authRoutes.HandleFunc("/{id}/reminders", reminderHandler.Create).Methods("POST")
+	authRoutes.HandleFunc("/{id}/reminders/{reminder_id}", reminderHandler.Delete).Methods("DELETE")
+	authRoutes.HandleFunc("/{id}/todos/{index}/toggle", todoHandler.Toggle).Methods("POST")
+	authRoutes.HandleFunc("/{id}/links", linkHandler.Links).Methods("GET")
+	authRoutes.HandleFunc("/{id}/backlinks", linkHandler.Backlinks).Methods("GET")
+	authRoutes.HandleFunc("/{id}/revisions", collabHandler.Revisions).Methods("GET")
+	authRoutes.HandleFunc("/{id}/attachments", attachmentHandler.List).Methods("GET")
+	authRoutes.Handle("/{id}/attachments", transfer(http.HandlerFunc(attachmentHandler.Upload))).Methods("POST")
+	authRoutes.Handle("/{id}/attachments/{attachment_id}", transfer(http.HandlerFunc(attachmentHandler.Download))).Methods("GET")
+	authRoutes.HandleFunc("/{id}/attachments/{attachment_id}", attachmentHandler.Delete).Methods("DELETE")
+	authRoutes.HandleFunc("/{id}/attachments/{attachment_id}/thumbnail", attachmentHandler.Thumbnail).Methods("GET")
+
+	authProtected := r.NewRoute().Subrouter()
+	authProtected.Use(jwtAuth)
+	authProtected.HandleFunc("/logout", authHandler.Logout).Methods("POST")
+	authProtected.HandleFunc("/jobs/{id}", jobHandler.Get).Methods("GET")
+	authProtected.HandleFunc("/todos", todoHandler.List).Methods("GET")
+	authProtected.HandleFunc("/notifications", reminderHandler.Notifications).Methods("GET")
+	authProtected.HandleFunc("/notifications/{id}/read", reminderHandler.MarkRead).Methods("POST")
+	authProtected.HandleFunc("/templates", templateHandler.List).Methods("GET")
+	authProtected.HandleFunc("/templates", templateHandler.Create).Methods("POST")
+	authProtected.HandleFunc("/templates/{id}", templateHandler.Get).Methods("GET")
+	authProtected.HandleFunc("/templates/{id}", templateHandler.Update).Methods("PUT")
+	authProtected.HandleFunc("/templates/{id}", templateHandler.Delete).Methods("DELETE")
+	authProtected.HandleFunc("/keys", keyHandler.List).Methods("GET")
+	authProtected.HandleFunc("/keys", keyHandler.Create).Methods("POST")
+	authProtected.HandleFunc("/keys/{kid}", keyHandler.Get).Methods("GET")
+	authProtected.HandleFunc("/keys/{kid}", keyHandler.Update).Methods("PUT")
+	authProtected.HandleFunc("/sync", syncHandler.Changes).Methods("GET")
+	authProtected.HandleFunc("/sync", syncHandler.Apply).Methods("POST")
+	authProtected.HandleFunc("/webhooks", webhookHandler.List).Methods("GET")
+	authProtected.HandleFunc("/webhooks", webhookHandler.Create).Methods("POST")
+	authProtected.HandleFunc("/webhooks/{id}", webhookHandler.Get).Methods("GET")
+	authProtected.HandleFunc("/webhooks/{id}", webhookHandler.Update).Methods("PUT")
+	authProtected.HandleFunc("/webhooks/{id}", webhookHandler.Delete).Methods("DELETE")
+	authProtected.HandleFunc("/webhooks/{id}/deliveries", webhookHandler.Deliveries).Methods("GET")
+	authProtected.HandleFunc("/webhooks/{id}/deliveries/{delivery_id}/replay", webhookHandler.Replay).Methods("POST")
+
+	srv := &http.Server{
+		Addr:              cfg.HTTP.Addr,
+		Handler:           middleware.RequestID(middleware.AccessLog(r)),
+		ReadTimeout:       cfg.HTTP.ReadTimeout,
+		ReadHeaderTimeout: cfg.HTTP.ReadHeaderTimeout,
+		WriteTimeout:      cfg.HTTP.WriteTimeout,
+		IdleTimeout:       cfg.HTTP.IdleTimeout,
+	}
+	serveErr := make(chan error, 2)
+	go func() {
+		serveErr <- srv.ListenAndServe()
+	}()
+	log.Println("Server started on " + cfg.HTTP.Addr)
+
+	var metricsSrv *http.Server
+	if cfg.Metrics.Addr != "" {
+		metricsMux := http.NewServeMux()
+		metricsMux.Handle("/metrics", metrics.Handler())
+		metricsSrv = &http.Server{
+			Addr:              cfg.Metrics.Addr,
+			Handler:           metricsMux,
+			ReadHeaderTimeout: cfg.HTTP.ReadHeaderTimeout,
+			WriteTimeout:      cfg.HTTP.WriteTimeout,
+		}
+		go func() {
+			if err := metricsSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				serveErr <- err
+			}
+		}()
+		log.Println("Metrics server started on " + cfg.Metrics.Addr)
+	}
+
+	select {
+	case err := <-serveErr:
+		log.Fatal(err)
+	case <-signals.Done():
+	}
+	// Повторный сигнал завершает процесс сразу, не дожидаясь остановки
+	stopSignals()
+	logger.Log.Info("Получен сигнал остановки, дорабатываем текущие запросы")
+
+	// Пока балансировщик не заметил 503 на /readyz, запросы продолжают приходить: принимаем их
+	healthHandler.Drain()
+	time.Sleep(cfg.HTTP.DrainDelay)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.HTTP.ShutdownTimeout)
+	defer cancel()
+
+	close(stopStreams)
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logger.Log.WithError(err).Warn("Не все запросы завершились до таймаута остановки")
+	}
+	// Метрики отдаются до конца остановки HTTP-сервера, чтобы последний сбор застал итоговые значения
+	if metricsSrv != nil {
+		metricsSrv.Shutdown(shutdownCtx)
+	}
+
+	// Воркеры дорабатывают взятые задачи; незавершённые вернёт в очередь другой экземпляр
+	stopBackground()
+	stopped := make(chan struct{})
+	go func() {
+		background.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		sqlDB.Close()
+	case <-shutdownCtx.Done():
+		// Базу не закрываем: зависшие задачи ещё пишут в неё, соединения закроются с процессом
+		logger.Log.Warn("Фоновые задачи не завершились до таймаута остановки")
+	}
+
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		logger.Log.WithError(err).Warn("Не удалось выгрузить спаны трассировки")
+	}
+	logger.Log.Info("Сервер остановлен")
+}
+
+// attachmentBlobStore выбирает хранилище вложений: s3 или локальный каталог (по умолчанию)
+func attachmentBlobStore(ctx context.Context, cfg config.Attachments) (attachments.BlobStore, error) {
+	switch cfg.Storage {
+	case "s3":
+		return attachments.NewS3BlobStore(ctx, attachments.S3Config{
+			Endpoint:  cfg.S3.Endpoint,
+			AccessKey: cfg.S3.AccessKey,
+			SecretKey: string(cfg.S3.SecretKey),
+			Bucket:    cfg.S3.Bucket,
+			Region:    cfg.S3.Region,
+			UseSSL:    cfg.S3.UseSSL,
+		})
+	case "", "fs":
+		return attachments.NewFSBlobStore(cfg.Dir)
+	default:
+		return nil, fmt.Errorf("неизвестное хранилище вложений %q", cfg.Storage)
+	}
+}
+
+func attachmentLimits(cfg config.Limits) attachments.Limits {
+	limits := attachments.DefaultLimits
+	limits.MaxSize = cfg.AttachmentMaxSize
+	if len(cfg.AttachmentTypes) > 0 {
+		limits.AllowedTypes = cfg.AttachmentTypes
+	}
+	return limits
+}
+
+func tokenConfig(cfg config.JWT) auth.TokenConfig {
+	return auth.TokenConfig{
+		Secret:     []byte(cfg.Secret),
+		AccessTTL:  cfg.AccessTTL,
+		RefreshTTL: cfg.RefreshTTL,
+	}
+}
+
+// mailer возвращает SMTP-отправщик писем, если задан SMTP-сервер, иначе письма только пишутся в лог
+func mailer(cfg config.SMTP) reminders.Mailer {
+	if cfg.Host == "" {
+		return reminders.LogMailer{}
+	}
+	return reminders.NewSMTPMailer(reminders.SMTPConfig{
+		Host:     cfg.Host,
+		Port:     cfg.Port,
+		Username: cfg.Username,
+		Password: string(cfg.Password),
+		From:     cfg.From,
+	})
+}
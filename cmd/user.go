@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"log"
+	"notes-api/auth"
+	"notes-api/config"
+	"notes-api/db"
+	"os"
+)
+
+// runUser выполняет подкоманды user create | disable | reset-password
+func runUser(cfg *config.Config, args []string) {
+	if len(args) == 0 {
+		userUsage()
+	}
+	command := args[0]
+	switch command {
+	case "create", "disable", "reset-password":
+	default:
+		userUsage()
+	}
+
+	flags := flag.NewFlagSet("user "+command, flag.ExitOnError)
+	email := flags.String("email", "", "email пользователя")
+	var password *string
+	if command != "disable" {
+		password = flags.String("password", "", "пароль (виден в списке процессов); без флага генерируется случайный")
+	}
+	flags.Parse(args[1:])
+	if *email == "" || flags.NArg() > 0 {
+		userUsage()
+	}
+
+	generated := false
+	if password != nil && *password == "" {
+		*password = randomPassword()
+		generated = true
+	}
+
+	connect(context.Background(), cfg)
+	authService := auth.NewAuthService(db.DB, tokenConfig(cfg.JWT))
+
+	var err error
+	switch command {
+	case "create":
+		err = authService.Register(*email, *password)
+	case "disable":
+		err = authService.Disable(*email)
+	case "reset-password":
+		err = authService.ResetPassword(*email, *password)
+	}
+	if err != nil {
+		log.Fatal("User command failed:", err)
+	}
+
+	switch command {
+	case "create":
+		fmt.Printf("пользователь %s создан\n", *email)
+	case "disable":
+		fmt.Printf("пользователь %s отключён\n", *email)
+	case "reset-password":
+		fmt.Printf("пароль пользователя %s изменён\n", *email)
+	}
+	if generated {
+		fmt.Printf("пароль: %s\n", *password)
+	}
+}
+
+// randomPassword возвращает случайный пароль из 24 символов base64url
+func randomPassword() string {
+	b := make([]byte, 18)
+	if _, err := rand.Read(b); err != nil {
+		log.Fatal("Failed to generate password:", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func userUsage() {
+	fmt.Fprintln(os.Stderr, "использование: notes-api user create|reset-password --email E [--password P] | user disable --email E")
+	os.Exit(2)
+}
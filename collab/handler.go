@@ -0,0 +1,220 @@
+package collab
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"notes-api/httpx"
+	"notes-api/logger"
+	"notes-api/middleware"
+	"notes-api/problem"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	pingPeriod = 25 * time.Second
+	// pongWait — сколько ждать ответа на ping, прежде чем считать соединение мёртвым
+	pongWait  = 2 * pingPeriod
+	writeWait = 10 * time.Second
+	// maxMessageSize — вставка максимального документа с запасом на экранирование в JSON
+	maxMessageSize = 8 * MaxContentLength
+
+	defaultRevisionsLimit = 20
+	maxRevisionsLimit     = 100
+)
+
+// Токен передаётся явно (заголовком или access_token), а не cookie,
+// поэтому проверка Origin не защищает ни от чего и только мешает нативным клиентам
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin:     func(r *http.Request) bool { return true },
+}
+
+type CollabHandler struct {
+	Hub *Hub
+}
+
+// Connect godoc
+// @Summary Совместное редактирование заметки (WebSocket)
+// @Description Открывает сеанс совместного редактирования содержимого заметки. Правки передаются операциями в формате ot.js ([5, "abc", -2] — пропустить 5 символов, вставить "abc", удалить 2; длины в кодовых единицах UTF-16). Сервер сводит одновременные правки, рассылает курсоры участников и раз в несколько секунд сохраняет документ в заметку и историю версий. Формат сообщений описан в collab.Message. Токен можно передать в параметре access_token
+// @Tags notes
+// @Security ApiKeyAuth
+// @Param id path int true "ID заметки"
+// @Param access_token query string false "JWT, если нельзя передать заголовок Authorization"
+// @Success 101 {object} collab.Message "Переход на WebSocket"
+// @Failure 400 {object} problem.Problem "Неверный ID"
+// @Failure 401 {object} problem.Problem "Пользователь не аутентифицирован"
+// @Failure 403 {object} problem.Problem "Доступ запрещен"
+// @Failure 404 {object} problem.Problem "Заметка не найдена"
+// @Router /notes/{id}/collab [get]
+func (h *CollabHandler) Connect(w http.ResponseWriter, r *http.Request) {
+	userID, noteID, ok := noteRequest(w, r)
+	if !ok {
+		return
+	}
+
+	session, client, err := h.Hub.Join(noteID, userID)
+	if err != nil {
+		logger.FromContext(r.Context()).WithError(err).WithFields(logger.Fields{"user_id": userID, "note_id": noteID}).
+			Warn("Не удалось подключиться к совместному редактированию")
+		problem.Error(w, r, err)
+		return
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		logger.FromContext(r.Context()).WithError(err).Warn("Не удалось установить WebSocket-соединение")
+		h.Hub.Leave(session, client, nil)
+		return
+	}
+	defer conn.Close()
+
+	log := logger.FromContext(r.Context()).WithFields(logger.Fields{
+		"user_id":   userID,
+		"note_id":   noteID,
+		"client_id": client.ID,
+	})
+	log.Info("Клиент подключился к совместному редактированию")
+
+	written := make(chan struct{})
+	go func() {
+		defer close(written)
+		writeLoop(conn, client, func() error { return middleware.CheckUser(r.Context()) })
+	}()
+
+	// При остановке сервера закрываем соединение: чтение ниже прервётся, сеанс сохранит правки
+	go func() {
+		select {
+		case <-r.Context().Done():
+			conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseGoingAway, ""),
+				time.Now().Add(writeWait))
+			conn.Close()
+		case <-written:
+		}
+	}()
+
+	conn.SetReadLimit(maxMessageSize)
+	conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	var reason error
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			break
+		}
+		var msg Message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			log.WithError(err).Warn("Некорректное сообщение совместного редактирования")
+			reason = errors.New("некорректное сообщение")
+			break
+		}
+		if err := h.Hub.Receive(session, client, msg); err != nil {
+			log.WithError(err).Warn("Сообщение совместного редактирования отклонено")
+			reason = err
+			break
+		}
+	}
+
+	// Leave закрывает client.Send; ждём, пока writeLoop допишет ошибку и закроет соединение
+	h.Hub.Leave(session, client, reason)
+	<-written
+	log.Info("Клиент отключился от совместного редактирования")
+}
+
+// writeLoop отправляет клиенту сообщения сеанса и ping. Завершается, когда
+// сеанс закрывает client.Send или checkUser перед очередным ping сообщает, что доступ
+// запрещён, и закрывает соединение — это прерывает чтение.
+func writeLoop(conn *websocket.Conn, client *Client, checkUser func() error) {
+	ticker := time.NewTicker(pingPeriod)
+	defer ticker.Stop()
+	defer conn.Close()
+
+	for {
+		select {
+		case data, ok := <-client.Send:
+			conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+				return
+			}
+			if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if err := checkUser(); err != nil {
+				conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "доступ запрещён"),
+					time.Now().Add(writeWait))
+				return
+			}
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Revisions godoc
+// @Summary История версий заметки
+// @Description Версии, сохранённые сеансами совместного редактирования, начиная с последней
+// @Tags notes
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path int true "ID заметки"
+// @Param limit query int false "Сколько версий вернуть (по умолчанию 20, не больше 100)"
+// @Success 200 {array} model.NoteRevision "Версии заметки"
+// @Failure 400 {object} problem.Problem "Неверный ID"
+// @Failure 401 {object} problem.Problem "Пользователь не аутентифицирован"
+// @Failure 403 {object} problem.Problem "Доступ запрещен"
+// @Failure 404 {object} problem.Problem "Заметка не найдена"
+// @Router /notes/{id}/revisions [get]
+func (h *CollabHandler) Revisions(w http.ResponseWriter, r *http.Request) {
+	userID, noteID, ok := noteRequest(w, r)
+	if !ok {
+		return
+	}
+
+	note, err := h.Hub.Notes.GetNoteByID(int(noteID))
+	if err != nil {
+		logger.FromContext(r.Context()).WithError(err).WithField("note_id", noteID).Warn("Заметка не найдена")
+		problem.Write(w, r, problem.NoteNotFound)
+		return
+	}
+	if note.UserID != userID {
+		logger.FromContext(r.Context()).WithFields(logger.Fields{"user_id": userID, "note_id": noteID}).Warn("Доступ запрещен")
+		problem.Write(w, r, problem.Forbidden)
+		return
+	}
+
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = defaultRevisionsLimit
+	}
+	limit = min(limit, maxRevisionsLimit)
+
+	revisions, err := h.Hub.Revisions(noteID, limit)
+	if err != nil {
+		logger.FromContext(r.Context()).WithError(err).WithField("note_id", noteID).Error("Ошибка при получении версий заметки")
+		problem.Write(w, r, problem.Internal)
+		return
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, revisions)
+}
+
+func noteRequest(w http.ResponseWriter, r *http.Request) (uint, uint, bool) {
+	userID, ok := httpx.UserID(w, r)
+	if !ok {
+		return 0, 0, false
+	}
+	noteID, ok := httpx.PathID(w, r, "id")
+	return userID, noteID, ok
+}
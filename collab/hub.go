@@ -0,0 +1,258 @@
+package collab
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"notes-api/events"
+	"notes-api/logger"
+	"notes-api/model"
+	storage "notes-api/repo"
+	"notes-api/service"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DefaultSnapshotInterval — как часто правки сеанса сохраняются в заметку
+const DefaultSnapshotInterval = 5 * time.Second
+
+var (
+	ErrEncrypted = errors.New("зашифрованную на клиенте заметку нельзя редактировать совместно")
+
+	errSessionBroken = errors.New("сеанс совместного редактирования прерван, подключитесь заново")
+)
+
+// Hub держит открытые сеансы совместного редактирования и периодически
+// сохраняет их в заметки.
+//
+// Сеансы живут в памяти процесса: если экземпляров сервиса несколько,
+// подключения к одной заметке должны попадать на один экземпляр
+// (например, балансировкой по ID заметки).
+type Hub struct {
+	DB               *gorm.DB
+	Notes            *service.NoteService
+	SnapshotInterval time.Duration
+
+	mu       sync.Mutex
+	sessions map[uint]*Session
+}
+
+func NewHub(db *gorm.DB, notes *service.NoteService) *Hub {
+	return &Hub{
+		DB:               db,
+		Notes:            notes,
+		SnapshotInterval: DefaultSnapshotInterval,
+		sessions:         make(map[uint]*Session),
+	}
+}
+
+// Join подключает пользователя к сеансу заметки, создавая сеанс при необходимости.
+// Редактировать заметку может только её владелец.
+func (h *Hub) Join(noteID, userID uint) (*Session, *Client, error) {
+	note, err := h.Notes.GetNoteByID(int(noteID))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, storage.ErrNoteNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if note.UserID != userID {
+		return nil, nil, storage.ErrForbidden
+	}
+	// Правки шифротекста нельзя сливать на сервере
+	if note.Encrypted {
+		return nil, nil, ErrEncrypted
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s := h.sessions[noteID]
+	if s == nil {
+		s, err = newSession(note)
+		if err != nil {
+			return nil, nil, err
+		}
+		h.sessions[noteID] = s
+	} else if err := s.rebase(note); err != nil {
+		// Заметку поменяли в обход сеанса; без rebase участники увидели бы старый текст
+		logger.Log.WithError(err).WithField("note_id", noteID).Error("Не удалось влить изменения заметки в сеанс")
+		return nil, nil, err
+	}
+
+	c := &Client{ID: newClientID(), UserID: userID, Send: make(chan []byte, clientSendBuffer)}
+	s.join(c)
+	return s, c, nil
+}
+
+// Receive обрабатывает сообщение клиента
+func (h *Hub) Receive(s *Session, c *Client, msg Message) error {
+	switch msg.Type {
+	case MessageOp:
+		if msg.Op == nil {
+			return errors.New("в сообщении op нет операции")
+		}
+		return s.receive(c, msg.Revision, msg.Op, msg.Cursor)
+	case MessageCursor:
+		s.moveCursor(c, msg.Cursor)
+		return nil
+	default:
+		return errors.New("неизвестный тип сообщения")
+	}
+}
+
+// Leave отключает клиента. Когда уходит последний участник, правки сохраняются сразу.
+func (h *Hub) Leave(s *Session, c *Client, reason error) {
+	if s.leave(c, reason) {
+		go h.snapshot(s)
+	}
+}
+
+// Publish реализует events.Publisher: при удалении заметки её сеанс закрывается,
+// а история версий удаляется вместе с ней
+func (h *Hub) Publish(e events.Event) {
+	if e.Type != events.NoteDeleted {
+		return
+	}
+	if err := h.DB.Where("note_id = ?", e.Note.ID).Delete(&model.NoteRevision{}).Error; err != nil {
+		logger.Log.WithError(err).WithField("note_id", e.Note.ID).Error("Не удалось удалить историю версий заметки")
+	}
+
+	h.mu.Lock()
+	s := h.sessions[e.Note.ID]
+	h.mu.Unlock()
+	if s != nil {
+		h.closeSession(s, ErrNoteDeleted)
+	}
+}
+
+// Run сохраняет сеансы раз в SnapshotInterval и убирает сеансы без участников.
+// Блокируется, пока не будет отменён ctx; перед выходом сохраняет всё несохранённое.
+func (h *Hub) Run(ctx context.Context) {
+	ticker := time.NewTicker(h.SnapshotInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			for _, s := range h.list() {
+				h.snapshot(s)
+			}
+			return
+		case <-ticker.C:
+			for _, s := range h.list() {
+				h.snapshot(s)
+			}
+			h.evictIdle()
+		}
+	}
+}
+
+// Revisions возвращает сохранённые версии заметки, начиная с последней
+func (h *Hub) Revisions(noteID uint, limit int) ([]model.NoteRevision, error) {
+	var revisions []model.NoteRevision
+	err := h.DB.Where("note_id = ?", noteID).Order("id DESC").Limit(limit).Find(&revisions).Error
+	return revisions, err
+}
+
+// snapshot сохраняет несохранённые правки сеанса в заметку и историю версий
+func (h *Hub) snapshot(s *Session) {
+	state, ok := s.beginSnapshot()
+	if !ok {
+		return
+	}
+
+	log := logger.Log.WithFields(logger.Fields{
+		"note_id":  s.noteID,
+		"revision": state.revision,
+	})
+
+	saved, err := h.Notes.UpdateNoteIfSeq(int(s.noteID), state.base.ChangeSeq, model.Note{
+		Title:   state.base.Title,
+		Content: state.content,
+	})
+	if err == nil {
+		revision := model.NoteRevision{
+			NoteID:    s.noteID,
+			UserID:    s.userID,
+			Revision:  state.revision,
+			ChangeSeq: saved.ChangeSeq,
+			Source:    model.RevisionSourceCollab,
+			Title:     saved.Title,
+			Content:   saved.Content,
+		}
+		if err := h.DB.Create(&revision).Error; err != nil {
+			log.WithError(err).Error("Не удалось сохранить версию заметки")
+		}
+	}
+
+	if finishErr := s.finishSnapshot(state, saved, err); finishErr != nil {
+		log.WithError(finishErr).Error("Не удалось вернуть несохранённые правки в сеанс")
+		h.closeSession(s, errSessionBroken)
+		return
+	}
+
+	switch {
+	case err == nil:
+		log.WithField("change_seq", saved.ChangeSeq).Info("Сеанс совместного редактирования сохранён в заметку")
+	case errors.Is(err, storage.ErrConflict):
+		// Заметку поменяли в обход сеанса: вливаем её версию, сохраним на следующем такте
+		log.Info("Заметка изменена в обход сеанса, изменения вливаются в сеанс")
+		note, getErr := h.Notes.GetNoteByID(int(s.noteID))
+		if getErr == nil {
+			getErr = s.rebase(note)
+		}
+		if errors.Is(getErr, gorm.ErrRecordNotFound) {
+			h.closeSession(s, ErrNoteDeleted)
+		} else if getErr != nil {
+			log.WithError(getErr).Error("Не удалось влить изменения заметки в сеанс")
+		}
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		h.closeSession(s, ErrNoteDeleted)
+	default:
+		log.WithError(err).Error("Не удалось сохранить сеанс совместного редактирования")
+	}
+}
+
+// closeSession закрывает сеанс и отключает участников с причиной reason
+func (h *Hub) closeSession(s *Session, reason error) {
+	h.mu.Lock()
+	if h.sessions[s.noteID] == s {
+		delete(h.sessions, s.noteID)
+	}
+	h.mu.Unlock()
+
+	s.close(reason)
+	logger.Log.WithError(reason).WithField("note_id", s.noteID).Info("Сеанс совместного редактирования закрыт")
+}
+
+func (h *Hub) list() []*Session {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	sessions := make([]*Session, 0, len(h.sessions))
+	for _, s := range h.sessions {
+		sessions = append(sessions, s)
+	}
+	return sessions
+}
+
+func (h *Hub) evictIdle() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for id, s := range h.sessions {
+		if s.idle() {
+			delete(h.sessions, id)
+		}
+	}
+}
+
+func newClientID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
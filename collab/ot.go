@@ -0,0 +1,391 @@
+package collab
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"unicode/utf16"
+)
+
+// Operation — правка текста в духе ot.js: последовательность компонентов
+// retain (пропустить n символов), insert (вставить строку) и delete (удалить n символов).
+// Длины считаются в кодовых единицах UTF-16, как length у строк в JavaScript,
+// чтобы клиенты на ot.js и подобных библиотеках работали без пересчёта позиций.
+//
+// В JSON операция — массив: положительное число — retain, строка — insert,
+// отрицательное число — delete. Например, [5, "abc", -2, 3].
+type Operation struct {
+	ops []component
+
+	// BaseLength — длина документа, к которому применима операция
+	BaseLength int
+	// TargetLength — длина документа после применения
+	TargetLength int
+}
+
+type component struct {
+	retain int
+	insert []uint16
+	delete int
+}
+
+func (c component) isRetain() bool { return c.retain > 0 }
+func (c component) isInsert() bool { return len(c.insert) > 0 }
+func (c component) isDelete() bool { return c.delete > 0 }
+
+var (
+	ErrBaseLength   = errors.New("длина документа не совпадает с базовой длиной операции")
+	ErrIncompatible = errors.New("операции применены к документам разной длины")
+)
+
+// Retain добавляет пропуск n символов
+func (o *Operation) Retain(n int) *Operation {
+	if n <= 0 {
+		return o
+	}
+	o.BaseLength += n
+	o.TargetLength += n
+	if last := len(o.ops) - 1; last >= 0 && o.ops[last].isRetain() {
+		o.ops[last].retain += n
+		return o
+	}
+	o.ops = append(o.ops, component{retain: n})
+	return o
+}
+
+// Insert добавляет вставку строки
+func (o *Operation) Insert(s string) *Operation {
+	return o.insertUnits(utf16.Encode([]rune(s)))
+}
+
+func (o *Operation) insertUnits(units []uint16) *Operation {
+	if len(units) == 0 {
+		return o
+	}
+	o.TargetLength += len(units)
+	last := len(o.ops) - 1
+	switch {
+	case last >= 0 && o.ops[last].isInsert():
+		o.ops[last].insert = append(o.ops[last].insert, units...)
+	case last >= 0 && o.ops[last].isDelete():
+		// Вставка всегда идёт перед удалением: так у равных операций одинаковая форма
+		if last > 0 && o.ops[last-1].isInsert() {
+			o.ops[last-1].insert = append(o.ops[last-1].insert, units...)
+		} else {
+			o.ops = append(o.ops, o.ops[last])
+			o.ops[last] = component{insert: cloneUnits(units)}
+		}
+	default:
+		o.ops = append(o.ops, component{insert: cloneUnits(units)})
+	}
+	return o
+}
+
+// Delete добавляет удаление n символов
+func (o *Operation) Delete(n int) *Operation {
+	if n <= 0 {
+		return o
+	}
+	o.BaseLength += n
+	if last := len(o.ops) - 1; last >= 0 && o.ops[last].isDelete() {
+		o.ops[last].delete += n
+		return o
+	}
+	o.ops = append(o.ops, component{delete: n})
+	return o
+}
+
+// IsNoop сообщает, что операция ничего не меняет
+func (o *Operation) IsNoop() bool {
+	return len(o.ops) == 0 || (len(o.ops) == 1 && o.ops[0].isRetain())
+}
+
+// Apply применяет операцию к документу
+func (o *Operation) Apply(doc []uint16) ([]uint16, error) {
+	if len(doc) != o.BaseLength {
+		return nil, ErrBaseLength
+	}
+
+	result := make([]uint16, 0, o.TargetLength)
+	pos := 0
+	for _, c := range o.ops {
+		switch {
+		case c.isRetain():
+			result = append(result, doc[pos:pos+c.retain]...)
+			pos += c.retain
+		case c.isInsert():
+			result = append(result, c.insert...)
+		case c.isDelete():
+			pos += c.delete
+		}
+	}
+	return result, nil
+}
+
+// TransformIndex сдвигает позицию курсора с учётом операции
+func (o *Operation) TransformIndex(index int) int {
+	pos, newIndex := 0, index
+	for _, c := range o.ops {
+		if pos > index {
+			break
+		}
+		switch {
+		case c.isRetain():
+			pos += c.retain
+		case c.isInsert():
+			newIndex += len(c.insert)
+		case c.isDelete():
+			newIndex -= min(c.delete, index-pos)
+			pos += c.delete
+		}
+	}
+	return newIndex
+}
+
+// Transform приводит две конкурентные операции a и b, применённые к одному документу,
+// к паре a', b' такой, что apply(apply(doc, a), b') == apply(apply(doc, b), a').
+// При одновременной вставке в одну позицию первой оказывается вставка a.
+func Transform(a, b *Operation) (*Operation, *Operation, error) {
+	if a.BaseLength != b.BaseLength {
+		return nil, nil, ErrIncompatible
+	}
+
+	aPrime, bPrime := &Operation{}, &Operation{}
+	ops1, ops2 := cloneOps(a.ops), cloneOps(b.ops)
+	i1, i2 := 0, 0
+
+	next := func(ops []component, i *int) *component {
+		if *i < len(ops) {
+			c := &ops[*i]
+			*i++
+			return c
+		}
+		return nil
+	}
+
+	op1, op2 := next(ops1, &i1), next(ops2, &i2)
+	for op1 != nil || op2 != nil {
+		if op1 != nil && op1.isInsert() {
+			aPrime.insertUnits(op1.insert)
+			bPrime.Retain(len(op1.insert))
+			op1 = next(ops1, &i1)
+			continue
+		}
+		if op2 != nil && op2.isInsert() {
+			aPrime.Retain(len(op2.insert))
+			bPrime.insertUnits(op2.insert)
+			op2 = next(ops2, &i2)
+			continue
+		}
+		if op1 == nil || op2 == nil {
+			return nil, nil, ErrIncompatible
+		}
+
+		switch {
+		case op1.isRetain() && op2.isRetain():
+			n := min(op1.retain, op2.retain)
+			aPrime.Retain(n)
+			bPrime.Retain(n)
+			op1.retain -= n
+			op2.retain -= n
+		case op1.isDelete() && op2.isDelete():
+			// Оба удалили один и тот же фрагмент — в результате ничего делать не нужно
+			n := min(op1.delete, op2.delete)
+			op1.delete -= n
+			op2.delete -= n
+		case op1.isDelete() && op2.isRetain():
+			n := min(op1.delete, op2.retain)
+			aPrime.Delete(n)
+			op1.delete -= n
+			op2.retain -= n
+		case op1.isRetain() && op2.isDelete():
+			n := min(op1.retain, op2.delete)
+			bPrime.Delete(n)
+			op1.retain -= n
+			op2.delete -= n
+		default:
+			return nil, nil, ErrIncompatible
+		}
+
+		if op1.retain == 0 && op1.delete == 0 {
+			op1 = next(ops1, &i1)
+		}
+		if op2.retain == 0 && op2.delete == 0 {
+			op2 = next(ops2, &i2)
+		}
+	}
+
+	return aPrime, bPrime, nil
+}
+
+// Compose объединяет последовательные операции a и b в одну с тем же эффектом
+func Compose(a, b *Operation) (*Operation, error) {
+	if a.TargetLength != b.BaseLength {
+		return nil, ErrIncompatible
+	}
+
+	out := &Operation{}
+	ops1, ops2 := cloneOps(a.ops), cloneOps(b.ops)
+	i1, i2 := 0, 0
+
+	next := func(ops []component, i *int) *component {
+		if *i < len(ops) {
+			c := &ops[*i]
+			*i++
+			return c
+		}
+		return nil
+	}
+
+	op1, op2 := next(ops1, &i1), next(ops2, &i2)
+	for op1 != nil || op2 != nil {
+		if op1 != nil && op1.isDelete() {
+			out.Delete(op1.delete)
+			op1 = next(ops1, &i1)
+			continue
+		}
+		if op2 != nil && op2.isInsert() {
+			out.insertUnits(op2.insert)
+			op2 = next(ops2, &i2)
+			continue
+		}
+		if op1 == nil || op2 == nil {
+			return nil, ErrIncompatible
+		}
+
+		switch {
+		case op1.isRetain() && op2.isRetain():
+			n := min(op1.retain, op2.retain)
+			out.Retain(n)
+			op1.retain -= n
+			op2.retain -= n
+		case op1.isInsert() && op2.isDelete():
+			// Вставленное в a тут же удалено в b
+			n := min(len(op1.insert), op2.delete)
+			op1.insert = op1.insert[n:]
+			op2.delete -= n
+		case op1.isInsert() && op2.isRetain():
+			n := min(len(op1.insert), op2.retain)
+			out.insertUnits(op1.insert[:n])
+			op1.insert = op1.insert[n:]
+			op2.retain -= n
+		case op1.isRetain() && op2.isDelete():
+			n := min(op1.retain, op2.delete)
+			out.Delete(n)
+			op1.retain -= n
+			op2.delete -= n
+		default:
+			return nil, ErrIncompatible
+		}
+
+		if op1.retain == 0 && op1.delete == 0 && len(op1.insert) == 0 {
+			op1 = next(ops1, &i1)
+		}
+		if op2.retain == 0 && op2.delete == 0 && len(op2.insert) == 0 {
+			op2 = next(ops2, &i2)
+		}
+	}
+
+	return out, nil
+}
+
+// Identity возвращает пустую операцию над документом длины n
+func Identity(n int) *Operation {
+	return (&Operation{}).Retain(n)
+}
+
+func cloneUnits(units []uint16) []uint16 {
+	return append([]uint16(nil), units...)
+}
+
+func cloneOps(ops []component) []component {
+	out := make([]component, len(ops))
+	copy(out, ops)
+	return out
+}
+
+// Diff строит операцию, превращающую old в new: общий префикс и суффикс
+// сохраняются, середина заменяется целиком
+func Diff(old, new []uint16) *Operation {
+	prefix := 0
+	for prefix < len(old) && prefix < len(new) && old[prefix] == new[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(old)-prefix && suffix < len(new)-prefix &&
+		old[len(old)-1-suffix] == new[len(new)-1-suffix] {
+		suffix++
+	}
+
+	op := &Operation{}
+	op.Retain(prefix)
+	op.insertUnits(new[prefix : len(new)-suffix])
+	op.Delete(len(old) - prefix - suffix)
+	op.Retain(suffix)
+	return op
+}
+
+func (o *Operation) MarshalJSON() ([]byte, error) {
+	out := make([]any, 0, len(o.ops))
+	for _, c := range o.ops {
+		switch {
+		case c.isRetain():
+			out = append(out, c.retain)
+		case c.isInsert():
+			out = append(out, string(utf16.Decode(c.insert)))
+		case c.isDelete():
+			out = append(out, -c.delete)
+		}
+	}
+	return json.Marshal(out)
+}
+
+func (o *Operation) UnmarshalJSON(data []byte) error {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*o = Operation{}
+	for _, item := range raw {
+		var s string
+		if err := json.Unmarshal(item, &s); err == nil {
+			if s == "" {
+				return errors.New("пустая вставка в операции")
+			}
+			o.Insert(s)
+			continue
+		}
+
+		// Длины компонентов ограничены размером документа: иначе огромное число
+		// переполнит BaseLength или дойдёт до Apply и выйдет за границы документа
+		var n int
+		if err := json.Unmarshal(item, &n); err != nil || n == 0 || n > MaxContentLength || n < -MaxContentLength {
+			return fmt.Errorf("некорректный компонент операции: %s", item)
+		}
+		if o.BaseLength+abs(n) > MaxContentLength {
+			return ErrTooLarge
+		}
+		if n > 0 {
+			o.Retain(n)
+		} else {
+			o.Delete(-n)
+		}
+	}
+	return nil
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func encode(s string) []uint16 {
+	return utf16.Encode([]rune(s))
+}
+
+func decode(units []uint16) string {
+	return string(utf16.Decode(units))
+}
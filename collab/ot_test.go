@@ -0,0 +1,250 @@
+package collab
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"testing"
+)
+
+func op(components ...any) *Operation {
+	o := &Operation{}
+	for _, c := range components {
+		switch v := c.(type) {
+		case int:
+			if v > 0 {
+				o.Retain(v)
+			} else {
+				o.Delete(-v)
+			}
+		case string:
+			o.Insert(v)
+		}
+	}
+	return o
+}
+
+func apply(t *testing.T, doc string, o *Operation) string {
+	t.Helper()
+	out, err := o.Apply(encode(doc))
+	if err != nil {
+		t.Fatalf("Apply(%q, %s): %v", doc, mustJSON(o), err)
+	}
+	return decode(out)
+}
+
+func mustJSON(o *Operation) string {
+	raw, _ := json.Marshal(o)
+	return string(raw)
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		doc  string
+		op   *Operation
+		want string
+	}{
+		{"hello", op(5, " world"), "hello world"},
+		{"hello", op(-1, "H", 4), "Hello"},
+		{"hello world", op(5, -6), "hello"},
+		{"", op("новая"), "новая"},
+		// Символ вне BMP занимает две кодовые единицы UTF-16
+		{"a😀b", op(1, -2, 1), "ab"},
+		{"a😀b", op(3, "!", 1), "a😀!b"},
+	}
+	for _, tt := range tests {
+		if got := apply(t, tt.doc, tt.op); got != tt.want {
+			t.Errorf("Apply(%q, %s) = %q, ожидалось %q", tt.doc, mustJSON(tt.op), got, tt.want)
+		}
+	}
+
+	if _, err := op(3).Apply(encode("hello")); !errors.Is(err, ErrBaseLength) {
+		t.Errorf("операция над документом другой длины: %v", err)
+	}
+}
+
+func TestTransformConvergence(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		a, b *Operation
+	}{
+		{"вставки в разных местах", "abcdef", op(1, "X", 5), op(4, "Y", 2)},
+		{"вставки в одну позицию", "abc", op(1, "X", 2), op(1, "Y", 2)},
+		{"вставка внутри удалённого", "abcdef", op(1, -4, 1), op(3, "X", 3)},
+		{"пересекающиеся удаления", "abcdef", op(1, -3, 2), op(2, -3, 1)},
+		{"одинаковые удаления", "abcdef", op(2, -2, 2), op(2, -2, 2)},
+		{"удаление всего и вставка", "abc", op(-3), op(3, "tail")},
+		{"пустая операция", "abc", op(3), op("head", 3)},
+		{"пустой документ", "", op("a"), op("b")},
+		{"суррогатные пары", "😀😀", op(2, "x", 2), op(-2, 2)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkConvergence(t, tt.doc, tt.a, tt.b)
+		})
+	}
+
+	// При одновременной вставке в одно место первой идёт вставка a
+	aPrime, _, _ := Transform(op(1, "X", 2), op(1, "Y", 2))
+	if got := apply(t, apply(t, "abc", op(1, "Y", 2)), aPrime); got != "aXYbc" {
+		t.Errorf("порядок одновременных вставок: %q", got)
+	}
+}
+
+func checkConvergence(t *testing.T, doc string, a, b *Operation) {
+	t.Helper()
+	aPrime, bPrime, err := Transform(a, b)
+	if err != nil {
+		t.Fatalf("Transform(%s, %s): %v", mustJSON(a), mustJSON(b), err)
+	}
+	left := apply(t, apply(t, doc, a), bPrime)
+	right := apply(t, apply(t, doc, b), aPrime)
+	if left != right {
+		t.Fatalf("документ %q, a=%s, b=%s: apply(apply(d,a),b')=%q, apply(apply(d,b),a')=%q",
+			doc, mustJSON(a), mustJSON(b), left, right)
+	}
+}
+
+// randomOp строит случайную операцию над документом длины n
+func randomOp(rng *rand.Rand, n int) *Operation {
+	o := &Operation{}
+	for pos := 0; pos < n; {
+		k := 1 + rng.IntN(n-pos)
+		switch rng.IntN(3) {
+		case 0:
+			o.Retain(k)
+			pos += k
+		case 1:
+			o.Delete(k)
+			pos += k
+		case 2:
+			o.Insert(strings.Repeat(string(rune('a'+rng.IntN(26))), 1+rng.IntN(3)))
+		}
+	}
+	if rng.IntN(2) == 0 {
+		o.Insert("я")
+	}
+	return o
+}
+
+func TestTransformRandom(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	for i := 0; i < 2000; i++ {
+		doc := "0123456789"[:rng.IntN(11)] + strings.Repeat("ж", rng.IntN(10))
+		n := len(encode(doc))
+		a, b := randomOp(rng, n), randomOp(rng, n)
+		checkConvergence(t, doc, a, b)
+	}
+}
+
+func TestTransformIncompatible(t *testing.T) {
+	if _, _, err := Transform(op(3), op(4)); !errors.Is(err, ErrIncompatible) {
+		t.Errorf("Transform операций разной длины: %v", err)
+	}
+}
+
+func TestCompose(t *testing.T) {
+	rng := rand.New(rand.NewPCG(3, 4))
+	for i := 0; i < 1000; i++ {
+		doc := "abcdefghijklmnop"[:rng.IntN(16)]
+		a := randomOp(rng, len(doc))
+		b := randomOp(rng, a.TargetLength)
+
+		ab, err := Compose(a, b)
+		if err != nil {
+			t.Fatalf("Compose(%s, %s): %v", mustJSON(a), mustJSON(b), err)
+		}
+		if want, got := apply(t, apply(t, doc, a), b), apply(t, doc, ab); got != want {
+			t.Fatalf("Compose(%s, %s) = %s: %q вместо %q", mustJSON(a), mustJSON(b), mustJSON(ab), got, want)
+		}
+	}
+
+	if _, err := Compose(op(3, "x"), op(3)); !errors.Is(err, ErrIncompatible) {
+		t.Errorf("Compose несовместимых операций: %v", err)
+	}
+}
+
+func TestTransformIndex(t *testing.T) {
+	tests := []struct {
+		op          *Operation
+		index, want int
+	}{
+		{op(2, "abc", 3), 1, 1},
+		{op(2, "abc", 3), 4, 7},
+		{op(1, -3, 1), 3, 1},
+		{op(1, -3, 1), 5, 2},
+	}
+	for _, tt := range tests {
+		if got := tt.op.TransformIndex(tt.index); got != tt.want {
+			t.Errorf("%s.TransformIndex(%d) = %d, ожидалось %d", mustJSON(tt.op), tt.index, got, tt.want)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	for _, pair := range [][2]string{
+		{"hello world", "hello brave world"},
+		{"abc", ""},
+		{"", "abc"},
+		{"same", "same"},
+		{"😀a😀", "😀b😀"},
+	} {
+		if got := apply(t, pair[0], Diff(encode(pair[0]), encode(pair[1]))); got != pair[1] {
+			t.Errorf("Diff(%q, %q) даёт %q", pair[0], pair[1], got)
+		}
+	}
+}
+
+func TestOperationJSON(t *testing.T) {
+	var o Operation
+	if err := json.Unmarshal([]byte(`[5,"abc",-2,3]`), &o); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if o.BaseLength != 10 || o.TargetLength != 11 {
+		t.Errorf("длины %d → %d, ожидалось 10 → 11", o.BaseLength, o.TargetLength)
+	}
+	if got := mustJSON(&o); got != `[5,"abc",-2,3]` {
+		t.Errorf("Marshal = %s", got)
+	}
+}
+
+func TestOperationJSONInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		err   error
+	}{
+		{"не массив", `{"retain":1}`, nil},
+		{"ноль", `[0]`, nil},
+		{"пустая вставка", `[1,""]`, nil},
+		{"дробное число", `[1.5]`, nil},
+		{"вложенный массив", `[[1]]`, nil},
+		{"null", `[null]`, nil},
+		{"retain больше документа", fmt.Sprintf(`[%d]`, MaxContentLength+1), nil},
+		{"delete больше документа", fmt.Sprintf(`[%d]`, -MaxContentLength-1), nil},
+		{"переполнение int", `[9223372036854775807]`, nil},
+		{"наименьший int", `[-9223372036854775808]`, nil},
+		{"за пределами int", `[99999999999999999999]`, nil},
+		{"сумма больше документа", fmt.Sprintf(`[%d,%d]`, MaxContentLength, -1), ErrTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var o Operation
+			err := json.Unmarshal([]byte(tt.input), &o)
+			if err == nil {
+				t.Fatalf("Unmarshal(%s) принял операцию с базовой длиной %d", tt.input, o.BaseLength)
+			}
+			if tt.err != nil && !errors.Is(err, tt.err) {
+				t.Errorf("Unmarshal(%s) = %v, ожидалось %v", tt.input, err, tt.err)
+			}
+		})
+	}
+
+	var o Operation
+	if err := json.Unmarshal([]byte(fmt.Sprintf(`[%d]`, MaxContentLength)), &o); err != nil {
+		t.Errorf("операция над документом наибольшей длины: %v", err)
+	}
+}
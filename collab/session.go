@@ -0,0 +1,413 @@
+package collab
+
+import (
+	"encoding/json"
+	"errors"
+	"notes-api/model"
+	"sync"
+)
+
+const (
+	// MaxContentLength — наибольшая длина содержимого заметки в кодовых единицах UTF-16
+	MaxContentLength = 1 << 20
+	// maxHistory — сколько последних операций хранит сеанс. Клиенту, отставшему
+	// сильнее, придётся переподключиться и получить документ заново.
+	maxHistory = 1000
+	// clientSendBuffer — очередь исходящих сообщений клиента; переполнение значит,
+	// что клиент не успевает читать, и его соединение закрывается
+	clientSendBuffer = 256
+)
+
+var (
+	ErrRevision    = errors.New("ревизия операции устарела или ещё не наступила")
+	ErrTooLarge    = errors.New("содержимое заметки слишком большое")
+	ErrNoteDeleted = errors.New("заметка удалена")
+
+	// errDisconnected — клиента уже отключили от сеанса, его сообщения не принимаются
+	errDisconnected = errors.New("клиент отключён от сеанса")
+)
+
+// Cursor — позиция курсора и конец выделения в кодовых единицах UTF-16
+type Cursor struct {
+	Position     int `json:"position"`
+	SelectionEnd int `json:"selection_end"`
+}
+
+// Presence — участник сеанса
+type Presence struct {
+	ClientID string  `json:"client_id"`
+	UserID   uint    `json:"user_id"`
+	Cursor   *Cursor `json:"cursor"`
+}
+
+// Message — сообщение протокола совместного редактирования. Поля заполняются по типу:
+//
+//	init     (сервер) client_id, revision, title, content, clients — состояние при подключении
+//	op       (клиент) revision, op[, cursor] — правка, сделанная поверх ревизии revision
+//	op       (сервер) client_id, revision, op — чужая правка; client_id пуст у правок не из сеанса
+//	ack      (сервер) revision — правка клиента принята и получила номер revision
+//	cursor   (оба)    [client_id,] cursor — перемещение курсора; без cursor — курсор убран
+//	join     (сервер) client_id, user_id, cursor — подключился участник
+//	leave    (сервер) client_id — участник отключился
+//	snapshot (сервер) revision, change_seq — документ до ревизии revision сохранён в заметку
+//	error    (сервер) error — ошибка; после неё сервер закрывает соединение
+type Message struct {
+	Type      string     `json:"type"`
+	ClientID  string     `json:"client_id,omitempty"`
+	UserID    uint       `json:"user_id,omitempty"`
+	Revision  int        `json:"revision"`
+	Op        *Operation `json:"op,omitempty" swaggertype:"array,object"`
+	Cursor    *Cursor    `json:"cursor,omitempty"`
+	Title     string     `json:"title,omitempty"`
+	Content   *string    `json:"content,omitempty"`
+	Clients   []Presence `json:"clients,omitempty"`
+	ChangeSeq int64      `json:"change_seq,omitempty"`
+	Error     string     `json:"error,omitempty"`
+}
+
+const (
+	MessageInit     = "init"
+	MessageOp       = "op"
+	MessageAck      = "ack"
+	MessageCursor   = "cursor"
+	MessageJoin     = "join"
+	MessageLeave    = "leave"
+	MessageSnapshot = "snapshot"
+	MessageError    = "error"
+)
+
+// Client — одно подключение к сеансу
+type Client struct {
+	ID     string
+	UserID uint
+	// Send — сериализованные сообщения для клиента. Закрывается, когда клиента
+	// отключили от сеанса (в том числе за медленное чтение).
+	Send chan []byte
+
+	cursor *Cursor
+}
+
+// Session — сеанс совместного редактирования одной заметки.
+//
+// Сервер держит авторитетную копию документа и линейную историю операций.
+// Операция клиента, сделанная поверх ревизии r, трансформируется против всех
+// операций после r, применяется к документу и рассылается остальным участникам.
+// При одновременной вставке в одно место первой оказывается вставка клиента,
+// чья операция пришла позже, — так же поступает клиент ot.js со своими
+// неподтверждёнными операциями, поэтому копии сходятся.
+type Session struct {
+	noteID uint
+	userID uint
+
+	mu       sync.Mutex
+	doc      []uint16
+	revision int
+	// history[i] — операция, переводящая документ из ревизии historyStart+i в следующую
+	history      []*Operation
+	historyStart int
+	clients      map[string]*Client
+
+	// base — версия заметки в базе, от которой отсчитывается pending
+	base model.Note
+	// pending — все правки после base, ещё не сохранённые в заметку
+	pending *Operation
+	dirty   bool
+	saving  bool
+	closed  bool
+}
+
+func newSession(note model.Note) (*Session, error) {
+	doc := encode(note.Content)
+	if len(doc) > MaxContentLength {
+		return nil, ErrTooLarge
+	}
+	return &Session{
+		noteID:  note.ID,
+		userID:  note.UserID,
+		doc:     doc,
+		clients: make(map[string]*Client),
+		base:    note,
+		pending: Identity(len(doc)),
+	}, nil
+}
+
+// join добавляет клиента и отправляет ему текущее состояние
+func (s *Session) join(c *Client) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	clients := make([]Presence, 0, len(s.clients))
+	for _, other := range s.clients {
+		clients = append(clients, presenceOf(other))
+	}
+	content := decode(s.doc)
+	s.sendTo(c, Message{
+		Type:     MessageInit,
+		ClientID: c.ID,
+		Revision: s.revision,
+		Title:    s.base.Title,
+		Content:  &content,
+		Clients:  clients,
+	})
+
+	s.clients[c.ID] = c
+	joined := presenceOf(c)
+	s.broadcast(c.ID, Message{Type: MessageJoin, ClientID: c.ID, UserID: c.UserID, Cursor: joined.Cursor})
+}
+
+// leave убирает клиента из сеанса; если reason не nil, клиенту сначала отправляется ошибка.
+// Возвращает true, если участников не осталось.
+func (s *Session) leave(c *Client, reason error) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.clients[c.ID]; ok && reason != nil {
+		s.sendTo(c, Message{Type: MessageError, Error: reason.Error()})
+	}
+	// sendTo мог уже отключить переполненного клиента
+	s.drop(c)
+	return len(s.clients) == 0
+}
+
+// drop отключает клиента, если он ещё в сеансе. Вызывается под s.mu.
+func (s *Session) drop(c *Client) {
+	if _, ok := s.clients[c.ID]; !ok {
+		return
+	}
+	delete(s.clients, c.ID)
+	close(c.Send)
+	s.broadcast("", Message{Type: MessageLeave, ClientID: c.ID})
+}
+
+// receive применяет правку клиента, сделанную поверх ревизии revision
+func (s *Session) receive(c *Client, revision int, op *Operation, cursor *Cursor) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.clients[c.ID]; !ok {
+		return errDisconnected
+	}
+	if revision < s.historyStart || revision > s.revision {
+		return ErrRevision
+	}
+
+	for _, concurrent := range s.history[revision-s.historyStart:] {
+		transformed, _, err := Transform(op, concurrent)
+		if err != nil {
+			return err
+		}
+		op = transformed
+	}
+
+	if err := s.apply(op); err != nil {
+		return err
+	}
+
+	if cursor != nil {
+		c.cursor = clampCursor(cursor, len(s.doc))
+	}
+	s.sendTo(c, Message{Type: MessageAck, Revision: s.revision})
+	s.broadcast(c.ID, Message{Type: MessageOp, ClientID: c.ID, Revision: s.revision, Op: op})
+	if cursor != nil {
+		s.broadcast(c.ID, Message{Type: MessageCursor, ClientID: c.ID, Cursor: c.cursor})
+	}
+	return nil
+}
+
+// apply применяет уже приведённую к текущей ревизии операцию. Вызывается под s.mu.
+func (s *Session) apply(op *Operation) error {
+	if op.TargetLength > MaxContentLength {
+		return ErrTooLarge
+	}
+	doc, err := op.Apply(s.doc)
+	if err != nil {
+		return err
+	}
+	pending, err := Compose(s.pending, op)
+	if err != nil {
+		return err
+	}
+
+	s.doc = doc
+	s.pending = pending
+	s.dirty = true
+	s.revision++
+	s.history = append(s.history, op)
+	if len(s.history) > maxHistory {
+		drop := len(s.history) - maxHistory
+		s.history = append([]*Operation(nil), s.history[drop:]...)
+		s.historyStart += drop
+	}
+
+	for _, other := range s.clients {
+		if other.cursor != nil {
+			other.cursor = &Cursor{
+				Position:     op.TransformIndex(other.cursor.Position),
+				SelectionEnd: op.TransformIndex(other.cursor.SelectionEnd),
+			}
+		}
+	}
+	return nil
+}
+
+// moveCursor запоминает курсор клиента и сообщает о нём остальным
+func (s *Session) moveCursor(c *Client, cursor *Cursor) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.clients[c.ID]; !ok {
+		return
+	}
+	if cursor != nil {
+		cursor = clampCursor(cursor, len(s.doc))
+	}
+	c.cursor = cursor
+	s.broadcast(c.ID, Message{Type: MessageCursor, ClientID: c.ID, Cursor: cursor})
+}
+
+// rebase вливает в сеанс версию заметки, изменённую в обход сеанса (например, через PUT /notes/{id}).
+// Разница между base и note превращается в серверную операцию и рассылается участникам.
+func (s *Session) rebase(note model.Note) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Во время сохранения base меняется; если сохранение упрётся в конфликт,
+	// версия будет влита на следующем такте
+	if s.saving || note.ChangeSeq == s.base.ChangeSeq {
+		return nil
+	}
+
+	external := Diff(encode(s.base.Content), encode(note.Content))
+	external, pending, err := Transform(external, s.pending)
+	if err != nil {
+		return err
+	}
+
+	s.base = note
+	if external.IsNoop() {
+		s.pending = pending
+		return nil
+	}
+	dirty := s.dirty
+	if err := s.apply(external); err != nil {
+		return err
+	}
+	// apply дописал external к pending, но в новой base он уже учтён
+	s.pending = pending
+	s.dirty = dirty
+	s.broadcast("", Message{Type: MessageOp, Revision: s.revision, Op: external})
+	return nil
+}
+
+// snapshotState — что нужно сохранить в заметку
+type snapshotState struct {
+	base     model.Note
+	content  string
+	revision int
+	pending  *Operation
+}
+
+// beginSnapshot забирает несохранённое состояние. Правки, пришедшие во время
+// сохранения, копятся в новом pending относительно забранного документа.
+func (s *Session) beginSnapshot() (snapshotState, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.dirty || s.saving || s.closed {
+		return snapshotState{}, false
+	}
+	state := snapshotState{
+		base:     s.base,
+		content:  decode(s.doc),
+		revision: s.revision,
+		pending:  s.pending,
+	}
+	s.pending = Identity(len(s.doc))
+	s.dirty = false
+	s.saving = true
+	return state, true
+}
+
+// finishSnapshot фиксирует результат сохранения. При неудаче забранные правки возвращаются в pending.
+func (s *Session) finishSnapshot(state snapshotState, saved model.Note, err error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.saving = false
+	if err == nil {
+		s.base = saved
+		s.broadcast("", Message{Type: MessageSnapshot, Revision: state.revision, ChangeSeq: saved.ChangeSeq})
+		return nil
+	}
+
+	pending, composeErr := Compose(state.pending, s.pending)
+	if composeErr != nil {
+		return composeErr
+	}
+	s.pending = pending
+	s.dirty = true
+	return nil
+}
+
+// close отключает всех участников с сообщением об ошибке
+func (s *Session) close(reason error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	for _, c := range s.clients {
+		s.sendTo(c, Message{Type: MessageError, Error: reason.Error()})
+	}
+	for id, c := range s.clients {
+		delete(s.clients, id)
+		close(c.Send)
+	}
+}
+
+func (s *Session) idle() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.clients) == 0 && !s.dirty && !s.saving
+}
+
+// broadcast рассылает сообщение всем, кроме except. Вызывается под s.mu.
+func (s *Session) broadcast(except string, msg Message) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	for id, c := range s.clients {
+		if id != except {
+			s.sendRaw(c, data)
+		}
+	}
+}
+
+func (s *Session) sendTo(c *Client, msg Message) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	s.sendRaw(c, data)
+}
+
+// sendRaw не блокируется: клиента, который не успевает читать, отключаем,
+// а после переподключения он получит документ заново. Вызывается под s.mu.
+func (s *Session) sendRaw(c *Client, data []byte) {
+	select {
+	case c.Send <- data:
+	default:
+		s.drop(c)
+	}
+}
+
+func presenceOf(c *Client) Presence {
+	return Presence{ClientID: c.ID, UserID: c.UserID, Cursor: c.cursor}
+}
+
+func clampCursor(c *Cursor, length int) *Cursor {
+	return &Cursor{
+		Position:     min(max(c.Position, 0), length),
+		SelectionEnd: min(max(c.SelectionEnd, 0), length),
+	}
+}
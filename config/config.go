@@ -0,0 +1,215 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// Config — настройки сервиса. Поле заполняется из YAML-файла (тег yaml), переменной
+// окружения (тег env) и флага командной строки (тег flag); usage — описание флага.
+type Config struct {
+	HTTP        HTTP        `yaml:"http"`
+	DB          DB          `yaml:"db"`
+	JWT         JWT         `yaml:"jwt"`
+	Log         Log         `yaml:"log"`
+	Limits      Limits      `yaml:"limits"`
+	Jobs        Jobs        `yaml:"jobs"`
+	Events      Events      `yaml:"events"`
+	Attachments Attachments `yaml:"attachments"`
+	SMTP        SMTP        `yaml:"smtp"`
+	Webhooks    Webhooks    `yaml:"webhooks"`
+	Encryption  Encryption  `yaml:"encryption"`
+	Metrics     Metrics     `yaml:"metrics"`
+	Tracing     Tracing     `yaml:"tracing"`
+}
+
+type HTTP struct {
+	Addr string `yaml:"addr" env:"HTTP_ADDR" flag:"http-addr" usage:"адрес HTTP-сервера"`
+
+	// Таймауты сервера. Потоки событий и WebSocket от таймаутов чтения и записи освобождены,
+	// загрузка и выгрузка файлов (вложения, импорт, экспорт) ограничены TransferTimeout.
+	ReadTimeout       time.Duration `yaml:"read_timeout" env:"HTTP_READ_TIMEOUT" usage:"наибольшее время чтения запроса вместе с телом"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" env:"HTTP_READ_HEADER_TIMEOUT" usage:"наибольшее время чтения заголовков запроса"`
+	WriteTimeout      time.Duration `yaml:"write_timeout" env:"HTTP_WRITE_TIMEOUT" usage:"наибольшее время записи ответа"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout" env:"HTTP_IDLE_TIMEOUT" usage:"время простоя keep-alive соединения"`
+	TransferTimeout   time.Duration `yaml:"transfer_timeout" env:"HTTP_TRANSFER_TIMEOUT" usage:"наибольшее время загрузки или выгрузки файла"`
+	// DrainDelay — сколько после SIGTERM отвечать «не готов» на /readyz, продолжая принимать
+	// запросы, пока балансировщик не уберёт экземпляр из ротации
+	DrainDelay time.Duration `yaml:"drain_delay" env:"HTTP_DRAIN_DELAY" flag:"drain-delay" usage:"пауза между снятием готовности и остановкой приёма запросов"`
+	// ShutdownTimeout — сколько ждать завершения запросов и фоновых задач после SIGTERM
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"HTTP_SHUTDOWN_TIMEOUT" flag:"shutdown-timeout" usage:"сколько ждать завершения запросов и фоновых задач при остановке"`
+}
+
+type DB struct {
+	Host     string `yaml:"host" env:"DB_HOST" flag:"db-host" usage:"хост Postgres"`
+	Port     int    `yaml:"port" env:"DB_PORT" flag:"db-port" usage:"порт Postgres"`
+	User     string `yaml:"user" env:"DB_USER" flag:"db-user" usage:"пользователь Postgres"`
+	Password Secret `yaml:"password" env:"DB_PASSWORD"`
+	Name     string `yaml:"name" env:"DB_NAME" flag:"db-name" usage:"имя базы"`
+	SSLMode  string `yaml:"sslmode" env:"DB_SSLMODE" flag:"db-sslmode" usage:"режим SSL подключения к Postgres"`
+
+	MaxOpenConns    int           `yaml:"max_open_conns" env:"DB_MAX_OPEN_CONNS" flag:"db-max-open-conns" usage:"наибольшее число открытых соединений, 0 — без ограничения"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" env:"DB_MAX_IDLE_CONNS" flag:"db-max-idle-conns" usage:"наибольшее число простаивающих соединений"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME" usage:"время жизни соединения, 0 — без ограничения"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time" env:"DB_CONN_MAX_IDLE_TIME" usage:"время простоя, после которого соединение закрывается"`
+	// ConnectTimeout — сколько при запуске повторять попытки подключения, пока база недоступна
+	ConnectTimeout time.Duration `yaml:"connect_timeout" env:"DB_CONNECT_TIMEOUT" usage:"сколько повторять подключение к базе при запуске"`
+}
+
+// DSN собирает строку подключения к Postgres
+func (c DB) DSN() string {
+	return fmt.Sprintf(
+		"host=%s user=%s password=%s dbname=%s port=%d sslmode=%s",
+		c.Host, c.User, string(c.Password), c.Name, c.Port, c.SSLMode,
+	)
+}
+
+type JWT struct {
+	Secret     Secret        `yaml:"secret" env:"JWT_SECRET"`
+	AccessTTL  time.Duration `yaml:"access_ttl" env:"JWT_ACCESS_TTL" usage:"срок действия access-токена"`
+	RefreshTTL time.Duration `yaml:"refresh_ttl" env:"JWT_REFRESH_TTL" usage:"срок действия refresh-токена"`
+}
+
+type Log struct {
+	// Level — уровень журнала logrus: trace, debug, info, warn, error
+	Level string `yaml:"level" env:"LOG_LEVEL" flag:"log-level" usage:"уровень журнала: trace, debug, info, warn, error"`
+	// Format — text для чтения глазами или json для сборщиков логов
+	Format string `yaml:"format" env:"LOG_FORMAT" flag:"log-format" usage:"формат журнала: text или json"`
+}
+
+// Limits — ограничения на размер загружаемых данных
+type Limits struct {
+	AttachmentMaxSize int64 `yaml:"attachment_max_size" env:"ATTACHMENTS_MAX_SIZE" usage:"наибольший размер вложения в байтах"`
+	// AttachmentTypes — разрешённые MIME-типы вложений; пусто — список по умолчанию
+	AttachmentTypes []string `yaml:"attachment_types" env:"ATTACHMENTS_ALLOWED_TYPES" usage:"разрешённые MIME-типы вложений через запятую"`
+	ImportMaxSize   int64    `yaml:"import_max_size" env:"IMPORT_MAX_SIZE" usage:"наибольший размер файла импорта в байтах"`
+}
+
+type Jobs struct {
+	// Concurrency — число обработчиков фоновых задач, 0 — по числу процессоров
+	Concurrency int `yaml:"concurrency" env:"JOBS_CONCURRENCY" flag:"jobs-concurrency" usage:"число обработчиков фоновых задач, 0 — по числу процессоров"`
+}
+
+type Events struct {
+	// PubSub — доставка событий между экземплярами: local или postgres (LISTEN/NOTIFY)
+	PubSub string `yaml:"pubsub" env:"EVENTS_PUBSUB" usage:"доставка событий между экземплярами: local или postgres"`
+}
+
+type Attachments struct {
+	// Storage — fs (локальный каталог Dir) или s3
+	Storage string `yaml:"storage" env:"ATTACHMENTS_STORAGE" usage:"хранилище вложений: fs или s3"`
+	Dir     string `yaml:"dir" env:"ATTACHMENTS_DIR" usage:"каталог вложений для хранилища fs"`
+	S3      S3     `yaml:"s3"`
+}
+
+type S3 struct {
+	Endpoint  string `yaml:"endpoint" env:"S3_ENDPOINT"`
+	AccessKey string `yaml:"access_key" env:"S3_ACCESS_KEY"`
+	SecretKey Secret `yaml:"secret_key" env:"S3_SECRET_KEY"`
+	Bucket    string `yaml:"bucket" env:"S3_BUCKET"`
+	Region    string `yaml:"region" env:"S3_REGION"`
+	UseSSL    bool   `yaml:"use_ssl" env:"S3_USE_SSL"`
+}
+
+// SMTP — сервер для писем-напоминаний; без Host письма только пишутся в лог
+type SMTP struct {
+	Host     string `yaml:"host" env:"SMTP_HOST"`
+	Port     int    `yaml:"port" env:"SMTP_PORT"`
+	Username string `yaml:"username" env:"SMTP_USERNAME"`
+	Password Secret `yaml:"password" env:"SMTP_PASSWORD"`
+	From     string `yaml:"from" env:"SMTP_FROM"`
+}
+
+type Webhooks struct {
+	// AllowPrivateNetworks разрешает вебхуки на адреса внутренней сети: петлю, частные сети и link-local
+	AllowPrivateNetworks bool `yaml:"allow_private_networks" env:"WEBHOOKS_ALLOW_PRIVATE_NETWORKS" usage:"разрешить вебхуки на адреса внутренней сети"`
+}
+
+// Encryption — шифрование заметок в базе; без KeyProvider выключено
+type Encryption struct {
+	// KeyProvider — env (мастер-ключи в MasterKeys) или file (в файле MasterKeyFile)
+	KeyProvider   string `yaml:"key_provider" env:"ENCRYPTION_KEY_PROVIDER"`
+	MasterKeys    Secret `yaml:"master_keys" env:"ENCRYPTION_MASTER_KEYS"`
+	MasterKeyFile string `yaml:"master_key_file" env:"ENCRYPTION_MASTER_KEY_FILE"`
+}
+
+// Metrics — отдельный HTTP-сервер с метриками Prometheus, чтобы /metrics не был доступен снаружи
+type Metrics struct {
+	// Addr — адрес сервера метрик; пусто — метрики не отдаются. По умолчанию слушает только
+	// localhost: чтобы Prometheus собирал метрики из внутренней сети, задайте адрес явно
+	Addr string `yaml:"addr" env:"METRICS_ADDR" flag:"metrics-addr" usage:"адрес сервера метрик Prometheus, пусто — выключен"`
+}
+
+// Tracing — трассировка OpenTelemetry; без Exporter спаны не создаются
+type Tracing struct {
+	// Exporter — otlp (OTLP/HTTP на Endpoint) или stdout (спаны в stderr, для локальной отладки)
+	Exporter string `yaml:"exporter" env:"TRACING_EXPORTER" flag:"tracing-exporter" usage:"экспортёр трассировки: otlp или stdout, пусто — выключена"`
+	// Endpoint — адрес коллектора host:port; пусто — OTEL_EXPORTER_OTLP_ENDPOINT или localhost:4318
+	Endpoint    string  `yaml:"endpoint" env:"TRACING_ENDPOINT" usage:"адрес OTLP-коллектора host:port"`
+	Insecure    bool    `yaml:"insecure" env:"TRACING_INSECURE" usage:"отправлять спаны коллектору без TLS"`
+	ServiceName string  `yaml:"service_name" env:"TRACING_SERVICE_NAME" usage:"имя сервиса в трассах"`
+	SampleRatio float64 `yaml:"sample_ratio" env:"TRACING_SAMPLE_RATIO" usage:"доля записываемых трасс от 0 до 1"`
+}
+
+// Default возвращает настройки по умолчанию
+func Default() *Config {
+	return &Config{
+		HTTP: HTTP{
+			Addr:              ":8080",
+			ReadTimeout:       15 * time.Second,
+			ReadHeaderTimeout: 5 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       2 * time.Minute,
+			TransferTimeout:   10 * time.Minute,
+			DrainDelay:        5 * time.Second,
+			ShutdownTimeout:   30 * time.Second,
+		},
+		DB: DB{
+			Host:            "localhost",
+			Port:            5432,
+			SSLMode:         "disable",
+			MaxOpenConns:    25,
+			MaxIdleConns:    10,
+			ConnMaxLifetime: time.Hour,
+			ConnMaxIdleTime: 10 * time.Minute,
+			ConnectTimeout:  time.Minute,
+		},
+		JWT: JWT{
+			AccessTTL:  2 * time.Hour,
+			RefreshTTL: 24 * time.Hour,
+		},
+		Log: Log{Level: "info", Format: "text"},
+		Limits: Limits{
+			AttachmentMaxSize: 25 << 20,
+			ImportMaxSize:     32 << 20,
+		},
+		Events:      Events{PubSub: "local"},
+		Attachments: Attachments{Storage: "fs", Dir: "data/attachments"},
+		SMTP:        SMTP{Port: 587},
+		Metrics:     Metrics{Addr: "127.0.0.1:9090"},
+		Tracing:     Tracing{ServiceName: "notes-api", SampleRatio: 1},
+	}
+}
+
+// Secret — строка, которая не попадает в вывод: в fmt и YAML вместо неё печатаются звёздочки.
+// Само значение берётся преобразованием string(secret).
+type Secret string
+
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return "***"
+}
+
+func (s Secret) GoString() string {
+	return fmt.Sprintf("%q", s.String())
+}
+
+func (s Secret) MarshalYAML() (any, error) {
+	return s.String(), nil
+}
+
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return []byte(fmt.Sprintf("%q", s.String())), nil
+}
@@ -0,0 +1,175 @@
+package config
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func load(t *testing.T, args ...string) (*Config, *flag.FlagSet, error) {
+	t.Helper()
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	cfg, err := Load(fs, args)
+	return cfg, fs, err
+}
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("HTTP_ADDR", "")
+	t.Setenv("HTTP_SHUTDOWN_TIMEOUT", "")
+	file := writeFile(t, "http:\n  addr: \":1000\"\n  shutdown_timeout: 1m\n")
+
+	cfg, _, err := load(t)
+	if err != nil {
+		t.Fatalf("по умолчанию: %v", err)
+	}
+	if cfg.HTTP.Addr != Default().HTTP.Addr {
+		t.Errorf("по умолчанию: addr %q", cfg.HTTP.Addr)
+	}
+
+	cfg, _, err = load(t, "--config", file)
+	if err != nil {
+		t.Fatalf("файл: %v", err)
+	}
+	if cfg.HTTP.Addr != ":1000" || cfg.HTTP.ShutdownTimeout != time.Minute {
+		t.Errorf("файл: addr %q, shutdown_timeout %v", cfg.HTTP.Addr, cfg.HTTP.ShutdownTimeout)
+	}
+	if cfg.HTTP.ReadTimeout != Default().HTTP.ReadTimeout {
+		t.Errorf("поле, которого нет в файле, потеряло значение по умолчанию: %v", cfg.HTTP.ReadTimeout)
+	}
+
+	// Путь к файлу из CONFIG_FILE, адрес — из окружения поверх файла
+	t.Setenv("CONFIG_FILE", file)
+	t.Setenv("HTTP_ADDR", ":2000")
+	cfg, _, err = load(t)
+	if err != nil {
+		t.Fatalf("окружение: %v", err)
+	}
+	if cfg.HTTP.Addr != ":2000" || cfg.HTTP.ShutdownTimeout != time.Minute {
+		t.Errorf("окружение: addr %q, shutdown_timeout %v", cfg.HTTP.Addr, cfg.HTTP.ShutdownTimeout)
+	}
+
+	cfg, fs, err := load(t, "--http-addr", ":3000", "--shutdown-timeout=5s", "up", "--dir", "x")
+	if err != nil {
+		t.Fatalf("флаги: %v", err)
+	}
+	if cfg.HTTP.Addr != ":3000" || cfg.HTTP.ShutdownTimeout != 5*time.Second {
+		t.Errorf("флаги: addr %q, shutdown_timeout %v", cfg.HTTP.Addr, cfg.HTTP.ShutdownTimeout)
+	}
+	if got := strings.Join(fs.Args(), " "); got != "up --dir x" {
+		t.Errorf("аргументы после флагов: %q", got)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+
+	if _, _, err := load(t, "--config", writeFile(t, "http:\n  adress: \":1\"\n")); err == nil {
+		t.Error("неизвестное поле в файле принято")
+	}
+	if _, _, err := load(t, "--config", filepath.Join(t.TempDir(), "нет.yaml")); err == nil {
+		t.Error("отсутствующий файл настроек принят")
+	}
+	if _, _, err := load(t, "--config", writeFile(t, "")); err != nil {
+		t.Errorf("пустой файл: %v", err)
+	}
+
+	t.Setenv("DB_PORT", "пять")
+	t.Setenv("HTTP_READ_TIMEOUT", "10")
+	_, _, err := load(t, "--db-max-open-conns", "много")
+	if err == nil {
+		t.Fatal("некорректные значения приняты")
+	}
+	for _, name := range []string{"DB_PORT", "HTTP_READ_TIMEOUT", "--db-max-open-conns"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("в ошибке нет %s: %v", name, err)
+		}
+	}
+}
+
+func validConfig() *Config {
+	cfg := Default()
+	cfg.DB.User = "notes"
+	cfg.DB.Name = "notes"
+	cfg.JWT.Secret = Secret(strings.Repeat("s", minJWTSecretLength))
+	return cfg
+}
+
+func TestValidate(t *testing.T) {
+	if err := validConfig().Validate(); err != nil {
+		t.Fatalf("корректные настройки: %v", err)
+	}
+	if err := Default().Validate(); err == nil {
+		t.Error("настройки по умолчанию без базы и секрета прошли проверку")
+	}
+
+	tests := []struct {
+		name   string
+		change func(c *Config)
+		want   []string
+	}{
+		{"короткий секрет", func(c *Config) { c.JWT.Secret = "short" }, []string{"jwt.secret"}},
+		{"refresh короче access", func(c *Config) { c.JWT.RefreshTTL = c.JWT.AccessTTL / 2 }, []string{"jwt.refresh_ttl"}},
+		{"несколько ошибок сразу", func(c *Config) {
+			c.HTTP.Addr = ""
+			c.DB.Port = 70000
+			c.Log.Level = "loud"
+		}, []string{"http.addr", "db.port", "log.level"}},
+		{"idle больше open", func(c *Config) { c.DB.MaxOpenConns, c.DB.MaxIdleConns = 5, 10 }, []string{"db.max_idle_conns"}},
+		{"хранилище s3 без бакета", func(c *Config) { c.Attachments.Storage = "s3"; c.Attachments.S3.Endpoint = "minio:9000" }, []string{"attachments.s3.bucket"}},
+		{"метрики на адресе API", func(c *Config) { c.Metrics.Addr = c.HTTP.Addr }, []string{"metrics.addr"}},
+		{"отрицательная пауза остановки", func(c *Config) { c.HTTP.DrainDelay = -time.Second }, []string{"http.drain_delay"}},
+		{"доля трасс", func(c *Config) { c.Tracing.SampleRatio = 2 }, []string{"tracing.sample_ratio"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			tt.change(cfg)
+			err := cfg.Validate()
+			if err == nil {
+				t.Fatal("ошибка не найдена")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("в ошибке нет %s: %v", want, err)
+				}
+			}
+		})
+	}
+}
+
+func TestSecretRedacted(t *testing.T) {
+	cfg := validConfig()
+	cfg.DB.Password = "db-password"
+
+	var out bytes.Buffer
+	if err := Print(&out, cfg); err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{string(cfg.JWT.Secret), "db-password"} {
+		if strings.Contains(out.String(), secret) {
+			t.Errorf("секрет %q попал в вывод Print", secret)
+		}
+	}
+	if !strings.Contains(out.String(), "***") {
+		t.Error("в выводе нет замены секрета")
+	}
+	if s := cfg.DB.Password.String(); s != "***" {
+		t.Errorf("Secret.String() = %q", s)
+	}
+}
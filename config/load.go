@@ -0,0 +1,180 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// EnvFile — файл переменных окружения для локального запуска. Переменные, уже заданные
+// в окружении процесса, из него не переопределяются.
+const EnvFile = ".env"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Load собирает настройки из источников по возрастанию приоритета: значения по умолчанию,
+// YAML-файл (флаг --config или CONFIG_FILE), переменные окружения (включая .env) и флаги.
+// Флаги регистрируются в fs и разбираются из args; аргументы после флагов остаются в fs.Args().
+// Проверка значений — отдельно, в Validate.
+func Load(fs *flag.FlagSet, args []string) (*Config, error) {
+	if err := godotenv.Load(EnvFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%s: %w", EnvFile, err)
+	}
+
+	cfg := Default()
+	path := fs.String("config", os.Getenv("CONFIG_FILE"), "YAML-файл настроек")
+	all := fields(cfg)
+	overrides := make(map[string]string)
+	for _, f := range all {
+		if f.flag == "" {
+			continue
+		}
+		name := f.flag
+		fs.Func(name, f.usage, func(value string) error {
+			overrides[name] = value
+			return nil
+		})
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *path != "" {
+		if err := loadFile(cfg, *path); err != nil {
+			return nil, err
+		}
+	}
+
+	var errs []error
+	for _, f := range all {
+		if f.env == "" {
+			continue
+		}
+		if value := os.Getenv(f.env); value != "" {
+			if err := set(f.value, value); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", f.env, err))
+			}
+		}
+	}
+	for _, f := range all {
+		if value, ok := overrides[f.flag]; ok && f.flag != "" {
+			if err := set(f.value, value); err != nil {
+				errs = append(errs, fmt.Errorf("--%s: %w", f.flag, err))
+			}
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func loadFile(cfg *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("файл настроек: %w", err)
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("файл настроек %s: %w", path, err)
+	}
+	return nil
+}
+
+// Print пишет настройки в w в формате YAML; секреты заменяются звёздочками
+func Print(w io.Writer, cfg *Config) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(cfg); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+type field struct {
+	value reflect.Value
+	env   string
+	flag  string
+	usage string
+}
+
+// fields перечисляет листовые поля настроек вместе с их тегами
+func fields(cfg *Config) []field {
+	var out []field
+	var walk func(v reflect.Value)
+	walk = func(v reflect.Value) {
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			sf, fv := t.Field(i), v.Field(i)
+			if fv.Kind() == reflect.Struct && fv.Type() != durationType {
+				walk(fv)
+				continue
+			}
+			out = append(out, field{
+				value: fv,
+				env:   sf.Tag.Get("env"),
+				flag:  sf.Tag.Get("flag"),
+				usage: sf.Tag.Get("usage"),
+			})
+		}
+	}
+	walk(reflect.ValueOf(cfg).Elem())
+	return out
+}
+
+// set разбирает строковое значение переменной окружения или флага в поле настроек
+func set(v reflect.Value, raw string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return fmt.Errorf("ожидается целое число, получено %q", raw)
+		}
+		v.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("ожидается число, получено %q", raw)
+		}
+		v.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("ожидается true или false, получено %q", raw)
+		}
+		v.SetBool(b)
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("неподдерживаемый тип %s", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// minJWTSecretLength — HS256 подписывает токены ключом не короче размера хеша
+const minJWTSecretLength = 32
+
+var logLevels = []string{"trace", "debug", "info", "warn", "warning", "error", "fatal", "panic"}
+
+// Validate проверяет настройки целиком и возвращает все ошибки разом,
+// чтобы при запуске их можно было исправить за один проход
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.HTTP.Addr != "", "http.addr: не задан адрес HTTP-сервера")
+	check(c.HTTP.ReadTimeout > 0, "http.read_timeout: должен быть положительным")
+	check(c.HTTP.ReadHeaderTimeout > 0, "http.read_header_timeout: должен быть положительным")
+	check(c.HTTP.WriteTimeout > 0, "http.write_timeout: должен быть положительным")
+	check(c.HTTP.IdleTimeout > 0, "http.idle_timeout: должен быть положительным")
+	check(c.HTTP.TransferTimeout > 0, "http.transfer_timeout: должен быть положительным")
+	check(c.HTTP.DrainDelay >= 0, "http.drain_delay: не может быть отрицательным")
+	check(c.HTTP.ShutdownTimeout > 0, "http.shutdown_timeout: должен быть положительным")
+
+	check(c.DB.Host != "", "db.host: не задан хост Postgres")
+	check(c.DB.Port > 0 && c.DB.Port <= 65535, "db.port: некорректный порт %d", c.DB.Port)
+	check(c.DB.User != "", "db.user: не задан пользователь Postgres")
+	check(c.DB.Name != "", "db.name: не задано имя базы")
+	check(c.DB.MaxOpenConns >= 0, "db.max_open_conns: не может быть отрицательным")
+	check(c.DB.MaxIdleConns >= 0, "db.max_idle_conns: не может быть отрицательным")
+	check(c.DB.MaxOpenConns == 0 || c.DB.MaxIdleConns <= c.DB.MaxOpenConns,
+		"db.max_idle_conns: больше max_open_conns (%d > %d)", c.DB.MaxIdleConns, c.DB.MaxOpenConns)
+	check(c.DB.ConnMaxLifetime >= 0, "db.conn_max_lifetime: не может быть отрицательным")
+	check(c.DB.ConnMaxIdleTime >= 0, "db.conn_max_idle_time: не может быть отрицательным")
+	check(c.DB.ConnectTimeout >= 0, "db.connect_timeout: не может быть отрицательным")
+
+	check(len(c.JWT.Secret) >= minJWTSecretLength, "jwt.secret: нужен секрет не короче %d байт (JWT_SECRET)", minJWTSecretLength)
+	check(c.JWT.AccessTTL > 0, "jwt.access_ttl: должен быть положительным")
+	check(c.JWT.RefreshTTL >= c.JWT.AccessTTL, "jwt.refresh_ttl: не может быть короче access_ttl")
+
+	check(slices.Contains(logLevels, c.Log.Level), "log.level: неизвестный уровень %q", c.Log.Level)
+	check(c.Log.Format == "text" || c.Log.Format == "json", "log.format: ожидается text или json, получено %q", c.Log.Format)
+
+	check(c.Limits.AttachmentMaxSize > 0, "limits.attachment_max_size: должен быть положительным")
+	check(c.Limits.ImportMaxSize > 0, "limits.import_max_size: должен быть положительным")
+
+	check(c.Jobs.Concurrency >= 0, "jobs.concurrency: не может быть отрицательным")
+	check(c.Events.PubSub == "local" || c.Events.PubSub == "postgres", "events.pubsub: ожидается local или postgres, получено %q", c.Events.PubSub)
+
+	switch c.Attachments.Storage {
+	case "fs":
+		check(c.Attachments.Dir != "", "attachments.dir: не задан каталог вложений")
+	case "s3":
+		check(c.Attachments.S3.Endpoint != "", "attachments.s3.endpoint: не задан адрес S3")
+		check(c.Attachments.S3.Bucket != "", "attachments.s3.bucket: не задан бакет")
+	default:
+		errs = append(errs, fmt.Errorf("attachments.storage: ожидается fs или s3, получено %q", c.Attachments.Storage))
+	}
+
+	if c.SMTP.Host != "" {
+		check(c.SMTP.Port > 0 && c.SMTP.Port <= 65535, "smtp.port: некорректный порт %d", c.SMTP.Port)
+		check(c.SMTP.From != "", "smtp.from: не задан адрес отправителя")
+	}
+
+	switch c.Encryption.KeyProvider {
+	case "":
+	case "env":
+		check(c.Encryption.MasterKeys != "", "encryption.master_keys: не заданы мастер-ключи")
+	case "file":
+		check(c.Encryption.MasterKeyFile != "", "encryption.master_key_file: не задан файл мастер-ключей")
+	default:
+		errs = append(errs, fmt.Errorf("encryption.key_provider: ожидается env или file, получено %q", c.Encryption.KeyProvider))
+	}
+
+	check(c.Metrics.Addr == "" || c.Metrics.Addr != c.HTTP.Addr, "metrics.addr: совпадает с http.addr")
+
+	switch c.Tracing.Exporter {
+	case "":
+	case "otlp", "stdout":
+		check(c.Tracing.ServiceName != "", "tracing.service_name: не задано имя сервиса")
+	default:
+		errs = append(errs, fmt.Errorf("tracing.exporter: ожидается otlp или stdout, получено %q", c.Tracing.Exporter))
+	}
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sample_ratio: ожидается число от 0 до 1, получено %g", c.Tracing.SampleRatio)
+
+	return errors.Join(errs...)
+}
@@ -1,10 +1,13 @@
 package db
 
 import (
-	"fmt"
 	"log"
-	"notes-api/model"
-	"os"
+	"notes-api/config"
+	"notes-api/logger"
+	"time"
+
+	// Регистрирует сериализатор encrypted до разбора схем моделей
+	_ "notes-api/encryption"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -12,25 +15,49 @@ import (
 
 var DB *gorm.DB
 
-func ConnectDB() {
+// Паузы между попытками подключения при запуске: от connectBackoffMin с удвоением до connectBackoffMax
+const (
+	connectBackoffMin = 500 * time.Millisecond
+	connectBackoffMax = 10 * time.Second
+)
+
+// ConnectDB подключается к Postgres и настраивает пул соединений. Пока база недоступна
+// (например, поднимается вместе с сервисом), попытки повторяются в течение cfg.ConnectTimeout.
+func ConnectDB(cfg config.DB) {
 	var err error
-	/*err = godotenv.Load()
-	if err != nil {
-		log.Fatal("Ошибка загрузки .env файла")
-	}*/
-	dsn := fmt.Sprintf(
-		"host=%s user=%s password=%s dbname=%s port=%s sslmode=%s",
-		os.Getenv("DB_HOST"),
-		os.Getenv("DB_USER"),
-		os.Getenv("DB_PASSWORD"),
-		os.Getenv("DB_NAME"),
-		os.Getenv("DB_PORT"),
-		os.Getenv("DB_SSLMODE"),
-	)
-	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	deadline := time.Now().Add(cfg.ConnectTimeout)
+	backoff := connectBackoffMin
+	for attempt := 1; ; attempt++ {
+		DB, err = gorm.Open(postgres.Open(cfg.DSN()), &gorm.Config{})
+		if err == nil {
+			break
+		}
+		if time.Now().Add(backoff).After(deadline) {
+			log.Fatal("Failed to connect to database:", err)
+		}
+		logger.Log.WithError(err).WithFields(logger.Fields{
+			"attempt": attempt,
+			"retry":   backoff.String(),
+		}).Warn("База недоступна, повторяем подключение")
+		time.Sleep(backoff)
+		backoff = min(backoff*2, connectBackoffMax)
+	}
+
+	sqlDB, err := DB.DB()
 	if err != nil {
 		log.Fatal("Failed to connect to database:", err)
 	}
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+}
 
-	DB.AutoMigrate(&model.Note{}, &model.User{})
+// SchemaMigrator возвращает мигратор схемы для текущего подключения
+func SchemaMigrator() (*Migrator, error) {
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return nil, err
+	}
+	return NewMigrator(sqlDB)
 }
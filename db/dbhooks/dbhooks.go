@@ -0,0 +1,40 @@
+// Package dbhooks регистрирует колбэки GORM вокруг всех операций с базой — общая часть
+// плагинов метрик и трассировки.
+package dbhooks
+
+import "gorm.io/gorm"
+
+// Hook возвращает колбэк для операции: create, query, update, delete, row или raw
+type Hook func(operation string) func(*gorm.DB)
+
+// Register ставит колбэк before перед каждой операцией GORM, а after — после неё.
+// Колбэки называются <plugin>:before_<операция> и <plugin>:after_<операция>.
+func Register(db *gorm.DB, plugin string, before, after Hook) error {
+	cb := db.Callback()
+	hooks := []struct {
+		operation string
+		before    func(name string, fn func(*gorm.DB)) error
+		after     func(name string, fn func(*gorm.DB)) error
+	}{
+		{"create", cb.Create().Before("gorm:create").Register, cb.Create().After("gorm:create").Register},
+		{"query", cb.Query().Before("gorm:query").Register, cb.Query().After("gorm:query").Register},
+		{"update", cb.Update().Before("gorm:update").Register, cb.Update().After("gorm:update").Register},
+		{"delete", cb.Delete().Before("gorm:delete").Register, cb.Delete().After("gorm:delete").Register},
+		{"row", cb.Row().Before("gorm:row").Register, cb.Row().After("gorm:row").Register},
+		{"raw", cb.Raw().Before("gorm:raw").Register, cb.Raw().After("gorm:raw").Register},
+	}
+	for _, h := range hooks {
+		if err := h.before(plugin+":before_"+h.operation, before(h.operation)); err != nil {
+			return err
+		}
+		if err := h.after(plugin+":after_"+h.operation, after(h.operation)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Each — Hook, одинаковый для всех операций
+func Each(fn func(*gorm.DB)) Hook {
+	return func(string) func(*gorm.DB) { return fn }
+}
@@ -0,0 +1,33 @@
+package dbhooks
+
+import (
+	"notes-api/db/dbtest"
+	"notes-api/model"
+	"slices"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestRegister(t *testing.T) {
+	db := dbtest.Open(t, &model.User{})
+
+	var calls []string
+	record := func(prefix string) Hook {
+		return func(operation string) func(*gorm.DB) {
+			return func(*gorm.DB) { calls = append(calls, prefix+operation) }
+		}
+	}
+	if err := Register(db, "test", record("before_"), record("after_")); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	db.Create(&model.User{Email: "user@example.com", Hash: "x"})
+	db.First(&model.User{})
+	db.Exec("DELETE FROM users")
+
+	want := []string{"before_create", "after_create", "before_query", "after_query", "before_raw", "after_raw"}
+	if !slices.Equal(calls, want) {
+		t.Errorf("вызовы колбэков %v, ожидались %v", calls, want)
+	}
+}
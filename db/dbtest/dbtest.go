@@ -0,0 +1,54 @@
+// Package dbtest открывает для тестов базу SQLite в памяти со схемой, построенной по моделям.
+// Запросы, которые зависят от возможностей Postgres (LISTEN/NOTIFY, SKIP LOCKED, jsonb-операторы),
+// такими тестами не проверяются.
+package dbtest
+
+import (
+	"database/sql/driver"
+	"strings"
+	"testing"
+
+	sqlitedriver "github.com/glebarez/go-sqlite"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// Функции Postgres, без которых не обойтись в запросах приложения
+func init() {
+	sqlitedriver.MustRegisterDeterministicScalarFunction("split_part", 3, splitPart)
+}
+
+// splitPart — split_part(строка, разделитель, номер) из Postgres; части нумеруются с 1
+func splitPart(_ *sqlitedriver.FunctionContext, args []driver.Value) (driver.Value, error) {
+	value, _ := args[0].(string)
+	sep, _ := args[1].(string)
+	n, _ := args[2].(int64)
+	parts := strings.Split(value, sep)
+	if n < 1 || int(n) > len(parts) {
+		return "", nil
+	}
+	return parts[n-1], nil
+}
+
+// Open возвращает пустую базу с таблицами для models. База закрывается по окончании теста.
+func Open(t testing.TB, models ...any) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: gormlogger.Discard})
+	if err != nil {
+		t.Fatalf("открыть тестовую базу: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("открыть тестовую базу: %v", err)
+	}
+	// Каждое соединение к :memory: получает свою базу, поэтому соединение одно
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatalf("создать таблицы: %v", err)
+	}
+	return db
+}
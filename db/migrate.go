@@ -0,0 +1,359 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MigrationsDir — каталог миграций в исходниках относительно корня репозитория, в него по
+// умолчанию пишет migrate create. В бинарник миграции попадают через embed при сборке.
+const MigrationsDir = "db/migrations"
+
+// migrationLockID — ключ advisory-блокировки, под которой применяются миграции:
+// экземпляры, запущенные одновременно, выполняют их по очереди
+const migrationLockID int64 = 0x6e6f746573 // "notes"
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+var migrationFileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// ErrSchemaMismatch — схема базы не совпадает с миграциями, собранными в бинарник
+var ErrSchemaMismatch = errors.New("версия схемы базы не совпадает с версией приложения")
+
+// Migration — версионированное изменение схемы: пара файлов <версия>_<имя>.up.sql и .down.sql.
+// Каждая миграция выполняется в отдельной транзакции вместе с записью в schema_migrations,
+// поэтому в ней нельзя использовать команды вне транзакций (CREATE INDEX CONCURRENTLY).
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus — миграция и время её применения; AppliedAt == nil, если она не применена
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	AppliedAt *time.Time
+}
+
+// Migrator применяет миграции к базе
+type Migrator struct {
+	DB         *sql.DB
+	Migrations []Migration
+}
+
+// NewMigrator возвращает мигратор с миграциями, встроенными в бинарник
+func NewMigrator(sqlDB *sql.DB) (*Migrator, error) {
+	migrations, err := LoadMigrations(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{DB: sqlDB, Migrations: migrations}, nil
+}
+
+// LoadMigrations читает миграции из каталога dir и сортирует их по версии
+func LoadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		m := migrationFileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || m == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("миграция %s: некорректная версия", entry.Name())
+		}
+		data, err := fs.ReadFile(fsys, dir+"/"+entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = migration
+		} else if migration.Name != m[2] {
+			return nil, fmt.Errorf("миграция %d: разные имена %q и %q", version, migration.Name, m[2])
+		}
+		if m[3] == "up" {
+			migration.Up = string(data)
+		} else {
+			migration.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if strings.TrimSpace(migration.Up) == "" || strings.TrimSpace(migration.Down) == "" {
+			return nil, fmt.Errorf("миграция %d_%s: нужны непустые .up.sql и .down.sql", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Up применяет все непримененные миграции по возрастанию версии
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var done []Migration
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if unknown := m.unknown(applied); len(unknown) > 0 {
+			return fmt.Errorf("%w: в базе применены миграции %v, которых нет в приложении", ErrSchemaMismatch, unknown)
+		}
+
+		for _, migration := range m.Migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+			err := inTx(ctx, conn, migration.Up,
+				"INSERT INTO schema_migrations (version, name) VALUES ($1, $2)", migration.Version, migration.Name)
+			if err != nil {
+				return fmt.Errorf("миграция %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down откатывает steps последних применённых миграций
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var done []Migration
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if unknown := m.unknown(applied); len(unknown) > 0 {
+			return fmt.Errorf("%w: в базе применены миграции %v, которых нет в приложении", ErrSchemaMismatch, unknown)
+		}
+
+		for i := len(m.Migrations) - 1; i >= 0 && len(done) < steps; i-- {
+			migration := m.Migrations[i]
+			if _, ok := applied[migration.Version]; !ok {
+				continue
+			}
+			err := inTx(ctx, conn, migration.Down,
+				"DELETE FROM schema_migrations WHERE version = $1", migration.Version)
+			if err != nil {
+				return fmt.Errorf("откат миграции %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Status возвращает все известные миграции и отметки о применении. Миграции, применённые
+// в базе, но отсутствующие в приложении, попадают в список с пустым именем.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	conn, err := m.DB.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	// Статус только читает базу: до первого migrate up таблицы schema_migrations ещё нет
+	var exists bool
+	if err := conn.QueryRowContext(ctx, "SELECT to_regclass('schema_migrations') IS NOT NULL").Scan(&exists); err != nil {
+		return nil, err
+	}
+	applied := make(map[int64]time.Time)
+	if exists {
+		if applied, err = appliedMigrations(ctx, conn); err != nil {
+			return nil, err
+		}
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.Migrations))
+	for _, migration := range m.Migrations {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if at, ok := applied[migration.Version]; ok {
+			status.AppliedAt = &at
+		}
+		statuses = append(statuses, status)
+	}
+	for _, version := range m.unknown(applied) {
+		at := applied[version]
+		statuses = append(statuses, MigrationStatus{Version: version, AppliedAt: &at})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// Check сверяет применённые миграции со встроенными. Сервис с другой версией схемы
+// запускать нельзя: запросы к отсутствующим колонкам упадут уже под нагрузкой.
+func (m *Migrator) Check(ctx context.Context) error {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	var pending, unknown []int64
+	for _, status := range statuses {
+		switch {
+		case status.AppliedAt == nil:
+			pending = append(pending, status.Version)
+		case status.Name == "":
+			unknown = append(unknown, status.Version)
+		}
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("%w: в базе применены миграции %v, которых нет в приложении", ErrSchemaMismatch, unknown)
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%w: не применены миграции %v, выполните migrate up", ErrSchemaMismatch, pending)
+	}
+	return nil
+}
+
+// unknown возвращает применённые версии, которых нет среди миграций приложения
+func (m *Migrator) unknown(applied map[int64]time.Time) []int64 {
+	known := make(map[int64]bool, len(m.Migrations))
+	for _, migration := range m.Migrations {
+		known[migration.Version] = true
+	}
+	var versions []int64
+	for version := range applied {
+		if !known[version] {
+			versions = append(versions, version)
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+	return versions
+}
+
+// locked выполняет fn на отдельном соединении под advisory-блокировкой. Блокировка
+// сессионная, поэтому держится на всё время fn и снимается при закрытии соединения,
+// даже если процесс упадёт посреди миграций.
+func (m *Migrator) locked(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.DB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLockID); err != nil {
+		return fmt.Errorf("не удалось взять блокировку миграций: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockID)
+
+	if err := ensureMigrationsTable(ctx, conn); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+func ensureMigrationsTable(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version bigint PRIMARY KEY,
+		name text NOT NULL,
+		applied_at timestamptz NOT NULL DEFAULT now()
+	)`)
+	return err
+}
+
+func appliedMigrations(ctx context.Context, conn *sql.Conn) (map[int64]time.Time, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]time.Time)
+	for rows.Next() {
+		var version int64
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		applied[version] = at
+	}
+	return applied, rows.Err()
+}
+
+// inTx выполняет скрипт миграции и запись о ней в одной транзакции
+func inTx(ctx context.Context, conn *sql.Conn, script, record string, args ...any) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Без аргументов драйвер отправляет запрос простым протоколом, и скрипт может содержать несколько команд
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// CreateMigration создаёт в каталоге dir пустую пару файлов миграции со следующей версией.
+// Каталог должен уже содержать миграции: в пустом или чужом каталоге версия начиналась бы
+// с 1 и совпала бы с существующими.
+func CreateMigration(dir, name string) (up, down string, err error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	name = regexp.MustCompile(`[^a-z0-9]+`).ReplaceAllString(name, "_")
+	name = strings.Trim(name, "_")
+	if name == "" {
+		return "", "", errors.New("имя миграции должно содержать латинские буквы или цифры")
+	}
+
+	var next int64 = 1
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", "", fmt.Errorf("каталог миграций %s не найден: запустите команду из корня репозитория или укажите --dir", dir)
+	}
+	if err != nil {
+		return "", "", err
+	}
+	found := false
+	for _, entry := range entries {
+		if m := migrationFileName.FindStringSubmatch(entry.Name()); m != nil {
+			found = true
+			if version, _ := strconv.ParseInt(m[1], 10, 64); version >= next {
+				next = version + 1
+			}
+		}
+	}
+	if !found {
+		return "", "", fmt.Errorf("в каталоге %s нет миграций: укажите каталог db/migrations репозитория", dir)
+	}
+
+	base := fmt.Sprintf("%04d_%s", next, name)
+	up = filepath.Join(dir, base+".up.sql")
+	down = filepath.Join(dir, base+".down.sql")
+	if err := os.WriteFile(up, []byte("-- "+name+"\n"), 0o644); err != nil {
+		return "", "", err
+	}
+	if err := os.WriteFile(down, []byte("-- откат "+name+"\n"), 0o644); err != nil {
+		return "", "", err
+	}
+	return up, down, nil
+}
@@ -0,0 +1,68 @@
+package db
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCreateMigration(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"0001_initial.up.sql", "0001_initial.down.sql", "0007_later.up.sql", "0007_later.down.sql", "README.md"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	up, down, err := CreateMigration(dir, "  Add Users' Index! ")
+	if err != nil {
+		t.Fatalf("CreateMigration: %v", err)
+	}
+	if want := filepath.Join(dir, "0008_add_users_index.up.sql"); up != want {
+		t.Errorf("up = %s, ожидалось %s", up, want)
+	}
+	if want := filepath.Join(dir, "0008_add_users_index.down.sql"); down != want {
+		t.Errorf("down = %s, ожидалось %s", down, want)
+	}
+	for _, path := range []string{up, down} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("файл не создан: %v", err)
+		}
+	}
+
+	if _, _, err := CreateMigration(dir, "Заметки"); err == nil {
+		t.Error("принято имя без латинских букв и цифр")
+	}
+}
+
+func TestCreateMigrationRequiresMigrationsDir(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "db", "migrations")
+	if _, _, err := CreateMigration(missing, "x"); err == nil || !strings.Contains(err.Error(), "не найден") {
+		t.Errorf("несуществующий каталог: %v", err)
+	}
+
+	empty := t.TempDir()
+	if _, _, err := CreateMigration(empty, "x"); err == nil {
+		t.Error("миграция создана в каталоге без миграций")
+	}
+	if entries, _ := os.ReadDir(empty); len(entries) != 0 {
+		t.Errorf("в чужом каталоге появились файлы: %v", entries)
+	}
+}
+
+// Встроенные миграции разбираются, и у каждой есть откат
+func TestEmbeddedMigrations(t *testing.T) {
+	migrations, err := LoadMigrations(migrationFiles, "migrations")
+	if err != nil {
+		t.Fatalf("LoadMigrations: %v", err)
+	}
+	for i, m := range migrations {
+		if m.Version != int64(i+1) {
+			t.Errorf("миграция %04d_%s на месте %d: версии идут с пропуском", m.Version, m.Name, i+1)
+		}
+		if strings.TrimSpace(m.Up) == "" || strings.TrimSpace(m.Down) == "" {
+			t.Errorf("у миграции %04d_%s пустой up или down", m.Version, m.Name)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+DROP TABLE IF EXISTS data_keys;
+DROP TABLE IF EXISTS key_bundles;
+DROP TABLE IF EXISTS notifications;
+DROP TABLE IF EXISTS reminders;
+DROP TABLE IF EXISTS templates;
+DROP TABLE IF EXISTS note_links;
+DROP TABLE IF EXISTS note_todos;
+DROP TABLE IF EXISTS attachments;
+DROP TABLE IF EXISTS note_revisions;
+DROP TABLE IF EXISTS sync_mutations;
+DROP TABLE IF EXISTS note_tombstones;
+DROP TABLE IF EXISTS note_events;
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhooks;
+DROP TABLE IF EXISTS jobs;
+DROP TABLE IF EXISTS notes;
+DROP TABLE IF EXISTS users;
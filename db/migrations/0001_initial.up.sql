@@ -0,0 +1,266 @@
+-- Исходная схема: совпадает с тем, что создавал AutoMigrate, поэтому на существующей базе
+-- миграция только фиксирует версию (IF NOT EXISTS), а на пустой создаёт все таблицы.
+
+CREATE TABLE IF NOT EXISTS users (
+    id bigserial,
+    email text,
+    password text,
+    hash text,
+    refresh_token text,
+    refresh_token_hash text,
+    change_seq bigint NOT NULL DEFAULT 0,
+    PRIMARY KEY (id),
+    CONSTRAINT uni_users_email UNIQUE (email)
+);
+
+CREATE TABLE IF NOT EXISTS notes (
+    id bigserial,
+    user_id bigint,
+    title text,
+    content text,
+    change_seq bigint,
+    created_at timestamptz,
+    updated_at timestamptz,
+    due_at timestamptz,
+    pinned boolean NOT NULL DEFAULT false,
+    archived boolean NOT NULL DEFAULT false,
+    starred boolean NOT NULL DEFAULT false,
+    position bigint NOT NULL DEFAULT 0,
+    encrypted boolean NOT NULL DEFAULT false,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_notes_user FOREIGN KEY (user_id) REFERENCES users (id)
+);
+CREATE INDEX IF NOT EXISTS idx_notes_archived ON notes (archived);
+CREATE INDEX IF NOT EXISTS idx_notes_due_at ON notes (due_at);
+CREATE INDEX IF NOT EXISTS idx_notes_user_seq ON notes (user_id, change_seq);
+
+CREATE TABLE IF NOT EXISTS jobs (
+    id bigserial,
+    user_id bigint,
+    type text,
+    status text,
+    payload jsonb,
+    data bytea,
+    result jsonb,
+    progress bigint,
+    attempts bigint,
+    max_attempts bigint,
+    last_error text,
+    unique_key text,
+    run_at timestamptz,
+    locked_at timestamptz,
+    locked_by text,
+    created_at timestamptz,
+    updated_at timestamptz,
+    finished_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_jobs_unique_key ON jobs (unique_key);
+CREATE INDEX IF NOT EXISTS idx_jobs_status_run_at ON jobs (status, run_at);
+CREATE INDEX IF NOT EXISTS idx_jobs_type ON jobs (type);
+CREATE INDEX IF NOT EXISTS idx_jobs_user_id ON jobs (user_id);
+
+CREATE TABLE IF NOT EXISTS webhooks (
+    id bigserial,
+    user_id bigint,
+    url text,
+    events text,
+    secret text,
+    active boolean,
+    consecutive_failures bigint,
+    disabled_at timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_webhooks_user_id ON webhooks (user_id);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id bigserial,
+    webhook_id bigint,
+    user_id bigint,
+    event_id text,
+    event text,
+    payload jsonb,
+    status text,
+    attempts bigint,
+    response_status bigint,
+    response_body text,
+    error text,
+    duration_ms bigint,
+    replay_of bigint,
+    created_at timestamptz,
+    updated_at timestamptz,
+    delivered_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_event_id ON webhook_deliveries (event_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook_id ON webhook_deliveries (webhook_id);
+
+CREATE TABLE IF NOT EXISTS note_events (
+    id bigserial,
+    user_id bigint,
+    type text,
+    note_id bigint,
+    data jsonb,
+    created_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_note_events_created_at ON note_events (created_at);
+CREATE INDEX IF NOT EXISTS idx_note_events_user_id_id ON note_events (user_id);
+
+CREATE TABLE IF NOT EXISTS note_tombstones (
+    id bigserial,
+    user_id bigint,
+    note_id bigint,
+    change_seq bigint,
+    deleted_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_note_tombstones_note_id ON note_tombstones (note_id);
+CREATE INDEX IF NOT EXISTS idx_note_tombstones_user_seq ON note_tombstones (user_id, change_seq);
+
+CREATE TABLE IF NOT EXISTS sync_mutations (
+    id bigserial,
+    user_id bigint,
+    mutation_id text,
+    result jsonb,
+    created_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_sync_mutations_created_at ON sync_mutations (created_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sync_mutations_user_mutation ON sync_mutations (user_id, mutation_id);
+
+CREATE TABLE IF NOT EXISTS note_revisions (
+    id bigserial,
+    note_id bigint,
+    user_id bigint,
+    revision bigint,
+    change_seq bigint,
+    source text,
+    title text,
+    content text,
+    created_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_note_revisions_note_id ON note_revisions (note_id);
+
+CREATE TABLE IF NOT EXISTS attachments (
+    id bigserial,
+    note_id bigint,
+    user_id bigint,
+    file_name text,
+    content_type text,
+    size bigint,
+    sha256 text,
+    storage_key text,
+    thumbnail_status text,
+    thumbnail_sizes text,
+    created_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_attachments_note_id ON attachments (note_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_attachments_storage_key ON attachments (storage_key);
+CREATE INDEX IF NOT EXISTS idx_attachments_user_id ON attachments (user_id);
+
+CREATE TABLE IF NOT EXISTS note_todos (
+    id bigserial,
+    user_id bigint,
+    note_id bigint,
+    position bigint,
+    line bigint,
+    text text,
+    done boolean,
+    change_seq bigint,
+    updated_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_note_todos_user_done ON note_todos (user_id, done);
+CREATE INDEX IF NOT EXISTS idx_note_todos_note_id ON note_todos (note_id);
+
+CREATE TABLE IF NOT EXISTS note_links (
+    id bigserial,
+    user_id bigint,
+    source_id bigint,
+    target_id bigint,
+    target_title text,
+    target_key text,
+    created_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_note_links_target_key ON note_links (target_key);
+CREATE INDEX IF NOT EXISTS idx_note_links_target_id ON note_links (target_id);
+CREATE INDEX IF NOT EXISTS idx_note_links_source_id ON note_links (source_id);
+CREATE INDEX IF NOT EXISTS idx_note_links_user_id ON note_links (user_id);
+
+CREATE TABLE IF NOT EXISTS templates (
+    id bigserial,
+    user_id bigint,
+    name text,
+    description text,
+    title text,
+    content text,
+    created_at timestamptz,
+    updated_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_templates_user_id ON templates (user_id);
+
+CREATE TABLE IF NOT EXISTS reminders (
+    id bigserial,
+    user_id bigint,
+    note_id bigint,
+    at timestamptz,
+    r_rule text,
+    timezone text,
+    channels text,
+    next_run_at timestamptz,
+    last_fired_at timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_reminders_user_id ON reminders (user_id);
+CREATE INDEX IF NOT EXISTS idx_reminders_next_run_at ON reminders (next_run_at);
+CREATE INDEX IF NOT EXISTS idx_reminders_note_id ON reminders (note_id);
+
+CREATE TABLE IF NOT EXISTS notifications (
+    id bigserial,
+    user_id bigint,
+    note_id bigint,
+    reminder_id bigint,
+    fired_at timestamptz,
+    title text,
+    body text,
+    read_at timestamptz,
+    created_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_notifications_reminder_fired ON notifications (reminder_id, fired_at);
+CREATE INDEX IF NOT EXISTS idx_notifications_user_id ON notifications (user_id);
+
+CREATE TABLE IF NOT EXISTS key_bundles (
+    id bigserial,
+    user_id bigint,
+    key_id text,
+    alg text,
+    wrapped_key text,
+    params text,
+    created_at timestamptz,
+    updated_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_key_bundles_user_kid ON key_bundles (user_id, key_id);
+
+CREATE TABLE IF NOT EXISTS data_keys (
+    id bigserial,
+    user_id bigint,
+    master_key_id text,
+    wrapped_key bytea NOT NULL,
+    retired_at timestamptz,
+    created_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_data_keys_user_id ON data_keys (user_id);
+CREATE INDEX IF NOT EXISTS idx_data_keys_retired_at ON data_keys (retired_at);
+CREATE INDEX IF NOT EXISTS idx_data_keys_master_key_id ON data_keys (master_key_id);
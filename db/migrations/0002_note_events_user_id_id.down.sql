@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_note_events_user_id_id;
+CREATE INDEX idx_note_events_user_id_id ON note_events (user_id);
@@ -0,0 +1,4 @@
+-- Догоняющая выборка потока событий идёт по (user_id, id > ?) с сортировкой по id,
+-- а AutoMigrate построил индекс только по user_id
+DROP INDEX IF EXISTS idx_note_events_user_id_id;
+CREATE INDEX idx_note_events_user_id_id ON note_events (user_id, id);
@@ -0,0 +1,3 @@
+-- Тело ответа подписчика больше не хранится: через вебхук на внутренний адрес
+-- его можно было прочитать в журнале доставок
+ALTER TABLE webhook_deliveries DROP COLUMN IF EXISTS response_body;
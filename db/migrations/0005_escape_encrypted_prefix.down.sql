@@ -0,0 +1,2 @@
+UPDATE notes SET title = substr(title, length('enc:plain:') + 1) WHERE title LIKE 'enc:plain:%';
+UPDATE notes SET content = substr(content, length('enc:plain:') + 1) WHERE content LIKE 'enc:plain:%';
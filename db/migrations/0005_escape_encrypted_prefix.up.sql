@@ -0,0 +1,6 @@
+-- Открытый текст, который начинается с «enc:», помечается префиксом enc:plain:, иначе
+-- сериализатор принимает его за шифротекст. Настоящий шифротекст не трогается.
+UPDATE notes SET title = 'enc:plain:' || title
+WHERE title LIKE 'enc:%' AND title !~ '^enc:v1:[0-9]+:[A-Za-z0-9+/]*={0,2}$';
+UPDATE notes SET content = 'enc:plain:' || content
+WHERE content LIKE 'enc:%' AND content !~ '^enc:v1:[0-9]+:[A-Za-z0-9+/]*={0,2}$';
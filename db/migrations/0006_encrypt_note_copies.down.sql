@@ -0,0 +1,18 @@
+-- Зашифрованные значения в старую схему не переносятся: события журнала и тела доставок
+-- с шифротекстом обнуляются, ключи ссылок строятся только по открытым заголовкам.
+UPDATE note_revisions SET title = substr(title, length('enc:plain:') + 1) WHERE title LIKE 'enc:plain:%';
+UPDATE note_revisions SET content = substr(content, length('enc:plain:') + 1) WHERE content LIKE 'enc:plain:%';
+UPDATE note_todos SET text = substr(text, length('enc:plain:') + 1) WHERE text LIKE 'enc:plain:%';
+UPDATE note_links SET target_title = substr(target_title, length('enc:plain:') + 1) WHERE target_title LIKE 'enc:plain:%';
+UPDATE notifications SET title = substr(title, length('enc:plain:') + 1) WHERE title LIKE 'enc:plain:%';
+UPDATE notifications SET body = substr(body, length('enc:plain:') + 1) WHERE body LIKE 'enc:plain:%';
+
+ALTER TABLE note_links ADD COLUMN IF NOT EXISTS target_key text;
+UPDATE note_links SET target_key = lower(regexp_replace(btrim(target_title), '\s+', ' ', 'g'))
+WHERE target_title NOT LIKE 'enc:v1:%';
+CREATE INDEX IF NOT EXISTS idx_note_links_target_key ON note_links (target_key);
+
+ALTER TABLE webhook_deliveries ALTER COLUMN payload TYPE jsonb
+    USING CASE WHEN payload LIKE 'enc:v1:%' THEN NULL ELSE payload::jsonb END;
+ALTER TABLE note_events ALTER COLUMN data TYPE jsonb
+    USING CASE WHEN data LIKE 'enc:v1:%' THEN NULL ELSE data::jsonb END;
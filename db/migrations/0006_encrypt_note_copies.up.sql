@@ -0,0 +1,18 @@
+-- Копии содержимого заметок шифруются так же, как сама заметка. Шифротекст — строка,
+-- поэтому столбцы jsonb становятся text.
+ALTER TABLE note_events ALTER COLUMN data TYPE text USING data::text;
+ALTER TABLE webhook_deliveries ALTER COLUMN payload TYPE text USING payload::text;
+
+-- Ссылки сравниваются по расшифрованному заголовку: ключ сравнения — тот же заголовок
+-- в открытом виде, и хранить его нельзя
+DROP INDEX IF EXISTS idx_note_links_target_key;
+ALTER TABLE note_links DROP COLUMN IF EXISTS target_key;
+
+-- До этой миграции столбцы не шифровались, поэтому всё, что начинается с «enc:», —
+-- открытый текст. Он помечается так же, как в 0005.
+UPDATE note_revisions SET title = 'enc:plain:' || title WHERE title LIKE 'enc:%';
+UPDATE note_revisions SET content = 'enc:plain:' || content WHERE content LIKE 'enc:%';
+UPDATE note_todos SET text = 'enc:plain:' || text WHERE text LIKE 'enc:%';
+UPDATE note_links SET target_title = 'enc:plain:' || target_title WHERE target_title LIKE 'enc:%';
+UPDATE notifications SET title = 'enc:plain:' || title WHERE title LIKE 'enc:%';
+UPDATE notifications SET body = 'enc:plain:' || body WHERE body LIKE 'enc:%';
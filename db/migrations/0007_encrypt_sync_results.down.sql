@@ -0,0 +1,4 @@
+-- Зашифрованные результаты в старую схему не переносятся: такие строки удаляются,
+-- и повтор мутации применит её заново. Удалённые загрузки и заголовки не восстановить.
+DELETE FROM sync_mutations WHERE result LIKE 'enc:%';
+ALTER TABLE sync_mutations ALTER COLUMN result TYPE jsonb USING result::jsonb;
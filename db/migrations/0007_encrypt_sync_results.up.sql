@@ -0,0 +1,13 @@
+-- Сохранённый результат синхронизации содержит заметку целиком и шифруется так же,
+-- как сама заметка. JSON не начинается с «enc:», поэтому помечать открытый текст не нужно.
+ALTER TABLE sync_mutations ALTER COLUMN result TYPE text USING result::text;
+
+-- Загруженный файл импорта больше не нужен после успешного завершения задачи
+UPDATE jobs SET data = NULL WHERE status = 'succeeded';
+
+-- Отчёт импорта хранится открытым и больше не содержит заголовков заметок
+UPDATE jobs SET result = jsonb_set(result, '{items}', (
+    SELECT coalesce(jsonb_agg(item - 'title'), '[]'::jsonb)
+    FROM jsonb_array_elements(result->'items') AS item
+))
+WHERE type = 'notes.import' AND jsonb_typeof(result->'items') = 'array';
@@ -15,82 +15,106 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/login": {
-            "post": {
-                "consumes": [
+        "/healthz": {
+            "get": {
+                "description": "Отвечает 200, пока процесс обрабатывает запросы. Зависимости не проверяются.",
+                "produces": [
                     "application/json"
                 ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Проверка живости",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/health.Status"
+                        }
+                    }
+                }
+            }
+        },
+        "/jobs/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "jobs"
                 ],
-                "summary": "Авторизация пользователя",
+                "summary": "Получить состояние фоновой задачи",
                 "parameters": [
                     {
-                        "description": "Данные пользователя",
-                        "name": "input",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/auth.AuthInput"
-                        }
+                        "type": "integer",
+                        "description": "ID задачи",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "Задача",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/model.Job"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный ID",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
                     "401": {
-                        "description": "Неверные данные",
+                        "description": "Пользователь не аутентифицирован",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Задача не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     }
                 }
             }
         },
-        "/notes": {
+        "/keys": {
             "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
+                "description": "Обёрнутые ключи для заметок, зашифрованных на клиенте. Сервер хранит их как есть и развернуть не может",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "notes"
+                    "keys"
                 ],
-                "summary": "Получить все заметки текущего пользователя",
+                "summary": "Связка ключей текущего пользователя",
                 "responses": {
                     "200": {
-                        "description": "Список заметок",
+                        "description": "Ключи",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/model.Note"
+                                "$ref": "#/definitions/model.KeyBundle"
                             }
                         }
                     },
                     "401": {
                         "description": "Пользователь не аутентифицирован",
                         "schema": {
-                            "type": "string"
-                        }
-                    },
-                    "500": {
-                        "description": "Ошибка при получении заметок",
-                        "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     }
                 }
@@ -101,6 +125,7 @@ const docTemplate = `{
                         "ApiKeyAuth": []
                     }
                 ],
+                "description": "Ключ оборачивается на клиенте; kid потом указывается в конвертах зашифрованных заметок",
                 "consumes": [
                     "application/json"
                 ],
@@ -108,37 +133,43 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "notes"
+                    "keys"
                 ],
-                "summary": "Создать новую заметку",
+                "summary": "Сохранить обёрнутый ключ",
                 "parameters": [
                     {
-                        "description": "Данные заметки",
-                        "name": "note",
+                        "description": "Обёрнутый ключ",
+                        "name": "input",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/model.Note"
+                            "$ref": "#/definitions/e2e.KeyBundleInput"
                         }
                     }
                 ],
                 "responses": {
                     "201": {
-                        "description": "Созданная заметка",
+                        "description": "Сохранённый ключ",
                         "schema": {
-                            "$ref": "#/definitions/model.Note"
+                            "$ref": "#/definitions/model.KeyBundle"
                         }
                     },
                     "400": {
                         "description": "Неверный запрос или ошибка валидации",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Ключ с таким kid уже есть или ключей слишком много",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     }
                 }
             }
         },
-        "/notes/{id}": {
+        "/keys/{kid}": {
             "get": {
                 "security": [
                     {
@@ -149,35 +180,29 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "notes"
+                    "keys"
                 ],
-                "summary": "Получить заметку по ID",
+                "summary": "Получить ключ по kid",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "ID заметки",
-                        "name": "id",
+                        "type": "string",
+                        "description": "kid ключа",
+                        "name": "kid",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Заметка",
-                        "schema": {
-                            "$ref": "#/definitions/model.Note"
-                        }
-                    },
-                    "400": {
-                        "description": "Неверный ID",
+                        "description": "Ключ",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/model.KeyBundle"
                         }
                     },
                     "404": {
-                        "description": "Заметка не найдена",
+                        "description": "Ключ не найден",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     }
                 }
@@ -188,6 +213,7 @@ const docTemplate = `{
                         "ApiKeyAuth": []
                     }
                 ],
+                "description": "Например, после смены пароля клиент заново оборачивает тот же ключ. Удалить ключ нельзя: зашифрованные им заметки стали бы нечитаемыми",
                 "consumes": [
                     "application/json"
                 ],
@@ -195,109 +221,157 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "notes"
+                    "keys"
                 ],
-                "summary": "Обновить заметку по ID (только владелец может обновить)",
+                "summary": "Заменить обёртку ключа",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "ID заметки",
-                        "name": "id",
+                        "type": "string",
+                        "description": "kid ключа",
+                        "name": "kid",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Обновлённые данные заметки",
-                        "name": "note",
+                        "description": "Новая обёртка ключа",
+                        "name": "input",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/model.Note"
+                            "$ref": "#/definitions/e2e.KeyBundleInput"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Обновленная заметка",
+                        "description": "Обновлённый ключ",
                         "schema": {
-                            "$ref": "#/definitions/model.Note"
+                            "$ref": "#/definitions/model.KeyBundle"
                         }
                     },
                     "400": {
-                        "description": "Неверный запрос или ID",
+                        "description": "Неверный запрос или ошибка валидации",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
-                    "403": {
-                        "description": "Доступ запрещён",
+                    "404": {
+                        "description": "Ключ не найден",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/login": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Авторизация пользователя",
+                "parameters": [
+                    {
+                        "description": "Данные пользователя",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/auth.AuthInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
-                    "404": {
-                        "description": "Заметка не найдена",
+                    "401": {
+                        "description": "Неверные данные",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/notes": {
+            "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
+                "description": "Сначала идут закреплённые заметки в порядке position, затем остальные. Архивные заметки возвращаются только с archived=true. С параметром due_before возвращаются только заметки со сроком раньше указанного момента, ближайшие первыми",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "notes"
                 ],
-                "summary": "Удалить заметку по ID (только владелец может удалить)",
+                "summary": "Получить все заметки текущего пользователя",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "ID заметки",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "type": "string",
+                        "description": "Момент в формате RFC 3339",
+                        "name": "due_before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Включить архивные заметки",
+                        "name": "archived",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Сообщение об удалении",
+                        "description": "Список заметок",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.Note"
                             }
                         }
                     },
                     "400": {
-                        "description": "Неверный ID",
+                        "description": "Неверный формат due_before",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
-                    "403": {
-                        "description": "Доступ запрещён",
+                    "401": {
+                        "description": "Пользователь не аутентифицирован",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
-                    "404": {
-                        "description": "Заметка не найдена",
+                    "500": {
+                        "description": "Ошибка при получении заметок",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     }
                 }
-            }
-        },
-        "/refresh": {
+            },
             "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "С параметром template_id заметка создаётся из шаблона, а тело запроса — templates.InstantiateInput с переменными шаблона. Заметка с encrypted=true шифруется на клиенте: content (и title, если не пустой) передаются JSON-конвертом {\"v\":1,\"alg\":\"AES-256-GCM\"|\"XChaCha20-Poly1305\",\"kid\":\"…\",\"nonce\":\"\u003cbase64\u003e\",\"ct\":\"\u003cbase64\u003e\"}, ключи хранятся в /keys",
                 "consumes": [
                     "application/json"
                 ],
@@ -305,112 +379,3147 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "notes"
                 ],
-                "summary": "Обновить access token",
+                "summary": "Создать новую заметку",
                 "parameters": [
                     {
-                        "description": "Refresh токен",
-                        "name": "input",
+                        "type": "integer",
+                        "description": "ID шаблона",
+                        "name": "template_id",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Данные заметки",
+                        "name": "note",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/model.Note"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Созданная заметка",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/model.Note"
                         }
                     },
                     "400": {
-                        "description": "Ошибка токена",
+                        "description": "Неверный запрос или ошибка валидации",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Шаблон не найден",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     }
                 }
             }
         },
-        "/register": {
-            "post": {
-                "consumes": [
-                    "application/json"
+        "/notes/export": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
                 ],
+                "description": "JSON-документ целиком или ZIP-архив с файлом на каждую заметку (Markdown с YAML front-matter — id, title, pinned, archived, starred, due_at — или HTML)",
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "application/zip"
                 ],
                 "tags": [
-                    "auth"
+                    "notes"
                 ],
-                "summary": "Регистрация пользователя",
+                "summary": "Выгрузить все заметки текущего пользователя",
                 "parameters": [
                     {
-                        "description": "Данные пользователя",
-                        "name": "input",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/auth.AuthInput"
-                        }
+                        "enum": [
+                            "json",
+                            "markdown",
+                            "html"
+                        ],
+                        "type": "string",
+                        "description": "Формат выгрузки",
+                        "name": "format",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "Выгрузка заметок",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "type": "file"
                         }
                     },
                     "400": {
-                        "description": "Неверный запрос",
+                        "description": "Неподдерживаемый формат",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/problem.Problem"
                         }
-                    }
-                }
+                    },
+                    "401": {
+                        "description": "Пользователь не аутентифицирован",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/notes/import": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Принимает ZIP с Markdown-файлами (с YAML front-matter), файл Evernote .enex или JSON-выгрузку этого API. Состояние заметки из выгрузки (pinned, archived, starred, due_at) восстанавливается. Импорт выполняется фоновой задачей, прогресс и отчёт по элементам доступны по GET /jobs/{id}",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "notes"
+                ],
+                "summary": "Импортировать заметки из файла",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "Файл импорта",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "markdown",
+                            "enex",
+                            "json"
+                        ],
+                        "type": "string",
+                        "description": "Формат файла, по умолчанию определяется по расширению",
+                        "name": "format",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Фоновая задача импорта, result содержит model.ImportReport",
+                        "schema": {
+                            "$ref": "#/definitions/model.Job"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный запрос или формат",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Пользователь не аутентифицирован",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/notes/stream": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Отправляет события note.created, note.updated и note.deleted по заметкам текущего пользователя. Для продолжения после обрыва передайте Last-Event-ID (заголовок или параметр last_event_id). Если пропущенных событий слишком много или они уже удалены из журнала, вместо них приходит stream.reset: загрузите заметки заново и продолжайте с его ID. Токен можно передать в параметре access_token",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "notes"
+                ],
+                "summary": "Поток изменений заметок (Server-Sent Events)",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID последнего полученного события",
+                        "name": "Last-Event-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID последнего полученного события",
+                        "name": "last_event_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "JWT, если нельзя передать заголовок Authorization",
+                        "name": "access_token",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Поток событий",
+                        "schema": {
+                            "$ref": "#/definitions/events.Event"
+                        }
+                    },
+                    "401": {
+                        "description": "Пользователь не аутентифицирован",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/notes/ws": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Те же события, что и в /notes/stream (включая stream.reset), в виде JSON-сообщений. Для продолжения после обрыва передайте last_event_id. Токен можно передать в параметре access_token",
+                "tags": [
+                    "notes"
+                ],
+                "summary": "Поток изменений заметок (WebSocket)",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID последнего полученного события",
+                        "name": "last_event_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "JWT, если нельзя передать заголовок Authorization",
+                        "name": "access_token",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "101": {
+                        "description": "Переход на WebSocket",
+                        "schema": {
+                            "$ref": "#/definitions/events.Event"
+                        }
+                    },
+                    "401": {
+                        "description": "Пользователь не аутентифицирован",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/notes/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "notes"
+                ],
+                "summary": "Получить заметку по ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID заметки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Заметка",
+                        "schema": {
+                            "$ref": "#/definitions/model.Note"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный ID",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Заметка не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "notes"
+                ],
+                "summary": "Обновить заметку по ID (только владелец может обновить)",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID заметки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Обновлённые данные заметки",
+                        "name": "note",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.Note"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Обновленная заметка",
+                        "schema": {
+                            "$ref": "#/definitions/model.Note"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный запрос, ID, шифротекст или ошибка валидации",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Доступ запрещён",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Заметка не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "notes"
+                ],
+                "summary": "Удалить заметку по ID (только владелец может удалить)",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID заметки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Сообщение об удалении",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный ID",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Доступ запрещён",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Заметка не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/notes/{id}/archive": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Архивная заметка пропадает из общего списка (кроме запроса с archived=true) и открепляется",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "notes"
+                ],
+                "summary": "Перенести заметку в архив",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID заметки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Обновлённая заметка",
+                        "schema": {
+                            "$ref": "#/definitions/model.Note"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный ID",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Доступ запрещен",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Заметка не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/notes/{id}/attachments": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "attachments"
+                ],
+                "summary": "Получить вложения заметки",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID заметки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Вложения",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.Attachment"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Доступ запрещен",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Заметка не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Файл передаётся в поле file и сохраняется потоково, без буферизации в памяти. Тип определяется по содержимому, а не по заголовку Content-Type; из JPEG и PNG удаляются метаданные (EXIF и т. п.). Для изображений JPEG, PNG и GIF в фоне строятся превью",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "attachments"
+                ],
+                "summary": "Прикрепить файл к заметке",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID заметки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "Файл",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Созданное вложение",
+                        "schema": {
+                            "$ref": "#/definitions/model.Attachment"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Доступ запрещен",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Заметка не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "Файл слишком большой",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "415": {
+                        "description": "Недопустимый тип файла",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/notes/{id}/attachments/{attachment_id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Поддерживает Range-запросы и условные запросы по ETag",
+                "produces": [
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "attachments"
+                ],
+                "summary": "Скачать вложение",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID заметки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID вложения",
+                        "name": "attachment_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Диапазон байт, например bytes=0-1023",
+                        "name": "Range",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Содержимое файла",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "206": {
+                        "description": "Запрошенный диапазон",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "403": {
+                        "description": "Доступ запрещен",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Заметка или вложение не найдены",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "attachments"
+                ],
+                "summary": "Удалить вложение",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID заметки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID вложения",
+                        "name": "attachment_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Сообщение об удалении",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Доступ запрещен",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Заметка или вложение не найдены",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/notes/{id}/attachments/{attachment_id}/thumbnail": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Превью строятся фоновой задачей после загрузки (64, 256 и 1024 пикселя по длинной стороне); возвращается наименьшее не меньше size. Пока превью строятся, thumbnail_status вложения равен pending, а запрос возвращает 404 с заголовком Retry-After",
+                "produces": [
+                    "image/jpeg",
+                    "image/png"
+                ],
+                "tags": [
+                    "attachments"
+                ],
+                "summary": "Превью изображения",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID заметки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID вложения",
+                        "name": "attachment_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Желаемый размер в пикселях (по умолчанию 256)",
+                        "name": "size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Превью",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "304": {
+                        "description": "Превью не изменилось",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Доступ запрещен",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Заметка, вложение или превью не найдены",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/notes/{id}/backlinks": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Заметки, в тексте которых есть ссылка на данную. При переименовании заметки ссылки на неё в других заметках переписываются на новый заголовок",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "links"
+                ],
+                "summary": "Обратные ссылки на заметку",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID заметки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Ссылающиеся заметки",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/links.Backlink"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Доступ запрещен",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Заметка не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/notes/{id}/collab": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Открывает сеанс совместного редактирования содержимого заметки. Правки передаются операциями в формате ot.js ([5, \"abc\", -2] — пропустить 5 символов, вставить \"abc\", удалить 2; длины в кодовых единицах UTF-16). Сервер сводит одновременные правки, рассылает курсоры участников и раз в несколько секунд сохраняет документ в заметку и историю версий. Формат сообщений описан в collab.Message. Токен можно передать в параметре access_token",
+                "tags": [
+                    "notes"
+                ],
+                "summary": "Совместное редактирование заметки (WebSocket)",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID заметки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "JWT, если нельзя передать заголовок Authorization",
+                        "name": "access_token",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "101": {
+                        "description": "Переход на WebSocket",
+                        "schema": {
+                            "$ref": "#/definitions/collab.Message"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный ID",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Пользователь не аутентифицирован",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Доступ запрещен",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Заметка не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/notes/{id}/due": {
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Обычное обновление заметки срок не меняет, он задаётся отдельно",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "notes"
+                ],
+                "summary": "Задать срок заметки",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID заметки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Срок заметки",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.DueInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Обновлённая заметка",
+                        "schema": {
+                            "$ref": "#/definitions/model.Note"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный запрос или ID",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Доступ запрещен",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Заметка не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/notes/{id}/links": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Ссылки пишутся в тексте как [[Заголовок]], [[Заголовок#раздел]] или [[Заголовок|подпись]]; заголовки сравниваются без учёта регистра. Ссылки на несуществующие заметки возвращаются в unresolved",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "links"
+                ],
+                "summary": "Исходящие ссылки заметки",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID заметки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Ссылки заметки",
+                        "schema": {
+                            "$ref": "#/definitions/links.NoteLinks"
+                        }
+                    },
+                    "403": {
+                        "description": "Доступ запрещен",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Заметка не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/notes/{id}/pin": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Закреплённые заметки идут в начале списка в порядке position. Повторный вызов с position перемещает заметку",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "notes"
+                ],
+                "summary": "Закрепить заметку",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID заметки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Позиция среди закреплённых",
+                        "name": "input",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handler.PinInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Обновлённая заметка",
+                        "schema": {
+                            "$ref": "#/definitions/model.Note"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный запрос или ID",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Доступ запрещен",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Заметка не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Заметка в архиве",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/notes/{id}/reminders": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reminders"
+                ],
+                "summary": "Напоминания заметки",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID заметки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Напоминания",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.Reminder"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Доступ запрещен",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Заметка не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Разовое напоминание срабатывает в момент at. Если задано rrule (например FREQ=WEEKLY;BYDAY=MO,WE), повторения отсчитываются от at в часовом поясе timezone; повторять чаще раза в час нельзя. Каналы доставки: inapp — во входящие (GET /notifications), email — письмом, webhook — событием note.reminder",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reminders"
+                ],
+                "summary": "Создать напоминание",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID заметки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Напоминание",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/reminders.ReminderInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Созданное напоминание",
+                        "schema": {
+                            "$ref": "#/definitions/model.Reminder"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Доступ запрещен",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Заметка не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "У заметки слишком много напоминаний",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/notes/{id}/reminders/{reminder_id}": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "tags": [
+                    "reminders"
+                ],
+                "summary": "Удалить напоминание",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID заметки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID напоминания",
+                        "name": "reminder_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Напоминание удалено"
+                    },
+                    "403": {
+                        "description": "Доступ запрещен",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Напоминание не найдено",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/notes/{id}/render": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Markdown заметки (CommonMark и GFM: таблицы, списки задач, блоки кода) рендерится в HTML-фрагмент. Сырой HTML из заметки не выводится, результат проходит санитайзер по белому списку; у заголовков есть якоря с префиксом user-content-. ETag меняется вместе с change_seq заметки",
+                "produces": [
+                    "text/html"
+                ],
+                "tags": [
+                    "notes"
+                ],
+                "summary": "Отрендерить заметку",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID заметки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "html"
+                        ],
+                        "type": "string",
+                        "description": "Формат (по умолчанию html)",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag ранее полученного результата",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "HTML-фрагмент",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "304": {
+                        "description": "Заметка не изменилась",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный ID или неподдерживаемый формат",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Доступ запрещен",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Заметка не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Заметка зашифрована на клиенте",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/notes/{id}/revisions": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Версии, сохранённые сеансами совместного редактирования, начиная с последней",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "notes"
+                ],
+                "summary": "История версий заметки",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID заметки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Сколько версий вернуть (по умолчанию 20, не больше 100)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Версии заметки",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.NoteRevision"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный ID",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Пользователь не аутентифицирован",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Доступ запрещен",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Заметка не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/notes/{id}/star": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "notes"
+                ],
+                "summary": "Добавить заметку в избранное",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID заметки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Обновлённая заметка",
+                        "schema": {
+                            "$ref": "#/definitions/model.Note"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный ID",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Доступ запрещен",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Заметка не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/notes/{id}/todos/{index}/toggle": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Меняет флажок пункта прямо в тексте заметки. Если передать change_seq и text пункта, который видел клиент, пункт будет найден по тексту даже после параллельных правок заметки; если его больше нет, вернётся 409",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "todos"
+                ],
+                "summary": "Переключить пункт списка задач",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID заметки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Номер пункта в заметке, начиная с 0",
+                        "name": "index",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Версия заметки и текст пункта, которые видел клиент",
+                        "name": "input",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/todos.ToggleInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Переключённый пункт и обновлённая заметка",
+                        "schema": {
+                            "$ref": "#/definitions/todos.ToggleResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Доступ запрещен",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Заметка или пункт не найдены",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Пункт изменился",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/notes/{id}/unarchive": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "notes"
+                ],
+                "summary": "Вернуть заметку из архива",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID заметки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Обновлённая заметка",
+                        "schema": {
+                            "$ref": "#/definitions/model.Note"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный ID",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Доступ запрещен",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Заметка не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/notes/{id}/unpin": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "notes"
+                ],
+                "summary": "Открепить заметку",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID заметки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Обновлённая заметка",
+                        "schema": {
+                            "$ref": "#/definitions/model.Note"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный ID",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Доступ запрещен",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Заметка не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/notes/{id}/unstar": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "notes"
+                ],
+                "summary": "Убрать заметку из избранного",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID заметки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Обновлённая заметка",
+                        "schema": {
+                            "$ref": "#/definitions/model.Note"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный ID",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Доступ запрещен",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Заметка не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/notifications": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Уведомления от напоминаний с каналом inapp, новые сверху",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reminders"
+                ],
+                "summary": "Входящие уведомления",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "Только непрочитанные",
+                        "name": "unread",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Количество (по умолчанию 50, максимум 200)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Уведомления",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.Notification"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Неверные параметры",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/notifications/{id}/read": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reminders"
+                ],
+                "summary": "Отметить уведомление прочитанным",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID уведомления",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Уведомление",
+                        "schema": {
+                            "$ref": "#/definitions/model.Notification"
+                        }
+                    },
+                    "404": {
+                        "description": "Уведомление не найдено",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/readyz": {
+            "get": {
+                "description": "Проверяет подключение к базе и версию схемы. Во время остановки сервиса отвечает 503.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Проверка готовности",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/health.Status"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/health.Status"
+                        }
+                    }
+                }
+            }
+        },
+        "/refresh": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Обновить access token",
+                "parameters": [
+                    {
+                        "description": "Refresh токен",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Ошибка токена",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/register": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Регистрация пользователя",
+                "parameters": [
+                    {
+                        "description": "Данные пользователя",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/auth.AuthInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/sync": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Без since возвращает все заметки. В ответе sync_token, который нужно передать в следующий запрос; пока has_more=true, следующую страницу нужно запрашивать сразу. Удалённые заметки приходят в deleted",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sync"
+                ],
+                "summary": "Получить изменения заметок с момента последней синхронизации",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "sync_token из предыдущего ответа",
+                        "name": "since",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Максимум изменений в ответе (по умолчанию 200, не больше 1000)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Изменения",
+                        "schema": {
+                            "$ref": "#/definitions/service.SyncChanges"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный sync_token",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Пользователь не аутентифицирован",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Мутации применяются по порядку. update и delete применяются, только если base_seq совпадает с текущим change_seq заметки, иначе в результате будет status=conflict и серверная версия заметки (note) или tombstone (deleted). Сервер всегда прав: клиент сливает свои изменения с серверной версией и отправляет новую мутацию с её change_seq. Повторная отправка мутации с тем же mutation_id возвращает прежний результат",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sync"
+                ],
+                "summary": "Отправить пакет изменений, сделанных на клиенте",
+                "parameters": [
+                    {
+                        "description": "Пакет мутаций (не больше 500)",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.syncRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Результат каждой мутации в том же порядке",
+                        "schema": {
+                            "$ref": "#/definitions/handler.syncResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Пользователь не аутентифицирован",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/templates": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Общие шаблоны (user_id = 0) и шаблоны текущего пользователя",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "templates"
+                ],
+                "summary": "Получить шаблоны заметок",
+                "responses": {
+                    "200": {
+                        "description": "Шаблоны",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.Template"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Пользователь не аутентифицирован",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Заголовок и текст — шаблоны Go text/template. Доступны {{date}} и {{time}} (с необязательным форматом Go, например {{date \"02.01.2006\"}}), {{now}}, {{user.email}}, {{user.id}}, переменные из запроса {{.имя}}, а также default, upper, lower и trim. Циклы, define и template не поддерживаются",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "templates"
+                ],
+                "summary": "Создать шаблон заметки",
+                "parameters": [
+                    {
+                        "description": "Шаблон",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/templates.TemplateInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Созданный шаблон",
+                        "schema": {
+                            "$ref": "#/definitions/model.Template"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный запрос или ошибка в шаблоне",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/templates/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "templates"
+                ],
+                "summary": "Получить шаблон по ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID шаблона",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Шаблон",
+                        "schema": {
+                            "$ref": "#/definitions/model.Template"
+                        }
+                    },
+                    "404": {
+                        "description": "Шаблон не найден",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "templates"
+                ],
+                "summary": "Изменить шаблон",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID шаблона",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Шаблон",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/templates.TemplateInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Изменённый шаблон",
+                        "schema": {
+                            "$ref": "#/definitions/model.Template"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный запрос или ошибка в шаблоне",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Общие шаблоны нельзя изменять",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Шаблон не найден",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "templates"
+                ],
+                "summary": "Удалить шаблон",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID шаблона",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Сообщение об удалении",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Общие шаблоны нельзя изменять",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Шаблон не найден",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/todos": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Пункты \"- [ ] ...\" извлекаются из текста заметок при сохранении. По умолчанию возвращаются только открытые, сначала из недавно изменённых заметок",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "todos"
+                ],
+                "summary": "Пункты списков задач из всех заметок",
+                "parameters": [
+                    {
+                        "enum": [
+                            "open",
+                            "done",
+                            "all"
+                        ],
+                        "type": "string",
+                        "description": "Состояние пунктов (по умолчанию open)",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Только пункты этой заметки",
+                        "name": "note_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Подстрока текста пункта",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Сколько пунктов вернуть (по умолчанию 100, максимум 500)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Сколько пунктов пропустить",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Пункты списков задач",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.NoteTodo"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Неверные параметры фильтра",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Пользователь не аутентифицирован",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Получить вебхуки текущего пользователя",
+                "responses": {
+                    "200": {
+                        "description": "Список вебхуков",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.Webhook"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Пользователь не аутентифицирован",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Подписывает URL на события заметок. Запросы подписываются HMAC-SHA256 с секретом вебхука, подпись передаётся в заголовке X-Webhook-Signature",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Создать вебхук",
+                "parameters": [
+                    {
+                        "description": "Параметры вебхука",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/webhooks.WebhookInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Созданный вебхук вместе с секретом",
+                        "schema": {
+                            "$ref": "#/definitions/model.Webhook"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный запрос или ошибка валидации",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Получить вебхук по ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID вебхука",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Вебхук",
+                        "schema": {
+                            "$ref": "#/definitions/model.Webhook"
+                        }
+                    },
+                    "404": {
+                        "description": "Вебхук не найден",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Передача active=true для отключённого вебхука включает его снова и сбрасывает счётчик неудач",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Изменить вебхук",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID вебхука",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Параметры вебхука",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/webhooks.WebhookInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Обновлённый вебхук",
+                        "schema": {
+                            "$ref": "#/definitions/model.Webhook"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный запрос или ошибка валидации",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Вебхук не найден",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Удалить вебхук",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID вебхука",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Сообщение об удалении",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Вебхук не найден",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/{id}/deliveries": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Журнал доставок вебхука",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID вебхука",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Сколько последних доставок вернуть (по умолчанию 50, максимум 200)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Доставки, новые сверху",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.WebhookDelivery"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Вебхук не найден",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/{id}/deliveries/{delivery_id}/replay": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Повторно отправить доставку",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID вебхука",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID доставки",
+                        "name": "delivery_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Новая доставка",
+                        "schema": {
+                            "$ref": "#/definitions/model.WebhookDelivery"
+                        }
+                    },
+                    "404": {
+                        "description": "Вебхук или доставка не найдены",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "auth.AuthInput": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                }
+            }
+        },
+        "collab.Cursor": {
+            "type": "object",
+            "properties": {
+                "position": {
+                    "type": "integer"
+                },
+                "selection_end": {
+                    "type": "integer"
+                }
+            }
+        },
+        "collab.Message": {
+            "type": "object",
+            "properties": {
+                "change_seq": {
+                    "type": "integer"
+                },
+                "client_id": {
+                    "type": "string"
+                },
+                "clients": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/collab.Presence"
+                    }
+                },
+                "content": {
+                    "type": "string"
+                },
+                "cursor": {
+                    "$ref": "#/definitions/collab.Cursor"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "op": {
+                    "type": "array",
+                    "items": {
+                        "type": "object"
+                    }
+                },
+                "revision": {
+                    "type": "integer"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "collab.Presence": {
+            "type": "object",
+            "properties": {
+                "client_id": {
+                    "type": "string"
+                },
+                "cursor": {
+                    "$ref": "#/definitions/collab.Cursor"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "e2e.KeyBundleInput": {
+            "type": "object",
+            "properties": {
+                "alg": {
+                    "type": "string",
+                    "example": "PBKDF2-SHA256+A256KW"
+                },
+                "kid": {
+                    "description": "KeyID задаётся только при создании; допустимы латиница, цифры, точка, дефис и подчёркивание",
+                    "type": "string",
+                    "example": "main-2024"
+                },
+                "params": {
+                    "type": "object",
+                    "additionalProperties": {}
+                },
+                "wrapped_key": {
+                    "type": "string"
+                }
+            }
+        },
+        "events.Event": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "description": "ID — порядковый номер события в журнале, заполняется при сохранении",
+                    "type": "integer"
+                },
+                "note": {
+                    "$ref": "#/definitions/events.NoteData"
+                },
+                "occurred_at": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "events.NoteData": {
+            "type": "object",
+            "properties": {
+                "content": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "handler.DueInput": {
+            "type": "object",
+            "properties": {
+                "due_at": {
+                    "description": "DueAt — срок в формате RFC 3339; null снимает срок",
+                    "type": "string"
+                }
+            }
+        },
+        "handler.PinInput": {
+            "type": "object",
+            "properties": {
+                "position": {
+                    "description": "Position — место среди закреплённых (по возрастанию); без него заметка встаёт в конец",
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "handler.syncRequest": {
+            "type": "object",
+            "properties": {
+                "mutations": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/service.SyncMutationInput"
+                    }
+                }
+            }
+        },
+        "handler.syncResponse": {
+            "type": "object",
+            "properties": {
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/service.SyncMutationResult"
+                    }
+                }
+            }
+        },
+        "health.Status": {
+            "type": "object",
+            "properties": {
+                "checks": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "status": {
+                    "type": "string",
+                    "example": "ok"
+                }
+            }
+        },
+        "links.Backlink": {
+            "type": "object",
+            "properties": {
+                "note_id": {
+                    "type": "integer"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "links.NoteLinks": {
+            "type": "object",
+            "properties": {
+                "links": {
+                    "description": "Links — ссылки на существующие заметки",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.NoteLink"
+                    }
+                },
+                "unresolved": {
+                    "description": "Unresolved — заголовки из ссылок, для которых заметок нет",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "model.Attachment": {
+            "description": "Вложение заметки",
+            "type": "object",
+            "properties": {
+                "content_type": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "file_name": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "note_id": {
+                    "type": "integer"
+                },
+                "sha256": {
+                    "type": "string"
+                },
+                "size": {
+                    "type": "integer"
+                },
+                "thumbnail_sizes": {
+                    "description": "ThumbnailSizes — готовые размеры превью (по длинной стороне, в пикселях)",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "thumbnail_status": {
+                    "description": "ThumbnailStatus пуст, если для типа файла превью не строятся",
+                    "type": "string",
+                    "enum": [
+                        "pending",
+                        "ready",
+                        "failed"
+                    ]
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.Job": {
+            "description": "Фоновая задача",
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "finished_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "max_attempts": {
+                    "type": "integer"
+                },
+                "progress": {
+                    "type": "integer"
+                },
+                "result": {
+                    "type": "object"
+                },
+                "run_at": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.KeyBundle": {
+            "description": "Обёрнутый ключ шифрования заметок",
+            "type": "object",
+            "properties": {
+                "alg": {
+                    "description": "Alg — способ обёртки ключа, например PBKDF2-SHA256+A256KW; сервер его не интерпретирует",
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "kid": {
+                    "type": "string"
+                },
+                "params": {
+                    "description": "Params — параметры, нужные клиенту для развёртки (соль, число итераций и т. п.)",
+                    "type": "object",
+                    "additionalProperties": {}
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "wrapped_key": {
+                    "description": "WrappedKey — обёрнутый ключ в base64",
+                    "type": "string"
+                }
+            }
+        },
+        "model.Note": {
+            "description": "Модель заметки",
+            "type": "object",
+            "properties": {
+                "archived": {
+                    "type": "boolean"
+                },
+                "change_seq": {
+                    "type": "integer"
+                },
+                "content": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "due_at": {
+                    "description": "DueAt — необязательный срок заметки; задаётся при создании или через PUT /notes/{id}/due",
+                    "type": "string"
+                },
+                "encrypted": {
+                    "description": "Encrypted — заметка зашифрована на клиенте: в Title и Content лежат конверты шифротекста\n(см. e2e.Envelope). Задаётся только при создании; такие заметки не рендерятся и не индексируются.",
+                    "type": "boolean"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "pinned": {
+                    "description": "Pinned, Archived и Starred задаются при создании или через POST /notes/{id}/pin|unpin|archive|unarchive|star|unstar.\nАрхивные заметки не попадают в общий список без ?archived=true и не бывают закреплёнными:\nпри создании с обоими флагами заметка создаётся откреплённой.",
+                    "type": "boolean"
+                },
+                "position": {
+                    "description": "Position — порядок закреплённой заметки в списке (по возрастанию); у незакреплённых 0",
+                    "type": "integer"
+                },
+                "starred": {
+                    "type": "boolean"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user": {
+                    "$ref": "#/definitions/model.User"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.NoteLink": {
+            "description": "Ссылка между заметками",
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "source_note_id": {
+                    "type": "integer"
+                },
+                "target_note_id": {
+                    "description": "TargetID пуст, если заметки с таким заголовком нет",
+                    "type": "integer"
+                },
+                "target_title": {
+                    "description": "TargetTitle — заголовок в том виде, в котором он написан в ссылке. Хранится\nзашифрованным, поэтому заголовки сравниваются в Go, а не в SQL.",
+                    "type": "string"
+                }
+            }
+        },
+        "model.NoteRevision": {
+            "description": "Версия заметки",
+            "type": "object",
+            "properties": {
+                "change_seq": {
+                    "type": "integer"
+                },
+                "content": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "note_id": {
+                    "type": "integer"
+                },
+                "revision": {
+                    "description": "Revision — номер ревизии сеанса совместного редактирования, вошедшей в версию",
+                    "type": "integer"
+                },
+                "source": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.NoteTodo": {
+            "description": "Пункт списка задач заметки",
+            "type": "object",
+            "properties": {
+                "change_seq": {
+                    "description": "ChangeSeq — версия заметки, из которой извлечён пункт",
+                    "type": "integer"
+                },
+                "done": {
+                    "type": "boolean"
+                },
+                "index": {
+                    "description": "Index — порядковый номер пункта в заметке, начиная с 0",
+                    "type": "integer"
+                },
+                "line": {
+                    "description": "Line — номер строки заметки, начиная с 1",
+                    "type": "integer"
+                },
+                "note_id": {
+                    "type": "integer"
+                },
+                "note_title": {
+                    "description": "NoteTitle подставляется при выборке и в таблице не хранится",
+                    "type": "string"
+                },
+                "text": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.NoteTombstone": {
+            "type": "object",
+            "properties": {
+                "change_seq": {
+                    "type": "integer"
+                },
+                "deleted_at": {
+                    "type": "string"
+                },
+                "note_id": {
+                    "type": "integer"
+                }
             }
-        }
-    },
-    "definitions": {
-        "auth.AuthInput": {
+        },
+        "model.Notification": {
+            "description": "Уведомление",
             "type": "object",
             "properties": {
-                "email": {
+                "body": {
                     "type": "string"
                 },
-                "password": {
+                "created_at": {
+                    "type": "string"
+                },
+                "fired_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "note_id": {
+                    "type": "integer"
+                },
+                "read_at": {
+                    "type": "string"
+                },
+                "reminder_id": {
+                    "type": "integer"
+                },
+                "title": {
                     "type": "string"
                 }
             }
         },
-        "model.Note": {
-            "description": "Модель заметки",
+        "model.Reminder": {
+            "description": "Напоминание о заметке",
+            "type": "object",
+            "properties": {
+                "at": {
+                    "type": "string"
+                },
+                "channels": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "last_fired_at": {
+                    "type": "string"
+                },
+                "next_run_at": {
+                    "description": "NextRunAt — следующее срабатывание; пусто, если напоминание больше не сработает",
+                    "type": "string"
+                },
+                "note_id": {
+                    "type": "integer"
+                },
+                "rrule": {
+                    "description": "RRule — правило повторения RFC 5545 без DTSTART, например FREQ=WEEKLY;BYDAY=MO",
+                    "type": "string"
+                },
+                "timezone": {
+                    "description": "Timezone — часовой пояс IANA, в котором считаются повторения",
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.Template": {
+            "description": "Шаблон заметки",
             "type": "object",
             "properties": {
                 "content": {
                     "type": "string"
                 },
+                "created_at": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
                 "id": {
                     "type": "integer"
                 },
+                "name": {
+                    "type": "string"
+                },
                 "title": {
                     "type": "string"
                 },
-                "user": {
-                    "$ref": "#/definitions/model.User"
+                "updated_at": {
+                    "type": "string"
                 },
                 "user_id": {
                     "type": "integer"
@@ -421,6 +3530,10 @@ const docTemplate = `{
             "description": "Модель пользователя",
             "type": "object",
             "properties": {
+                "disabled_at": {
+                    "description": "DisabledAt — учётная запись отключена администратором: вход и обновление токена запрещены",
+                    "type": "string"
+                },
                 "email": {
                     "type": "string"
                 },
@@ -432,6 +3545,463 @@ const docTemplate = `{
                 },
                 "password": {
                     "type": "string"
+                },
+                "refresh_token": {
+                    "type": "string"
+                },
+                "refresh_token_hash": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.Webhook": {
+            "description": "Подписка на исходящие вебхуки",
+            "type": "object",
+            "properties": {
+                "active": {
+                    "type": "boolean"
+                },
+                "consecutive_failures": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "disabled_at": {
+                    "type": "string"
+                },
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "secret": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.WebhookDelivery": {
+            "description": "Доставка вебхука",
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "delivered_at": {
+                    "type": "string"
+                },
+                "duration_ms": {
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "event": {
+                    "type": "string"
+                },
+                "event_id": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "payload": {
+                    "type": "object"
+                },
+                "replay_of": {
+                    "type": "integer"
+                },
+                "response_status": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                },
+                "webhook_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "problem.Code": {
+            "type": "string",
+            "enum": [
+                "bad_request",
+                "validation_failed",
+                "invalid_id",
+                "unauthorized",
+                "invalid_token",
+                "invalid_credentials",
+                "user_disabled",
+                "user_exists",
+                "forbidden",
+                "not_found",
+                "method_not_allowed",
+                "conflict",
+                "payload_too_large",
+                "unsupported_media_type",
+                "internal_error",
+                "note_not_found",
+                "note_conflict",
+                "note_encrypted",
+                "note_too_large",
+                "note_archived",
+                "invalid_envelope",
+                "invalid_sync_token",
+                "unsupported_format",
+                "attachment_not_found",
+                "attachment_too_large",
+                "attachment_type_not_allowed",
+                "invalid_image",
+                "thumbnail_not_ready",
+                "key_not_found",
+                "key_exists",
+                "reminder_not_found",
+                "notification_not_found",
+                "webhook_not_found",
+                "delivery_not_found",
+                "template_not_found",
+                "template_read_only",
+                "todo_not_found",
+                "todo_changed",
+                "job_not_found",
+                "reminder_limit_reached",
+                "key_limit_reached",
+                "streaming_unsupported",
+                "service_unavailable"
+            ],
+            "x-enum-varnames": [
+                "BadRequest",
+                "ValidationFailed",
+                "InvalidID",
+                "Unauthorized",
+                "InvalidToken",
+                "InvalidCredentials",
+                "UserDisabled",
+                "UserExists",
+                "Forbidden",
+                "NotFound",
+                "MethodNotAllowed",
+                "Conflict",
+                "PayloadTooLarge",
+                "UnsupportedMediaType",
+                "Internal",
+                "NoteNotFound",
+                "NoteConflict",
+                "NoteEncrypted",
+                "NoteTooLarge",
+                "NoteArchived",
+                "InvalidEnvelope",
+                "InvalidSyncToken",
+                "UnsupportedFormat",
+                "AttachmentNotFound",
+                "AttachmentTooLarge",
+                "AttachmentTypeDenied",
+                "InvalidImage",
+                "ThumbnailNotReady",
+                "KeyNotFound",
+                "KeyExists",
+                "ReminderNotFound",
+                "NotificationNotFound",
+                "WebhookNotFound",
+                "DeliveryNotFound",
+                "TemplateNotFound",
+                "TemplateReadOnly",
+                "TodoNotFound",
+                "TodoChanged",
+                "JobNotFound",
+                "ReminderLimit",
+                "KeyLimit",
+                "StreamingUnsupported",
+                "ServiceUnavailable"
+            ]
+        },
+        "problem.FieldCode": {
+            "type": "string",
+            "enum": [
+                "required",
+                "invalid",
+                "invalid_format",
+                "invalid_email",
+                "too_short",
+                "too_long",
+                "negative",
+                "too_many",
+                "duplicate",
+                "immutable",
+                "invalid_url",
+                "private_url",
+                "unknown_timezone",
+                "no_occurrences",
+                "too_frequent",
+                "invalid_template",
+                "unsupported_template_action",
+                "output_too_long"
+            ],
+            "x-enum-varnames": [
+                "FieldRequired",
+                "FieldInvalid",
+                "FieldInvalidFormat",
+                "FieldInvalidEmail",
+                "FieldTooShort",
+                "FieldTooLong",
+                "FieldNegative",
+                "FieldTooMany",
+                "FieldDuplicate",
+                "FieldImmutable",
+                "FieldInvalidURL",
+                "FieldPrivateURL",
+                "FieldTimezone",
+                "FieldNoOccurrences",
+                "FieldTooFrequent",
+                "FieldTemplate",
+                "FieldTemplateAction",
+                "FieldOutputTooLong"
+            ]
+        },
+        "problem.FieldError": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "$ref": "#/definitions/problem.FieldCode"
+                },
+                "field": {
+                    "type": "string"
+                },
+                "limit": {
+                    "type": "integer"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "problem.Problem": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "$ref": "#/definitions/problem.Code"
+                },
+                "detail": {
+                    "type": "string"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/problem.FieldError"
+                    }
+                },
+                "instance": {
+                    "type": "string"
+                },
+                "request_id": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "integer"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "reminders.ReminderInput": {
+            "type": "object",
+            "properties": {
+                "at": {
+                    "description": "At — первое (или единственное) срабатывание",
+                    "type": "string"
+                },
+                "channels": {
+                    "description": "Channels — каналы доставки (по умолчанию inapp)",
+                    "type": "array",
+                    "items": {
+                        "type": "string",
+                        "enum": [
+                            "inapp",
+                            "email",
+                            "webhook"
+                        ]
+                    }
+                },
+                "rrule": {
+                    "description": "RRule — правило повторения RFC 5545, например FREQ=DAILY;COUNT=5; пусто для разового напоминания",
+                    "type": "string"
+                },
+                "timezone": {
+                    "description": "Timezone — часовой пояс IANA для повторений (по умолчанию UTC)",
+                    "type": "string",
+                    "example": "Europe/Moscow"
+                }
+            }
+        },
+        "service.SyncChanges": {
+            "type": "object",
+            "properties": {
+                "deleted": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.NoteTombstone"
+                    }
+                },
+                "has_more": {
+                    "type": "boolean"
+                },
+                "notes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.Note"
+                    }
+                },
+                "sync_token": {
+                    "type": "string"
+                }
+            }
+        },
+        "service.SyncMutationInput": {
+            "type": "object",
+            "properties": {
+                "base_seq": {
+                    "description": "BaseSeq — change_seq версии заметки, от которой клиент вносил изменения (обязателен для update и delete)",
+                    "type": "integer"
+                },
+                "content": {
+                    "type": "string"
+                },
+                "encrypted": {
+                    "description": "Encrypted создаёт заметку, зашифрованную на клиенте (только для create); у update режим берётся из заметки",
+                    "type": "boolean"
+                },
+                "mutation_id": {
+                    "description": "MutationID — уникальный в пределах пользователя идентификатор, по которому\nповторно отправленная мутация распознаётся и не применяется второй раз",
+                    "type": "string"
+                },
+                "note_id": {
+                    "type": "integer"
+                },
+                "op": {
+                    "type": "string",
+                    "enum": [
+                        "create",
+                        "update",
+                        "delete"
+                    ]
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "service.SyncMutationResult": {
+            "type": "object",
+            "properties": {
+                "deleted": {
+                    "$ref": "#/definitions/model.NoteTombstone"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "mutation_id": {
+                    "type": "string"
+                },
+                "note": {
+                    "$ref": "#/definitions/model.Note"
+                },
+                "op": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "applied",
+                        "conflict",
+                        "not_found",
+                        "invalid"
+                    ]
+                }
+            }
+        },
+        "templates.TemplateInput": {
+            "type": "object",
+            "properties": {
+                "content": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "title": {
+                    "description": "Title и Content — шаблоны text/template, например \"Встреча {{date}}\"",
+                    "type": "string"
+                }
+            }
+        },
+        "todos.ToggleInput": {
+            "type": "object",
+            "properties": {
+                "change_seq": {
+                    "type": "integer"
+                },
+                "text": {
+                    "type": "string"
+                }
+            }
+        },
+        "todos.ToggleResult": {
+            "type": "object",
+            "properties": {
+                "note": {
+                    "$ref": "#/definitions/model.Note"
+                },
+                "todo": {
+                    "$ref": "#/definitions/model.NoteTodo"
+                }
+            }
+        },
+        "webhooks.WebhookInput": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "type": "boolean"
+                },
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "secret": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
                 }
             }
         }
@@ -0,0 +1,96 @@
+package e2e
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// EnvelopeVersion — текущая версия формата конверта
+const EnvelopeVersion = 1
+
+// Алгоритмы шифрования содержимого, которые принимает сервер
+const (
+	AlgAES256GCM         = "AES-256-GCM"
+	AlgXChaCha20Poly1305 = "XChaCha20-Poly1305"
+)
+
+// nonceSizes — длина nonce для каждого алгоритма
+var nonceSizes = map[string]int{
+	AlgAES256GCM:         12,
+	AlgXChaCha20Poly1305: 24,
+}
+
+// tagSize — длина тега аутентификации у обоих алгоритмов
+const tagSize = 16
+
+var keyIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// ErrInvalidEnvelope — поле зашифрованной заметки не является конвертом
+var ErrInvalidEnvelope = errors.New("некорректный конверт шифротекста")
+
+// Envelope — шифротекст поля заметки вместе со сведениями, нужными клиенту для расшифровки.
+// В Title и Content зашифрованной заметки лежит JSON конверта. Сервер не видит ни текста,
+// ни ключей: он хранит конверты и обёрнутые ключи пользователя и проверяет только форму конверта.
+type Envelope struct {
+	Version int    `json:"v"`
+	Alg     string `json:"alg"`
+	// KeyID — kid ключа из связки пользователя (GET /keys), которым зашифровано поле
+	KeyID string `json:"kid"`
+	// Nonce и Ciphertext — в base64; тег аутентификации входит в Ciphertext
+	Nonce      string `json:"nonce"`
+	Ciphertext string `json:"ct"`
+}
+
+// ParseEnvelope разбирает и проверяет конверт. Расшифровать его сервер не может
+// и не пытается: проверяется только, что поле похоже на шифротекст, а не на открытый текст.
+func ParseEnvelope(s string) (Envelope, error) {
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.DisallowUnknownFields()
+	var env Envelope
+	if err := dec.Decode(&env); err != nil || dec.More() {
+		return Envelope{}, fmt.Errorf("%w: ожидается JSON с полями v, alg, kid, nonce и ct", ErrInvalidEnvelope)
+	}
+
+	if env.Version != EnvelopeVersion {
+		return Envelope{}, fmt.Errorf("%w: неподдерживаемая версия %d", ErrInvalidEnvelope, env.Version)
+	}
+	nonceSize, ok := nonceSizes[env.Alg]
+	if !ok {
+		return Envelope{}, fmt.Errorf("%w: неподдерживаемый алгоритм %q", ErrInvalidEnvelope, env.Alg)
+	}
+	if !keyIDPattern.MatchString(env.KeyID) {
+		return Envelope{}, fmt.Errorf("%w: некорректный kid", ErrInvalidEnvelope)
+	}
+	nonce, err := base64.StdEncoding.DecodeString(env.Nonce)
+	if err != nil || len(nonce) != nonceSize {
+		return Envelope{}, fmt.Errorf("%w: nonce для %s — %d байт в base64", ErrInvalidEnvelope, env.Alg, nonceSize)
+	}
+	ct, err := base64.StdEncoding.DecodeString(env.Ciphertext)
+	if err != nil || len(ct) < tagSize {
+		return Envelope{}, fmt.Errorf("%w: ct должен быть base64 не короче тега аутентификации", ErrInvalidEnvelope)
+	}
+	return env, nil
+}
+
+// ValidateNote проверяет поля зашифрованной заметки: содержимое — обязательно конверт,
+// заголовок — конверт или пустая строка. Открытый текст сервер не требует и не принимает.
+func ValidateNote(title, content string) error {
+	if title != "" {
+		if _, err := ParseEnvelope(title); err != nil {
+			return fmt.Errorf("title: %w", err)
+		}
+	}
+	if _, err := ParseEnvelope(content); err != nil {
+		return fmt.Errorf("content: %w", err)
+	}
+	return nil
+}
+
+// ValidKeyID сообщает, подходит ли строка в качестве kid
+func ValidKeyID(kid string) bool {
+	return keyIDPattern.MatchString(kid)
+}
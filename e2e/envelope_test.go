@@ -0,0 +1,89 @@
+package e2e
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func b64(n int) string {
+	return base64.StdEncoding.EncodeToString(make([]byte, n))
+}
+
+func envelope(t *testing.T, fields map[string]any) string {
+	t.Helper()
+	env := map[string]any{"v": 1, "alg": AlgAES256GCM, "kid": "main-2024", "nonce": b64(12), "ct": b64(tagSize + 5)}
+	for k, v := range fields {
+		if v == nil {
+			delete(env, k)
+		} else {
+			env[k] = v
+		}
+	}
+	data, err := json.Marshal(env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestParseEnvelope(t *testing.T) {
+	valid := []map[string]any{
+		{},
+		{"alg": AlgXChaCha20Poly1305, "nonce": b64(24)},
+		{"ct": b64(tagSize)},
+		{"kid": strings.Repeat("k", 64)},
+	}
+	for _, fields := range valid {
+		s := envelope(t, fields)
+		env, err := ParseEnvelope(s)
+		if err != nil {
+			t.Errorf("ParseEnvelope(%s): %v", s, err)
+			continue
+		}
+		if env.Version != EnvelopeVersion || env.KeyID == "" {
+			t.Errorf("ParseEnvelope(%s) = %+v", s, env)
+		}
+	}
+
+	invalid := map[string]string{
+		"открытый текст":        "просто заметка",
+		"пустая строка":         "",
+		"лишнее поле":           envelope(t, map[string]any{"extra": 1}),
+		"два объекта":           envelope(t, nil) + envelope(t, nil),
+		"версия":                envelope(t, map[string]any{"v": 2}),
+		"без версии":            envelope(t, map[string]any{"v": nil}),
+		"алгоритм":              envelope(t, map[string]any{"alg": "ROT13"}),
+		"kid":                   envelope(t, map[string]any{"kid": "ключ"}),
+		"длинный kid":           envelope(t, map[string]any{"kid": strings.Repeat("k", 65)}),
+		"nonce не той длины":    envelope(t, map[string]any{"nonce": b64(24)}),
+		"nonce не base64":       envelope(t, map[string]any{"nonce": "***"}),
+		"ct короче тега":        envelope(t, map[string]any{"ct": b64(tagSize - 1)}),
+		"ct не base64":          envelope(t, map[string]any{"ct": "не base64"}),
+		"ct отсутствует":        envelope(t, map[string]any{"ct": nil}),
+		"строка вместо объекта": `"{\"v\":1}"`,
+	}
+	for name, s := range invalid {
+		if _, err := ParseEnvelope(s); !errors.Is(err, ErrInvalidEnvelope) {
+			t.Errorf("%s: ParseEnvelope(%q) = %v, ожидалась ErrInvalidEnvelope", name, s, err)
+		}
+	}
+}
+
+func TestValidateNote(t *testing.T) {
+	env := envelope(t, nil)
+	if err := ValidateNote("", env); err != nil {
+		t.Errorf("пустой заголовок: %v", err)
+	}
+	if err := ValidateNote(env, env); err != nil {
+		t.Errorf("заголовок-конверт: %v", err)
+	}
+	if err := ValidateNote("Заголовок", env); !errors.Is(err, ErrInvalidEnvelope) || !strings.HasPrefix(err.Error(), "title:") {
+		t.Errorf("открытый заголовок: %v", err)
+	}
+	if err := ValidateNote("", "Текст"); !errors.Is(err, ErrInvalidEnvelope) || !strings.HasPrefix(err.Error(), "content:") {
+		t.Errorf("открытое содержимое: %v", err)
+	}
+}
@@ -0,0 +1,140 @@
+package e2e
+
+import (
+	"encoding/json"
+	"net/http"
+	"notes-api/httpx"
+	"notes-api/logger"
+	"notes-api/problem"
+
+	"github.com/gorilla/mux"
+)
+
+type KeyHandler struct {
+	Service *KeyService
+}
+
+// List godoc
+// @Summary Связка ключей текущего пользователя
+// @Description Обёрнутые ключи для заметок, зашифрованных на клиенте. Сервер хранит их как есть и развернуть не может
+// @Tags keys
+// @Security ApiKeyAuth
+// @Produce json
+// @Success 200 {array} model.KeyBundle "Ключи"
+// @Failure 401 {object} problem.Problem "Пользователь не аутентифицирован"
+// @Router /keys [get]
+func (h *KeyHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := httpx.UserID(w, r)
+	if !ok {
+		return
+	}
+
+	bundles, err := h.Service.List(userID)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, bundles)
+}
+
+// Get godoc
+// @Summary Получить ключ по kid
+// @Tags keys
+// @Security ApiKeyAuth
+// @Produce json
+// @Param kid path string true "kid ключа"
+// @Success 200 {object} model.KeyBundle "Ключ"
+// @Failure 404 {object} problem.Problem "Ключ не найден"
+// @Router /keys/{kid} [get]
+func (h *KeyHandler) Get(w http.ResponseWriter, r *http.Request) {
+	userID, ok := httpx.UserID(w, r)
+	if !ok {
+		return
+	}
+
+	bundle, err := h.Service.Get(userID, mux.Vars(r)["kid"])
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, bundle)
+}
+
+// Create godoc
+// @Summary Сохранить обёрнутый ключ
+// @Description Ключ оборачивается на клиенте; kid потом указывается в конвертах зашифрованных заметок
+// @Tags keys
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param input body e2e.KeyBundleInput true "Обёрнутый ключ"
+// @Success 201 {object} model.KeyBundle "Сохранённый ключ"
+// @Failure 400 {object} problem.Problem "Неверный запрос или ошибка валидации"
+// @Failure 409 {object} problem.Problem "Ключ с таким kid уже есть или ключей слишком много"
+// @Router /keys [post]
+func (h *KeyHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, ok := httpx.UserID(w, r)
+	if !ok {
+		return
+	}
+
+	var input KeyBundleInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		logger.FromContext(r.Context()).WithError(err).Warn("Неверный запрос")
+		problem.Write(w, r, problem.BadRequest)
+		return
+	}
+
+	bundle, err := h.Service.Create(userID, input)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	logger.FromContext(r.Context()).WithFields(logger.Fields{
+		"user_id": userID,
+		"kid":     bundle.KeyID,
+	}).Info("Ключ шифрования сохранён")
+	httpx.WriteJSON(w, http.StatusCreated, bundle)
+}
+
+// Update godoc
+// @Summary Заменить обёртку ключа
+// @Description Например, после смены пароля клиент заново оборачивает тот же ключ. Удалить ключ нельзя: зашифрованные им заметки стали бы нечитаемыми
+// @Tags keys
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param kid path string true "kid ключа"
+// @Param input body e2e.KeyBundleInput true "Новая обёртка ключа"
+// @Success 200 {object} model.KeyBundle "Обновлённый ключ"
+// @Failure 400 {object} problem.Problem "Неверный запрос или ошибка валидации"
+// @Failure 404 {object} problem.Problem "Ключ не найден"
+// @Router /keys/{kid} [put]
+func (h *KeyHandler) Update(w http.ResponseWriter, r *http.Request) {
+	userID, ok := httpx.UserID(w, r)
+	if !ok {
+		return
+	}
+
+	var input KeyBundleInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		logger.FromContext(r.Context()).WithError(err).Warn("Неверный запрос")
+		problem.Write(w, r, problem.BadRequest)
+		return
+	}
+
+	bundle, err := h.Service.Update(userID, mux.Vars(r)["kid"], input)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	logger.FromContext(r.Context()).WithFields(logger.Fields{
+		"user_id": userID,
+		"kid":     bundle.KeyID,
+	}).Info("Обёртка ключа шифрования заменена")
+	httpx.WriteJSON(w, http.StatusOK, bundle)
+}
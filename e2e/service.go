@@ -0,0 +1,146 @@
+package e2e
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"notes-api/model"
+	"notes-api/problem"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	maxKeyBundles   = 100
+	maxAlgLength    = 100
+	maxWrappedKey   = 4 << 10
+	maxParamsLength = 4 << 10
+)
+
+var (
+	ErrKeyNotFound = errors.New("ключ не найден")
+	ErrKeyExists   = errors.New("ключ с таким kid уже есть")
+	ErrTooManyKeys = errors.New("слишком много ключей шифрования")
+)
+
+// KeyBundleInput — обёрнутый ключ, который клиент сохраняет на сервере
+type KeyBundleInput struct {
+	// KeyID задаётся только при создании; допустимы латиница, цифры, точка, дефис и подчёркивание
+	KeyID      string         `json:"kid" example:"main-2024"`
+	Alg        string         `json:"alg" example:"PBKDF2-SHA256+A256KW"`
+	WrappedKey string         `json:"wrapped_key"`
+	Params     map[string]any `json:"params"`
+}
+
+// KeyService хранит связку обёрнутых ключей пользователя
+type KeyService struct {
+	DB *gorm.DB
+}
+
+func NewKeyService(db *gorm.DB) *KeyService {
+	return &KeyService{DB: db}
+}
+
+func (s *KeyService) List(userID uint) ([]model.KeyBundle, error) {
+	bundles := []model.KeyBundle{}
+	err := s.DB.Where("user_id = ?", userID).Order("id").Find(&bundles).Error
+	return bundles, err
+}
+
+func (s *KeyService) Get(userID uint, kid string) (model.KeyBundle, error) {
+	var bundle model.KeyBundle
+	if err := s.DB.Where("user_id = ? AND key_id = ?", userID, kid).First(&bundle).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.KeyBundle{}, ErrKeyNotFound
+		}
+		return model.KeyBundle{}, err
+	}
+	return bundle, nil
+}
+
+func (s *KeyService) Create(userID uint, input KeyBundleInput) (model.KeyBundle, error) {
+	if !ValidKeyID(input.KeyID) {
+		return model.KeyBundle{}, problem.Invalid(problem.Field("kid", problem.FieldInvalidFormat))
+	}
+	if err := validateBundle(input); err != nil {
+		return model.KeyBundle{}, err
+	}
+
+	var count int64
+	if err := s.DB.Model(&model.KeyBundle{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+		return model.KeyBundle{}, err
+	}
+	if count >= maxKeyBundles {
+		return model.KeyBundle{}, ErrTooManyKeys
+	}
+
+	bundle := model.KeyBundle{
+		UserID:     userID,
+		KeyID:      input.KeyID,
+		Alg:        input.Alg,
+		WrappedKey: input.WrappedKey,
+		Params:     input.Params,
+	}
+	res := s.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&bundle)
+	if res.Error != nil {
+		return model.KeyBundle{}, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return model.KeyBundle{}, ErrKeyExists
+	}
+	return bundle, nil
+}
+
+// Update заменяет обёртку ключа, например после смены пароля. Сам ключ при этом
+// не меняется, поэтому уже зашифрованные им заметки перешифровывать не нужно.
+// Удалить ключ нельзя: заметки, зашифрованные им, стали бы нечитаемыми навсегда.
+func (s *KeyService) Update(userID uint, kid string, input KeyBundleInput) (model.KeyBundle, error) {
+	if input.KeyID != "" && input.KeyID != kid {
+		return model.KeyBundle{}, problem.Invalid(problem.Field("kid", problem.FieldImmutable))
+	}
+	if err := validateBundle(input); err != nil {
+		return model.KeyBundle{}, err
+	}
+
+	bundle, err := s.Get(userID, kid)
+	if err != nil {
+		return model.KeyBundle{}, err
+	}
+	bundle.Alg = input.Alg
+	bundle.WrappedKey = input.WrappedKey
+	bundle.Params = input.Params
+	if err := s.DB.Save(&bundle).Error; err != nil {
+		return model.KeyBundle{}, err
+	}
+	return bundle, nil
+}
+
+func validateBundle(input KeyBundleInput) error {
+	var fields []problem.FieldError
+	switch {
+	case input.Alg == "":
+		fields = append(fields, problem.Field("alg", problem.FieldRequired))
+	case len(input.Alg) > maxAlgLength:
+		fields = append(fields, problem.FieldLimit("alg", problem.FieldTooLong, maxAlgLength))
+	}
+
+	key, err := base64.StdEncoding.DecodeString(input.WrappedKey)
+	switch {
+	case input.WrappedKey == "":
+		fields = append(fields, problem.Field("wrapped_key", problem.FieldRequired))
+	case err != nil || len(key) == 0:
+		fields = append(fields, problem.Field("wrapped_key", problem.FieldInvalidFormat))
+	case len(key) > maxWrappedKey:
+		fields = append(fields, problem.FieldLimit("wrapped_key", problem.FieldTooLong, maxWrappedKey))
+	}
+
+	if params, err := json.Marshal(input.Params); err != nil || len(params) > maxParamsLength {
+		fields = append(fields, problem.FieldLimit("params", problem.FieldTooLong, maxParamsLength))
+	}
+
+	if len(fields) > 0 {
+		return problem.Invalid(fields...)
+	}
+	return nil
+}
@@ -0,0 +1,229 @@
+package encryption
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"notes-api/model"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// Prefix отличает зашифрованное значение от открытого текста, записанного до включения шифрования.
+	// Формат: enc:v1:<ID ключа данных>:<base64(nonce || шифротекст)>
+	Prefix = "enc:v1:"
+
+	// reservedPrefix — общее начало служебных форматов значения
+	reservedPrefix = "enc:"
+	// plainPrefix помечает открытый текст, который сам начинается с reservedPrefix:
+	// без пометки его приняли бы за шифротекст. Формат: enc:plain:<текст>
+	plainPrefix = "enc:plain:"
+
+	dataKeySize = 32
+
+	// activeKeyTTL — сколько помнить действующий ключ пользователя. После ротации другие
+	// экземпляры сервиса переходят на новый ключ не позже чем через это время.
+	activeKeyTTL = time.Minute
+)
+
+var (
+	errCorrupted = errors.New("зашифрованное значение повреждено")
+	errNoOwner   = errors.New("у зашифрованного значения нет владельца")
+)
+
+type activeKey struct {
+	id      uint
+	expires time.Time
+}
+
+// Keyring выдаёт ключи данных пользователей: создаёт их при первой записи, разворачивает
+// через KeyProvider и держит развёрнутые ключи в памяти.
+type Keyring struct {
+	DB       *gorm.DB
+	Provider KeyProvider
+
+	mu     sync.Mutex
+	keys   map[uint][]byte
+	active map[uint]activeKey
+}
+
+func NewKeyring(db *gorm.DB, provider KeyProvider) *Keyring {
+	return &Keyring{
+		DB:       db,
+		Provider: provider,
+		keys:     make(map[uint][]byte),
+		active:   make(map[uint]activeKey),
+	}
+}
+
+// Encrypt шифрует значение действующим ключом данных пользователя
+func (k *Keyring) Encrypt(ctx context.Context, userID uint, plaintext string) (string, error) {
+	if userID == 0 {
+		return "", errNoOwner
+	}
+	id, key, err := k.activeKey(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := seal(aead, []byte(plaintext), additionalData(id))
+	if err != nil {
+		return "", err
+	}
+	return Prefix + strconv.FormatUint(uint64(id), 10) + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt расшифровывает значение. Открытый текст, записанный до включения шифрования,
+// возвращается как есть, помеченный enc:plain: — без пометки.
+func (k *Keyring) Decrypt(ctx context.Context, value string) (string, error) {
+	if plaintext, ok := strings.CutPrefix(value, plainPrefix); ok {
+		return plaintext, nil
+	}
+	id, sealed, ok, err := parse(value)
+	if err != nil || !ok {
+		return value, err
+	}
+	key, err := k.key(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := open(aead, sealed, additionalData(id))
+	if err != nil {
+		return "", fmt.Errorf("ключ данных %d: %w", id, err)
+	}
+	return string(plaintext), nil
+}
+
+// escape помечает открытый текст, который иначе спутали бы с зашифрованным значением
+func escape(value string) string {
+	if strings.HasPrefix(value, reservedPrefix) {
+		return plainPrefix + value
+	}
+	return value
+}
+
+// parse разбирает зашифрованное значение; ok = false для открытого текста
+func parse(value string) (id uint, sealed []byte, ok bool, err error) {
+	rest, found := strings.CutPrefix(value, Prefix)
+	if !found {
+		return 0, nil, false, nil
+	}
+	rawID, encoded, found := strings.Cut(rest, ":")
+	if !found {
+		return 0, nil, false, errCorrupted
+	}
+	n, err := strconv.ParseUint(rawID, 10, 64)
+	if err != nil {
+		return 0, nil, false, errCorrupted
+	}
+	sealed, err = base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return 0, nil, false, errCorrupted
+	}
+	return uint(n), sealed, true, nil
+}
+
+// additionalData привязывает шифротекст к ключу данных, указанному в его заголовке
+func additionalData(keyID uint) []byte {
+	return []byte(Prefix + strconv.FormatUint(uint64(keyID), 10))
+}
+
+// activeKey возвращает действующий ключ данных пользователя, создавая его при первой записи.
+// Если два экземпляра создадут ключ одновременно, оба останутся рабочими, а действующим
+// станет более новый.
+func (k *Keyring) activeKey(ctx context.Context, userID uint) (uint, []byte, error) {
+	k.mu.Lock()
+	cached, ok := k.active[userID]
+	k.mu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		key, err := k.key(ctx, cached.id)
+		return cached.id, key, err
+	}
+
+	var dataKey model.DataKey
+	err := k.DB.WithContext(ctx).
+		Where("user_id = ? AND retired_at IS NULL", userID).
+		Order("id DESC").
+		First(&dataKey).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		dataKey, err = k.createKey(ctx, userID)
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+
+	key, err := k.key(ctx, dataKey.ID)
+	if err != nil {
+		return 0, nil, err
+	}
+	k.mu.Lock()
+	k.active[userID] = activeKey{id: dataKey.ID, expires: time.Now().Add(activeKeyTTL)}
+	k.mu.Unlock()
+	return dataKey.ID, key, nil
+}
+
+func (k *Keyring) createKey(ctx context.Context, userID uint) (model.DataKey, error) {
+	key := make([]byte, dataKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return model.DataKey{}, err
+	}
+	masterKeyID, wrapped, err := k.Provider.Wrap(ctx, key)
+	if err != nil {
+		return model.DataKey{}, err
+	}
+
+	dataKey := model.DataKey{UserID: userID, MasterKeyID: masterKeyID, WrappedKey: wrapped}
+	if err := k.DB.WithContext(ctx).Create(&dataKey).Error; err != nil {
+		return model.DataKey{}, err
+	}
+
+	k.mu.Lock()
+	k.keys[dataKey.ID] = key
+	k.mu.Unlock()
+	return dataKey, nil
+}
+
+// key возвращает развёрнутый ключ данных по ID
+func (k *Keyring) key(ctx context.Context, id uint) ([]byte, error) {
+	k.mu.Lock()
+	key, ok := k.keys[id]
+	k.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+
+	var dataKey model.DataKey
+	if err := k.DB.WithContext(ctx).First(&dataKey, id).Error; err != nil {
+		return nil, fmt.Errorf("ключ данных %d: %w", id, err)
+	}
+	key, err := k.Provider.Unwrap(ctx, dataKey.MasterKeyID, dataKey.WrappedKey)
+	if err != nil {
+		return nil, fmt.Errorf("ключ данных %d: %w", id, err)
+	}
+
+	k.mu.Lock()
+	k.keys[id] = key
+	k.mu.Unlock()
+	return key, nil
+}
+
+// forgetActive сбрасывает кеш действующих ключей после ротации
+func (k *Keyring) forgetActive() {
+	k.mu.Lock()
+	k.active = make(map[uint]activeKey)
+	k.mu.Unlock()
+}
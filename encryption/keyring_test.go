@@ -0,0 +1,170 @@
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"notes-api/model"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func randomAEADKey(t *testing.T) []byte {
+	t.Helper()
+	key := make([]byte, dataKeySize)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestSealOpen(t *testing.T) {
+	aead, err := newAEAD(randomAEADKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ad := additionalData(1)
+	plaintext := []byte("секретная заметка")
+
+	sealed, err := seal(aead, plaintext, ad)
+	if err != nil {
+		t.Fatalf("seal: %v", err)
+	}
+	if bytes.Contains(sealed, plaintext) {
+		t.Fatal("шифротекст содержит открытый текст")
+	}
+	opened, err := open(aead, sealed, ad)
+	if err != nil || !bytes.Equal(opened, plaintext) {
+		t.Fatalf("open = %q, %v", opened, err)
+	}
+
+	again, _ := seal(aead, plaintext, ad)
+	if bytes.Equal(again, sealed) {
+		t.Error("два шифрования одного текста совпали: nonce не случайный")
+	}
+
+	otherAEAD, _ := newAEAD(randomAEADKey(t))
+	tampered := bytes.Clone(sealed)
+	tampered[len(tampered)-1] ^= 1
+
+	rejected := map[string]func() ([]byte, error){
+		"чужой ключ":       func() ([]byte, error) { return open(otherAEAD, sealed, ad) },
+		"другие AAD":       func() ([]byte, error) { return open(aead, sealed, additionalData(2)) },
+		"изменённый байт":  func() ([]byte, error) { return open(aead, tampered, ad) },
+		"обрезанное":       func() ([]byte, error) { return open(aead, sealed[:aead.NonceSize()+aead.Overhead()-1], ad) },
+		"пустое":           func() ([]byte, error) { return open(aead, nil, ad) },
+		"только nonce+тег": func() ([]byte, error) { return open(aead, sealed[:aead.NonceSize()+aead.Overhead()], ad) },
+	}
+	for name, try := range rejected {
+		if out, err := try(); !errors.Is(err, errCorrupted) {
+			t.Errorf("%s: open = %q, %v; ожидалась errCorrupted", name, out, err)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	sealed := []byte{1, 2, 3, 250}
+	valid := Prefix + "42:" + base64.StdEncoding.EncodeToString(sealed)
+
+	id, got, ok, err := parse(valid)
+	if err != nil || !ok || id != 42 || !bytes.Equal(got, sealed) {
+		t.Fatalf("parse(%q) = %d, %v, %v, %v", valid, id, got, ok, err)
+	}
+
+	for _, plaintext := range []string{"", "текст", "enc:v2:1:AAAA", "ENC:V1:1:AAAA", " " + valid} {
+		if _, _, ok, err := parse(plaintext); ok || err != nil {
+			t.Errorf("parse(%q): ok=%v, err=%v; ожидался открытый текст", plaintext, ok, err)
+		}
+	}
+
+	for _, corrupted := range []string{
+		Prefix,
+		Prefix + "42",
+		Prefix + ":AAAA",
+		Prefix + "x:AAAA",
+		Prefix + "-1:AAAA",
+		Prefix + "99999999999999999999999:AAAA",
+		Prefix + "42:не base64",
+		Prefix + "42:AAA",
+	} {
+		if _, _, ok, err := parse(corrupted); !errors.Is(err, errCorrupted) || ok {
+			t.Errorf("parse(%q): ok=%v, err=%v; ожидалась errCorrupted", corrupted, ok, err)
+		}
+	}
+}
+
+func TestKeyringRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	db, userID := openDB(t)
+	k1 := masterKey(t, "k1")
+	k := newKeyring(t, db, k1)
+
+	ciphertext, err := k.Encrypt(ctx, userID, "заметка")
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	if !strings.HasPrefix(ciphertext, Prefix) {
+		t.Fatalf("Encrypt = %q", ciphertext)
+	}
+	if plaintext, err := k.Decrypt(ctx, ciphertext); err != nil || plaintext != "заметка" {
+		t.Fatalf("Decrypt = %q, %v", plaintext, err)
+	}
+
+	// Другой экземпляр с теми же мастер-ключами разворачивает ключ данных из базы
+	if plaintext, err := newKeyring(t, db, k1).Decrypt(ctx, ciphertext); err != nil || plaintext != "заметка" {
+		t.Errorf("Decrypt другим экземпляром = %q, %v", plaintext, err)
+	}
+
+	// Без нужного мастер-ключа значение не расшифровать
+	if _, err := newKeyring(t, db, masterKey(t, "k2")).Decrypt(ctx, ciphertext); !errors.Is(err, ErrUnknownMasterKey) {
+		t.Errorf("Decrypt без мастер-ключа: %v", err)
+	}
+	// Мастер-ключ с тем же ID, но другими байтами не развернёт ключ данных
+	if _, err := newKeyring(t, db, masterKey(t, "k1")).Decrypt(ctx, ciphertext); !errors.Is(err, errCorrupted) {
+		t.Errorf("Decrypt подменённым мастер-ключом: %v", err)
+	}
+
+	if _, err := k.Encrypt(ctx, 0, "без владельца"); !errors.Is(err, errNoOwner) {
+		t.Errorf("Encrypt без владельца: %v", err)
+	}
+}
+
+// Шифротекст привязан к ключу данных из своего заголовка: значение, переписанное
+// на ID другого ключа того же пользователя, не расшифровывается
+func TestKeyringRejectsSwappedKeyID(t *testing.T) {
+	ctx := context.Background()
+	db, userID := openDB(t)
+	k := newKeyring(t, db, masterKey(t, "k1"))
+
+	first, err := k.Encrypt(ctx, userID, "заметка")
+	if err != nil {
+		t.Fatal(err)
+	}
+	firstID, sealed, _, _ := parse(first)
+
+	// Второй ключ данных того же пользователя
+	if err := db.Model(&model.DataKey{}).Where("id = ?", firstID).Update("retired_at", db.NowFunc()).Error; err != nil {
+		t.Fatal(err)
+	}
+	k.forgetActive()
+	second, err := k.Encrypt(ctx, userID, "заметка")
+	if err != nil {
+		t.Fatal(err)
+	}
+	secondID, _, _, _ := parse(second)
+	if secondID == firstID {
+		t.Fatalf("после вывода ключа %d шифрование идёт им же", firstID)
+	}
+
+	swapped := Prefix + strconv.FormatUint(uint64(secondID), 10) + ":" + base64.StdEncoding.EncodeToString(sealed)
+	if _, err := k.Decrypt(ctx, swapped); !errors.Is(err, errCorrupted) {
+		t.Errorf("Decrypt с чужим ID ключа: %v", err)
+	}
+	// Выведенный ключ по-прежнему расшифровывает свои значения
+	if plaintext, err := k.Decrypt(ctx, first); err != nil || plaintext != "заметка" {
+		t.Errorf("Decrypt выведенным ключом = %q, %v", plaintext, err)
+	}
+}
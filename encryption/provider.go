@@ -0,0 +1,139 @@
+package encryption
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// masterKeySize — длина мастер-ключа: AES-256
+const masterKeySize = 32
+
+// ErrUnknownMasterKey — ключ данных обёрнут мастер-ключом, которого нет у провайдера
+var ErrUnknownMasterKey = errors.New("неизвестный мастер-ключ")
+
+// KeyProvider оборачивает ключи данных пользователей мастер-ключом. Сами мастер-ключи
+// наружу не отдаются, поэтому провайдер можно заменить на KMS без изменений остального кода.
+type KeyProvider interface {
+	// CurrentKeyID — мастер-ключ, которым оборачиваются новые ключи данных
+	CurrentKeyID() string
+	// Wrap оборачивает ключ данных текущим мастер-ключом
+	Wrap(ctx context.Context, dataKey []byte) (masterKeyID string, wrapped []byte, err error)
+	// Unwrap разворачивает ключ данных мастер-ключом masterKeyID
+	Unwrap(ctx context.Context, masterKeyID string, wrapped []byte) ([]byte, error)
+}
+
+// StaticKeyProvider держит мастер-ключи в памяти процесса. Первый ключ списка текущий,
+// остальные нужны только для разворачивания ключей, обёрнутых до ротации.
+type StaticKeyProvider struct {
+	current string
+	keys    map[string]cipher.AEAD
+}
+
+// NewEnvKeyProvider читает мастер-ключи из строки вида "id:base64,id:base64"
+// (обычно переменная ENCRYPTION_MASTER_KEYS)
+func NewEnvKeyProvider(value string) (*StaticKeyProvider, error) {
+	return parseMasterKeys(strings.Split(value, ","))
+}
+
+// NewFileKeyProvider читает мастер-ключи из файла: по ключу "id:base64" в строке,
+// пустые строки и строки с # пропускаются
+func NewFileKeyProvider(path string) (*StaticKeyProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось прочитать файл мастер-ключей: %w", err)
+	}
+
+	var entries []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			entries = append(entries, line)
+		}
+	}
+	return parseMasterKeys(entries)
+}
+
+func parseMasterKeys(entries []string) (*StaticKeyProvider, error) {
+	p := &StaticKeyProvider{keys: make(map[string]cipher.AEAD)}
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, encoded, ok := strings.Cut(entry, ":")
+		if !ok || id == "" {
+			return nil, errors.New("мастер-ключ задаётся как id:base64")
+		}
+		if _, dup := p.keys[id]; dup {
+			return nil, fmt.Errorf("мастер-ключ %q указан дважды", id)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(key) != masterKeySize {
+			return nil, fmt.Errorf("мастер-ключ %q должен быть %d байтами в base64", id, masterKeySize)
+		}
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, err
+		}
+		if p.current == "" {
+			p.current = id
+		}
+		p.keys[id] = aead
+	}
+	if p.current == "" {
+		return nil, errors.New("не задан ни один мастер-ключ")
+	}
+	return p, nil
+}
+
+func (p *StaticKeyProvider) CurrentKeyID() string {
+	return p.current
+}
+
+func (p *StaticKeyProvider) Wrap(ctx context.Context, dataKey []byte) (string, []byte, error) {
+	wrapped, err := seal(p.keys[p.current], dataKey, []byte(p.current))
+	return p.current, wrapped, err
+}
+
+func (p *StaticKeyProvider) Unwrap(ctx context.Context, masterKeyID string, wrapped []byte) ([]byte, error) {
+	aead, ok := p.keys[masterKeyID]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownMasterKey, masterKeyID)
+	}
+	return open(aead, wrapped, []byte(masterKeyID))
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal шифрует plaintext со случайным nonce и возвращает nonce вместе с шифротекстом
+func seal(aead cipher.AEAD, plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func open(aead cipher.AEAD, sealed, additionalData []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize()+aead.Overhead() {
+		return nil, errCorrupted
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, additionalData)
+	if err != nil {
+		return nil, errCorrupted
+	}
+	return plaintext, nil
+}
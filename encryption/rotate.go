@@ -0,0 +1,245 @@
+package encryption
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"notes-api/jobs"
+	"notes-api/logger"
+	"notes-api/model"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RotateJobType — фоновая ротация ключей и перешифровка заметок
+const RotateJobType = "encryption.rotate"
+
+const rotateBatch = 100
+
+// encryptedColumns — все таблицы с полями serializer:encrypted и их столбцы. Копии
+// содержимого заметок (версии, пункты задач, ссылки, уведомления, журнал событий,
+// доставки вебхуков, сохранённые результаты синхронизации) шифруются так же, как сама заметка, и ротируются вместе с ней.
+var encryptedColumns = []struct {
+	model   any
+	columns []string
+}{
+	{&model.Note{}, []string{"title", "content"}},
+	{&model.NoteRevision{}, []string{"title", "content"}},
+	{&model.NoteTodo{}, []string{"text"}},
+	{&model.NoteLink{}, []string{"target_title"}},
+	{&model.Notification{}, []string{"title", "body"}},
+	{&model.NoteEvent{}, []string{"data"}},
+	{&model.WebhookDelivery{}, []string{"payload"}},
+	{&model.SyncMutation{}, []string{"result"}},
+}
+
+// RotateOptions — параметры задачи ротации
+type RotateOptions struct {
+	// NewDataKeys выводит из оборота действующие ключи данных: пользователи получат новые,
+	// и заметки перешифруются ими. Без флага перешифровываются только заметки в открытом виде.
+	NewDataKeys bool `json:"new_data_keys"`
+}
+
+// HandleRotateJob выполняет Rotate в фоновой задаче
+func (k *Keyring) HandleRotateJob(ctx context.Context, job *model.Job) error {
+	var opts RotateOptions
+	if len(job.Payload) > 0 {
+		if err := json.Unmarshal(job.Payload, &opts); err != nil {
+			return jobs.Permanent(err)
+		}
+	}
+	return k.Rotate(ctx, opts)
+}
+
+// Rotate приводит хранилище к текущим ключам:
+//  1. ключи данных, обёрнутые прежним мастер-ключом, переоборачиваются текущим
+//     (заметки при этом не трогаются);
+//  2. с NewDataKeys действующие ключи данных выводятся из оборота;
+//  3. значения в открытом виде или под выведенными ключами перешифровываются
+//     во всех таблицах encryptedColumns.
+//
+// Ротация идемпотентна: повторный запуск продолжает с того места, где прервался прошлый.
+// Выведенные ключи не удаляются — значения, записанные другими экземплярами сервиса
+// в пределах activeKeyTTL после ротации, ещё могут быть зашифрованы ими.
+func (k *Keyring) Rotate(ctx context.Context, opts RotateOptions) error {
+	rewrapped, err := k.rewrap(ctx)
+	if err != nil {
+		return err
+	}
+
+	if opts.NewDataKeys {
+		err := k.DB.WithContext(ctx).Model(&model.DataKey{}).
+			Where("retired_at IS NULL").
+			Update("retired_at", time.Now()).Error
+		if err != nil {
+			return err
+		}
+		k.forgetActive()
+	}
+
+	reencrypted, err := k.reencryptAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	logger.Log.WithFields(logger.Fields{
+		"rewrapped_keys":   rewrapped,
+		"reencrypted_rows": reencrypted,
+		"new_data_keys":    opts.NewDataKeys,
+	}).Info("Ротация ключей шифрования завершена")
+	return nil
+}
+
+// rewrap переоборачивает ключи данных текущим мастер-ключом
+func (k *Keyring) rewrap(ctx context.Context) (int, error) {
+	current := k.Provider.CurrentKeyID()
+	rewrapped := 0
+
+	var batch []model.DataKey
+	err := k.DB.WithContext(ctx).
+		Where("master_key_id <> ?", current).
+		FindInBatches(&batch, rotateBatch, func(tx *gorm.DB, _ int) error {
+			for _, dataKey := range batch {
+				key, err := k.Provider.Unwrap(ctx, dataKey.MasterKeyID, dataKey.WrappedKey)
+				if err != nil {
+					return err
+				}
+				masterKeyID, wrapped, err := k.Provider.Wrap(ctx, key)
+				if err != nil {
+					return err
+				}
+				err = k.DB.WithContext(ctx).Model(&dataKey).Updates(map[string]any{
+					"master_key_id": masterKeyID,
+					"wrapped_key":   wrapped,
+				}).Error
+				if err != nil {
+					return err
+				}
+				rewrapped++
+			}
+			return nil
+		}).Error
+	return rewrapped, err
+}
+
+// reencryptAll перешифровывает значения в открытом виде или под выведенными ключами
+// во всех таблицах encryptedColumns. Таблица, в которой не удалось перешифровать часть
+// строк, не останавливает остальные: ошибка возвращается в конце, и задача повторится.
+func (k *Keyring) reencryptAll(ctx context.Context) (int, error) {
+	var retiredIDs []uint
+	err := k.DB.WithContext(ctx).Model(&model.DataKey{}).
+		Where("retired_at IS NOT NULL").
+		Pluck("id", &retiredIDs).Error
+	if err != nil {
+		return 0, err
+	}
+	retired := make([]string, len(retiredIDs))
+	for i, id := range retiredIDs {
+		retired[i] = strconv.FormatUint(uint64(id), 10)
+	}
+
+	total, failed := 0, 0
+	for _, table := range encryptedColumns {
+		reencrypted, tableFailed, err := k.reencryptTable(ctx, table.model, table.columns, retired)
+		if err != nil {
+			return total, err
+		}
+		total += reencrypted
+		failed += tableFailed
+	}
+	if failed > 0 {
+		return total, fmt.Errorf("не удалось перешифровать строк: %d", failed)
+	}
+	return total, nil
+}
+
+// staleRow — строка, которую нужно перешифровать
+type staleRow struct {
+	ID     uint64
+	UserID uint
+}
+
+// reencryptTable перешифровывает строки одной таблицы. Значение или столбец в открытом
+// виде, или его ключ данных выведен из оборота.
+func (k *Keyring) reencryptTable(ctx context.Context, m any, columns, retired []string) (reencrypted, failed int, err error) {
+	var conds []string
+	var args []any
+	for _, column := range columns {
+		conds = append(conds, column+" NOT LIKE ?")
+		args = append(args, Prefix+"%")
+		if len(retired) > 0 {
+			// Третья часть значения enc:v1:<id>:<данные> — ID ключа данных
+			conds = append(conds, "split_part("+column+", ':', 3) IN ?")
+			args = append(args, retired)
+		}
+	}
+	stale := "(" + strings.Join(conds, " OR ") + ")"
+
+	var last uint64
+	for {
+		var rows []staleRow
+		err := k.DB.WithContext(ctx).Model(m).
+			Select("id", "user_id").
+			Where(stale, args...).
+			Where("id > ?", last).
+			Order("id").
+			Limit(rotateBatch).
+			Scan(&rows).Error
+		if err != nil || len(rows) == 0 {
+			return reencrypted, failed, err
+		}
+
+		for _, row := range rows {
+			last = row.ID
+			if err := k.reencryptRow(ctx, m, row, columns); err != nil {
+				if ctx.Err() != nil {
+					return reencrypted, failed, ctx.Err()
+				}
+				// Одна нечитаемая строка не должна останавливать ротацию для всех остальных
+				logger.Log.WithError(err).WithFields(logger.Fields{
+					"table": tableName(k.DB, m),
+					"id":    row.ID,
+				}).Error("Не удалось перешифровать строку")
+				failed++
+				continue
+			}
+			reencrypted++
+		}
+	}
+}
+
+// reencryptRow перезаписывает столбцы строки: сериализатор шифрует их действующим ключом
+// владельца. Содержимое не меняется, поэтому change_seq и updated_at остаются прежними.
+func (k *Keyring) reencryptRow(ctx context.Context, m any, row staleRow, columns []string) error {
+	// Ключ берётся до транзакции: без него в кеше сериализатор пошёл бы за ключом посреди
+	// UPDATE и занял второе соединение, пока первое держит блокировку строки
+	if _, _, err := k.activeKey(ctx, row.UserID); err != nil {
+		return err
+	}
+
+	return k.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		record := reflect.New(reflect.TypeOf(m).Elem()).Interface()
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(record, row.ID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return tx.Model(record).Select(columns).UpdateColumns(record).Error
+	})
+}
+
+func tableName(db *gorm.DB, m any) string {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(m); err != nil {
+		return ""
+	}
+	return stmt.Table
+}
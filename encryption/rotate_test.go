@@ -0,0 +1,223 @@
+package encryption
+
+import (
+	"context"
+	"encoding/json"
+	"notes-api/db/dbtest"
+	"notes-api/model"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// rotateFixture — по строке с открытым текстом в каждой таблице encryptedColumns
+type rotateFixture struct {
+	db     *gorm.DB
+	userID uint
+	text   string
+}
+
+func newRotateFixture(t *testing.T) *rotateFixture {
+	t.Helper()
+	db := dbtest.Open(t, &model.User{}, &model.DataKey{}, &model.Note{}, &model.NoteRevision{},
+		&model.NoteTodo{}, &model.NoteLink{}, &model.Notification{}, &model.NoteEvent{}, &model.WebhookDelivery{},
+		&model.SyncMutation{})
+	user := model.User{Email: "user@example.com"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	f := &rotateFixture{db: db, userID: user.ID, text: "секрет"}
+
+	// Записано до включения шифрования
+	data, _ := json.Marshal(map[string]string{"title": f.text})
+	for _, record := range []any{
+		&model.Note{UserID: f.userID, Title: f.text, Content: f.text},
+		&model.NoteRevision{UserID: f.userID, Title: f.text, Content: f.text},
+		&model.NoteTodo{UserID: f.userID, Text: f.text},
+		&model.NoteLink{UserID: f.userID, TargetTitle: f.text},
+		&model.Notification{UserID: f.userID, Title: f.text, Body: f.text},
+		&model.NoteEvent{UserID: f.userID, Data: data},
+		&model.WebhookDelivery{UserID: f.userID, Payload: data},
+		&model.SyncMutation{UserID: f.userID, MutationID: "m1", Result: data},
+	} {
+		if err := db.Create(record).Error; err != nil {
+			t.Fatalf("создать %T: %v", record, err)
+		}
+	}
+	return f
+}
+
+// raw возвращает зашифрованные столбцы первой строки каждой таблицы в том виде, в котором они лежат в базе
+func (f *rotateFixture) raw(t *testing.T) map[string]string {
+	t.Helper()
+	values := make(map[string]string)
+	for _, table := range encryptedColumns {
+		name := tableName(f.db, table.model)
+		for _, column := range table.columns {
+			var value string
+			if err := f.db.Table(name).Select(column).Order("id").Limit(1).Scan(&value).Error; err != nil {
+				t.Fatalf("прочитать %s.%s: %v", name, column, err)
+			}
+			values[name+"."+column] = value
+		}
+	}
+	return values
+}
+
+// checkEncrypted проверяет, что все значения зашифрованы ключами keyIDs и читаются обратно
+func (f *rotateFixture) checkEncrypted(t *testing.T, raw map[string]string) map[uint]bool {
+	t.Helper()
+	keyIDs := make(map[uint]bool)
+	for column, value := range raw {
+		id, _, ok, err := parse(value)
+		if !ok || err != nil || strings.Contains(value, f.text) {
+			t.Errorf("%s не зашифрован: %q", column, value)
+			continue
+		}
+		keyIDs[id] = true
+	}
+
+	var note model.Note
+	var event model.NoteEvent
+	var delivery model.WebhookDelivery
+	var mutation model.SyncMutation
+	if err := f.db.First(&note).Error; err != nil || note.Title != f.text || note.Content != f.text {
+		t.Errorf("заметка читается как %q / %q: %v", note.Title, note.Content, err)
+	}
+	if err := f.db.First(&event).Error; err != nil || !strings.Contains(string(event.Data), f.text) {
+		t.Errorf("событие читается как %s: %v", event.Data, err)
+	}
+	if err := f.db.First(&delivery).Error; err != nil || !json.Valid(delivery.Payload) {
+		t.Errorf("доставка читается как %s: %v", delivery.Payload, err)
+	}
+	if err := f.db.First(&mutation).Error; err != nil || !strings.Contains(string(mutation.Result), f.text) {
+		t.Errorf("результат синхронизации читается как %s: %v", mutation.Result, err)
+	}
+	return keyIDs
+}
+
+// warm разворачивает все ключи данных заранее: у тестовой базы одно соединение, а сериализатор
+// идёт за ключом посреди чтения строки
+func warm(t *testing.T, k *Keyring) {
+	t.Helper()
+	var ids []uint
+	if err := k.DB.Model(&model.DataKey{}).Pluck("id", &ids).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range ids {
+		if _, err := k.key(context.Background(), id); err != nil {
+			t.Fatalf("развернуть ключ данных %d: %v", id, err)
+		}
+	}
+}
+
+func masterKeyIDs(t *testing.T, db *gorm.DB) []string {
+	t.Helper()
+	var ids []string
+	if err := db.Model(&model.DataKey{}).Distinct().Pluck("master_key_id", &ids).Error; err != nil {
+		t.Fatal(err)
+	}
+	return ids
+}
+
+func TestRotate(t *testing.T) {
+	ctx := context.Background()
+	f := newRotateFixture(t)
+	k1, k2 := masterKey(t, "k1"), masterKey(t, "k2")
+
+	// Включение шифрования: открытый текст во всех таблицах шифруется
+	k := newKeyring(t, f.db, k1)
+	enable(t, k, f.userID)
+	if err := k.Rotate(ctx, RotateOptions{}); err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	encrypted := f.raw(t)
+	if ids := f.checkEncrypted(t, encrypted); len(ids) != 1 {
+		t.Fatalf("значения зашифрованы ключами %v, ожидался один", ids)
+	}
+
+	// Повторный запуск ничего не меняет
+	if err := k.Rotate(ctx, RotateOptions{}); err != nil {
+		t.Fatalf("повторный Rotate: %v", err)
+	}
+	assertRawEqual(t, encrypted, f.raw(t))
+
+	// Новый мастер-ключ: ключи данных переоборачиваются, значения не трогаются
+	k = newKeyring(t, f.db, k2, k1)
+	warm(t, k)
+	enable(t, k, f.userID)
+	if err := k.Rotate(ctx, RotateOptions{}); err != nil {
+		t.Fatalf("Rotate с новым мастер-ключом: %v", err)
+	}
+	if ids := masterKeyIDs(t, f.db); len(ids) != 1 || ids[0] != "k2" {
+		t.Errorf("ключи данных обёрнуты мастер-ключами %v, ожидался только k2", ids)
+	}
+	assertRawEqual(t, encrypted, f.raw(t))
+
+	// Прежний мастер-ключ больше не нужен
+	k = newKeyring(t, f.db, k2)
+	warm(t, k)
+	enable(t, k, f.userID)
+	f.checkEncrypted(t, f.raw(t))
+
+	// Новые ключи данных: значения перешифровываются, выведенные ключи остаются
+	oldIDs := f.checkEncrypted(t, encrypted)
+	if err := k.Rotate(ctx, RotateOptions{NewDataKeys: true}); err != nil {
+		t.Fatalf("Rotate с новыми ключами данных: %v", err)
+	}
+	reencrypted := f.raw(t)
+	newIDs := f.checkEncrypted(t, reencrypted)
+	for id := range newIDs {
+		if oldIDs[id] {
+			t.Errorf("значения остались под выведенным ключом %d", id)
+		}
+	}
+	var retired int64
+	f.db.Model(&model.DataKey{}).Where("retired_at IS NOT NULL").Count(&retired)
+	if retired != int64(len(oldIDs)) {
+		t.Errorf("выведено ключей: %d, ожидалось %d", retired, len(oldIDs))
+	}
+
+	// И снова повтор ничего не меняет
+	if err := k.Rotate(ctx, RotateOptions{}); err != nil {
+		t.Fatalf("Rotate после смены ключей данных: %v", err)
+	}
+	assertRawEqual(t, reencrypted, f.raw(t))
+}
+
+// Нечитаемая строка не останавливает ротацию остальных
+func TestRotateSkipsUnreadableRows(t *testing.T) {
+	ctx := context.Background()
+	f := newRotateFixture(t)
+	broken := model.Note{UserID: f.userID}
+	if err := f.db.Create(&broken).Error; err != nil {
+		t.Fatal(err)
+	}
+	f.db.Table("notes").Where("id = ?", broken.ID).Update("title", Prefix+"1:испорчено")
+
+	k := newKeyring(t, f.db, masterKey(t, "k1"))
+	enable(t, k, f.userID)
+	if err := k.Rotate(ctx, RotateOptions{}); err == nil {
+		t.Error("Rotate не сообщил о нечитаемой строке")
+	}
+
+	raw := f.raw(t)
+	if title := raw["notes.title"]; !strings.HasPrefix(title, Prefix) || strings.Contains(title, "испорчено") {
+		t.Errorf("заметка перед испорченной не перешифрована: %q", title)
+	}
+	for column, value := range raw {
+		if !strings.HasPrefix(value, Prefix) {
+			t.Errorf("%s не перешифрован: %q", column, value)
+		}
+	}
+}
+
+func assertRawEqual(t *testing.T, want, got map[string]string) {
+	t.Helper()
+	for column, value := range want {
+		if got[column] != value {
+			t.Errorf("%s изменился: %q → %q", column, value, got[column])
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package encryption
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync/atomic"
+
+	"gorm.io/gorm/schema"
+)
+
+// SerializerName — имя сериализатора для тега gorm:"serializer:encrypted"
+const SerializerName = "encrypted"
+
+var errNoKeyring = errors.New("значение зашифровано, но шифрование не настроено")
+
+// keyring — ключи, которыми работает сериализатор. GORM запоминает сериализатор при разборе
+// схемы модели, поэтому он регистрируется при импорте пакета, а ключи подключаются позже.
+var keyring atomic.Pointer[Keyring]
+
+func init() {
+	schema.RegisterSerializer(SerializerName, Serializer{})
+}
+
+// Enable включает шифрование полей с тегом serializer:encrypted
+func Enable(k *Keyring) {
+	keyring.Store(k)
+}
+
+// Serializer шифрует строковое поле или поле []byte (например json.RawMessage) ключом данных
+// владельца записи (поле UserID модели) и расшифровывает при чтении. Пока шифрование не включено, значения пишутся как есть;
+// только текст, похожий на шифротекст, помечается префиксом enc:plain:.
+// Владелец нужен только при записи: ID ключа хранится в самом значении, поэтому
+// читать поле можно и без UserID, например в выборке Select("id", "title").
+type Serializer struct{}
+
+func (Serializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue any) error {
+	var value string
+	switch v := dbValue.(type) {
+	case nil:
+	case string:
+		value = v
+	case []byte:
+		value = string(v)
+	default:
+		return fmt.Errorf("поле %s: неподдерживаемый тип %T", field.Name, dbValue)
+	}
+
+	value, err := decode(ctx, value)
+	if err != nil {
+		return fmt.Errorf("поле %s: %w", field.Name, err)
+	}
+
+	target := field.ReflectValueOf(ctx, dst)
+	switch {
+	case target.Kind() == reflect.String:
+		target.SetString(value)
+	case dbValue == nil:
+		target.SetZero()
+	default:
+		target.SetBytes([]byte(value))
+	}
+	return nil
+}
+
+func (Serializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue any) (any, error) {
+	v := reflect.ValueOf(fieldValue)
+	var value string
+	switch {
+	case v.Kind() == reflect.String:
+		value = v.String()
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+		if v.IsNil() {
+			return nil, nil
+		}
+		value = string(v.Bytes())
+	default:
+		return nil, fmt.Errorf("поле %s: шифруются только строки и []byte", field.Name)
+	}
+
+	k := keyring.Load()
+	if k == nil {
+		return escape(value), nil
+	}
+
+	owner := field.Schema.LookUpField("UserID")
+	if owner == nil {
+		return nil, fmt.Errorf("поле %s: %w", field.Name, errNoOwner)
+	}
+	userID, _ := owner.ValueOf(ctx, dst)
+	id, ok := userID.(uint)
+	if !ok {
+		return nil, fmt.Errorf("поле %s: %w", field.Name, errNoOwner)
+	}
+	return k.Encrypt(ctx, id, value)
+}
+
+// decode возвращает открытый текст значения из базы
+func decode(ctx context.Context, value string) (string, error) {
+	if plaintext, ok := strings.CutPrefix(value, plainPrefix); ok {
+		return plaintext, nil
+	}
+	if _, _, encrypted, err := parse(value); err != nil || !encrypted {
+		return value, err
+	}
+	k := keyring.Load()
+	if k == nil {
+		return "", errNoKeyring
+	}
+	return k.Decrypt(ctx, value)
+}
@@ -0,0 +1,139 @@
+package encryption
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"notes-api/db/dbtest"
+	"notes-api/model"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func masterKey(t *testing.T, id string) string {
+	t.Helper()
+	key := make([]byte, masterKeySize)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	return id + ":" + base64.StdEncoding.EncodeToString(key)
+}
+
+// newKeyring открывает тестовую базу и возвращает связку ключей с мастер-ключами keys
+// (первый — текущий). Шифрование сериализатора не включается.
+func newKeyring(t *testing.T, db *gorm.DB, keys ...string) *Keyring {
+	t.Helper()
+	provider, err := NewEnvKeyProvider(strings.Join(keys, ","))
+	if err != nil {
+		t.Fatalf("NewEnvKeyProvider: %v", err)
+	}
+	return NewKeyring(db, provider)
+}
+
+// enable включает шифрование сериализатора до конца теста. Ключ пользователя создаётся
+// заранее: у тестовой базы одно соединение, а сериализатор ходит за ключом посреди запроса.
+func enable(t *testing.T, k *Keyring, userID uint) {
+	t.Helper()
+	if _, err := k.Encrypt(context.Background(), userID, ""); err != nil {
+		t.Fatalf("создать ключ данных: %v", err)
+	}
+	Enable(k)
+	t.Cleanup(func() { Enable(nil) })
+}
+
+func openDB(t *testing.T) (*gorm.DB, uint) {
+	t.Helper()
+	db := dbtest.Open(t, &model.User{}, &model.Note{}, &model.DataKey{})
+	user := model.User{Email: "user@example.com"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("создать пользователя: %v", err)
+	}
+	return db, user.ID
+}
+
+func rawColumn(t *testing.T, db *gorm.DB, id uint, column string) string {
+	t.Helper()
+	var value string
+	if err := db.Table("notes").Where("id = ?", id).Select(column).Scan(&value).Error; err != nil {
+		t.Fatalf("прочитать %s: %v", column, err)
+	}
+	return value
+}
+
+// Текст, похожий на шифротекст, должен читаться обратно как есть: раньше он принимался
+// за шифротекст и заметка не читалась
+func TestSerializerEscapesReservedPrefix(t *testing.T) {
+	db, userID := openDB(t)
+	texts := []string{
+		Prefix + "1:AAAA",
+		Prefix + "не шифротекст",
+		plainPrefix + "уже с пометкой",
+		"enc:v2:будущая версия",
+		"обычный текст",
+		"",
+	}
+
+	check := func(t *testing.T) {
+		for _, text := range texts {
+			note := model.Note{UserID: userID, Title: text, Content: text}
+			if err := db.Create(&note).Error; err != nil {
+				t.Fatalf("Create(%q): %v", text, err)
+			}
+			var stored model.Note
+			if err := db.First(&stored, note.ID).Error; err != nil {
+				t.Fatalf("First(%q): %v", text, err)
+			}
+			if stored.Title != text || stored.Content != text {
+				t.Errorf("записано %q, прочитано %q / %q", text, stored.Title, stored.Content)
+			}
+		}
+	}
+
+	t.Run("без шифрования", func(t *testing.T) {
+		check(t)
+		escaped := model.Note{UserID: userID, Title: Prefix + "1:AAAA"}
+		if err := db.Create(&escaped).Error; err != nil {
+			t.Fatal(err)
+		}
+		if raw := rawColumn(t, db, escaped.ID, "title"); raw != plainPrefix+Prefix+"1:AAAA" {
+			t.Errorf("в базе %q, ожидался текст с пометкой %s", raw, plainPrefix)
+		}
+		if raw := rawColumn(t, db, escaped.ID, "content"); raw != "" {
+			t.Errorf("пустой текст записан как %q", raw)
+		}
+	})
+	t.Run("с шифрованием", func(t *testing.T) {
+		enable(t, newKeyring(t, db, masterKey(t, "k1")), userID)
+		check(t)
+	})
+}
+
+func TestSerializerCorrupted(t *testing.T) {
+	db, userID := openDB(t)
+	enable(t, newKeyring(t, db, masterKey(t, "k1")), userID)
+
+	note := model.Note{UserID: userID, Title: "заголовок", Content: "текст"}
+	if err := db.Create(&note).Error; err != nil {
+		t.Fatal(err)
+	}
+	raw := rawColumn(t, db, note.ID, "content")
+	if !strings.HasPrefix(raw, Prefix) || strings.Contains(raw, "текст") {
+		t.Fatalf("текст записан в открытом виде: %q", raw)
+	}
+
+	// Испорченный шифротекст — ошибка чтения, а не мусор в ответе
+	db.Table("notes").Where("id = ?", note.ID).Update("content", raw[:len(raw)-8]+"AAAAAAA=")
+	err := db.First(&model.Note{}, note.ID).Error
+	if !errors.Is(err, errCorrupted) {
+		t.Errorf("чтение испорченного значения: %v", err)
+	}
+
+	// Без ключей зашифрованное значение не читается
+	Enable(nil)
+	if _, err := decode(context.Background(), raw); !errors.Is(err, errNoKeyring) {
+		t.Errorf("чтение без ключей: %v", err)
+	}
+}
@@ -0,0 +1,77 @@
+package events
+
+import (
+	"notes-api/model"
+	"time"
+)
+
+const (
+	NoteCreated = "note.created"
+	NoteUpdated = "note.updated"
+	NoteDeleted = "note.deleted"
+
+	// NoteReminder — сработало напоминание о заметке; рассылается только на вебхуки
+	NoteReminder = "note.reminder"
+
+	// StreamReset — пропущенные события не переданы целиком: клиенту нужно заново загрузить
+	// заметки (GET /notes или /sync) и продолжить поток с ID этого события
+	StreamReset = "stream.reset"
+)
+
+// NoteTypes — все типы событий заметки, на которые можно подписать вебхук
+var NoteTypes = []string{NoteCreated, NoteUpdated, NoteDeleted, NoteReminder}
+
+// Event — событие об изменении заметки
+type Event struct {
+	// ID — порядковый номер события в журнале, заполняется при сохранении
+	ID         uint64    `json:"id,omitempty"`
+	Type       string    `json:"type"`
+	UserID     uint      `json:"user_id"`
+	Note       NoteData  `json:"note"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// NoteData — заметка в том виде, в котором она попадает в события
+type NoteData struct {
+	ID      uint   `json:"id"`
+	Title   string `json:"title"`
+	Content string `json:"content"`
+}
+
+func NewNoteEvent(eventType string, note model.Note) Event {
+	return Event{
+		Type:   eventType,
+		UserID: note.UserID,
+		Note: NoteData{
+			ID:      note.ID,
+			Title:   note.Title,
+			Content: note.Content,
+		},
+		OccurredAt: time.Now().UTC(),
+	}
+}
+
+// Publisher получает события об изменениях заметок.
+// Publish не должен надолго блокировать вызывающий код.
+type Publisher interface {
+	Publish(e Event)
+}
+
+// Publishers рассылает событие всем подписчикам по очереди
+type Publishers []Publisher
+
+func (p Publishers) Publish(e Event) {
+	for _, pub := range p {
+		pub.Publish(e)
+	}
+}
+
+// IsNoteType сообщает, является ли t известным типом события заметки
+func IsNoteType(t string) bool {
+	for _, known := range NoteTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,114 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"notes-api/logger"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"gorm.io/gorm"
+)
+
+const (
+	notifyChannel = "note_events"
+
+	listenRetryMin = time.Second
+	listenRetryMax = 30 * time.Second
+)
+
+type notification struct {
+	ID     uint64 `json:"id"`
+	UserID uint   `json:"user_id"`
+}
+
+// PostgresPubSub рассылает события между экземплярами сервиса через LISTEN/NOTIFY.
+// В уведомлении передаётся только ID события, само событие читается из журнала:
+// так не упираемся в ограничение Postgres на размер payload.
+type PostgresPubSub struct {
+	DB    *gorm.DB
+	Store *Store
+	DSN   string
+
+	hub *hub
+}
+
+func NewPostgresPubSub(db *gorm.DB, store *Store, dsn string) *PostgresPubSub {
+	return &PostgresPubSub{DB: db, Store: store, DSN: dsn, hub: newHub()}
+}
+
+func (p *PostgresPubSub) Publish(ctx context.Context, e Event) error {
+	payload, err := json.Marshal(notification{ID: e.ID, UserID: e.UserID})
+	if err != nil {
+		return err
+	}
+	return p.DB.WithContext(ctx).Exec("SELECT pg_notify(?, ?)", notifyChannel, string(payload)).Error
+}
+
+func (p *PostgresPubSub) Subscribe(userID uint) *Subscription {
+	return p.hub.subscribe(userID)
+}
+
+// Run слушает канал уведомлений и переподключается при обрыве соединения.
+// Блокируется, пока не будет отменён ctx.
+func (p *PostgresPubSub) Run(ctx context.Context) {
+	delay := listenRetryMin
+	for {
+		err := p.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		logger.Log.WithError(err).WithField("retry_in", delay).Warn("Потеряно соединение LISTEN, переподключение")
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, listenRetryMax)
+	}
+}
+
+func (p *PostgresPubSub) listen(ctx context.Context) error {
+	conn, err := pgx.Connect(ctx, p.DSN)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+notifyChannel); err != nil {
+		return err
+	}
+	logger.Log.Info("Подписка на уведомления о событиях заметок активна")
+
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		p.handle(ctx, n.Payload)
+	}
+}
+
+func (p *PostgresPubSub) handle(ctx context.Context, payload string) {
+	var n notification
+	if err := json.Unmarshal([]byte(payload), &n); err != nil {
+		logger.Log.WithError(err).Warn("Некорректное уведомление о событии")
+		return
+	}
+
+	// Событие нужно загружать, только если на этом экземпляре есть подписчики пользователя
+	p.hub.mu.RLock()
+	interested := len(p.hub.subs[n.UserID]) > 0
+	p.hub.mu.RUnlock()
+	if !interested {
+		return
+	}
+
+	e, err := p.Store.Get(ctx, n.ID)
+	if err != nil {
+		logger.Log.WithError(err).WithField("event_id", n.ID).Error("Не удалось загрузить событие")
+		return
+	}
+	p.hub.dispatch(e)
+}
@@ -0,0 +1,103 @@
+package events
+
+import (
+	"context"
+	"sync"
+)
+
+// subscriberBuffer — сколько событий может накопиться у медленного подписчика,
+// прежде чем он будет отключён и должен будет переподключиться с Last-Event-ID
+const subscriberBuffer = 64
+
+// PubSub доставляет сохранённые события подписчикам. Локальная реализация
+// работает в пределах процесса, Postgres-реализация — между экземплярами сервиса.
+type PubSub interface {
+	Publish(ctx context.Context, e Event) error
+	Subscribe(userID uint) *Subscription
+}
+
+// Subscription — подписка на события одного пользователя.
+// Канал C закрывается при Close или если подписчик не успевает читать.
+type Subscription struct {
+	C <-chan Event
+
+	c      chan Event
+	userID uint
+	hub    *hub
+	once   sync.Once
+}
+
+func (s *Subscription) Close() {
+	s.hub.remove(s)
+}
+
+// hub раздаёт события локальным подписчикам
+type hub struct {
+	mu   sync.RWMutex
+	subs map[uint]map[*Subscription]struct{}
+}
+
+func newHub() *hub {
+	return &hub{subs: make(map[uint]map[*Subscription]struct{})}
+}
+
+func (h *hub) subscribe(userID uint) *Subscription {
+	c := make(chan Event, subscriberBuffer)
+	sub := &Subscription{C: c, c: c, userID: userID, hub: h}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.subs[userID] == nil {
+		h.subs[userID] = make(map[*Subscription]struct{})
+	}
+	h.subs[userID][sub] = struct{}{}
+	return sub
+}
+
+func (h *hub) remove(sub *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if subs, ok := h.subs[sub.userID]; ok {
+		delete(subs, sub)
+		if len(subs) == 0 {
+			delete(h.subs, sub.userID)
+		}
+	}
+	sub.once.Do(func() { close(sub.c) })
+}
+
+func (h *hub) dispatch(e Event) {
+	h.mu.RLock()
+	var slow []*Subscription
+	for sub := range h.subs[e.UserID] {
+		select {
+		case sub.c <- e:
+		default:
+			slow = append(slow, sub)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, sub := range slow {
+		h.remove(sub)
+	}
+}
+
+// LocalPubSub — PubSub в пределах одного процесса
+type LocalPubSub struct {
+	hub *hub
+}
+
+func NewLocalPubSub() *LocalPubSub {
+	return &LocalPubSub{hub: newHub()}
+}
+
+func (p *LocalPubSub) Publish(_ context.Context, e Event) error {
+	p.hub.dispatch(e)
+	return nil
+}
+
+func (p *LocalPubSub) Subscribe(userID uint) *Subscription {
+	return p.hub.subscribe(userID)
+}
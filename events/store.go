@@ -0,0 +1,134 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"notes-api/logger"
+	"notes-api/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	CleanupJobType = "events.cleanup"
+
+	// retention — сколько хранить журнал событий для догоняющих клиентов
+	retention = 7 * 24 * time.Hour
+	// maxBacklog — сколько пропущенных событий максимум отдаётся при переподключении
+	maxBacklog = 1000
+)
+
+// ErrBacklogGap — пропущенные события не отдать целиком: их больше maxBacklog
+// или часть уже удалена из журнала. Клиенту нужно заново загрузить заметки.
+var ErrBacklogGap = errors.New("пропущенные события недоступны целиком")
+
+// Store — журнал событий заметок в Postgres
+type Store struct {
+	DB *gorm.DB
+}
+
+func NewStore(db *gorm.DB) *Store {
+	return &Store{DB: db}
+}
+
+// Append сохраняет событие и заполняет его ID
+func (s *Store) Append(ctx context.Context, e *Event) error {
+	data, err := json.Marshal(e.Note)
+	if err != nil {
+		return err
+	}
+
+	record := model.NoteEvent{
+		UserID:    e.UserID,
+		Type:      e.Type,
+		NoteID:    e.Note.ID,
+		Data:      data,
+		CreatedAt: e.OccurredAt,
+	}
+	if err := s.DB.WithContext(ctx).Create(&record).Error; err != nil {
+		return err
+	}
+
+	e.ID = record.ID
+	return nil
+}
+
+func (s *Store) Get(ctx context.Context, id uint64) (Event, error) {
+	var record model.NoteEvent
+	if err := s.DB.WithContext(ctx).First(&record, id).Error; err != nil {
+		return Event{}, err
+	}
+	return fromRecord(record)
+}
+
+// Since возвращает события пользователя с ID больше afterID в порядке возрастания.
+// Если вернуть их все нельзя, возвращает ErrBacklogGap.
+func (s *Store) Since(ctx context.Context, userID uint, afterID uint64) ([]Event, error) {
+	// ID событий общие для всех пользователей, поэтому по самому старому событию журнала
+	// видно, не удалила ли очистка события, которых клиент ещё не получил
+	var oldest uint64
+	if err := s.DB.WithContext(ctx).Model(&model.NoteEvent{}).Select("COALESCE(MIN(id), 0)").Scan(&oldest).Error; err != nil {
+		return nil, err
+	}
+	if oldest > afterID+1 {
+		return nil, ErrBacklogGap
+	}
+
+	var records []model.NoteEvent
+	err := s.DB.WithContext(ctx).
+		Where("user_id = ? AND id > ?", userID, afterID).
+		Order("id").
+		Limit(maxBacklog + 1).
+		Find(&records).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(records) > maxBacklog {
+		return nil, ErrBacklogGap
+	}
+
+	result := make([]Event, 0, len(records))
+	for _, record := range records {
+		e, err := fromRecord(record)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, e)
+	}
+	return result, nil
+}
+
+// LastID возвращает ID последнего события пользователя или 0, если событий нет
+func (s *Store) LastID(ctx context.Context, userID uint) (uint64, error) {
+	var id uint64
+	err := s.DB.WithContext(ctx).Model(&model.NoteEvent{}).
+		Where("user_id = ?", userID).
+		Select("COALESCE(MAX(id), 0)").
+		Scan(&id).Error
+	return id, err
+}
+
+// HandleCleanup удаляет из журнала события старше срока хранения
+func (s *Store) HandleCleanup(ctx context.Context, job *model.Job) error {
+	res := s.DB.WithContext(ctx).Where("created_at < ?", time.Now().Add(-retention)).Delete(&model.NoteEvent{})
+	if res.Error != nil {
+		return res.Error
+	}
+	logger.Log.WithField("count", res.RowsAffected).Info("Старые события заметок удалены")
+	return nil
+}
+
+func fromRecord(record model.NoteEvent) (Event, error) {
+	e := Event{
+		ID:         record.ID,
+		Type:       record.Type,
+		UserID:     record.UserID,
+		OccurredAt: record.CreatedAt,
+	}
+	if err := json.Unmarshal(record.Data, &e.Note); err != nil {
+		return Event{}, err
+	}
+	return e, nil
+}
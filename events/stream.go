@@ -0,0 +1,61 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"notes-api/logger"
+	"time"
+)
+
+// Stream сохраняет события в журнал и рассылает их подписчикам
+type Stream struct {
+	Store  *Store
+	PubSub PubSub
+}
+
+func NewStream(store *Store, ps PubSub) *Stream {
+	return &Stream{Store: store, PubSub: ps}
+}
+
+// Publish реализует Publisher
+func (s *Stream) Publish(e Event) {
+	ctx := context.Background()
+	log := logger.Log.WithFields(logger.Fields{
+		"user_id": e.UserID,
+		"event":   e.Type,
+	})
+
+	if err := s.Store.Append(ctx, &e); err != nil {
+		log.WithError(err).Error("Не удалось сохранить событие заметки")
+		return
+	}
+	if err := s.PubSub.Publish(ctx, e); err != nil {
+		log.WithError(err).Error("Не удалось разослать событие заметки")
+	}
+}
+
+// Subscribe подписывает на события пользователя и возвращает пропущенные события
+// после lastEventID. Подписка оформляется до чтения журнала, поэтому событие
+// может прийти и в backlog, и в канал — такие дубли вызывающий код отбрасывает по ID.
+//
+// Если пропущенные события не отдать целиком, вместо них возвращается одно событие
+// StreamReset с ID последнего события пользователя: события из канала с ID не больше
+// него вызывающий код тоже отбрасывает, клиент получит их при повторной загрузке.
+func (s *Stream) Subscribe(ctx context.Context, userID uint, lastEventID uint64) ([]Event, *Subscription, error) {
+	sub := s.PubSub.Subscribe(userID)
+	if lastEventID == 0 {
+		return nil, sub, nil
+	}
+
+	backlog, err := s.Store.Since(ctx, userID, lastEventID)
+	if errors.Is(err, ErrBacklogGap) {
+		var lastID uint64
+		lastID, err = s.Store.LastID(ctx, userID)
+		backlog = []Event{{ID: lastID, Type: StreamReset, UserID: userID, OccurredAt: time.Now().UTC()}}
+	}
+	if err != nil {
+		sub.Close()
+		return nil, nil, err
+	}
+	return backlog, sub, nil
+}
@@ -0,0 +1,98 @@
+package events
+
+import (
+	"context"
+	"notes-api/db/dbtest"
+	_ "notes-api/encryption"
+	"notes-api/model"
+	"testing"
+	"time"
+)
+
+func newTestStream(t *testing.T) *Stream {
+	db := dbtest.Open(t, &model.NoteEvent{})
+	return NewStream(NewStore(db), NewLocalPubSub())
+}
+
+func publishN(s *Stream, userID uint, n int) {
+	for i := 0; i < n; i++ {
+		s.Publish(NewNoteEvent(NoteUpdated, model.Note{ID: uint(i + 1), UserID: userID}))
+	}
+}
+
+func TestSubscribeBacklog(t *testing.T) {
+	s := newTestStream(t)
+	publishN(s, 1, 5)
+	publishN(s, 2, 3)
+
+	backlog, sub, err := s.Subscribe(context.Background(), 1, 2)
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	defer sub.Close()
+
+	if len(backlog) != 3 {
+		t.Fatalf("получено %d событий, ожидалось 3", len(backlog))
+	}
+	for i, e := range backlog {
+		if e.UserID != 1 || e.Type != NoteUpdated || e.ID != uint64(i+3) {
+			t.Errorf("событие %d: %+v", i, e)
+		}
+	}
+}
+
+func TestSubscribeResetWhenBacklogTruncated(t *testing.T) {
+	s := newTestStream(t)
+	publishN(s, 1, maxBacklog+2)
+
+	backlog, sub, err := s.Subscribe(context.Background(), 1, 1)
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	defer sub.Close()
+
+	if len(backlog) != 1 || backlog[0].Type != StreamReset {
+		t.Fatalf("ожидалось одно событие %s, получено %d событий", StreamReset, len(backlog))
+	}
+	if backlog[0].ID != maxBacklog+2 {
+		t.Errorf("ID события сброса = %d, ожидался ID последнего события %d", backlog[0].ID, maxBacklog+2)
+	}
+
+	// Ровно maxBacklog пропущенных событий отдаются без сброса
+	backlog, sub2, err := s.Subscribe(context.Background(), 1, 2)
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	defer sub2.Close()
+	if len(backlog) != maxBacklog || backlog[0].Type == StreamReset {
+		t.Errorf("получено %d событий, ожидалось %d без сброса", len(backlog), maxBacklog)
+	}
+}
+
+func TestSubscribeResetWhenEventsCleanedUp(t *testing.T) {
+	s := newTestStream(t)
+	publishN(s, 1, 4)
+	s.Store.DB.Model(&model.NoteEvent{}).Where("id <= ?", 2).Update("created_at", time.Now().Add(-2*retention))
+	if err := s.Store.HandleCleanup(context.Background(), &model.Job{}); err != nil {
+		t.Fatalf("HandleCleanup: %v", err)
+	}
+
+	backlog, sub, err := s.Subscribe(context.Background(), 1, 1)
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	defer sub.Close()
+	if len(backlog) != 1 || backlog[0].Type != StreamReset || backlog[0].ID != 4 {
+		t.Fatalf("ожидался %s с ID 4, получено %+v", StreamReset, backlog)
+	}
+
+	// Клиент, получивший всё до удалённых событий, догоняет без сброса
+	backlog, sub2, err := s.Subscribe(context.Background(), 1, 2)
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	defer sub2.Close()
+	if len(backlog) != 2 || backlog[0].ID != 3 {
+		t.Errorf("ожидались события 3 и 4, получено %+v", backlog)
+	}
+}
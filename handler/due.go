@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"notes-api/logger"
+	"notes-api/middleware"
+	"notes-api/problem"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// DueInput — новый срок заметки
+type DueInput struct {
+	// DueAt — срок в формате RFC 3339; null снимает срок
+	DueAt *time.Time `json:"due_at"`
+}
+
+// SetDue godoc
+// @Summary Задать срок заметки
+// @Description Обычное обновление заметки срок не меняет, он задаётся отдельно
+// @Tags notes
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "ID заметки"
+// @Param input body handler.DueInput true "Срок заметки"
+// @Success 200 {object} model.Note "Обновлённая заметка"
+// @Failure 400 {object} problem.Problem "Неверный запрос или ID"
+// @Failure 403 {object} problem.Problem "Доступ запрещен"
+// @Failure 404 {object} problem.Problem "Заметка не найдена"
+// @Router /notes/{id}/due [put]
+func (h *NoteHandler) SetDue(w http.ResponseWriter, r *http.Request) {
+	rawUserID := r.Context().Value(middleware.UserIDKey)
+	userID, ok := rawUserID.(uint)
+	if !ok {
+		logger.FromContext(r.Context()).Warn("Пользователь не аутентифицирован")
+		problem.Write(w, r, problem.Unauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		logger.FromContext(r.Context()).WithError(err).Warn("Неверный ID")
+		problem.Write(w, r, problem.InvalidID)
+		return
+	}
+
+	note, err := h.Store.WithContext(r.Context()).GetNoteByID(id)
+	if err != nil {
+		logger.FromContext(r.Context()).WithError(err).Warn("Заметка не найдена")
+		problem.Write(w, r, problem.NoteNotFound)
+		return
+	}
+
+	if note.UserID != userID {
+		logger.FromContext(r.Context()).Warn("Доступ запрещён")
+		problem.Write(w, r, problem.Forbidden)
+		return
+	}
+
+	var input DueInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		logger.FromContext(r.Context()).WithError(err).Warn("Неверный запрос")
+		problem.Write(w, r, problem.BadRequest)
+		return
+	}
+
+	updated, err := h.Store.WithContext(r.Context()).SetDueAt(id, input.DueAt)
+	if err != nil {
+		logger.FromContext(r.Context()).WithError(err).WithField("id", id).Warn("Ошибка при изменении срока")
+		problem.Write(w, r, problem.Internal)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+
+	logger.FromContext(r.Context()).WithFields(logger.Fields{
+		"user_id": userID,
+		"note_id": id,
+	}).Info("Срок заметки изменён")
+}
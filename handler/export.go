@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"notes-api/logger"
+	"notes-api/middleware"
+	"notes-api/problem"
+	"notes-api/service"
+)
+
+// Export godoc
+// @Summary Выгрузить все заметки текущего пользователя
+// @Description JSON-документ целиком или ZIP-архив с файлом на каждую заметку (Markdown с YAML front-matter — id, title, pinned, archived, starred, due_at — или HTML)
+// @Tags notes
+// @Security ApiKeyAuth
+// @Produce json
+// @Produce application/zip
+// @Param format query string false "Формат выгрузки" Enums(json, markdown, html)
+// @Success 200 {file} file "Выгрузка заметок"
+// @Failure 400 {object} problem.Problem "Неподдерживаемый формат"
+// @Failure 401 {object} problem.Problem "Пользователь не аутентифицирован"
+// @Router /notes/export [get]
+func (h *NoteHandler) Export(w http.ResponseWriter, r *http.Request) {
+	rawUserID := r.Context().Value(middleware.UserIDKey)
+	userID, ok := rawUserID.(uint)
+	if !ok {
+		logger.FromContext(r.Context()).Warn("Пользователь не аутентифицирован")
+		problem.Write(w, r, problem.Unauthorized)
+		return
+	}
+
+	format, err := service.ParseExportFormat(r.URL.Query().Get("format"))
+	if err != nil {
+		logger.FromContext(r.Context()).WithError(err).Warn("Неподдерживаемый формат выгрузки")
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, format.FileName()))
+
+	// Заголовки уже отправлены, поэтому ошибку посреди выгрузки можно только залогировать
+	if err := h.Store.WithContext(r.Context()).ExportNotes(userID, format, w); err != nil {
+		logger.FromContext(r.Context()).WithError(err).WithField("user_id", userID).Error("Ошибка при выгрузке заметок")
+		return
+	}
+
+	logger.FromContext(r.Context()).WithFields(logger.Fields{
+		"user_id": userID,
+		"format":  format,
+	}).Info("Заметки выгружены")
+}
@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"notes-api/logger"
+	"notes-api/middleware"
+	"notes-api/problem"
+	"notes-api/service"
+)
+
+// maxImportUploadSize ограничивает размер загружаемого файла импорта
+const maxImportUploadSize = 32 << 20
+
+type ImportHandler struct {
+	Service *service.ImportService
+	// MaxUploadSize — наибольший размер файла импорта; 0 — по умолчанию 32 МБ
+	MaxUploadSize int64
+}
+
+// Import godoc
+// @Summary Импортировать заметки из файла
+// @Description Принимает ZIP с Markdown-файлами (с YAML front-matter), файл Evernote .enex или JSON-выгрузку этого API. Состояние заметки из выгрузки (pinned, archived, starred, due_at) восстанавливается. Импорт выполняется фоновой задачей, прогресс и отчёт по элементам доступны по GET /jobs/{id}
+// @Tags notes
+// @Security ApiKeyAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "Файл импорта"
+// @Param format formData string false "Формат файла, по умолчанию определяется по расширению" Enums(markdown, enex, json)
+// @Success 202 {object} model.Job "Фоновая задача импорта, result содержит model.ImportReport"
+// @Failure 400 {object} problem.Problem "Неверный запрос или формат"
+// @Failure 401 {object} problem.Problem "Пользователь не аутентифицирован"
+// @Router /notes/import [post]
+func (h *ImportHandler) Import(w http.ResponseWriter, r *http.Request) {
+	rawUserID := r.Context().Value(middleware.UserIDKey)
+	userID, ok := rawUserID.(uint)
+	if !ok {
+		logger.FromContext(r.Context()).Warn("Пользователь не аутентифицирован")
+		problem.Write(w, r, problem.Unauthorized)
+		return
+	}
+
+	maxSize := h.MaxUploadSize
+	if maxSize <= 0 {
+		maxSize = maxImportUploadSize
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxSize)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		logger.FromContext(r.Context()).WithError(err).Warn("Не удалось прочитать файл импорта")
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			problem.Write(w, r, problem.PayloadTooLarge)
+			return
+		}
+		problem.Error(w, r, problem.Invalid(problem.Field("file", problem.FieldRequired)))
+		return
+	}
+	defer file.Close()
+
+	format, err := service.DetectImportFormat(r.FormValue("format"), header.Filename)
+	if err != nil {
+		logger.FromContext(r.Context()).WithError(err).Warn("Неподдерживаемый формат импорта")
+		problem.Error(w, r, err)
+		return
+	}
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		logger.FromContext(r.Context()).WithError(err).Warn("Ошибка при загрузке файла импорта")
+		problem.Write(w, r, problem.BadRequest)
+		return
+	}
+
+	job, err := h.Service.StartImport(r.Context(), userID, format, header.Filename, data)
+	if err != nil {
+		logger.FromContext(r.Context()).WithError(err).WithField("user_id", userID).Error("Не удалось запустить импорт")
+		problem.Write(w, r, problem.Internal)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job)
+
+	logger.FromContext(r.Context()).WithFields(logger.Fields{
+		"user_id": userID,
+		"job_id":  job.ID,
+		"format":  format,
+	}).Info("Импорт заметок запущен")
+}
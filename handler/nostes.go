@@ -6,8 +6,10 @@ import (
 	"notes-api/logger"
 	"notes-api/middleware"
 	"notes-api/model"
+	"notes-api/problem"
 	"notes-api/service"
 	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
 )
@@ -18,31 +20,49 @@ type NoteHandler struct {
 
 // GetAll godoc
 // @Summary Получить все заметки текущего пользователя
+// @Description Сначала идут закреплённые заметки в порядке position, затем остальные. Архивные заметки возвращаются только с archived=true. С параметром due_before возвращаются только заметки со сроком раньше указанного момента, ближайшие первыми
 // @Tags notes
 // @Security ApiKeyAuth
 // @Produce json
+// @Param due_before query string false "Момент в формате RFC 3339"
+// @Param archived query bool false "Включить архивные заметки"
 // @Success 200 {array} model.Note "Список заметок"
-// @Failure 401 {string} string "Пользователь не аутентифицирован"
-// @Failure 500 {string} string "Ошибка при получении заметок"
+// @Failure 400 {object} problem.Problem "Неверный формат due_before"
+// @Failure 401 {object} problem.Problem "Пользователь не аутентифицирован"
+// @Failure 500 {object} problem.Problem "Ошибка при получении заметок"
 // @Router /notes [get]
 func (h *NoteHandler) GetAll(w http.ResponseWriter, r *http.Request) {
 	rawUserID := r.Context().Value(middleware.UserIDKey)
 	userID, ok := rawUserID.(uint)
 	if !ok {
-		logger.Log.Warn("Пользователь не аутентифицирован")
-		http.Error(w, "Пользователь не аутентифицирован", http.StatusUnauthorized)
+		logger.FromContext(r.Context()).Warn("Пользователь не аутентифицирован")
+		problem.Write(w, r, problem.Unauthorized)
 		return
 	}
 
-	notes, err := h.Store.GetNotesByUserID(int(userID))
+	includeArchived := r.URL.Query().Get("archived") == "true"
+
+	var notes []model.Note
+	var err error
+	if raw := r.URL.Query().Get("due_before"); raw != "" {
+		dueBefore, parseErr := time.Parse(time.RFC3339, raw)
+		if parseErr != nil {
+			logger.FromContext(r.Context()).WithError(parseErr).Warn("Неверный формат due_before")
+			problem.Error(w, r, problem.Invalid(problem.Field("due_before", problem.FieldInvalidFormat)))
+			return
+		}
+		notes, err = h.Store.WithContext(r.Context()).GetNotesDueBefore(userID, dueBefore, includeArchived)
+	} else {
+		notes, err = h.Store.WithContext(r.Context()).GetNotesByUserID(int(userID), includeArchived)
+	}
 	if err != nil {
-		logger.Log.WithError(err).WithField("user_id", userID).Error("Ошибка при получении заметок")
-		http.Error(w, "Ошибка при получении заметок", http.StatusInternalServerError)
+		logger.FromContext(r.Context()).WithError(err).WithField("user_id", userID).Warn("Ошибка при получении заметок")
+		problem.Error(w, r, err)
 		return
 	}
 	json.NewEncoder(w).Encode(notes)
 
-	logger.Log.WithField("user_id", userID).Info("Получение всех заметок пользователя")
+	logger.FromContext(r.Context()).WithField("user_id", userID).Info("Получение всех заметок пользователя")
 }
 
 // GetByID godoc
@@ -52,8 +72,8 @@ func (h *NoteHandler) GetAll(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Param id path int true "ID заметки"
 // @Success 200 {object} model.Note "Заметка"
-// @Failure 400 {string} string "Неверный ID"
-// @Failure 404 {string} string "Заметка не найдена"
+// @Failure 400 {object} problem.Problem "Неверный ID"
+// @Failure 404 {object} problem.Problem "Заметка не найдена"
 // @Router /notes/{id} [get]
 func (h *NoteHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -61,64 +81,66 @@ func (h *NoteHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		logger.Log.WithError(err).Warn("Неверный ID")
-		http.Error(w, "Неверный ID", http.StatusBadRequest)
+		logger.FromContext(r.Context()).WithError(err).Warn("Неверный ID")
+		problem.Write(w, r, problem.InvalidID)
 		return
 	}
 
-	note, err := h.Store.GetNoteByID(id)
+	note, err := h.Store.WithContext(r.Context()).GetNoteByID(id)
 	if err != nil {
-		logger.Log.WithError(err).Warn("Заметка не найдена")
-		http.Error(w, "Заметка не найдена", http.StatusNotFound)
+		logger.FromContext(r.Context()).WithError(err).Warn("Заметка не найдена")
+		problem.Write(w, r, problem.NoteNotFound)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(note)
 
-	logger.Log.WithFields(logger.Fields{
+	logger.FromContext(r.Context()).WithFields(logger.Fields{
 		"note_id": id,
 	}).Info("Заметка найдена и возвращена")
 }
 
 // Create godoc
 // @Summary Создать новую заметку
+// @Description С параметром template_id заметка создаётся из шаблона, а тело запроса — templates.InstantiateInput с переменными шаблона. Заметка с encrypted=true шифруется на клиенте: content (и title, если не пустой) передаются JSON-конвертом {"v":1,"alg":"AES-256-GCM"|"XChaCha20-Poly1305","kid":"…","nonce":"<base64>","ct":"<base64>"}, ключи хранятся в /keys
 // @Tags notes
 // @Security ApiKeyAuth
 // @Accept json
 // @Produce json
+// @Param template_id query int false "ID шаблона"
 // @Param note body model.Note true "Данные заметки"
 // @Success 201 {object} model.Note "Созданная заметка"
-// @Failure 400 {string} string "Неверный запрос или ошибка валидации"
+// @Failure 400 {object} problem.Problem "Неверный запрос или ошибка валидации"
+// @Failure 404 {object} problem.Problem "Шаблон не найден"
 // @Router /notes [post]
 func (h *NoteHandler) Create(w http.ResponseWriter, r *http.Request) {
 	rawUserID := r.Context().Value(middleware.UserIDKey)
 	userID, ok := rawUserID.(uint)
 	if !ok {
-		logger.Log.Warn("Пользователь не аутентифицирован")
-		http.Error(w, "Пользователь не аутентифицирован", http.StatusUnauthorized)
+		logger.FromContext(r.Context()).Warn("Пользователь не аутентифицирован")
+		problem.Write(w, r, problem.Unauthorized)
 		return
 	}
 
 	var note model.Note
 	if err := json.NewDecoder(r.Body).Decode(&note); err != nil {
-		logger.Log.Warn("Неверный запрос")
-		http.Error(w, "Неверный запрос", http.StatusBadRequest)
+		logger.FromContext(r.Context()).Warn("Неверный запрос")
+		problem.Write(w, r, problem.BadRequest)
 		return
 	}
-	created, err := h.Store.CreateNote(userID, note)
+	created, err := h.Store.WithContext(r.Context()).CreateNote(userID, note)
 	if err != nil {
-		logger.Log.WithError(err).WithFields(logger.Fields{
+		logger.FromContext(r.Context()).WithError(err).WithFields(logger.Fields{
 			"user_id": userID,
-			"note":    note,
 		}).Warn("Ошибка при создании заметки")
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		problem.Error(w, r, err)
 		return
 	}
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(created)
 
-	logger.Log.WithFields(logger.Fields{
+	logger.FromContext(r.Context()).WithFields(logger.Fields{
 		"user_id": userID,
 		"note_id": created.ID,
 	}).Info("Заметка успешно создана")
@@ -133,60 +155,57 @@ func (h *NoteHandler) Create(w http.ResponseWriter, r *http.Request) {
 // @Param id path int true "ID заметки"
 // @Param note body model.Note true "Обновлённые данные заметки"
 // @Success 200 {object} model.Note "Обновленная заметка"
-// @Failure 400 {string} string "Неверный запрос или ID"
-// @Failure 403 {string} string "Доступ запрещён"
-// @Failure 404 {string} string "Заметка не найдена"
+// @Failure 400 {object} problem.Problem "Неверный запрос, ID, шифротекст или ошибка валидации"
+// @Failure 403 {object} problem.Problem "Доступ запрещён"
+// @Failure 404 {object} problem.Problem "Заметка не найдена"
 // @Router /notes/{id} [put]
 func (h *NoteHandler) Update(w http.ResponseWriter, r *http.Request) {
 	raw := r.Context().Value(middleware.UserIDKey)
 	userID, ok := raw.(uint)
 	if !ok {
-		logger.Log.Warn("Пользователь не авторизован")
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		logger.FromContext(r.Context()).Warn("Пользователь не авторизован")
+		problem.Write(w, r, problem.Unauthorized)
 		return
 	}
 
 	idStr := mux.Vars(r)["id"]
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		logger.Log.WithError(err).Warn("Неверный ID")
-		http.Error(w, "Неверный ID", http.StatusBadRequest)
+		logger.FromContext(r.Context()).WithError(err).Warn("Неверный ID")
+		problem.Write(w, r, problem.InvalidID)
 		return
 	}
 
 	var note model.Note
-	note, err = h.Store.GetNoteByID(id)
+	note, err = h.Store.WithContext(r.Context()).GetNoteByID(id)
 	if err != nil {
-		logger.Log.WithError(err).Warn("Заметка не найдена")
-		http.Error(w, "Заметка не найдена", http.StatusNotFound)
+		logger.FromContext(r.Context()).WithError(err).Warn("Заметка не найдена")
+		problem.Write(w, r, problem.NoteNotFound)
 		return
 	}
 
 	if note.UserID != userID {
-		logger.Log.Warn("Доступ запрещён")
-		http.Error(w, "Доступ запрещен", http.StatusForbidden)
+		logger.FromContext(r.Context()).Warn("Доступ запрещён")
+		problem.Write(w, r, problem.Forbidden)
 		return
 	}
 
 	var updated model.Note
 	if err := json.NewDecoder(r.Body).Decode(&updated); err != nil {
-		logger.Log.WithError(err).Warn("Неверный запрос")
-		http.Error(w, "Неверный запрос", http.StatusBadRequest)
+		logger.FromContext(r.Context()).WithError(err).Warn("Неверный запрос")
+		problem.Write(w, r, problem.BadRequest)
 		return
 	}
 
-	updatedNote, err := h.Store.UpdateNote(id, updated)
+	updatedNote, err := h.Store.WithContext(r.Context()).UpdateNote(id, updated)
 	if err != nil {
-		logger.Log.WithError(err).WithFields(logger.Fields{
-			"id":   id,
-			"note": updated,
-		}).Warn("Ошибка при обновлении")
-		http.Error(w, "Ошибка при обновлении", http.StatusInternalServerError)
+		logger.FromContext(r.Context()).WithError(err).WithField("note_id", id).Warn("Ошибка при обновлении")
+		problem.Error(w, r, err)
 		return
 	}
 	json.NewEncoder(w).Encode(updatedNote)
 
-	logger.Log.WithFields(logger.Fields{
+	logger.FromContext(r.Context()).WithFields(logger.Fields{
 		"user_id": userID,
 		"note_id": id,
 	}).Info("Заметка успешно обновлена")
@@ -199,49 +218,49 @@ func (h *NoteHandler) Update(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Param id path int true "ID заметки"
 // @Success 200 {object} map[string]string "Сообщение об удалении"
-// @Failure 400 {string} string "Неверный ID"
-// @Failure 403 {string} string "Доступ запрещён"
-// @Failure 404 {string} string "Заметка не найдена"
+// @Failure 400 {object} problem.Problem "Неверный ID"
+// @Failure 403 {object} problem.Problem "Доступ запрещён"
+// @Failure 404 {object} problem.Problem "Заметка не найдена"
 // @Router /notes/{id} [delete]
 func (h *NoteHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	rawUserID := r.Context().Value(middleware.UserIDKey)
 	userID, ok := rawUserID.(uint)
 	if !ok {
-		logger.Log.Warn("Пользователь не аутентифицирован")
-		http.Error(w, "Пользователь не аутентифицирован", http.StatusUnauthorized)
+		logger.FromContext(r.Context()).Warn("Пользователь не аутентифицирован")
+		problem.Write(w, r, problem.Unauthorized)
 		return
 	}
 
 	idStr := mux.Vars(r)["id"]
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		logger.Log.WithError(err).Warn("Неверный ID")
-		http.Error(w, "Неверный ID", http.StatusBadRequest)
+		logger.FromContext(r.Context()).WithError(err).Warn("Неверный ID")
+		problem.Write(w, r, problem.InvalidID)
 		return
 	}
 
-	note, err := h.Store.GetNoteByID(id)
+	note, err := h.Store.WithContext(r.Context()).GetNoteByID(id)
 	if err != nil {
-		logger.Log.WithError(err).Warn("Заметка не найдена")
-		http.Error(w, "Заметка не найдена", http.StatusNotFound)
+		logger.FromContext(r.Context()).WithError(err).Warn("Заметка не найдена")
+		problem.Write(w, r, problem.NoteNotFound)
 		return
 	}
 
 	if note.UserID != userID {
-		logger.Log.Warn("Доступ запрещён")
-		http.Error(w, "Доступ запрещен", http.StatusForbidden)
+		logger.FromContext(r.Context()).Warn("Доступ запрещён")
+		problem.Write(w, r, problem.Forbidden)
 		return
 	}
 
-	if err := h.Store.DeleteNote(id); err != nil {
-		logger.Log.WithError(err).WithField("id", id).Warn("Ошибка при удалении")
-		http.Error(w, "Ошибка при удалении", http.StatusInternalServerError)
+	if err := h.Store.WithContext(r.Context()).DeleteNote(id); err != nil {
+		logger.FromContext(r.Context()).WithError(err).WithField("id", id).Warn("Ошибка при удалении")
+		problem.Error(w, r, err)
 		return
 	}
 
 	json.NewEncoder(w).Encode(map[string]string{"message": "Заметка удалена"})
 
-	logger.Log.WithFields(logger.Fields{
+	logger.FromContext(r.Context()).WithFields(logger.Fields{
 		"user_id": userID,
 		"note_id": id,
 	}).Info("Заметка успешно удалена")
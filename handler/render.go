@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"notes-api/logger"
+	"notes-api/middleware"
+	"notes-api/problem"
+	"notes-api/service"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// Render godoc
+// @Summary Отрендерить заметку
+// @Description Markdown заметки (CommonMark и GFM: таблицы, списки задач, блоки кода) рендерится в HTML-фрагмент. Сырой HTML из заметки не выводится, результат проходит санитайзер по белому списку; у заголовков есть якоря с префиксом user-content-. ETag меняется вместе с change_seq заметки
+// @Tags notes
+// @Security ApiKeyAuth
+// @Produce html
+// @Param id path int true "ID заметки"
+// @Param format query string false "Формат (по умолчанию html)" Enums(html)
+// @Param If-None-Match header string false "ETag ранее полученного результата"
+// @Success 200 {string} string "HTML-фрагмент"
+// @Success 304 {string} string "Заметка не изменилась"
+// @Failure 400 {object} problem.Problem "Неверный ID или неподдерживаемый формат"
+// @Failure 403 {object} problem.Problem "Доступ запрещен"
+// @Failure 404 {object} problem.Problem "Заметка не найдена"
+// @Failure 409 {object} problem.Problem "Заметка зашифрована на клиенте"
+// @Router /notes/{id}/render [get]
+func (h *NoteHandler) Render(w http.ResponseWriter, r *http.Request) {
+	rawUserID := r.Context().Value(middleware.UserIDKey)
+	userID, ok := rawUserID.(uint)
+	if !ok {
+		logger.FromContext(r.Context()).Warn("Пользователь не аутентифицирован")
+		problem.Write(w, r, problem.Unauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		logger.FromContext(r.Context()).WithError(err).Warn("Неверный ID")
+		problem.Write(w, r, problem.InvalidID)
+		return
+	}
+
+	format, err := service.ParseRenderFormat(r.URL.Query().Get("format"))
+	if err != nil {
+		logger.FromContext(r.Context()).WithError(err).Warn("Неподдерживаемый формат отображения")
+		problem.Error(w, r, err)
+		return
+	}
+
+	note, err := h.Store.WithContext(r.Context()).GetNoteByID(id)
+	if err != nil {
+		logger.FromContext(r.Context()).WithError(err).Warn("Заметка не найдена")
+		problem.Write(w, r, problem.NoteNotFound)
+		return
+	}
+
+	if note.UserID != userID {
+		logger.FromContext(r.Context()).Warn("Доступ запрещён")
+		problem.Write(w, r, problem.Forbidden)
+		return
+	}
+
+	rendered, err := h.Store.WithContext(r.Context()).RenderNote(note, format)
+	if errors.Is(err, service.ErrEncryptedNote) {
+		problem.Write(w, r, problem.NoteEncrypted)
+		return
+	}
+	if err != nil {
+		logger.FromContext(r.Context()).WithError(err).WithField("note_id", id).Error("Ошибка при рендеринге заметки")
+		problem.Write(w, r, problem.Internal)
+		return
+	}
+
+	// Клиент всегда перепроверяет версию, но при неизменной заметке получает 304 без тела
+	w.Header().Set("ETag", rendered.ETag())
+	w.Header().Set("Cache-Control", "private, no-cache")
+	if match := r.Header.Get("If-None-Match"); match != "" && match == rendered.ETag() {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Write(rendered.Body)
+}
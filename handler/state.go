@@ -0,0 +1,186 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"notes-api/logger"
+	"notes-api/middleware"
+	"notes-api/model"
+	"notes-api/problem"
+	storage "notes-api/repo"
+	"notes-api/service"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// PinInput — необязательные параметры закрепления
+type PinInput struct {
+	// Position — место среди закреплённых (по возрастанию); без него заметка встаёт в конец
+	Position *int `json:"position" example:"1"`
+}
+
+// Pin godoc
+// @Summary Закрепить заметку
+// @Description Закреплённые заметки идут в начале списка в порядке position. Повторный вызов с position перемещает заметку
+// @Tags notes
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "ID заметки"
+// @Param input body handler.PinInput false "Позиция среди закреплённых"
+// @Success 200 {object} model.Note "Обновлённая заметка"
+// @Failure 400 {object} problem.Problem "Неверный запрос или ID"
+// @Failure 403 {object} problem.Problem "Доступ запрещен"
+// @Failure 404 {object} problem.Problem "Заметка не найдена"
+// @Failure 409 {object} problem.Problem "Заметка в архиве"
+// @Router /notes/{id}/pin [post]
+func (h *NoteHandler) Pin(w http.ResponseWriter, r *http.Request) {
+	var input PinInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil && !errors.Is(err, io.EOF) {
+		logger.FromContext(r.Context()).WithError(err).Warn("Неверный запрос")
+		problem.Write(w, r, problem.BadRequest)
+		return
+	}
+
+	h.changeState(w, r, "Заметка закреплена", func(id int) (model.Note, error) {
+		return h.Store.WithContext(r.Context()).SetPinned(id, true, input.Position)
+	})
+}
+
+// Unpin godoc
+// @Summary Открепить заметку
+// @Tags notes
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path int true "ID заметки"
+// @Success 200 {object} model.Note "Обновлённая заметка"
+// @Failure 400 {object} problem.Problem "Неверный ID"
+// @Failure 403 {object} problem.Problem "Доступ запрещен"
+// @Failure 404 {object} problem.Problem "Заметка не найдена"
+// @Router /notes/{id}/unpin [post]
+func (h *NoteHandler) Unpin(w http.ResponseWriter, r *http.Request) {
+	h.changeState(w, r, "Заметка откреплена", func(id int) (model.Note, error) {
+		return h.Store.WithContext(r.Context()).SetPinned(id, false, nil)
+	})
+}
+
+// Archive godoc
+// @Summary Перенести заметку в архив
+// @Description Архивная заметка пропадает из общего списка (кроме запроса с archived=true) и открепляется
+// @Tags notes
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path int true "ID заметки"
+// @Success 200 {object} model.Note "Обновлённая заметка"
+// @Failure 400 {object} problem.Problem "Неверный ID"
+// @Failure 403 {object} problem.Problem "Доступ запрещен"
+// @Failure 404 {object} problem.Problem "Заметка не найдена"
+// @Router /notes/{id}/archive [post]
+func (h *NoteHandler) Archive(w http.ResponseWriter, r *http.Request) {
+	h.changeState(w, r, "Заметка перенесена в архив", func(id int) (model.Note, error) {
+		return h.Store.WithContext(r.Context()).SetArchived(id, true)
+	})
+}
+
+// Unarchive godoc
+// @Summary Вернуть заметку из архива
+// @Tags notes
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path int true "ID заметки"
+// @Success 200 {object} model.Note "Обновлённая заметка"
+// @Failure 400 {object} problem.Problem "Неверный ID"
+// @Failure 403 {object} problem.Problem "Доступ запрещен"
+// @Failure 404 {object} problem.Problem "Заметка не найдена"
+// @Router /notes/{id}/unarchive [post]
+func (h *NoteHandler) Unarchive(w http.ResponseWriter, r *http.Request) {
+	h.changeState(w, r, "Заметка возвращена из архива", func(id int) (model.Note, error) {
+		return h.Store.WithContext(r.Context()).SetArchived(id, false)
+	})
+}
+
+// Star godoc
+// @Summary Добавить заметку в избранное
+// @Tags notes
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path int true "ID заметки"
+// @Success 200 {object} model.Note "Обновлённая заметка"
+// @Failure 400 {object} problem.Problem "Неверный ID"
+// @Failure 403 {object} problem.Problem "Доступ запрещен"
+// @Failure 404 {object} problem.Problem "Заметка не найдена"
+// @Router /notes/{id}/star [post]
+func (h *NoteHandler) Star(w http.ResponseWriter, r *http.Request) {
+	h.changeState(w, r, "Заметка добавлена в избранное", func(id int) (model.Note, error) {
+		return h.Store.WithContext(r.Context()).SetStarred(id, true)
+	})
+}
+
+// Unstar godoc
+// @Summary Убрать заметку из избранного
+// @Tags notes
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path int true "ID заметки"
+// @Success 200 {object} model.Note "Обновлённая заметка"
+// @Failure 400 {object} problem.Problem "Неверный ID"
+// @Failure 403 {object} problem.Problem "Доступ запрещен"
+// @Failure 404 {object} problem.Problem "Заметка не найдена"
+// @Router /notes/{id}/unstar [post]
+func (h *NoteHandler) Unstar(w http.ResponseWriter, r *http.Request) {
+	h.changeState(w, r, "Заметка убрана из избранного", func(id int) (model.Note, error) {
+		return h.Store.WithContext(r.Context()).SetStarred(id, false)
+	})
+}
+
+// changeState проверяет, что заметка принадлежит пользователю, и меняет её состояние
+func (h *NoteHandler) changeState(w http.ResponseWriter, r *http.Request, message string, change func(id int) (model.Note, error)) {
+	rawUserID := r.Context().Value(middleware.UserIDKey)
+	userID, ok := rawUserID.(uint)
+	if !ok {
+		logger.FromContext(r.Context()).Warn("Пользователь не аутентифицирован")
+		problem.Write(w, r, problem.Unauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		logger.FromContext(r.Context()).WithError(err).Warn("Неверный ID")
+		problem.Write(w, r, problem.InvalidID)
+		return
+	}
+
+	note, err := h.Store.WithContext(r.Context()).GetNoteByID(id)
+	if err != nil {
+		logger.FromContext(r.Context()).WithError(err).Warn("Заметка не найдена")
+		problem.Write(w, r, problem.NoteNotFound)
+		return
+	}
+
+	if note.UserID != userID {
+		logger.FromContext(r.Context()).Warn("Доступ запрещён")
+		problem.Write(w, r, problem.Forbidden)
+		return
+	}
+
+	updated, err := change(id)
+	if errors.Is(err, service.ErrInvalidPosition) || errors.Is(err, storage.ErrNoteArchived) {
+		problem.Error(w, r, err)
+		return
+	}
+	if err != nil {
+		logger.FromContext(r.Context()).WithError(err).WithField("id", id).Warn("Ошибка при изменении заметки")
+		problem.Write(w, r, problem.Internal)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+
+	logger.FromContext(r.Context()).WithFields(logger.Fields{
+		"user_id": userID,
+		"note_id": id,
+	}).Info(message)
+}
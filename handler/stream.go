@@ -0,0 +1,260 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"notes-api/events"
+	"notes-api/logger"
+	"notes-api/middleware"
+	"notes-api/problem"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	streamHeartbeat = 25 * time.Second
+	// wsPongWait — сколько ждать ответа на ping, прежде чем считать соединение мёртвым
+	wsPongWait   = 2 * streamHeartbeat
+	wsWriteWait  = 10 * time.Second
+	sseRetryMsec = 3000
+)
+
+// Токен передаётся явно (заголовком или access_token), а не cookie,
+// поэтому проверка Origin не защищает ни от чего и только мешает нативным клиентам
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin:     func(r *http.Request) bool { return true },
+}
+
+type StreamHandler struct {
+	Stream *events.Stream
+}
+
+// SSE godoc
+// @Summary Поток изменений заметок (Server-Sent Events)
+// @Description Отправляет события note.created, note.updated и note.deleted по заметкам текущего пользователя. Для продолжения после обрыва передайте Last-Event-ID (заголовок или параметр last_event_id). Если пропущенных событий слишком много или они уже удалены из журнала, вместо них приходит stream.reset: загрузите заметки заново и продолжайте с его ID. Токен можно передать в параметре access_token
+// @Tags notes
+// @Security ApiKeyAuth
+// @Produce text/event-stream
+// @Param Last-Event-ID header int false "ID последнего полученного события"
+// @Param last_event_id query int false "ID последнего полученного события"
+// @Param access_token query string false "JWT, если нельзя передать заголовок Authorization"
+// @Success 200 {object} events.Event "Поток событий"
+// @Failure 401 {object} problem.Problem "Пользователь не аутентифицирован"
+// @Router /notes/stream [get]
+func (h *StreamHandler) SSE(w http.ResponseWriter, r *http.Request) {
+	rawUserID := r.Context().Value(middleware.UserIDKey)
+	userID, ok := rawUserID.(uint)
+	if !ok {
+		logger.FromContext(r.Context()).Warn("Пользователь не аутентифицирован")
+		problem.Write(w, r, problem.Unauthorized)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		problem.Write(w, r, problem.StreamingUnsupported)
+		return
+	}
+
+	lastEventID := parseLastEventID(r)
+	backlog, sub, err := h.Stream.Subscribe(r.Context(), userID, lastEventID)
+	if err != nil {
+		logger.FromContext(r.Context()).WithError(err).WithField("user_id", userID).Error("Ошибка при подписке на события")
+		problem.Write(w, r, problem.Internal)
+		return
+	}
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", sseRetryMsec)
+	flusher.Flush()
+
+	logger.FromContext(r.Context()).WithFields(logger.Fields{
+		"user_id":       userID,
+		"last_event_id": lastEventID,
+	}).Info("Клиент подключился к потоку событий")
+
+	sent := make(map[uint64]bool, len(backlog))
+	var resetID uint64
+	for _, e := range backlog {
+		if err := writeSSE(w, e); err != nil {
+			return
+		}
+		sent[e.ID] = true
+		if e.Type == events.StreamReset {
+			resetID = e.ID
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			if err := middleware.CheckUser(r.Context()); err != nil {
+				logger.FromContext(r.Context()).WithError(err).WithField("user_id", userID).Info("Поток событий закрыт: доступ запрещён")
+				return
+			}
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case e, ok := <-sub.C:
+			if !ok {
+				// Клиент не успевал читать — пусть переподключится с Last-Event-ID
+				return
+			}
+			if sent[e.ID] || e.ID <= resetID {
+				continue
+			}
+			if err := writeSSE(w, e); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// WebSocket godoc
+// @Summary Поток изменений заметок (WebSocket)
+// @Description Те же события, что и в /notes/stream (включая stream.reset), в виде JSON-сообщений. Для продолжения после обрыва передайте last_event_id. Токен можно передать в параметре access_token
+// @Tags notes
+// @Security ApiKeyAuth
+// @Param last_event_id query int false "ID последнего полученного события"
+// @Param access_token query string false "JWT, если нельзя передать заголовок Authorization"
+// @Success 101 {object} events.Event "Переход на WebSocket"
+// @Failure 401 {object} problem.Problem "Пользователь не аутентифицирован"
+// @Router /notes/ws [get]
+func (h *StreamHandler) WebSocket(w http.ResponseWriter, r *http.Request) {
+	rawUserID := r.Context().Value(middleware.UserIDKey)
+	userID, ok := rawUserID.(uint)
+	if !ok {
+		logger.FromContext(r.Context()).Warn("Пользователь не аутентифицирован")
+		problem.Write(w, r, problem.Unauthorized)
+		return
+	}
+
+	lastEventID := parseLastEventID(r)
+	backlog, sub, err := h.Stream.Subscribe(r.Context(), userID, lastEventID)
+	if err != nil {
+		logger.FromContext(r.Context()).WithError(err).WithField("user_id", userID).Error("Ошибка при подписке на события")
+		problem.Write(w, r, problem.Internal)
+		return
+	}
+	defer sub.Close()
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		logger.FromContext(r.Context()).WithError(err).Warn("Не удалось установить WebSocket-соединение")
+		return
+	}
+	defer conn.Close()
+
+	logger.FromContext(r.Context()).WithFields(logger.Fields{
+		"user_id":       userID,
+		"last_event_id": lastEventID,
+	}).Info("Клиент подключился к WebSocket-потоку событий")
+
+	// Читаем входящие кадры только ради pong и закрытия соединения клиентом
+	closed := make(chan struct{})
+	conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	sent := make(map[uint64]bool, len(backlog))
+	var resetID uint64
+	for _, e := range backlog {
+		if err := writeWS(conn, e); err != nil {
+			return
+		}
+		sent[e.ID] = true
+		if e.Type == events.StreamReset {
+			resetID = e.ID
+		}
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case <-r.Context().Done():
+			// Сервер останавливается: клиент переподключится к другому экземпляру с Last-Event-ID
+			conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseGoingAway, ""),
+				time.Now().Add(wsWriteWait))
+			return
+		case <-heartbeat.C:
+			if err := middleware.CheckUser(r.Context()); err != nil {
+				logger.FromContext(r.Context()).WithError(err).WithField("user_id", userID).Info("WebSocket-поток событий закрыт: доступ запрещён")
+				conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "доступ запрещён"),
+					time.Now().Add(wsWriteWait))
+				return
+			}
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait)); err != nil {
+				return
+			}
+		case e, ok := <-sub.C:
+			if !ok {
+				conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "слишком медленное чтение"),
+					time.Now().Add(wsWriteWait))
+				return
+			}
+			if sent[e.ID] || e.ID <= resetID {
+				continue
+			}
+			if err := writeWS(conn, e); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func writeSSE(w http.ResponseWriter, e events.Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+	return err
+}
+
+func writeWS(conn *websocket.Conn, e events.Event) error {
+	conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+	return conn.WriteJSON(e)
+}
+
+func parseLastEventID(r *http.Request) uint64 {
+	raw := r.Header.Get("Last-Event-ID")
+	if raw == "" {
+		raw = r.URL.Query().Get("last_event_id")
+	}
+	id, _ := strconv.ParseUint(raw, 10, 64)
+	return id
+}
@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"notes-api/logger"
+	"notes-api/middleware"
+	"notes-api/problem"
+	"notes-api/service"
+	"strconv"
+)
+
+type SyncHandler struct {
+	Service *service.SyncService
+}
+
+type syncRequest struct {
+	Mutations []service.SyncMutationInput `json:"mutations"`
+}
+
+type syncResponse struct {
+	Results []service.SyncMutationResult `json:"results"`
+}
+
+// Changes godoc
+// @Summary Получить изменения заметок с момента последней синхронизации
+// @Description Без since возвращает все заметки. В ответе sync_token, который нужно передать в следующий запрос; пока has_more=true, следующую страницу нужно запрашивать сразу. Удалённые заметки приходят в deleted
+// @Tags sync
+// @Security ApiKeyAuth
+// @Produce json
+// @Param since query string false "sync_token из предыдущего ответа"
+// @Param limit query int false "Максимум изменений в ответе (по умолчанию 200, не больше 1000)"
+// @Success 200 {object} service.SyncChanges "Изменения"
+// @Failure 400 {object} problem.Problem "Некорректный sync_token"
+// @Failure 401 {object} problem.Problem "Пользователь не аутентифицирован"
+// @Router /sync [get]
+func (h *SyncHandler) Changes(w http.ResponseWriter, r *http.Request) {
+	rawUserID := r.Context().Value(middleware.UserIDKey)
+	userID, ok := rawUserID.(uint)
+	if !ok {
+		logger.FromContext(r.Context()).Warn("Пользователь не аутентифицирован")
+		problem.Write(w, r, problem.Unauthorized)
+		return
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	changes, err := h.Service.Changes(userID, r.URL.Query().Get("since"), limit)
+	if errors.Is(err, service.ErrInvalidSyncToken) {
+		logger.FromContext(r.Context()).WithError(err).Warn("Некорректный sync_token")
+		problem.Error(w, r, err)
+		return
+	}
+	if err != nil {
+		logger.FromContext(r.Context()).WithError(err).WithField("user_id", userID).Error("Ошибка при получении изменений")
+		problem.Write(w, r, problem.Internal)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(changes)
+
+	logger.FromContext(r.Context()).WithFields(logger.Fields{
+		"user_id":  userID,
+		"notes":    len(changes.Notes),
+		"deleted":  len(changes.Deleted),
+		"has_more": changes.HasMore,
+	}).Info("Изменения для синхронизации отданы")
+}
+
+// Apply godoc
+// @Summary Отправить пакет изменений, сделанных на клиенте
+// @Description Мутации применяются по порядку. update и delete применяются, только если base_seq совпадает с текущим change_seq заметки, иначе в результате будет status=conflict и серверная версия заметки (note) или tombstone (deleted). Сервер всегда прав: клиент сливает свои изменения с серверной версией и отправляет новую мутацию с её change_seq. Повторная отправка мутации с тем же mutation_id возвращает прежний результат
+// @Tags sync
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param input body handler.syncRequest true "Пакет мутаций (не больше 500)"
+// @Success 200 {object} handler.syncResponse "Результат каждой мутации в том же порядке"
+// @Failure 400 {object} problem.Problem "Неверный запрос"
+// @Failure 401 {object} problem.Problem "Пользователь не аутентифицирован"
+// @Router /sync [post]
+func (h *SyncHandler) Apply(w http.ResponseWriter, r *http.Request) {
+	rawUserID := r.Context().Value(middleware.UserIDKey)
+	userID, ok := rawUserID.(uint)
+	if !ok {
+		logger.FromContext(r.Context()).Warn("Пользователь не аутентифицирован")
+		problem.Write(w, r, problem.Unauthorized)
+		return
+	}
+
+	var input syncRequest
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		logger.FromContext(r.Context()).WithError(err).Warn("Неверный запрос")
+		problem.Write(w, r, problem.BadRequest)
+		return
+	}
+
+	results, err := h.Service.Apply(userID, input.Mutations)
+	if err != nil {
+		logger.FromContext(r.Context()).WithError(err).WithField("user_id", userID).Warn("Ошибка при применении мутаций")
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(syncResponse{Results: results})
+
+	logger.FromContext(r.Context()).WithFields(logger.Fields{
+		"user_id":   userID,
+		"mutations": len(results),
+	}).Info("Мутации синхронизации применены")
+}
@@ -0,0 +1,87 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"notes-api/logger"
+	"sync/atomic"
+	"time"
+)
+
+// checkTimeout ограничивает одну проверку готовности: проба не должна висеть дольше, чем её ждёт оркестратор
+const checkTimeout = 2 * time.Second
+
+// Check проверяет зависимость сервиса; nil — зависимость доступна
+type Check func(ctx context.Context) error
+
+// Status — ответ пробы
+type Status struct {
+	Status string            `json:"status" example:"ok"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// HealthHandler отвечает на пробы: /healthz — процесс жив, /readyz — сервис готов принимать запросы
+type HealthHandler struct {
+	Checks map[string]Check
+
+	draining atomic.Bool
+}
+
+// Drain переводит readiness в 503: балансировщик перестаёт направлять запросы,
+// пока сервер дорабатывает текущие перед остановкой
+func (h *HealthHandler) Drain() {
+	h.draining.Store(true)
+}
+
+// Live godoc
+// @Summary Проверка живости
+// @Description Отвечает 200, пока процесс обрабатывает запросы. Зависимости не проверяются.
+// @Tags health
+// @Produce json
+// @Success 200 {object} health.Status
+// @Router /healthz [get]
+func (h *HealthHandler) Live(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, Status{Status: "ok"})
+}
+
+// Ready godoc
+// @Summary Проверка готовности
+// @Description Проверяет подключение к базе и версию схемы. Во время остановки сервиса отвечает 503.
+// @Tags health
+// @Produce json
+// @Success 200 {object} health.Status
+// @Failure 503 {object} health.Status
+// @Router /readyz [get]
+func (h *HealthHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	if h.draining.Load() {
+		writeJSON(w, http.StatusServiceUnavailable, Status{Status: "shutting_down"})
+		return
+	}
+
+	status := Status{Status: "ok", Checks: make(map[string]string, len(h.Checks))}
+	code := http.StatusOK
+	for name, check := range h.Checks {
+		ctx, cancel := context.WithTimeout(r.Context(), checkTimeout)
+		err := check(ctx)
+		cancel()
+
+		// Текст ошибки остаётся в журнале: проба доступна без авторизации
+		if err != nil {
+			logger.FromContext(r.Context()).WithError(err).WithField("check", name).Warn("Проверка готовности не пройдена")
+			status.Checks[name] = "unavailable"
+			status.Status = "unavailable"
+			code = http.StatusServiceUnavailable
+			continue
+		}
+		status.Checks[name] = "ok"
+	}
+	writeJSON(w, code, status)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,43 @@
+// Package httpx — общие помощники обработчиков HTTP: пользователь запроса, ID из пути и JSON-ответ
+package httpx
+
+import (
+	"encoding/json"
+	"net/http"
+	"notes-api/logger"
+	"notes-api/middleware"
+	"notes-api/problem"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// UserID возвращает пользователя, прошедшего аутентификацию. Если его нет,
+// отвечает 401 и возвращает false.
+func UserID(w http.ResponseWriter, r *http.Request) (uint, bool) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(uint)
+	if !ok {
+		logger.FromContext(r.Context()).Warn("Пользователь не аутентифицирован")
+		problem.Write(w, r, problem.Unauthorized)
+	}
+	return userID, ok
+}
+
+// PathID возвращает положительный ID из параметра пути name. Если он неверный,
+// отвечает 400 и возвращает false.
+func PathID(w http.ResponseWriter, r *http.Request, name string) (uint, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)[name])
+	if err != nil || id <= 0 {
+		logger.FromContext(r.Context()).WithError(err).Warn("Неверный ID")
+		problem.Write(w, r, problem.InvalidID)
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// WriteJSON отвечает статусом status и значением v в JSON
+func WriteJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,39 @@
+package httpx
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"notes-api/middleware"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestUserID(t *testing.T) {
+	rec := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	if _, ok := UserID(rec, r); ok || rec.Code != http.StatusUnauthorized {
+		t.Errorf("без пользователя: ok=%v, статус %d", ok, rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	r = r.WithContext(context.WithValue(r.Context(), middleware.UserIDKey, uint(7)))
+	if id, ok := UserID(rec, r); !ok || id != 7 {
+		t.Errorf("UserID = %d, %v, ожидалось 7", id, ok)
+	}
+}
+
+func TestPathID(t *testing.T) {
+	for raw, want := range map[string]uint{"12": 12, "0": 0, "-1": 0, "abc": 0, "": 0} {
+		rec := httptest.NewRecorder()
+		r := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/", nil), map[string]string{"id": raw})
+		id, ok := PathID(rec, r, "id")
+		if ok != (want != 0) || id != want {
+			t.Errorf("PathID(%q) = %d, %v, ожидалось %d", raw, id, ok, want)
+		}
+		if !ok && rec.Code != http.StatusBadRequest {
+			t.Errorf("PathID(%q): статус %d, ожидался %d", raw, rec.Code, http.StatusBadRequest)
+		}
+	}
+}
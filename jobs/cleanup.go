@@ -0,0 +1,25 @@
+package jobs
+
+import (
+	"context"
+	"notes-api/logger"
+	"notes-api/model"
+	"time"
+)
+
+const (
+	CleanupJobType = "jobs.cleanup"
+
+	// cleanupRetention — сколько хранить выполненные задачи и задачи из dead-letter
+	cleanupRetention = 7 * 24 * time.Hour
+)
+
+// HandleCleanup удаляет из очереди давно завершённые задачи
+func (q *Queue) HandleCleanup(ctx context.Context, job *model.Job) error {
+	n, err := q.Cleanup(ctx, cleanupRetention)
+	if err != nil {
+		return err
+	}
+	logger.Log.WithField("count", n).Info("Старые задачи удалены")
+	return nil
+}
@@ -0,0 +1,60 @@
+package jobs
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"notes-api/logger"
+	"notes-api/middleware"
+	"notes-api/problem"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type JobHandler struct {
+	Queue *Queue
+}
+
+// Get godoc
+// @Summary Получить состояние фоновой задачи
+// @Tags jobs
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path int true "ID задачи"
+// @Success 200 {object} model.Job "Задача"
+// @Failure 400 {object} problem.Problem "Неверный ID"
+// @Failure 401 {object} problem.Problem "Пользователь не аутентифицирован"
+// @Failure 404 {object} problem.Problem "Задача не найдена"
+// @Router /jobs/{id} [get]
+func (h *JobHandler) Get(w http.ResponseWriter, r *http.Request) {
+	rawUserID := r.Context().Value(middleware.UserIDKey)
+	userID, ok := rawUserID.(uint)
+	if !ok {
+		logger.FromContext(r.Context()).Warn("Пользователь не аутентифицирован")
+		problem.Write(w, r, problem.Unauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || id <= 0 {
+		logger.FromContext(r.Context()).WithError(err).Warn("Неверный ID")
+		problem.Write(w, r, problem.InvalidID)
+		return
+	}
+
+	job, err := h.Queue.Get(r.Context(), uint(id))
+	if errors.Is(err, ErrJobNotFound) || (err == nil && job.UserID != userID) {
+		logger.FromContext(r.Context()).WithField("job_id", id).Warn("Задача не найдена")
+		problem.Write(w, r, problem.JobNotFound)
+		return
+	}
+	if err != nil {
+		logger.FromContext(r.Context()).WithError(err).WithField("job_id", id).Error("Ошибка при получении задачи")
+		problem.Write(w, r, problem.Internal)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
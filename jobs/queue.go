@@ -0,0 +1,214 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/rand/v2"
+	"notes-api/model"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	DefaultMaxAttempts = 5
+
+	backoffBase = 10 * time.Second
+	backoffMax  = time.Hour
+)
+
+var ErrJobNotFound = errors.New("задача не найдена")
+
+// Options задаёт необязательные параметры постановки задачи в очередь
+type Options struct {
+	UserID      uint
+	RunAt       time.Time
+	MaxAttempts int
+	// UniqueKey не даёт поставить одну и ту же задачу дважды (например, из нескольких экземпляров планировщика)
+	UniqueKey string
+	// Data — произвольные бинарные данные задачи, которые неудобно класть в JSON
+	Data []byte
+}
+
+// Queue — очередь задач поверх таблицы jobs в Postgres
+type Queue struct {
+	DB *gorm.DB
+}
+
+func NewQueue(db *gorm.DB) *Queue {
+	return &Queue{DB: db}
+}
+
+// Enqueue ставит задачу в очередь. Если задача с таким UniqueKey уже есть,
+// новая не создаётся и возвращается задача с нулевым ID.
+func (q *Queue) Enqueue(ctx context.Context, jobType string, payload any, opts Options) (model.Job, error) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return model.Job{}, err
+	}
+
+	job := model.Job{
+		UserID:      opts.UserID,
+		Type:        jobType,
+		Status:      model.JobQueued,
+		Payload:     raw,
+		Data:        opts.Data,
+		MaxAttempts: opts.MaxAttempts,
+		RunAt:       opts.RunAt,
+	}
+	if job.MaxAttempts <= 0 {
+		job.MaxAttempts = DefaultMaxAttempts
+	}
+	if job.RunAt.IsZero() {
+		job.RunAt = time.Now()
+	}
+	if opts.UniqueKey != "" {
+		job.UniqueKey = &opts.UniqueKey
+	}
+
+	err = q.DB.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&job).Error
+	return job, err
+}
+
+func (q *Queue) Get(ctx context.Context, id uint) (model.Job, error) {
+	var job model.Job
+	if err := q.DB.WithContext(ctx).Omit("data").First(&job, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.Job{}, ErrJobNotFound
+		}
+		return model.Job{}, err
+	}
+	return job, nil
+}
+
+// SetProgress сохраняет промежуточный результат и процент выполнения задачи
+func (q *Queue) SetProgress(ctx context.Context, id uint, progress int, result any) error {
+	raw, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	return q.DB.WithContext(ctx).Model(&model.Job{}).Where("id = ?", id).Updates(map[string]any{
+		"progress": progress,
+		"result":   raw,
+	}).Error
+}
+
+// claim забирает одну готовую к выполнению задачу из перечисленных типов.
+// Строка блокируется через SELECT ... FOR UPDATE SKIP LOCKED, поэтому несколько
+// воркеров (и экземпляров сервиса) никогда не получат одну и ту же задачу.
+func (q *Queue) claim(ctx context.Context, types []string, workerID string) (*model.Job, error) {
+	var job model.Job
+	err := q.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND run_at <= ? AND type IN ?", model.JobQueued, time.Now(), types).
+			Order("run_at, id").
+			Take(&job).Error
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		job.Status = model.JobRunning
+		job.Attempts++
+		job.LockedAt = &now
+		job.LockedBy = workerID
+		return tx.Model(&job).Select("status", "attempts", "locked_at", "locked_by").Updates(&job).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (q *Queue) complete(ctx context.Context, job *model.Job) error {
+	now := time.Now()
+	return q.DB.WithContext(ctx).Model(job).Updates(map[string]any{
+		"status":      model.JobSucceeded,
+		"progress":    100,
+		"data":        nil,
+		"last_error":  "",
+		"locked_at":   nil,
+		"locked_by":   "",
+		"finished_at": now,
+	}).Error
+}
+
+// fail планирует повтор с экспоненциальной задержкой или, если попытки
+// исчерпаны, переводит задачу в dead-letter состояние
+func (q *Queue) fail(ctx context.Context, job *model.Job, jobErr error) error {
+	updates := map[string]any{
+		"last_error": jobErr.Error(),
+		"locked_at":  nil,
+		"locked_by":  "",
+	}
+
+	if job.Attempts >= job.MaxAttempts || IsPermanent(jobErr) {
+		updates["status"] = model.JobDead
+		updates["finished_at"] = time.Now()
+		// Повторно задача уже не запустится, а данные импорта могут занимать десятки мегабайт
+		updates["data"] = nil
+	} else {
+		updates["status"] = model.JobQueued
+		updates["run_at"] = time.Now().Add(Backoff(job.Attempts))
+	}
+
+	return q.DB.WithContext(ctx).Model(job).Updates(updates).Error
+}
+
+// requeueStale возвращает в очередь задачи, воркер которых пропал, не завершив их.
+// Попытка засчитывается ещё при захвате задачи, поэтому задача, которая роняет воркер,
+// после MaxAttempts таких попыток переводится в dead-letter, а не крутится по кругу.
+// Возвращает число задач, вернувшихся в очередь, и задачи, переведённые в dead-letter.
+func (q *Queue) requeueStale(ctx context.Context, timeout time.Duration) (int64, []model.Job, error) {
+	const staleError = "воркер не завершил задачу вовремя"
+	cutoff := time.Now().Add(-timeout)
+
+	var dead []model.Job
+	err := q.DB.WithContext(ctx).Model(&dead).Clauses(clause.Returning{}).
+		Where("status = ? AND locked_at < ? AND attempts >= max_attempts", model.JobRunning, cutoff).
+		Updates(map[string]any{
+			"status":      model.JobDead,
+			"last_error":  staleError,
+			"locked_at":   nil,
+			"locked_by":   "",
+			"finished_at": time.Now(),
+			"data":        nil,
+		}).Error
+	if err != nil {
+		return 0, nil, err
+	}
+
+	res := q.DB.WithContext(ctx).Model(&model.Job{}).
+		Where("status = ? AND locked_at < ?", model.JobRunning, cutoff).
+		Updates(map[string]any{
+			"status":     model.JobQueued,
+			"last_error": staleError,
+			"locked_at":  nil,
+			"locked_by":  "",
+			"run_at":     time.Now().Add(backoffBase),
+		})
+	return res.RowsAffected, dead, res.Error
+}
+
+// Cleanup удаляет завершённые задачи (выполненные и из dead-letter) старше olderThan
+func (q *Queue) Cleanup(ctx context.Context, olderThan time.Duration) (int64, error) {
+	res := q.DB.WithContext(ctx).
+		Where("status IN ? AND finished_at < ?", []string{model.JobSucceeded, model.JobDead}, time.Now().Add(-olderThan)).
+		Delete(&model.Job{})
+	return res.RowsAffected, res.Error
+}
+
+// Backoff возвращает задержку перед следующей попыткой: 10s, 20s, 40s ... но не больше часа, с небольшим разбросом
+func Backoff(attempt int) time.Duration {
+	delay := backoffMax
+	if attempt < 20 {
+		delay = min(backoffBase<<max(attempt-1, 0), backoffMax)
+	}
+	jitter := time.Duration(rand.Int64N(int64(delay / 10)))
+	return delay + jitter
+}
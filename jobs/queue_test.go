@@ -0,0 +1,121 @@
+package jobs
+
+import (
+	"context"
+	"notes-api/db/dbtest"
+	"notes-api/model"
+	"testing"
+	"time"
+)
+
+func newTestQueue(t *testing.T) *Queue {
+	return NewQueue(dbtest.Open(t, &model.Job{}))
+}
+
+func reload(t *testing.T, q *Queue, id uint) model.Job {
+	t.Helper()
+	var job model.Job
+	if err := q.DB.First(&job, id).Error; err != nil {
+		t.Fatalf("задача %d не найдена: %v", id, err)
+	}
+	return job
+}
+
+// crash имитирует воркер, который забрал задачу и пропал, не завершив её
+func crash(t *testing.T, q *Queue, id uint) {
+	t.Helper()
+	job, err := q.claim(context.Background(), []string{"test"}, "worker")
+	if err != nil || job == nil || job.ID != id {
+		t.Fatalf("claim: %v, %v", job, err)
+	}
+	q.DB.Model(job).Update("locked_at", time.Now().Add(-time.Hour))
+}
+
+func TestRequeueStaleDeadLettersCrashingJob(t *testing.T) {
+	q := newTestQueue(t)
+	ctx := context.Background()
+	job, err := q.Enqueue(ctx, "test", nil, Options{MaxAttempts: 2, Data: []byte("import archive")})
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	crash(t, q, job.ID)
+	n, dead, err := q.requeueStale(ctx, time.Minute)
+	if err != nil || n != 1 || len(dead) != 0 {
+		t.Fatalf("первый возврат: %d в очереди, %d в dead-letter, %v", n, len(dead), err)
+	}
+	requeued := reload(t, q, job.ID)
+	if requeued.Status != model.JobQueued || requeued.Attempts != 1 {
+		t.Fatalf("после первого сбоя: статус %q, попыток %d", requeued.Status, requeued.Attempts)
+	}
+
+	q.DB.Model(&requeued).Update("run_at", time.Now().Add(-time.Second))
+	crash(t, q, job.ID)
+	n, dead, err = q.requeueStale(ctx, time.Minute)
+	if err != nil || n != 0 || len(dead) != 1 || dead[0].ID != job.ID {
+		t.Fatalf("второй возврат: %d в очереди, %v в dead-letter, %v", n, dead, err)
+	}
+	final := reload(t, q, job.ID)
+	if final.Status != model.JobDead || final.FinishedAt == nil || final.Data != nil {
+		t.Errorf("после исчерпания попыток: статус %q, finished_at %v, данных %d байт", final.Status, final.FinishedAt, len(final.Data))
+	}
+}
+
+func TestCompleteDropsData(t *testing.T) {
+	q := newTestQueue(t)
+	ctx := context.Background()
+	job, _ := q.Enqueue(ctx, "test", nil, Options{Data: []byte("import archive")})
+	claimed, err := q.claim(ctx, []string{"test"}, "worker")
+	if err != nil || claimed == nil {
+		t.Fatalf("claim: %v, %v", claimed, err)
+	}
+	if err := q.complete(ctx, claimed); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+
+	done := reload(t, q, job.ID)
+	if done.Status != model.JobSucceeded || done.Data != nil {
+		t.Errorf("после завершения: статус %q, данных %d байт", done.Status, len(done.Data))
+	}
+}
+
+func TestRequeueStaleSkipsFreshJobs(t *testing.T) {
+	q := newTestQueue(t)
+	ctx := context.Background()
+	job, _ := q.Enqueue(ctx, "test", nil, Options{})
+	if _, err := q.claim(ctx, []string{"test"}, "worker"); err != nil {
+		t.Fatal(err)
+	}
+
+	n, dead, err := q.requeueStale(ctx, time.Minute)
+	if err != nil || n != 0 || len(dead) != 0 {
+		t.Fatalf("задача, которая ещё выполняется, возвращена в очередь: %d, %d, %v", n, len(dead), err)
+	}
+	if reload(t, q, job.ID).Status != model.JobRunning {
+		t.Error("статус выполняющейся задачи изменён")
+	}
+}
+
+func TestCleanupRemovesDeadJobs(t *testing.T) {
+	q := newTestQueue(t)
+	ctx := context.Background()
+	old := time.Now().Add(-2 * cleanupRetention)
+
+	succeeded, _ := q.Enqueue(ctx, "test", nil, Options{})
+	dead, _ := q.Enqueue(ctx, "test", nil, Options{Data: []byte("archive")})
+	recent, _ := q.Enqueue(ctx, "test", nil, Options{})
+	queued, _ := q.Enqueue(ctx, "test", nil, Options{})
+	q.DB.Model(&succeeded).Updates(map[string]any{"status": model.JobSucceeded, "finished_at": old})
+	q.DB.Model(&dead).Updates(map[string]any{"status": model.JobDead, "finished_at": old})
+	q.DB.Model(&recent).Updates(map[string]any{"status": model.JobDead, "finished_at": time.Now()})
+
+	if err := q.HandleCleanup(ctx, &model.Job{}); err != nil {
+		t.Fatalf("HandleCleanup: %v", err)
+	}
+
+	var left []uint
+	q.DB.Model(&model.Job{}).Order("id").Pluck("id", &left)
+	if len(left) != 2 || left[0] != recent.ID || left[1] != queued.ID {
+		t.Errorf("после очистки остались задачи %v, ожидались %d и %d", left, recent.ID, queued.ID)
+	}
+}
@@ -0,0 +1,85 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"notes-api/logger"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+const schedulerTick = 15 * time.Second
+
+type scheduleEntry struct {
+	spec     string
+	jobType  string
+	payload  any
+	schedule cron.Schedule
+	next     time.Time
+}
+
+// Scheduler ставит задачи в очередь по cron-расписанию.
+// Каждый запуск получает UniqueKey из типа и времени запуска, поэтому
+// несколько экземпляров сервиса с одинаковым расписанием не создают дублей.
+type Scheduler struct {
+	Queue *Queue
+
+	entries []*scheduleEntry
+}
+
+func NewScheduler(q *Queue) *Scheduler {
+	return &Scheduler{Queue: q}
+}
+
+// Add регистрирует периодическую задачу. spec — стандартное cron-выражение
+// из пяти полей или дескриптор вроде "@hourly" и "@every 10m".
+func (s *Scheduler) Add(spec, jobType string, payload any) error {
+	schedule, err := cron.ParseStandard(spec)
+	if err != nil {
+		return fmt.Errorf("некорректное расписание %q: %w", spec, err)
+	}
+
+	s.entries = append(s.entries, &scheduleEntry{
+		spec:     spec,
+		jobType:  jobType,
+		payload:  payload,
+		schedule: schedule,
+		next:     schedule.Next(time.Now()),
+	})
+	return nil
+}
+
+// Run блокируется, пока не будет отменён ctx
+func (s *Scheduler) Run(ctx context.Context) {
+	if len(s.entries) == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(schedulerTick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.enqueueDue(ctx, now)
+		}
+	}
+}
+
+func (s *Scheduler) enqueueDue(ctx context.Context, now time.Time) {
+	for _, e := range s.entries {
+		if now.Before(e.next) {
+			continue
+		}
+
+		key := fmt.Sprintf("cron:%s:%d", e.jobType, e.next.Unix())
+		if _, err := s.Queue.Enqueue(ctx, e.jobType, e.payload, Options{RunAt: e.next, UniqueKey: key}); err != nil {
+			logger.Log.WithError(err).WithField("type", e.jobType).Error("Не удалось поставить задачу по расписанию")
+			continue
+		}
+		e.next = e.schedule.Next(now)
+	}
+}
@@ -0,0 +1,230 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"notes-api/logger"
+	"notes-api/model"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	defaultConcurrency  = 4
+	defaultPollInterval = time.Second
+	// defaultJobTimeout — сколько задача может выполняться, прежде чем её отменят и вернут в очередь
+	defaultJobTimeout = 10 * time.Minute
+)
+
+// Handler выполняет задачу. Возвращённая ошибка приводит к повтору с задержкой,
+// ошибка, обёрнутая в Permanent, сразу переводит задачу в dead-letter.
+type Handler func(ctx context.Context, job *model.Job) error
+
+// DeadHandler вызывается, когда задача переходит в dead-letter: попытки исчерпаны, ошибка
+// неисправимая или воркер слишком часто пропадал, не завершив задачу. Нужен, чтобы
+// обработчик мог отметить, что результата не будет.
+type DeadHandler func(ctx context.Context, job *model.Job)
+
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent помечает ошибку как неисправимую: повторять такую задачу бессмысленно
+func Permanent(err error) error {
+	return permanentError{err: err}
+}
+
+func IsPermanent(err error) bool {
+	var p permanentError
+	return errors.As(err, &p)
+}
+
+// Worker — пул воркеров, выбирающих задачи из очереди
+type Worker struct {
+	Queue        *Queue
+	Concurrency  int
+	PollInterval time.Duration
+	JobTimeout   time.Duration
+
+	id       string
+	handlers map[string]Handler
+	dead     map[string]DeadHandler
+}
+
+func NewWorker(q *Queue, concurrency int) *Worker {
+	if concurrency <= 0 {
+		concurrency = defaultConcurrency
+	}
+	host, _ := os.Hostname()
+	return &Worker{
+		Queue:        q,
+		Concurrency:  concurrency,
+		PollInterval: defaultPollInterval,
+		JobTimeout:   defaultJobTimeout,
+		id:           fmt.Sprintf("%s:%d", host, os.Getpid()),
+		handlers:     make(map[string]Handler),
+		dead:         make(map[string]DeadHandler),
+	}
+}
+
+// Register задаёт обработчик для типа задач. Вызывается до Run.
+func (w *Worker) Register(jobType string, h Handler) {
+	w.handlers[jobType] = h
+}
+
+// OnDead задаёт обработчик перехода задач типа jobType в dead-letter. Вызывается до Run.
+func (w *Worker) OnDead(jobType string, h DeadHandler) {
+	w.dead[jobType] = h
+}
+
+// Run запускает воркеры и блокируется, пока не будет отменён ctx.
+// После отмены новые задачи не берутся, а выполняющиеся дорабатывают до конца.
+func (w *Worker) Run(ctx context.Context) {
+	types := make([]string, 0, len(w.handlers))
+	for t := range w.handlers {
+		types = append(types, t)
+	}
+	if len(types) == 0 {
+		return
+	}
+
+	logger.Log.WithFields(logger.Fields{
+		"concurrency": w.Concurrency,
+		"types":       types,
+	}).Info("Воркеры фоновых задач запущены")
+
+	var wg sync.WaitGroup
+	for i := 0; i < w.Concurrency; i++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			w.loop(ctx, types, fmt.Sprintf("%s/%d", w.id, n))
+		}(i)
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		w.reapStale(ctx)
+	}()
+
+	wg.Wait()
+	logger.Log.Info("Воркеры фоновых задач остановлены")
+}
+
+func (w *Worker) loop(ctx context.Context, types []string, workerID string) {
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+
+		job, err := w.Queue.claim(ctx, types, workerID)
+		if err != nil && ctx.Err() == nil {
+			logger.Log.WithError(err).Error("Ошибка при получении задачи из очереди")
+		}
+		if job == nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(w.PollInterval):
+			}
+			continue
+		}
+
+		w.process(ctx, job)
+	}
+}
+
+func (w *Worker) process(ctx context.Context, job *model.Job) {
+	log := logger.Log.WithFields(logger.Fields{
+		"job_id":  job.ID,
+		"type":    job.Type,
+		"attempt": job.Attempts,
+	})
+
+	// Задача не прерывается остановкой воркера, её ограничивает только собственный таймаут
+	jobCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), w.JobTimeout)
+	defer cancel()
+
+	err := w.run(jobCtx, job)
+	if err == nil {
+		if err := w.Queue.complete(jobCtx, job); err != nil {
+			log.WithError(err).Error("Не удалось отметить задачу выполненной")
+			return
+		}
+		log.Info("Задача выполнена")
+		return
+	}
+
+	if ferr := w.Queue.fail(jobCtx, job, err); ferr != nil {
+		log.WithError(ferr).Error("Не удалось сохранить ошибку задачи")
+		return
+	}
+	if job.Attempts >= job.MaxAttempts || IsPermanent(err) {
+		log.WithError(err).Error("Задача переведена в dead-letter")
+		w.notifyDead(jobCtx, job)
+		return
+	}
+	log.WithError(err).Warn("Задача завершилась с ошибкой и будет повторена")
+}
+
+func (w *Worker) run(ctx context.Context, job *model.Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("паника в обработчике задачи: %v", r)
+		}
+	}()
+
+	h, ok := w.handlers[job.Type]
+	if !ok {
+		return Permanent(fmt.Errorf("нет обработчика для задачи типа %q", job.Type))
+	}
+	return h(ctx, job)
+}
+
+func (w *Worker) notifyDead(ctx context.Context, job *model.Job) {
+	h, ok := w.dead[job.Type]
+	if !ok {
+		return
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Log.WithField("job_id", job.ID).Errorf("Паника в обработчике dead-letter: %v", r)
+		}
+	}()
+	h(ctx, job)
+}
+
+func (w *Worker) reapStale(ctx context.Context) {
+	ticker := time.NewTicker(w.JobTimeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, dead, err := w.Queue.requeueStale(ctx, w.JobTimeout+time.Minute)
+			if err != nil {
+				logger.Log.WithError(err).Error("Ошибка при возврате зависших задач в очередь")
+				continue
+			}
+			if n > 0 {
+				logger.Log.WithField("count", n).Warn("Зависшие задачи возвращены в очередь")
+			}
+			for _, job := range dead {
+				logger.Log.WithFields(logger.Fields{
+					"job_id":  job.ID,
+					"type":    job.Type,
+					"attempt": job.Attempts,
+				}).Error("Зависшая задача исчерпала попытки и переведена в dead-letter")
+				w.notifyDead(ctx, &job)
+			}
+		}
+	}
+}
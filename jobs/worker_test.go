@@ -0,0 +1,40 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"notes-api/model"
+	"testing"
+	"time"
+)
+
+func TestWorkerNotifiesDead(t *testing.T) {
+	q := newTestQueue(t)
+	ctx := context.Background()
+	w := NewWorker(q, 1)
+
+	var notified []uint
+	w.Register("test", func(ctx context.Context, job *model.Job) error {
+		return errors.New("сбой")
+	})
+	w.OnDead("test", func(ctx context.Context, job *model.Job) {
+		notified = append(notified, job.ID)
+	})
+
+	job, _ := q.Enqueue(ctx, "test", nil, Options{MaxAttempts: 2})
+	for attempt := 1; attempt <= 2; attempt++ {
+		q.DB.Model(&model.Job{}).Where("id = ?", job.ID).Update("run_at", time.Now().Add(-time.Second))
+		claimed, err := q.claim(ctx, []string{"test"}, "worker")
+		if err != nil || claimed == nil {
+			t.Fatalf("попытка %d: claim: %v", attempt, err)
+		}
+		w.process(ctx, claimed)
+	}
+
+	if reload(t, q, job.ID).Status != model.JobDead {
+		t.Fatal("задача не переведена в dead-letter")
+	}
+	if len(notified) != 1 || notified[0] != job.ID {
+		t.Errorf("обработчик dead-letter вызван для %v", notified)
+	}
+}
@@ -0,0 +1,71 @@
+package links
+
+import (
+	"net/http"
+	"notes-api/httpx"
+	"notes-api/problem"
+)
+
+type LinkHandler struct {
+	Service *LinkService
+}
+
+// Links godoc
+// @Summary Исходящие ссылки заметки
+// @Description Ссылки пишутся в тексте как [[Заголовок]], [[Заголовок#раздел]] или [[Заголовок|подпись]]; заголовки сравниваются без учёта регистра. Ссылки на несуществующие заметки возвращаются в unresolved
+// @Tags links
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path int true "ID заметки"
+// @Success 200 {object} links.NoteLinks "Ссылки заметки"
+// @Failure 403 {object} problem.Problem "Доступ запрещен"
+// @Failure 404 {object} problem.Problem "Заметка не найдена"
+// @Router /notes/{id}/links [get]
+func (h *LinkHandler) Links(w http.ResponseWriter, r *http.Request) {
+	userID, ok := httpx.UserID(w, r)
+	if !ok {
+		return
+	}
+	noteID, ok := httpx.PathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	links, err := h.Service.Links(userID, noteID)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, links)
+}
+
+// Backlinks godoc
+// @Summary Обратные ссылки на заметку
+// @Description Заметки, в тексте которых есть ссылка на данную. При переименовании заметки ссылки на неё в других заметках переписываются на новый заголовок
+// @Tags links
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path int true "ID заметки"
+// @Success 200 {array} links.Backlink "Ссылающиеся заметки"
+// @Failure 403 {object} problem.Problem "Доступ запрещен"
+// @Failure 404 {object} problem.Problem "Заметка не найдена"
+// @Router /notes/{id}/backlinks [get]
+func (h *LinkHandler) Backlinks(w http.ResponseWriter, r *http.Request) {
+	userID, ok := httpx.UserID(w, r)
+	if !ok {
+		return
+	}
+	noteID, ok := httpx.PathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	backlinks, err := h.Service.Backlinks(userID, noteID)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, backlinks)
+}
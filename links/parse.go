@@ -0,0 +1,95 @@
+package links
+
+import (
+	"regexp"
+	"strings"
+)
+
+// maxLinksPerNote ограничивает индекс одной заметки
+const maxLinksPerNote = 500
+
+// wikiLink — [[Заголовок]], [[Заголовок#раздел]] или [[Заголовок|подпись]].
+// Группа 1 — заголовок, группа 2 — всё после него до закрывающих скобок.
+var wikiLink = regexp.MustCompile(`\[\[([^\[\]|#\n]+)((?:#[^\[\]|\n]*)?(?:\|[^\[\]\n]*)?)\]\]`)
+
+// fence — начало или конец блока кода; ссылки внутри блоков не считаются
+var fence = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})")
+
+// Link — вики-ссылка в тексте заметки
+type Link struct {
+	Title string
+	Key   string
+	// start и end — границы заголовка в тексте заметки
+	start, end int
+}
+
+// TitleKey приводит заголовок к виду для сравнения: без учёта регистра и лишних пробелов
+func TitleKey(title string) string {
+	return strings.ToLower(strings.Join(strings.Fields(title), " "))
+}
+
+// ParseLinks находит вики-ссылки вне блоков кода
+func ParseLinks(content string) []Link {
+	var links []Link
+	openFence := ""
+	offset := 0
+	for _, line := range strings.SplitAfter(content, "\n") {
+		start := offset
+		offset += len(line)
+
+		if m := fence.FindStringSubmatch(line); m != nil {
+			switch {
+			case openFence == "":
+				openFence = m[1]
+			case m[1][0] == openFence[0] && len(m[1]) >= len(openFence) && strings.TrimSpace(line[len(m[0]):]) == "":
+				openFence = ""
+			}
+			continue
+		}
+		if openFence != "" {
+			continue
+		}
+
+		for _, m := range wikiLink.FindAllStringSubmatchIndex(line, -1) {
+			title := strings.TrimSpace(line[m[2]:m[3]])
+			if title == "" {
+				continue
+			}
+			links = append(links, Link{
+				Title: title,
+				Key:   TitleKey(title),
+				start: start + m[2],
+				end:   start + m[3],
+			})
+			if len(links) >= maxLinksPerNote {
+				return links
+			}
+		}
+	}
+	return links
+}
+
+// representable сообщает, можно ли записать заголовок внутри [[...]]
+func representable(title string) bool {
+	return TitleKey(title) != "" && !strings.ContainsAny(title, "[]|#\n")
+}
+
+// rewrite заменяет в тексте заголовок во всех ссылках с ключом oldKey на newTitle,
+// сохраняя раздел и подпись ссылки
+func rewrite(content, oldKey, newTitle string) (string, bool) {
+	var b strings.Builder
+	last := 0
+	for _, link := range ParseLinks(content) {
+		if link.Key != oldKey {
+			continue
+		}
+		b.WriteString(content[last:link.start])
+		b.WriteString(newTitle)
+		last = link.end
+	}
+	if last == 0 {
+		return content, false
+	}
+	b.WriteString(content[last:])
+	return b.String(), true
+}
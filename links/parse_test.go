@@ -0,0 +1,96 @@
+package links
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTitleKey(t *testing.T) {
+	for title, want := range map[string]string{
+		"Заметка":               "заметка",
+		"  Планы   на\tНеделю ": "планы на неделю",
+		"":                      "",
+	} {
+		if got := TitleKey(title); got != want {
+			t.Errorf("TitleKey(%q) = %q, ожидалось %q", title, got, want)
+		}
+	}
+}
+
+func TestParseLinks(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{"простая", "см. [[Планы]]", []string{"Планы"}},
+		{"раздел и подпись", "[[Планы#Май|на май]] и [[ Отпуск |тут]] и [[Идеи#]]", []string{"Планы", "Отпуск", "Идеи"}},
+		{"несколько в строке и на разных строках", "[[a]][[b]]\n[[c]]", []string{"a", "b", "c"}},
+		{"не ссылки", "[[]] [[ ]] [[a\nb]] [a] [[a|b|c]x]] [[#раздел]]", nil},
+		{"блоки кода", "```\n[[в коде]]\n```\n[[после]]\n~~~\n[[в тильдах]]\n```\n[[всё ещё в коде]]\n~~~\n[[снаружи]]", []string{"после", "снаружи"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			links := ParseLinks(tt.content)
+			var got []string
+			for _, link := range links {
+				got = append(got, link.Title)
+				if link.Key != TitleKey(link.Title) {
+					t.Errorf("ключ %q для заголовка %q", link.Key, link.Title)
+				}
+				if strings.TrimSpace(tt.content[link.start:link.end]) != link.Title {
+					t.Errorf("границы %d:%d указывают на %q, а не на %q", link.start, link.end, tt.content[link.start:link.end], link.Title)
+				}
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("ParseLinks(%q) = %q, ожидалось %q", tt.content, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLinksLimit(t *testing.T) {
+	content := strings.Repeat("[[a]] ", maxLinksPerNote+10)
+	if got := len(ParseLinks(content)); got != maxLinksPerNote {
+		t.Errorf("найдено %d ссылок, ожидалось не больше %d", got, maxLinksPerNote)
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		oldKey  string
+		want    string
+		changed bool
+	}{
+		{"раздел и подпись сохраняются", "[[Планы#Май|на май]] и [[планы]]", "планы", "[[Цели#Май|на май]] и [[Цели]]", true},
+		{"пробелы и регистр", "[[  ПЛАНЫ  ]]", "планы", "[[Цели]]", true},
+		{"другие ссылки не меняются", "[[Планы]] [[Отпуск]]", "отпуск", "[[Планы]] [[Цели]]", true},
+		{"в коде не меняются", "```\n[[Планы]]\n```\n[[Планы]]", "планы", "```\n[[Планы]]\n```\n[[Цели]]", true},
+		{"нет ссылок", "Планы без ссылки", "планы", "Планы без ссылки", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, changed := rewrite(tt.content, tt.oldKey, "Цели")
+			if got != tt.want || changed != tt.changed {
+				t.Errorf("rewrite(%q) = %q, %v, ожидалось %q, %v", tt.content, got, changed, tt.want, tt.changed)
+			}
+		})
+	}
+}
+
+func TestRepresentable(t *testing.T) {
+	for title, want := range map[string]bool{
+		"Планы":       true,
+		"  ":          false,
+		"a|b":         false,
+		"a#b":         false,
+		"[a]":         false,
+		"строка\nдве": false,
+	} {
+		if got := representable(title); got != want {
+			t.Errorf("representable(%q) = %v, ожидалось %v", title, got, want)
+		}
+	}
+}
@@ -0,0 +1,424 @@
+package links
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"notes-api/encryption"
+	"notes-api/events"
+	"notes-api/jobs"
+	"notes-api/logger"
+	"notes-api/model"
+	storage "notes-api/repo"
+	"notes-api/service"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// ReindexJobType — задача индексации ссылок заметок, сохранённых до появления графа ссылок
+	ReindexJobType = "links.reindex"
+	// RewriteJobType — задача переписывания ссылок на переименованную заметку
+	RewriteJobType = "links.rewrite"
+
+	// rewriteAttempts — сколько раз переписывать ссылающуюся заметку, если её меняют параллельно
+	rewriteAttempts = 3
+	reindexBatch    = 100
+)
+
+// NoteLinks — исходящие ссылки заметки
+type NoteLinks struct {
+	// Links — ссылки на существующие заметки
+	Links []model.NoteLink `json:"links"`
+	// Unresolved — заголовки из ссылок, для которых заметок нет
+	Unresolved []string `json:"unresolved"`
+}
+
+// Backlink — заметка, которая ссылается на данную
+type Backlink struct {
+	NoteID    uint      `json:"note_id"`
+	Title     string    `json:"title" gorm:"serializer:encrypted"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// rename — заметка сменила заголовок, и на неё ссылались по прежним. Это же полезная
+// нагрузка задачи RewriteJobType. Прежние заголовки в задаче не передаются: в очереди
+// они лежали бы в открытом виде, поэтому задача находит их по ссылкам сама.
+type rename struct {
+	UserID uint `json:"user_id"`
+	NoteID uint `json:"note_id"`
+}
+
+// staleSource — заметка, которая ссылается на переименованную по прежнему заголовку oldKey
+type staleSource struct {
+	noteID uint
+	oldKey string
+}
+
+type LinkService struct {
+	DB    *gorm.DB
+	Notes *service.NoteService
+	Queue *jobs.Queue
+}
+
+func NewLinkService(db *gorm.DB, notes *service.NoteService, q *jobs.Queue) *LinkService {
+	return &LinkService{DB: db, Notes: notes, Queue: q}
+}
+
+// Links возвращает исходящие ссылки заметки
+func (s *LinkService) Links(userID, noteID uint) (NoteLinks, error) {
+	if err := storage.CheckNoteOwner(s.DB, userID, noteID); err != nil {
+		return NoteLinks{}, err
+	}
+
+	var all []model.NoteLink
+	if err := s.DB.Where("source_id = ?", noteID).Order("id").Find(&all).Error; err != nil {
+		return NoteLinks{}, err
+	}
+
+	result := NoteLinks{Links: []model.NoteLink{}, Unresolved: []string{}}
+	for _, link := range all {
+		if link.TargetID == nil {
+			result.Unresolved = append(result.Unresolved, link.TargetTitle)
+		} else {
+			result.Links = append(result.Links, link)
+		}
+	}
+	return result, nil
+}
+
+// Backlinks возвращает заметки, которые ссылаются на данную
+func (s *LinkService) Backlinks(userID, noteID uint) ([]Backlink, error) {
+	if err := storage.CheckNoteOwner(s.DB, userID, noteID); err != nil {
+		return nil, err
+	}
+
+	backlinks := []Backlink{}
+	err := s.DB.Table("notes").
+		Select("notes.id AS note_id, notes.title, notes.updated_at").
+		Where("notes.id IN (?)", s.DB.Model(&model.NoteLink{}).Select("source_id").Where("target_id = ?", noteID)).
+		Order("notes.updated_at DESC").
+		Scan(&backlinks).Error
+	return backlinks, err
+}
+
+// Publish реализует events.Publisher: ссылки пересобираются при каждом сохранении заметки,
+// а при смене заголовка ссылки на заметку в других заметках переписываются на новый.
+// Переписывание идёт в фоне: на заметку могут ссылаться сотни других, и их сохранение
+// не должно задерживать запрос, в котором её переименовали.
+func (s *LinkService) Publish(e events.Event) {
+	var err error
+	switch e.Type {
+	case events.NoteCreated, events.NoteUpdated:
+		var renamed *rename
+		renamed, err = s.index(context.Background(), e.Note.ID)
+		if err == nil && renamed != nil {
+			_, err = s.Queue.Enqueue(context.Background(), RewriteJobType, renamed, jobs.Options{UserID: renamed.UserID})
+		}
+	case events.NoteDeleted:
+		err = s.unlink(context.Background(), e.UserID, e.Note.ID)
+	}
+	if err != nil {
+		logger.Log.WithError(err).WithField("note_id", e.Note.ID).Error("Не удалось обновить ссылки заметки")
+	}
+}
+
+// index пересобирает исходящие ссылки заметки и привязывает к ней ссылки, которые ждали
+// заметку с таким заголовком. Если заголовок сменился, возвращает заметки, где нужно
+// переписать ссылки.
+func (s *LinkService) index(ctx context.Context, noteID uint) (*rename, error) {
+	var renamed *rename
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var note model.Note
+		err := tx.Clauses(clause.Locking{Strength: "SHARE"}).
+			Select("id", "user_id", "title", "content", "encrypted").
+			First(&note, noteID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		// Зашифрованная заметка не видна в графе: ни её ссылки, ни её заголовок сервер прочитать не может
+		if note.Encrypted {
+			return nil
+		}
+
+		titles, err := titleIndex(tx, note.UserID)
+		if err != nil {
+			return err
+		}
+		if err := replaceLinks(tx, note, titles); err != nil {
+			return err
+		}
+
+		key := TitleKey(note.Title)
+		if key != "" {
+			if err := resolveDangling(tx, note, key); err != nil {
+				return err
+			}
+		}
+
+		renamed, err = detectRename(tx, note, key, titles)
+		return err
+	})
+	return renamed, err
+}
+
+// resolveDangling привязывает к заметке ссылки, которые ждали заметку с ключом заголовка key.
+// Заголовки в ссылках зашифрованы, поэтому сравниваются в Go.
+func resolveDangling(tx *gorm.DB, note model.Note, key string) error {
+	var dangling []model.NoteLink
+	if err := tx.Where("user_id = ? AND target_id IS NULL", note.UserID).Find(&dangling).Error; err != nil {
+		return err
+	}
+	var ids []uint
+	for _, link := range dangling {
+		if TitleKey(link.TargetTitle) == key {
+			ids = append(ids, link.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	return tx.Model(&model.NoteLink{}).Where("id IN ?", ids).Update("target_id", note.ID).Error
+}
+
+// staleLinks возвращает ссылки на заметку, заголовок в которых уже не её
+func staleLinks(tx *gorm.DB, noteID uint, key string) ([]model.NoteLink, error) {
+	var incoming []model.NoteLink
+	if err := tx.Where("target_id = ?", noteID).Order("id").Find(&incoming).Error; err != nil {
+		return nil, err
+	}
+	var stale []model.NoteLink
+	for _, link := range incoming {
+		if TitleKey(link.TargetTitle) != key {
+			stale = append(stale, link)
+		}
+	}
+	return stale, nil
+}
+
+// detectRename находит ссылки на заметку по прежнему заголовку. Если другая заметка
+// носит прежний заголовок, ссылки переходят к ней; иначе их нужно переписать.
+func detectRename(tx *gorm.DB, note model.Note, key string, titles map[string]uint) (*rename, error) {
+	stale, err := staleLinks(tx, note.ID, key)
+	if err != nil || len(stale) == 0 {
+		return nil, err
+	}
+
+	var renamed *rename
+	for _, link := range stale {
+		if other, ok := titles[TitleKey(link.TargetTitle)]; ok {
+			if err := tx.Model(&link).Update("target_id", other).Error; err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if !representable(note.Title) {
+			if err := tx.Model(&link).Update("target_id", nil).Error; err != nil {
+				return nil, err
+			}
+			continue
+		}
+		renamed = &rename{UserID: note.UserID, NoteID: note.ID}
+	}
+	return renamed, nil
+}
+
+// HandleRewriteJob переписывает [[Старый заголовок]] на текущий заголовок переименованной
+// заметки в ссылающихся заметках. Берётся заголовок на момент выполнения: если заметку успели
+// переименовать ещё раз, ссылки сразу получат последний. Сохранение идёт через NoteService,
+// поэтому ссылки этих заметок переиндексируются сами. Повтор задачи безопасен: уже
+// переписанные заметки не меняются.
+func (s *LinkService) HandleRewriteJob(ctx context.Context, job *model.Job) error {
+	var r rename
+	if err := json.Unmarshal(job.Payload, &r); err != nil {
+		return jobs.Permanent(err)
+	}
+
+	var note model.Note
+	err := s.DB.WithContext(ctx).Select("id", "user_id", "title", "encrypted").First(&note, r.NoteID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if note.UserID != r.UserID || note.Encrypted || !representable(note.Title) {
+		return nil
+	}
+
+	db := s.DB.WithContext(ctx)
+	stale, err := staleLinks(db, note.ID, TitleKey(note.Title))
+	if err != nil {
+		return err
+	}
+	titles, err := titleIndex(db, note.UserID)
+	if err != nil {
+		return err
+	}
+	// Ссылку с одним прежним заголовком в заметке достаточно переписать один раз. Если
+	// прежний заголовок успела занять другая заметка, текст не трогается: ссылка теперь о ней.
+	var sources []staleSource
+	seen := make(map[staleSource]bool)
+	for _, link := range stale {
+		source := staleSource{noteID: link.SourceID, oldKey: TitleKey(link.TargetTitle)}
+		if _, taken := titles[source.oldKey]; !taken && !seen[source] {
+			seen[source] = true
+			sources = append(sources, source)
+		}
+	}
+
+	failed := 0
+	for _, source := range sources {
+		if err := s.rewriteSource(source, r.UserID, note.Title); err != nil {
+			failed++
+			logger.Log.WithError(err).WithFields(logger.Fields{
+				"note_id":   r.NoteID,
+				"source_id": source.noteID,
+			}).Warn("Не удалось переписать ссылки на переименованную заметку")
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("не переписаны ссылки в %d из %d заметок", failed, len(sources))
+	}
+	return nil
+}
+
+func (s *LinkService) rewriteSource(stale staleSource, userID uint, newTitle string) error {
+	for attempt := 0; attempt < rewriteAttempts; attempt++ {
+		source, err := s.Notes.GetNoteByID(int(stale.noteID))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if source.UserID != userID {
+			return nil
+		}
+
+		content, changed := rewrite(source.Content, stale.oldKey, newTitle)
+		if !changed {
+			return nil
+		}
+		_, err = s.Notes.UpdateNoteIfSeq(int(stale.noteID), source.ChangeSeq, model.Note{Title: source.Title, Content: content})
+		if errors.Is(err, storage.ErrConflict) {
+			continue
+		}
+		return err
+	}
+	return storage.ErrConflict
+}
+
+// unlink удаляет ссылки удалённой заметки, а ссылки на неё переводит на другую заметку
+// с тем же заголовком или оставляет неразрешёнными
+func (s *LinkService) unlink(ctx context.Context, userID, noteID uint) error {
+	return s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("source_id = ?", noteID).Delete(&model.NoteLink{}).Error; err != nil {
+			return err
+		}
+
+		var incoming []model.NoteLink
+		if err := tx.Where("target_id = ?", noteID).Find(&incoming).Error; err != nil {
+			return err
+		}
+		if len(incoming) == 0 {
+			return nil
+		}
+
+		titles, err := titleIndex(tx, userID)
+		if err != nil {
+			return err
+		}
+		for _, link := range incoming {
+			var target *uint
+			if id, ok := titles[TitleKey(link.TargetTitle)]; ok && id != noteID {
+				target = &id
+			}
+			if err := tx.Model(&link).Update("target_id", target).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func replaceLinks(tx *gorm.DB, note model.Note, titles map[string]uint) error {
+	if err := tx.Where("source_id = ?", note.ID).Delete(&model.NoteLink{}).Error; err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool)
+	var links []model.NoteLink
+	for _, link := range ParseLinks(note.Content) {
+		if seen[link.Key] {
+			continue
+		}
+		seen[link.Key] = true
+
+		row := model.NoteLink{
+			UserID:      note.UserID,
+			SourceID:    note.ID,
+			TargetTitle: link.Title,
+		}
+		if id, ok := titles[link.Key]; ok {
+			row.TargetID = &id
+		}
+		links = append(links, row)
+	}
+	if len(links) == 0 {
+		return nil
+	}
+	return tx.Create(&links).Error
+}
+
+// titleIndex сопоставляет ключи заголовков заметок пользователя с их ID. Заголовки
+// сравниваются в Go, а не в SQL: результат LOWER в Postgres зависит от локали базы.
+// При совпадении заголовков ссылка ведёт на самую старую заметку.
+func titleIndex(tx *gorm.DB, userID uint) (map[string]uint, error) {
+	var notes []model.Note
+	if err := tx.Select("id", "title").Where("user_id = ? AND NOT encrypted", userID).Order("id").Find(&notes).Error; err != nil {
+		return nil, err
+	}
+
+	titles := make(map[string]uint, len(notes))
+	for _, note := range notes {
+		key := TitleKey(note.Title)
+		if _, ok := titles[key]; key != "" && !ok {
+			titles[key] = note.ID
+		}
+	}
+	return titles, nil
+}
+
+// HandleReindexJob индексирует заметки со ссылками, которых ещё нет в графе.
+// Повторный запуск безопасен: уже проиндексированные заметки пропускаются.
+func (s *LinkService) HandleReindexJob(ctx context.Context, job *model.Job) error {
+	var notes []model.Note
+	indexed := 0
+	err := s.DB.WithContext(ctx).
+		Select("id").
+		Where("content LIKE ? OR content LIKE ?", "%[[%]]%", encryption.Prefix+"%").
+		Where("NOT EXISTS (SELECT 1 FROM note_links WHERE note_links.source_id = notes.id)").
+		FindInBatches(&notes, reindexBatch, func(tx *gorm.DB, batch int) error {
+			for _, note := range notes {
+				// Старые заметки не переименовывались, переписывать ссылки не нужно
+				if _, err := s.index(ctx, note.ID); err != nil {
+					return err
+				}
+				indexed++
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return err
+	}
+
+	logger.Log.WithField("count", indexed).Info("Ссылки заметок проиндексированы")
+	return nil
+}
@@ -0,0 +1,97 @@
+package logger
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type requestKey struct{}
+
+// request — сведения о HTTP-запросе, которые добавляются в каждую запись журнала,
+// сделанную с его контекстом. Маршрут и пользователь становятся известны уже после
+// создания контекста, поэтому они заполняются позже через SetRoute и SetUserID.
+type request struct {
+	id    string
+	start time.Time
+
+	mu     sync.Mutex
+	route  string
+	userID uint
+}
+
+// NewRequestContext возвращает контекст запроса с идентификатором id
+func NewRequestContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestKey{}, &request{id: id, start: time.Now()})
+}
+
+// RequestID возвращает идентификатор запроса из контекста или пустую строку
+func RequestID(ctx context.Context) string {
+	if req := requestFrom(ctx); req != nil {
+		return req.id
+	}
+	return ""
+}
+
+// SetRoute запоминает шаблон маршрута запроса, например /notes/{id}
+func SetRoute(ctx context.Context, route string) {
+	if req := requestFrom(ctx); req != nil {
+		req.mu.Lock()
+		req.route = route
+		req.mu.Unlock()
+	}
+}
+
+// SetUserID запоминает аутентифицированного пользователя запроса
+func SetUserID(ctx context.Context, userID uint) {
+	if req := requestFrom(ctx); req != nil {
+		req.mu.Lock()
+		req.userID = userID
+		req.mu.Unlock()
+	}
+}
+
+// FromContext возвращает запись журнала для контекста: к ней добавятся request_id,
+// route, user_id и latency запроса, а также trace_id, если запрос трассируется
+func FromContext(ctx context.Context) *logrus.Entry {
+	return Log.WithContext(ctx)
+}
+
+func requestFrom(ctx context.Context) *request {
+	if ctx == nil {
+		return nil
+	}
+	req, _ := ctx.Value(requestKey{}).(*request)
+	return req
+}
+
+// contextHook переносит сведения о запросе из контекста записи в её поля
+type contextHook struct{}
+
+func (contextHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (contextHook) Fire(entry *logrus.Entry) error {
+	req := requestFrom(entry.Context)
+	if req == nil {
+		return nil
+	}
+	req.mu.Lock()
+	route, userID := req.route, req.userID
+	req.mu.Unlock()
+
+	entry.Data["request_id"] = req.id
+	if route != "" {
+		entry.Data["route"] = route
+	}
+	if _, ok := entry.Data["user_id"]; !ok && userID != 0 {
+		entry.Data["user_id"] = userID
+	}
+	if _, ok := entry.Data["latency"]; !ok {
+		entry.Data["latency"] = time.Since(req.start).String()
+	}
+	return nil
+}
@@ -1,7 +1,9 @@
 package logger
 
 import (
+	"fmt"
 	"os"
+	"time"
 
 	"github.com/sirupsen/logrus"
 )
@@ -12,8 +14,38 @@ type Fields = logrus.Fields
 
 func Init() {
 	Log.SetOutput(os.Stdout)
-	Log.SetLevel(logrus.DebugLevel)
-	Log.SetFormatter(&logrus.TextFormatter{
+	Log.SetLevel(logrus.InfoLevel)
+	Log.SetFormatter(textFormatter())
+	Log.AddHook(contextHook{})
+	// Маскирование — последним, чтобы под него попали и поля, добавленные другими хуками
+	Log.AddHook(redactHook{})
+}
+
+// SetLevel задаёт уровень журнала по имени: trace, debug, info, warn, error
+func SetLevel(level string) error {
+	lvl, err := logrus.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	Log.SetLevel(lvl)
+	return nil
+}
+
+// SetFormat задаёт формат записей: text для чтения глазами или json для сборщиков логов
+func SetFormat(format string) error {
+	switch format {
+	case "text":
+		Log.SetFormatter(textFormatter())
+	case "json":
+		Log.SetFormatter(&logrus.JSONFormatter{TimestampFormat: time.RFC3339Nano})
+	default:
+		return fmt.Errorf("неизвестный формат журнала %q", format)
+	}
+	return nil
+}
+
+func textFormatter() logrus.Formatter {
+	return &logrus.TextFormatter{
 		FullTimestamp: true,
-	})
+	}
 }
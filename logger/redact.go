@@ -0,0 +1,80 @@
+package logger
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	emailPattern  = regexp.MustCompile(`([A-Za-z0-9._%+-])[A-Za-z0-9._%+-]*@([A-Za-z0-9.-]+\.[A-Za-z]{2,})`)
+	jwtPattern    = regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`)
+	bearerPattern = regexp.MustCompile(`(?i)(bearer\s+)\S+`)
+)
+
+// secretFields — поля, значение которых не выводится вовсе
+var secretFields = map[string]bool{
+	"password":      true,
+	"token":         true,
+	"access_token":  true,
+	"refresh_token": true,
+	"authorization": true,
+	"secret":        true,
+}
+
+// contentFields — поля с содержимым заметок. Числа (например, количество заметок) остаются как есть.
+var contentFields = map[string]bool{
+	"note":    true,
+	"notes":   true,
+	"title":   true,
+	"content": true,
+	"body":    true,
+}
+
+// Redact маскирует в строке адреса почты, JWT и Bearer-токены
+func Redact(s string) string {
+	s = jwtPattern.ReplaceAllString(s, "***")
+	s = bearerPattern.ReplaceAllString(s, "${1}***")
+	return emailPattern.ReplaceAllString(s, "${1}***@${2}")
+}
+
+// redactHook — последняя линия защиты: маскирует секреты, содержимое заметок и адреса
+// почты, даже если их по ошибке передали в журнал
+type redactHook struct{}
+
+func (redactHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (redactHook) Fire(entry *logrus.Entry) error {
+	entry.Message = Redact(entry.Message)
+	for key, value := range entry.Data {
+		name := strings.ToLower(key)
+		switch {
+		case secretFields[name]:
+			entry.Data[key] = "***"
+		case contentFields[name] && !isNumber(value):
+			entry.Data[key] = "[скрыто]"
+		default:
+			switch v := value.(type) {
+			case string:
+				entry.Data[key] = Redact(v)
+			case error:
+				entry.Data[key] = Redact(v.Error())
+			case fmt.Stringer:
+				entry.Data[key] = Redact(v.String())
+			}
+		}
+	}
+	return nil
+}
+
+func isNumber(value any) bool {
+	switch value.(type) {
+	case int, int32, int64, uint, uint32, uint64, float64:
+		return true
+	}
+	return false
+}
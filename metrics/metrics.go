@@ -0,0 +1,104 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "notes"
+
+// Registry — реестр метрик сервиса. Отдельный от prometheus.DefaultRegisterer,
+// чтобы в выдачу не попадали метрики сторонних библиотек.
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Число HTTP-запросов по маршруту, методу и статусу ответа.",
+	}, []string{"route", "method", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Время обработки HTTP-запроса. Потоки событий и WebSocket не учитываются.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	httpInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "http_requests_in_flight",
+		Help:      "Число обрабатываемых сейчас HTTP-запросов.",
+	})
+
+	dbDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Время выполнения запросов GORM по операции и таблице.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	}, []string{"operation", "table"})
+
+	dbErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "db_query_errors_total",
+		Help:      "Число запросов GORM, завершившихся ошибкой (кроме «запись не найдена»).",
+	}, []string{"operation", "table"})
+
+	logins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "auth_logins_total",
+		Help:      "Число попыток входа по результату: success или failure.",
+	}, []string{"result"})
+
+	refreshes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "auth_token_refreshes_total",
+		Help:      "Число обновлений access-токена по результату: success или failure.",
+	}, []string{"result"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests, httpDuration, httpInFlight,
+		dbDuration, dbErrors,
+		logins, refreshes,
+	)
+	// Нули для известных результатов, чтобы rate() работал с первой попытки входа
+	for _, result := range []string{"success", "failure"} {
+		logins.WithLabelValues(result)
+		refreshes.WithLabelValues(result)
+	}
+}
+
+// RegisterDB добавляет статистику пула соединений: открытые, занятые, ожидания
+func RegisterDB(db *sql.DB) {
+	Registry.MustRegister(collectors.NewDBStatsCollector(db, "notes"))
+}
+
+// Handler отдаёт метрики в формате Prometheus
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// Login учитывает попытку входа
+func Login(ok bool) {
+	logins.WithLabelValues(result(ok)).Inc()
+}
+
+// TokenRefresh учитывает обновление access-токена
+func TokenRefresh(ok bool) {
+	refreshes.WithLabelValues(result(ok)).Inc()
+}
+
+func result(ok bool) string {
+	if ok {
+		return "success"
+	}
+	return "failure"
+}
@@ -0,0 +1,92 @@
+package metrics
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Middleware считает запросы и время их обработки по шаблону маршрута gorilla/mux
+// (/notes/{id}, а не /notes/42), чтобы число рядов метрик не росло с числом заметок.
+// Подключается через Router.Use, поэтому запросы без подходящего маршрута не учитываются.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := "unknown"
+		if current := mux.CurrentRoute(r); current != nil {
+			if tmpl, err := current.GetPathTemplate(); err == nil {
+				route = tmpl
+			}
+		}
+
+		httpInFlight.Inc()
+		defer httpInFlight.Dec()
+
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+
+		status := strconv.Itoa(rec.statusCode())
+		httpRequests.WithLabelValues(route, r.Method, status).Inc()
+		// Потоки живут до отключения клиента, их длительность исказила бы гистограмму
+		if !rec.hijacked && !rec.flushed {
+			httpDuration.WithLabelValues(route, r.Method, status).Observe(time.Since(start).Seconds())
+		}
+	})
+}
+
+// statusRecorder запоминает статус ответа. Flush и Hijack пробрасываются,
+// потому что обработчики SSE и WebSocket проверяют их приведением типа.
+type statusRecorder struct {
+	http.ResponseWriter
+	status   int
+	flushed  bool
+	hijacked bool
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *statusRecorder) Flush() {
+	r.flushed = true
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (r *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("соединение не поддерживает Hijack")
+	}
+	r.hijacked = true
+	r.status = http.StatusSwitchingProtocols
+	return h.Hijack()
+}
+
+// Unwrap нужен http.ResponseController, например для снятия таймаутов записи
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+func (r *statusRecorder) statusCode() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
@@ -5,55 +5,122 @@ import (
 	"errors"
 	"net/http"
 	"notes-api/logger"
-	"os"
+	"notes-api/problem"
+	"notes-api/tracing"
 	"strings"
 
 	"github.com/golang-jwt/jwt"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type contextKey string
 
-const UserIDKey contextKey = "user_id"
+const (
+	UserIDKey     contextKey = "user_id"
+	userStatusKey contextKey = "user_status"
+)
+
+// UserStatus проверяет, что владельцу токена по-прежнему разрешён доступ.
+// Возвращает ошибку, зарегистрированную в problem, если учётная запись отключена или удалена.
+type UserStatus interface {
+	CheckUser(ctx context.Context, userID uint) error
+}
 
-func JWTAuthMiddleware(next http.Handler) http.Handler {
+// JWTAuthMiddleware проверяет access-токен из заголовка Authorization, подписанный ключом secret,
+// и состояние учётной записи его владельца
+func JWTAuthMiddleware(secret []byte, users UserStatus) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return jwtAuth(secret, users, next)
+	}
+}
+
+func jwtAuth(secret []byte, users UserStatus, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
-			logger.Log.Warn("Отсутствует или некорректный заголовок Authorization")
-			http.Error(w, "Missing or invalid Authorization header", http.StatusUnauthorized)
+			logger.FromContext(r.Context()).Warn("Отсутствует или некорректный заголовок Authorization")
+			problem.Write(w, r, problem.Unauthorized)
 			return
 		}
 
-		tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
-		secret := os.Getenv("JWT_SECRET")
-		if secret == "" {
-			secret = "default_secret"
-		}
+		authenticate(w, r, next, secret, users, strings.TrimPrefix(authHeader, "Bearer "))
+	})
+}
 
-		token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				logger.Log.Error("Недопустимый метод подписи")
-				return nil, errors.New("invalid signing method")
-			}
-			return []byte(secret), nil
-		})
-
-		if err != nil || !token.Valid {
-			logger.Log.WithError(err).Warn("Недопустимый или просроченный токен")
-			http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
+// JWTQueryAuthMiddleware дополнительно принимает токен из параметра access_token.
+// Нужен для EventSource и WebSocket в браузере, где нельзя задать заголовок Authorization.
+func JWTQueryAuthMiddleware(secret []byte, users UserStatus) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return jwtQueryAuth(secret, users, next)
+	}
+}
+
+func jwtQueryAuth(secret []byte, users UserStatus, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if authHeader := r.Header.Get("Authorization"); strings.HasPrefix(authHeader, "Bearer ") {
+			authenticate(w, r, next, secret, users, strings.TrimPrefix(authHeader, "Bearer "))
 			return
 		}
 
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok || claims["user_id"] == nil {
-			logger.Log.Warn("Отсутствует user_id в токене")
-			http.Error(w, "Invalid token payload", http.StatusUnauthorized)
+		tokenStr := r.URL.Query().Get("access_token")
+		if tokenStr == "" {
+			logger.FromContext(r.Context()).Warn("Отсутствует токен в заголовке Authorization и параметре access_token")
+			problem.Write(w, r, problem.Unauthorized)
 			return
 		}
 
-		userID := uint(claims["user_id"].(float64))
-		logger.Log.WithField("user_id", userID).Info("Аутентификация прошла успешно")
-		ctx := context.WithValue(r.Context(), UserIDKey, userID)
-		next.ServeHTTP(w, r.WithContext(ctx))
+		authenticate(w, r, next, secret, users, tokenStr)
 	})
 }
+
+func authenticate(w http.ResponseWriter, r *http.Request, next http.Handler, secret []byte, users UserStatus, tokenStr string) {
+	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			logger.FromContext(r.Context()).Error("Недопустимый метод подписи")
+			return nil, errors.New("invalid signing method")
+		}
+		return secret, nil
+	})
+
+	if err != nil || !token.Valid {
+		logger.FromContext(r.Context()).WithError(err).Warn("Недопустимый или просроченный токен")
+		problem.Write(w, r, problem.InvalidToken)
+		return
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["user_id"] == nil {
+		logger.FromContext(r.Context()).Warn("Отсутствует user_id в токене")
+		problem.Write(w, r, problem.InvalidToken)
+		return
+	}
+
+	userID := uint(claims["user_id"].(float64))
+	if err := users.CheckUser(r.Context(), userID); err != nil {
+		logger.FromContext(r.Context()).WithError(err).WithField("user_id", userID).Warn("Доступ по токену запрещён")
+		problem.Error(w, r, err)
+		return
+	}
+
+	logger.SetUserID(r.Context(), userID)
+	logger.FromContext(r.Context()).Debug("Аутентификация прошла успешно")
+	trace.SpanFromContext(r.Context()).SetAttributes(tracing.UserID(userID))
+	ctx := context.WithValue(r.Context(), UserIDKey, userID)
+	ctx = context.WithValue(ctx, userStatusKey, users)
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// CheckUser повторяет проверку учётной записи для запроса, прошедшего аутентификацию.
+// Долгие соединения (SSE, WebSocket) вызывают её периодически, чтобы отключённый
+// пользователь не оставался на связи до разрыва соединения.
+func CheckUser(ctx context.Context) error {
+	userID, ok := ctx.Value(UserIDKey).(uint)
+	if !ok {
+		return errors.New("запрос не аутентифицирован")
+	}
+	users, ok := ctx.Value(userStatusKey).(UserStatus)
+	if !ok {
+		return nil
+	}
+	return users.CheckUser(ctx, userID)
+}
@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"notes-api/logger"
+
+	"github.com/felixge/httpsnoop"
+	"github.com/gorilla/mux"
+)
+
+// RequestIDHeader — заголовок с идентификатором запроса. Присланный клиентом или
+// балансировщиком идентификатор сохраняется, иначе создаётся новый.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength ограничивает чужой идентификатор, чтобы он не раздувал журнал
+const maxRequestIDLength = 128
+
+// RequestID присваивает запросу идентификатор, возвращает его в ответе и кладёт
+// в контекст: записи журнала через logger.FromContext получают поле request_id.
+// Оборачивает весь роутер, чтобы идентификатор был и у запросов без маршрута.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(logger.NewRequestContext(r.Context(), id)))
+	})
+}
+
+// Route запоминает шаблон маршрута для журнала. Подключается через Router.Use,
+// потому что маршрут известен только после сопоставления.
+func Route(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if route := mux.CurrentRoute(r); route != nil {
+			if tmpl, err := route.GetPathTemplate(); err == nil {
+				logger.SetRoute(r.Context(), tmpl)
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// AccessLog пишет по строке на каждый запрос: метод, путь, статус, размер ответа и время.
+// Ставится внутри RequestID, чтобы строка получила идентификатор запроса.
+func AccessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m := httpsnoop.CaptureMetrics(next, w, r)
+
+		entry := logger.FromContext(r.Context()).WithFields(logger.Fields{
+			"method":  r.Method,
+			"path":    r.URL.Path,
+			"status":  m.Code,
+			"bytes":   m.Written,
+			"latency": m.Duration.String(),
+		})
+		switch {
+		case m.Code >= http.StatusInternalServerError:
+			entry.Error("HTTP-запрос")
+		case m.Code >= http.StatusBadRequest:
+			entry.Warn("HTTP-запрос")
+		case r.URL.Path == "/healthz" || r.URL.Path == "/readyz":
+			// Пробы оркестратора идут каждые несколько секунд и засоряли бы журнал
+			entry.Debug("HTTP-запрос")
+		default:
+			entry.Info("HTTP-запрос")
+		}
+	})
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		if c <= ' ' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
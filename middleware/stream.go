@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Stream готовит долгоживущие запросы — потоки событий и WebSocket — к работе за http.Server:
+// снимает с них таймауты чтения и записи сервера и отменяет контекст запроса, когда закрывается
+// stop. Shutdown сам не прерывает такие запросы и без отмены ждал бы их до своего таймаута.
+func Stream(stop <-chan struct{}) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Обёртки ответа без Unwrap возвращают ErrNotSupported — тогда остаются таймауты сервера
+			rc := http.NewResponseController(w)
+			rc.SetReadDeadline(time.Time{})
+			rc.SetWriteDeadline(time.Time{})
+
+			ctx, cancel := context.WithCancel(r.Context())
+			defer cancel()
+			go func() {
+				select {
+				case <-stop:
+					cancel()
+				case <-ctx.Done():
+				}
+			}()
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// Transfer продлевает таймауты чтения и записи сервера до timeout для загрузки и выгрузки
+// файлов: тело в сотни мегабайт на медленном канале не укладывается в обычные таймауты,
+// а снимать их совсем нельзя — зависший клиент держал бы соединение бесконечно.
+// В отличие от Stream, запрос не прерывается при остановке: Shutdown дождётся его.
+func Transfer(timeout time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			deadline := time.Now().Add(timeout)
+			rc := http.NewResponseController(w)
+			rc.SetReadDeadline(deadline)
+			rc.SetWriteDeadline(deadline)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package model
+
+import "time"
+
+// Состояния превью вложения
+const (
+	ThumbnailsPending = "pending"
+	ThumbnailsReady   = "ready"
+	ThumbnailsFailed  = "failed"
+)
+
+// Attachment — файл, прикреплённый к заметке. Само содержимое лежит в хранилище файлов по StorageKey.
+// @Description Вложение заметки
+type Attachment struct {
+	ID          uint   `json:"id" gorm:"primarykey"`
+	NoteID      uint   `json:"note_id" gorm:"index"`
+	UserID      uint   `json:"user_id" gorm:"index"`
+	FileName    string `json:"file_name"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	SHA256      string `json:"sha256"`
+	StorageKey  string `json:"-" gorm:"uniqueIndex"`
+	// ThumbnailStatus пуст, если для типа файла превью не строятся
+	ThumbnailStatus string `json:"thumbnail_status,omitempty" enums:"pending,ready,failed"`
+	// ThumbnailSizes — готовые размеры превью (по длинной стороне, в пикселях)
+	ThumbnailSizes []int     `json:"thumbnail_sizes,omitempty" gorm:"serializer:json"`
+	CreatedAt      time.Time `json:"created_at"`
+}
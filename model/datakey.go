@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// DataKey — ключ данных пользователя для шифрования заметок в базе. Хранится только
+// обёрнутым мастер-ключом MasterKeyID; у пользователя один действующий ключ, выведенные
+// из оборота (RetiredAt) остаются, пока ими зашифрованы заметки.
+type DataKey struct {
+	ID          uint       `gorm:"primarykey"`
+	UserID      uint       `gorm:"index"`
+	MasterKeyID string     `gorm:"index"`
+	WrappedKey  []byte     `gorm:"not null"`
+	RetiredAt   *time.Time `gorm:"index"`
+	CreatedAt   time.Time
+}
@@ -0,0 +1,29 @@
+package model
+
+const (
+	ImportItemDuplicate = "duplicate"
+	ImportItemFailed    = "failed"
+)
+
+// ImportReport — результат (и прогресс) задачи импорта заметок
+// @Description Отчёт об импорте заметок
+type ImportReport struct {
+	Format     string             `json:"format"`
+	FileName   string             `json:"file_name"`
+	Total      int                `json:"total"`
+	Processed  int                `json:"processed"`
+	Imported   int                `json:"imported"`
+	Duplicates int                `json:"duplicates"`
+	Failed     int                `json:"failed"`
+	Items      []ImportItemReport `json:"items"`
+}
+
+// ImportItemReport описывает элемент импорта, который не был сохранён как новая заметка.
+// Отчёт хранится в задаче открытым, поэтому заголовок заметки в него не попадает:
+// элемент узнаётся по номеру и месту в загруженном файле.
+type ImportItemReport struct {
+	Index  int    `json:"index"`
+	Source string `json:"source"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
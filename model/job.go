@@ -0,0 +1,36 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobDead      = "dead"
+)
+
+// Job представляет фоновую задачу в очереди
+// @Description Фоновая задача
+type Job struct {
+	ID          uint            `json:"id" gorm:"primarykey"`
+	UserID      uint            `json:"user_id,omitempty" gorm:"index"`
+	Type        string          `json:"type" gorm:"index"`
+	Status      string          `json:"status" gorm:"index:idx_jobs_status_run_at,priority:1"`
+	Payload     json.RawMessage `json:"-" gorm:"type:jsonb"`
+	Data        []byte          `json:"-"` // загруженный файл; удаляется, когда задача завершена
+	Result      json.RawMessage `json:"result,omitempty" gorm:"type:jsonb" swaggertype:"object"`
+	Progress    int             `json:"progress"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"max_attempts"`
+	LastError   string          `json:"last_error,omitempty"`
+	UniqueKey   *string         `json:"-" gorm:"uniqueIndex"`
+	RunAt       time.Time       `json:"run_at" gorm:"index:idx_jobs_status_run_at,priority:2"`
+	LockedAt    *time.Time      `json:"-"`
+	LockedBy    string          `json:"-"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+	FinishedAt  *time.Time      `json:"finished_at,omitempty"`
+}
@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// KeyBundle — ключ пользователя для зашифрованных на клиенте заметок, обёрнутый на клиенте
+// (например, ключом из пароля). Сервер хранит его как есть и развернуть не может.
+// @Description Обёрнутый ключ шифрования заметок
+type KeyBundle struct {
+	ID     uint   `json:"-" gorm:"primarykey"`
+	UserID uint   `json:"-" gorm:"uniqueIndex:idx_key_bundles_user_kid,priority:1"`
+	KeyID  string `json:"kid" gorm:"uniqueIndex:idx_key_bundles_user_kid,priority:2"`
+	// Alg — способ обёртки ключа, например PBKDF2-SHA256+A256KW; сервер его не интерпретирует
+	Alg string `json:"alg"`
+	// WrappedKey — обёрнутый ключ в base64
+	WrappedKey string `json:"wrapped_key"`
+	// Params — параметры, нужные клиенту для развёртки (соль, число итераций и т. п.)
+	Params    map[string]any `json:"params,omitempty" gorm:"serializer:json"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
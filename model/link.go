@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// NoteLink — вики-ссылка [[Заголовок]] из текста заметки SourceID. Таблица — производный
+// индекс: ссылки заметки пересобираются при каждом её сохранении.
+// @Description Ссылка между заметками
+type NoteLink struct {
+	ID       uint `json:"-" gorm:"primarykey"`
+	UserID   uint `json:"-" gorm:"index"`
+	SourceID uint `json:"source_note_id" gorm:"index"`
+	// TargetID пуст, если заметки с таким заголовком нет
+	TargetID *uint `json:"target_note_id" gorm:"index"`
+	// TargetTitle — заголовок в том виде, в котором он написан в ссылке. Хранится
+	// зашифрованным, поэтому заголовки сравниваются в Go, а не в SQL.
+	TargetTitle string    `json:"target_title" gorm:"serializer:encrypted"`
+	CreatedAt   time.Time `json:"created_at"`
+}
@@ -1,11 +1,39 @@
 package model
 
-// Note представляет заметку пользователя
+import "time"
+
+// Note представляет заметку пользователя. Title и Content шифруются в базе, если настроено
+// шифрование (см. encryption.Serializer), поэтому искать по ним в SQL нельзя.
 // @Description Модель заметки
 type Note struct {
-	ID      uint   `json:"id" gorm:"primarykey"`
-	UserID  uint   `json:"user_id"`
-	User    User   `json:"user" gorm:"foreignKey:UserID"`
-	Title   string `json:"title"`
-	Content string `json:"content"`
+	ID        uint      `json:"id" gorm:"primarykey"`
+	UserID    uint      `json:"user_id" gorm:"index:idx_notes_user_seq,priority:1"`
+	User      User      `json:"user" gorm:"foreignKey:UserID"`
+	Title     string    `json:"title" gorm:"serializer:encrypted"`
+	Content   string    `json:"content" gorm:"serializer:encrypted"`
+	ChangeSeq int64     `json:"change_seq" gorm:"index:idx_notes_user_seq,priority:2"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// DueAt — необязательный срок заметки; задаётся при создании или через PUT /notes/{id}/due
+	DueAt *time.Time `json:"due_at,omitempty" gorm:"index"`
+	// Pinned, Archived и Starred задаются при создании или через POST /notes/{id}/pin|unpin|archive|unarchive|star|unstar.
+	// Архивные заметки не попадают в общий список без ?archived=true и не бывают закреплёнными:
+	// при создании с обоими флагами заметка создаётся откреплённой.
+	Pinned   bool `json:"pinned" gorm:"not null;default:false"`
+	Archived bool `json:"archived" gorm:"not null;default:false;index"`
+	Starred  bool `json:"starred" gorm:"not null;default:false"`
+	// Position — порядок закреплённой заметки в списке (по возрастанию); у незакреплённых 0
+	Position int `json:"position" gorm:"not null;default:0"`
+	// Encrypted — заметка зашифрована на клиенте: в Title и Content лежат конверты шифротекста
+	// (см. e2e.Envelope). Задаётся только при создании; такие заметки не рендерятся и не индексируются.
+	Encrypted bool `json:"encrypted" gorm:"not null;default:false"`
+}
+
+// NoteTombstone — след удалённой заметки, по которому клиенты синхронизации узнают об удалении
+type NoteTombstone struct {
+	ID        uint      `json:"-" gorm:"primarykey"`
+	UserID    uint      `json:"-" gorm:"index:idx_note_tombstones_user_seq,priority:1"`
+	NoteID    uint      `json:"note_id" gorm:"index"`
+	ChangeSeq int64     `json:"change_seq" gorm:"index:idx_note_tombstones_user_seq,priority:2"`
+	DeletedAt time.Time `json:"deleted_at"`
 }
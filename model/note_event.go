@@ -0,0 +1,18 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// NoteEvent — запись журнала изменений заметок, по которой клиенты
+// потока событий догоняют пропущенное после переподключения
+type NoteEvent struct {
+	ID     uint64 `gorm:"primarykey;index:idx_note_events_user_id_id,priority:2"`
+	UserID uint   `gorm:"index:idx_note_events_user_id_id,priority:1"`
+	Type   string
+	NoteID uint
+	// Data — заметка на момент события; хранится зашифрованной, как и сама заметка
+	Data      json.RawMessage `gorm:"type:text;serializer:encrypted"`
+	CreatedAt time.Time       `gorm:"index"`
+}
@@ -0,0 +1,44 @@
+package model
+
+import "time"
+
+// Каналы доставки напоминаний
+const (
+	ChannelInApp   = "inapp"
+	ChannelEmail   = "email"
+	ChannelWebhook = "webhook"
+)
+
+// Reminder — напоминание о заметке: разовое в момент At или повторяющееся по RRULE,
+// начиная с At
+// @Description Напоминание о заметке
+type Reminder struct {
+	ID     uint      `json:"id" gorm:"primarykey"`
+	UserID uint      `json:"user_id" gorm:"index"`
+	NoteID uint      `json:"note_id" gorm:"index"`
+	At     time.Time `json:"at"`
+	// RRule — правило повторения RFC 5545 без DTSTART, например FREQ=WEEKLY;BYDAY=MO
+	RRule string `json:"rrule,omitempty"`
+	// Timezone — часовой пояс IANA, в котором считаются повторения
+	Timezone string   `json:"timezone"`
+	Channels []string `json:"channels" gorm:"serializer:json"`
+	// NextRunAt — следующее срабатывание; пусто, если напоминание больше не сработает
+	NextRunAt   *time.Time `json:"next_run_at" gorm:"index"`
+	LastFiredAt *time.Time `json:"last_fired_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// Notification — уведомление во входящих пользователя
+// @Description Уведомление
+type Notification struct {
+	ID         uint       `json:"id" gorm:"primarykey"`
+	UserID     uint       `json:"-" gorm:"index"`
+	NoteID     uint       `json:"note_id"`
+	ReminderID uint       `json:"reminder_id" gorm:"uniqueIndex:idx_notifications_reminder_fired,priority:1"`
+	FiredAt    time.Time  `json:"fired_at" gorm:"uniqueIndex:idx_notifications_reminder_fired,priority:2"`
+	Title      string     `json:"title" gorm:"serializer:encrypted"`
+	Body       string     `json:"body" gorm:"serializer:encrypted"`
+	ReadAt     *time.Time `json:"read_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
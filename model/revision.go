@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+const (
+	RevisionSourceCollab = "collab"
+)
+
+// NoteRevision — сохранённая версия содержимого заметки
+// @Description Версия заметки
+type NoteRevision struct {
+	ID     uint `json:"id" gorm:"primarykey"`
+	NoteID uint `json:"note_id" gorm:"index"`
+	UserID uint `json:"user_id"`
+	// Revision — номер ревизии сеанса совместного редактирования, вошедшей в версию
+	Revision  int       `json:"revision"`
+	ChangeSeq int64     `json:"change_seq"`
+	Source    string    `json:"source"`
+	Title     string    `json:"title" gorm:"serializer:encrypted"`
+	Content   string    `json:"content" gorm:"serializer:encrypted"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,17 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// SyncMutation запоминает результат уже применённой мутации синхронизации,
+// чтобы повторная отправка того же пакета клиентом не применяла её дважды.
+// Результат содержит заметку целиком и поэтому хранится зашифрованным.
+type SyncMutation struct {
+	ID         uint            `gorm:"primarykey"`
+	UserID     uint            `gorm:"uniqueIndex:idx_sync_mutations_user_mutation,priority:1"`
+	MutationID string          `gorm:"uniqueIndex:idx_sync_mutations_user_mutation,priority:2"`
+	Result     json.RawMessage `gorm:"type:text;serializer:encrypted"`
+	CreatedAt  time.Time       `gorm:"index"`
+}
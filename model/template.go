@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+// Template — шаблон заметки. Заголовок и текст — шаблоны text/template с ограниченным
+// набором функций. Шаблоны с UserID = 0 общие: их видят все пользователи, но изменить нельзя.
+// @Description Шаблон заметки
+type Template struct {
+	ID          uint      `json:"id" gorm:"primarykey"`
+	UserID      uint      `json:"user_id" gorm:"index"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Title       string    `json:"title"`
+	Content     string    `json:"content"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Global сообщает, общий ли шаблон
+func (t Template) Global() bool {
+	return t.UserID == 0
+}
@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// NoteTodo — пункт списка задач ("- [ ] ...") из текста заметки. Таблица — производный
+// индекс: строки пересобираются при каждом сохранении заметки.
+// @Description Пункт списка задач заметки
+type NoteTodo struct {
+	ID     uint `json:"-" gorm:"primarykey"`
+	UserID uint `json:"-" gorm:"index:idx_note_todos_user_done,priority:1"`
+	NoteID uint `json:"note_id" gorm:"index"`
+	// NoteTitle подставляется при выборке и в таблице не хранится
+	NoteTitle string `json:"note_title" gorm:"->;-:migration;serializer:encrypted"`
+	// Index — порядковый номер пункта в заметке, начиная с 0
+	Index int `json:"index" gorm:"column:position"`
+	// Line — номер строки заметки, начиная с 1
+	Line int    `json:"line"`
+	Text string `json:"text" gorm:"serializer:encrypted"`
+	Done bool   `json:"done" gorm:"index:idx_note_todos_user_done,priority:2"`
+	// ChangeSeq — версия заметки, из которой извлечён пункт
+	ChangeSeq int64     `json:"change_seq"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
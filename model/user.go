@@ -1,5 +1,7 @@
 package model
 
+import "time"
+
 // User представляет пользователя
 // @Description Модель пользователя
 type User struct {
@@ -9,4 +11,8 @@ type User struct {
 	Hash             string `json:"hash"`
 	RefreshToken     string `json:"refresh_token" gorm:"column:refresh_token"`
 	RefreshTokenHash string `json:"refresh_token_hash" gorm:"column:refresh_token_hash"`
+	// ChangeSeq — последний выданный номер изменения заметок пользователя
+	ChangeSeq int64 `json:"-" gorm:"not null;default:0"`
+	// DisabledAt — учётная запись отключена администратором: вход и обновление токена запрещены
+	DisabledAt *time.Time `json:"disabled_at,omitempty"`
 }
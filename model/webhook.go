@@ -0,0 +1,47 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Webhook представляет подписку пользователя на события заметок
+// @Description Подписка на исходящие вебхуки
+type Webhook struct {
+	ID                  uint       `json:"id" gorm:"primarykey"`
+	UserID              uint       `json:"user_id" gorm:"index"`
+	URL                 string     `json:"url"`
+	Events              []string   `json:"events" gorm:"serializer:json"`
+	Secret              string     `json:"secret,omitempty"`
+	Active              bool       `json:"active"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	DisabledAt          *time.Time `json:"disabled_at,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+}
+
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// WebhookDelivery — попытка доставки события на вебхук
+// @Description Доставка вебхука
+type WebhookDelivery struct {
+	ID             uint            `json:"id" gorm:"primarykey"`
+	WebhookID      uint            `json:"webhook_id" gorm:"index"`
+	UserID         uint            `json:"user_id"`
+	EventID        string          `json:"event_id" gorm:"index"`
+	Event          string          `json:"event"`
+	Payload        json.RawMessage `json:"payload" gorm:"type:text;serializer:encrypted" swaggertype:"object"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	ResponseStatus int             `json:"response_status,omitempty"`
+	Error          string          `json:"error,omitempty"`
+	DurationMs     int64           `json:"duration_ms"`
+	ReplayOf       *uint           `json:"replay_of,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+}
@@ -0,0 +1,159 @@
+package problem
+
+import "net/http"
+
+// Code — стабильный машиночитаемый код ошибки. Коды — часть API: их нельзя менять
+// или переиспользовать для другого смысла, можно только добавлять новые.
+type Code string
+
+const (
+	BadRequest           Code = "bad_request"
+	ValidationFailed     Code = "validation_failed"
+	InvalidID            Code = "invalid_id"
+	Unauthorized         Code = "unauthorized"
+	InvalidToken         Code = "invalid_token"
+	InvalidCredentials   Code = "invalid_credentials"
+	UserDisabled         Code = "user_disabled"
+	UserExists           Code = "user_exists"
+	Forbidden            Code = "forbidden"
+	NotFound             Code = "not_found"
+	MethodNotAllowed     Code = "method_not_allowed"
+	Conflict             Code = "conflict"
+	PayloadTooLarge      Code = "payload_too_large"
+	UnsupportedMediaType Code = "unsupported_media_type"
+	Internal             Code = "internal_error"
+
+	NoteNotFound         Code = "note_not_found"
+	NoteConflict         Code = "note_conflict"
+	NoteEncrypted        Code = "note_encrypted"
+	NoteTooLarge         Code = "note_too_large"
+	NoteArchived         Code = "note_archived"
+	InvalidEnvelope      Code = "invalid_envelope"
+	InvalidSyncToken     Code = "invalid_sync_token"
+	UnsupportedFormat    Code = "unsupported_format"
+	AttachmentNotFound   Code = "attachment_not_found"
+	AttachmentTooLarge   Code = "attachment_too_large"
+	AttachmentTypeDenied Code = "attachment_type_not_allowed"
+	InvalidImage         Code = "invalid_image"
+	ThumbnailNotReady    Code = "thumbnail_not_ready"
+	KeyNotFound          Code = "key_not_found"
+	KeyExists            Code = "key_exists"
+	ReminderNotFound     Code = "reminder_not_found"
+	NotificationNotFound Code = "notification_not_found"
+	WebhookNotFound      Code = "webhook_not_found"
+	DeliveryNotFound     Code = "delivery_not_found"
+	TemplateNotFound     Code = "template_not_found"
+	TemplateReadOnly     Code = "template_read_only"
+	TodoNotFound         Code = "todo_not_found"
+	TodoChanged          Code = "todo_changed"
+	JobNotFound          Code = "job_not_found"
+	ReminderLimit        Code = "reminder_limit_reached"
+	KeyLimit             Code = "key_limit_reached"
+	StreamingUnsupported Code = "streaming_unsupported"
+	ServiceUnavailable   Code = "service_unavailable"
+)
+
+type description struct {
+	status int
+	title  text
+}
+
+var codes = map[Code]description{
+	BadRequest:           {http.StatusBadRequest, text{"Неверный формат запроса", "Malformed request"}},
+	ValidationFailed:     {http.StatusBadRequest, text{"Ошибка валидации", "Validation failed"}},
+	InvalidID:            {http.StatusBadRequest, text{"Неверный ID", "Invalid ID"}},
+	Unauthorized:         {http.StatusUnauthorized, text{"Пользователь не аутентифицирован", "Authentication required"}},
+	InvalidToken:         {http.StatusUnauthorized, text{"Недопустимый или просроченный токен", "Invalid or expired token"}},
+	InvalidCredentials:   {http.StatusUnauthorized, text{"Неверный email или пароль", "Invalid email or password"}},
+	UserDisabled:         {http.StatusForbidden, text{"Учётная запись отключена", "Account is disabled"}},
+	UserExists:           {http.StatusConflict, text{"Пользователь с таким email уже существует", "A user with this email already exists"}},
+	Forbidden:            {http.StatusForbidden, text{"Доступ запрещен", "Access denied"}},
+	NotFound:             {http.StatusNotFound, text{"Ресурс не найден", "Resource not found"}},
+	MethodNotAllowed:     {http.StatusMethodNotAllowed, text{"Метод не поддерживается", "Method not allowed"}},
+	Conflict:             {http.StatusConflict, text{"Конфликт с текущим состоянием", "Conflict with the current state"}},
+	PayloadTooLarge:      {http.StatusRequestEntityTooLarge, text{"Слишком большой запрос", "Request is too large"}},
+	UnsupportedMediaType: {http.StatusUnsupportedMediaType, text{"Неподдерживаемый тип содержимого", "Unsupported media type"}},
+	Internal:             {http.StatusInternalServerError, text{"Внутренняя ошибка сервера", "Internal server error"}},
+
+	NoteNotFound:         {http.StatusNotFound, text{"Заметка не найдена", "Note not found"}},
+	NoteConflict:         {http.StatusConflict, text{"Заметка изменена на сервере", "Note was changed on the server"}},
+	NoteEncrypted:        {http.StatusConflict, text{"Заметка зашифрована на клиенте", "Note is end-to-end encrypted"}},
+	NoteTooLarge:         {http.StatusRequestEntityTooLarge, text{"Заметка слишком большая", "Note is too large"}},
+	NoteArchived:         {http.StatusConflict, text{"Архивную заметку нельзя закрепить", "Archived notes cannot be pinned"}},
+	InvalidEnvelope:      {http.StatusBadRequest, text{"Некорректный конверт шифротекста", "Invalid ciphertext envelope"}},
+	InvalidSyncToken:     {http.StatusBadRequest, text{"Некорректный sync_token", "Invalid sync_token"}},
+	UnsupportedFormat:    {http.StatusBadRequest, text{"Неподдерживаемый формат", "Unsupported format"}},
+	AttachmentNotFound:   {http.StatusNotFound, text{"Вложение не найдено", "Attachment not found"}},
+	AttachmentTooLarge:   {http.StatusRequestEntityTooLarge, text{"Файл слишком большой", "File is too large"}},
+	AttachmentTypeDenied: {http.StatusUnsupportedMediaType, text{"Недопустимый тип файла", "File type is not allowed"}},
+	InvalidImage:         {http.StatusBadRequest, text{"Повреждённый файл изображения", "Corrupted image file"}},
+	ThumbnailNotReady:    {http.StatusNotFound, text{"Превью ещё не готово", "Thumbnail is not ready yet"}},
+	KeyNotFound:          {http.StatusNotFound, text{"Ключ не найден", "Key not found"}},
+	KeyExists:            {http.StatusConflict, text{"Ключ с таким kid уже есть", "A key with this kid already exists"}},
+	ReminderNotFound:     {http.StatusNotFound, text{"Напоминание не найдено", "Reminder not found"}},
+	NotificationNotFound: {http.StatusNotFound, text{"Уведомление не найдено", "Notification not found"}},
+	WebhookNotFound:      {http.StatusNotFound, text{"Вебхук не найден", "Webhook not found"}},
+	DeliveryNotFound:     {http.StatusNotFound, text{"Доставка не найдена", "Delivery not found"}},
+	TemplateNotFound:     {http.StatusNotFound, text{"Шаблон не найден", "Template not found"}},
+	TemplateReadOnly:     {http.StatusForbidden, text{"Общие шаблоны нельзя изменять", "Global templates are read-only"}},
+	TodoNotFound:         {http.StatusNotFound, text{"Пункт списка задач не найден", "Todo item not found"}},
+	TodoChanged:          {http.StatusConflict, text{"Пункт списка задач изменился, обновите заметку", "Todo item has changed, reload the note"}},
+	JobNotFound:          {http.StatusNotFound, text{"Задача не найдена", "Job not found"}},
+	ReminderLimit:        {http.StatusConflict, text{"У заметки слишком много напоминаний", "The note has too many reminders"}},
+	KeyLimit:             {http.StatusConflict, text{"Слишком много ключей шифрования", "Too many encryption keys"}},
+	StreamingUnsupported: {http.StatusInternalServerError, text{"Потоковая передача не поддерживается", "Streaming is not supported"}},
+	ServiceUnavailable:   {http.StatusServiceUnavailable, text{"Сервис временно недоступен", "Service is temporarily unavailable"}},
+}
+
+func describe(code Code) description {
+	if desc, ok := codes[code]; ok {
+		return desc
+	}
+	return codes[Internal]
+}
+
+// FieldCode — машиночитаемая причина ошибки в поле
+type FieldCode string
+
+const (
+	FieldRequired       FieldCode = "required"
+	FieldInvalid        FieldCode = "invalid"
+	FieldInvalidFormat  FieldCode = "invalid_format"
+	FieldInvalidEmail   FieldCode = "invalid_email"
+	FieldTooShort       FieldCode = "too_short"
+	FieldTooLong        FieldCode = "too_long"
+	FieldNegative       FieldCode = "negative"
+	FieldTooMany        FieldCode = "too_many"
+	FieldDuplicate      FieldCode = "duplicate"
+	FieldImmutable      FieldCode = "immutable"
+	FieldInvalidURL     FieldCode = "invalid_url"
+	FieldPrivateURL     FieldCode = "private_url"
+	FieldTimezone       FieldCode = "unknown_timezone"
+	FieldNoOccurrences  FieldCode = "no_occurrences"
+	FieldTooFrequent    FieldCode = "too_frequent"
+	FieldTemplate       FieldCode = "invalid_template"
+	FieldTemplateAction FieldCode = "unsupported_template_action"
+	FieldOutputTooLong  FieldCode = "output_too_long"
+)
+
+// В тексты с %d подставляется FieldError.Limit
+var fieldTexts = map[FieldCode]text{
+	FieldRequired:       {"обязательное поле", "is required"},
+	FieldInvalid:        {"недопустимое значение", "is invalid"},
+	FieldInvalidFormat:  {"неверный формат", "has invalid format"},
+	FieldInvalidEmail:   {"неверный формат email", "is not a valid email address"},
+	FieldTooShort:       {"не короче %d символов", "must be at least %d characters long"},
+	FieldTooLong:        {"не длиннее %d байт", "must be at most %d bytes long"},
+	FieldNegative:       {"не может быть отрицательным", "must not be negative"},
+	FieldTooMany:        {"не больше %d элементов", "must contain at most %d items"},
+	FieldDuplicate:      {"значения повторяются", "must not contain duplicates"},
+	FieldImmutable:      {"нельзя изменить", "cannot be changed"},
+	FieldInvalidURL:     {"должен быть абсолютным http(s)-адресом", "must be an absolute http(s) URL"},
+	FieldPrivateURL:     {"не может вести во внутреннюю сеть", "must not point to a private network"},
+	FieldTimezone:       {"неизвестный часовой пояс", "is not a known time zone"},
+	FieldNoOccurrences:  {"нет срабатываний в будущем", "has no occurrences in the future"},
+	FieldTooFrequent:    {"срабатывает чаще раза в час", "must not fire more often than once an hour"},
+	FieldTemplate:       {"ошибка в шаблоне", "is not a valid template"},
+	FieldTemplateAction: {"range, template, define и block не поддерживаются", "range, template, define and block are not supported"},
+	FieldOutputTooLong:  {"результат шаблона длиннее %d байт", "must render to at most %d bytes"},
+}
@@ -0,0 +1,96 @@
+package problem
+
+import (
+	"errors"
+	"strings"
+	"sync"
+)
+
+// ErrValidation — ошибка во входных данных. Пакеты оборачивают её пояснением
+// (fmt.Errorf("%w: ...", ErrValidation)), которое попадает в detail ответа.
+var ErrValidation = errors.New("ошибка валидации")
+
+var (
+	mu       sync.RWMutex
+	mappings []mapping
+)
+
+type mapping struct {
+	target error
+	code   Code
+}
+
+// Register сопоставляет ошибку target и все ошибки, которые её оборачивают, с кодом ответа.
+// Все сопоставления собраны в одном месте при запуске сервера, чтобы один и тот же
+// сбой давал один и тот же ответ во всех обработчиках.
+func Register(target error, code Code) {
+	mu.Lock()
+	defer mu.Unlock()
+	mappings = append(mappings, mapping{target: target, code: code})
+}
+
+// classify определяет код ответа для ошибки, пояснение и ошибки полей
+func classify(err error) (Code, string, []FieldError) {
+	var invalid *ValidationError
+	if errors.As(err, &invalid) {
+		return ValidationFailed, "", invalid.Fields
+	}
+	if errors.Is(err, ErrValidation) {
+		return ValidationFailed, strings.TrimPrefix(err.Error(), ErrValidation.Error()+": "), nil
+	}
+
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, m := range mappings {
+		if errors.Is(err, m.target) {
+			return m.code, "", nil
+		}
+	}
+	return Internal, "", nil
+}
+
+// FieldError — ошибка в отдельном поле запроса. Message заполняется при ответе
+// на языке клиента; Limit — граница для too_short и too_long.
+type FieldError struct {
+	Field   string    `json:"field"`
+	Code    FieldCode `json:"code"`
+	Message string    `json:"message"`
+	Limit   int       `json:"limit,omitempty"`
+}
+
+// Field описывает ошибку в поле field
+func Field(field string, code FieldCode) FieldError {
+	return FieldError{Field: field, Code: code}
+}
+
+// FieldLimit описывает ошибку в поле field с границей допустимых значений
+func FieldLimit(field string, code FieldCode, limit int) FieldError {
+	return FieldError{Field: field, Code: code, Limit: limit}
+}
+
+func (f FieldError) message(lang Lang) string {
+	return fieldMessage(f.Code, f.Limit, lang)
+}
+
+// ValidationError — ошибка входных данных с перечнем неверных полей
+type ValidationError struct {
+	Fields []FieldError
+}
+
+// Invalid возвращает ошибку валидации для перечисленных полей
+func Invalid(fields ...FieldError) error {
+	return &ValidationError{Fields: fields}
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		parts = append(parts, f.Field+": "+f.message(Russian))
+	}
+	return ErrValidation.Error() + ": " + strings.Join(parts, "; ")
+}
+
+// Is позволяет проверять ошибки полей через errors.Is(err, ErrValidation)
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrValidation
+}
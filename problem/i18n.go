@@ -0,0 +1,57 @@
+package problem
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// Lang — язык сообщений об ошибках
+type Lang string
+
+const (
+	Russian Lang = "ru"
+	English Lang = "en"
+)
+
+// Первый язык — язык по умолчанию для клиентов без Accept-Language
+var matcher = language.NewMatcher([]language.Tag{language.Russian, language.English})
+
+// Language выбирает язык ответа по заголовку Accept-Language
+func Language(r *http.Request) Lang {
+	tags, _, err := language.ParseAcceptLanguage(r.Header.Get("Accept-Language"))
+	if err != nil || len(tags) == 0 {
+		return Russian
+	}
+	_, index, confidence := matcher.Match(tags...)
+	if confidence != language.No && index == 1 {
+		return English
+	}
+	return Russian
+}
+
+// text — сообщение на поддерживаемых языках
+type text struct {
+	ru, en string
+}
+
+func (t text) in(lang Lang) string {
+	if lang == English {
+		return t.en
+	}
+	return t.ru
+}
+
+func fieldMessage(code FieldCode, limit int, lang Lang) string {
+	t, ok := fieldTexts[code]
+	if !ok {
+		t = fieldTexts[FieldInvalid]
+	}
+	msg := t.in(lang)
+	if strings.Contains(msg, "%d") {
+		return fmt.Sprintf(msg, limit)
+	}
+	return msg
+}
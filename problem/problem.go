@@ -0,0 +1,73 @@
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+	"notes-api/logger"
+)
+
+// ContentType — тип тела ответа об ошибке по RFC 7807
+const ContentType = "application/problem+json"
+
+// typePrefix — префикс URI типа ошибки. URN не обязан открываться в браузере,
+// но однозначно определяет тип по коду.
+const typePrefix = "urn:notes-api:problem:"
+
+// Problem — тело ответа об ошибке (RFC 7807). Code — стабильный машиночитаемый код,
+// на который опираются клиенты; Title и Detail переведены на язык из Accept-Language.
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      Code         `json:"code"`
+	Errors    []FieldError `json:"errors,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+}
+
+// Write отвечает ошибкой с кодом code; статус берётся из описания кода
+func Write(w http.ResponseWriter, r *http.Request, code Code) {
+	write(w, r, code, "", nil)
+}
+
+// WriteDetail отвечает ошибкой с кодом code и пояснением detail. Пояснение не переводится,
+// поэтому подходит для данных, а не для текста: например, для списка допустимых значений.
+func WriteDetail(w http.ResponseWriter, r *http.Request, code Code, detail string) {
+	write(w, r, code, detail, nil)
+}
+
+// Error отвечает ошибкой err. Ошибки валидации превращаются в 400 с перечнем полей,
+// зарегистрированные через Register — в их код, остальные — в 500 без подробностей,
+// которые при этом пишутся в журнал.
+func Error(w http.ResponseWriter, r *http.Request, err error) {
+	code, detail, fields := classify(err)
+	if code == Internal {
+		logger.FromContext(r.Context()).WithError(err).Error("Необработанная ошибка")
+	}
+	write(w, r, code, detail, fields)
+}
+
+func write(w http.ResponseWriter, r *http.Request, code Code, detail string, fields []FieldError) {
+	lang := Language(r)
+	desc := describe(code)
+	p := Problem{
+		Type:      typePrefix + string(code),
+		Title:     desc.title.in(lang),
+		Status:    desc.status,
+		Detail:    detail,
+		Instance:  r.URL.Path,
+		Code:      code,
+		RequestID: logger.RequestID(r.Context()),
+	}
+	for _, f := range fields {
+		f.Message = f.message(lang)
+		p.Errors = append(p.Errors, f)
+	}
+
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("Content-Language", string(lang))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
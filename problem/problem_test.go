@@ -0,0 +1,133 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLanguage(t *testing.T) {
+	for header, want := range map[string]Lang{
+		"":                           Russian,
+		"en":                         English,
+		"en-US,en;q=0.9":             English,
+		"ru-RU,ru;q=0.9,en;q=0.8":    Russian,
+		"de-DE,en;q=0.5":             English,
+		"de-DE":                      Russian,
+		"fr;q=0.9,ru;q=0.5,en;q=0.7": English,
+		"не заголовок;;;":            Russian,
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if header != "" {
+			r.Header.Set("Accept-Language", header)
+		}
+		if got := Language(r); got != want {
+			t.Errorf("Language(%q) = %s, ожидался %s", header, got, want)
+		}
+	}
+}
+
+func TestClassify(t *testing.T) {
+	errFirst := errors.New("первая")
+	errSecond := errors.New("вторая")
+	Register(errFirst, NotFound)
+	Register(errSecond, Conflict)
+
+	tests := []struct {
+		name       string
+		err        error
+		wantCode   Code
+		wantDetail string
+		wantFields int
+	}{
+		{"ошибка полей", Invalid(Field("title", FieldRequired), Field("content", FieldRequired)), ValidationFailed, "", 2},
+		{"обёрнутая ошибка полей", fmt.Errorf("создание: %w", Invalid(Field("title", FieldRequired))), ValidationFailed, "", 1},
+		{"ErrValidation с пояснением", fmt.Errorf("%w: неверный курсор", ErrValidation), ValidationFailed, "неверный курсор", 0},
+		{"зарегистрированная", errSecond, Conflict, "", 0},
+		{"обёрнутая зарегистрированная", fmt.Errorf("сохранение: %w", errFirst), NotFound, "", 0},
+		{"порядок регистрации", errors.Join(errSecond, errFirst), NotFound, "", 0},
+		{"неизвестная", errors.New("сбой базы"), Internal, "", 0},
+	}
+	for _, tt := range tests {
+		code, detail, fields := classify(tt.err)
+		if code != tt.wantCode || detail != tt.wantDetail || len(fields) != tt.wantFields {
+			t.Errorf("%s: classify = %s, %q, %d полей; ожидалось %s, %q, %d", tt.name, code, detail, len(fields), tt.wantCode, tt.wantDetail, tt.wantFields)
+		}
+	}
+}
+
+func TestValidationError(t *testing.T) {
+	err := Invalid(FieldLimit("name", FieldTooLong, 100), Field("timezone", FieldTimezone))
+	if !errors.Is(err, ErrValidation) {
+		t.Error("ошибка полей не распознаётся как ErrValidation")
+	}
+	want := "ошибка валидации: name: не длиннее 100 байт; timezone: неизвестный часовой пояс"
+	if err.Error() != want {
+		t.Errorf("Error() = %q, ожидалось %q", err.Error(), want)
+	}
+}
+
+func TestFieldMessage(t *testing.T) {
+	tests := []struct {
+		code  FieldCode
+		limit int
+		lang  Lang
+		want  string
+	}{
+		{FieldRequired, 0, Russian, "обязательное поле"},
+		{FieldRequired, 0, English, "is required"},
+		{FieldTooShort, 6, Russian, "не короче 6 символов"},
+		{FieldTooMany, 20, English, "must contain at most 20 items"},
+		{"unknown", 0, English, "is invalid"},
+	}
+	for _, tt := range tests {
+		if got := fieldMessage(tt.code, tt.limit, tt.lang); got != tt.want {
+			t.Errorf("fieldMessage(%s, %d, %s) = %q, ожидалось %q", tt.code, tt.limit, tt.lang, got, tt.want)
+		}
+	}
+	for code, text := range fieldTexts {
+		if text.ru == "" || text.en == "" {
+			t.Errorf("у кода поля %s нет перевода", code)
+		}
+	}
+}
+
+func TestCodesDescribed(t *testing.T) {
+	for code, desc := range codes {
+		if desc.status < 400 || desc.title.ru == "" || desc.title.en == "" {
+			t.Errorf("код %s описан не полностью: %+v", code, desc)
+		}
+	}
+	if describe("unknown") != codes[Internal] {
+		t.Error("неизвестный код не сводится к internal_error")
+	}
+}
+
+func TestError(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/notes", nil)
+	r.Header.Set("Accept-Language", "en")
+	rec := httptest.NewRecorder()
+	Error(rec, r, Invalid(FieldLimit("password", FieldTooShort, 6)))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("статус %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != ContentType {
+		t.Errorf("Content-Type %q", ct)
+	}
+	if lang := rec.Header().Get("Content-Language"); lang != "en" {
+		t.Errorf("Content-Language %q", lang)
+	}
+
+	var p Problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil {
+		t.Fatal(err)
+	}
+	want := FieldError{Field: "password", Code: FieldTooShort, Message: "must be at least 6 characters long", Limit: 6}
+	if p.Code != ValidationFailed || p.Title != "Validation failed" || p.Instance != "/notes" || len(p.Errors) != 1 || p.Errors[0] != want {
+		t.Errorf("ответ %+v", p)
+	}
+}
@@ -0,0 +1,191 @@
+package reminders
+
+import (
+	"encoding/json"
+	"net/http"
+	"notes-api/httpx"
+	"notes-api/logger"
+	"notes-api/problem"
+	"strconv"
+)
+
+type ReminderHandler struct {
+	Service *ReminderService
+}
+
+// List godoc
+// @Summary Напоминания заметки
+// @Tags reminders
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path int true "ID заметки"
+// @Success 200 {array} model.Reminder "Напоминания"
+// @Failure 403 {object} problem.Problem "Доступ запрещен"
+// @Failure 404 {object} problem.Problem "Заметка не найдена"
+// @Router /notes/{id}/reminders [get]
+func (h *ReminderHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := httpx.UserID(w, r)
+	if !ok {
+		return
+	}
+	noteID, ok := httpx.PathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	reminders, err := h.Service.List(userID, noteID)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, reminders)
+}
+
+// Create godoc
+// @Summary Создать напоминание
+// @Description Разовое напоминание срабатывает в момент at. Если задано rrule (например FREQ=WEEKLY;BYDAY=MO,WE), повторения отсчитываются от at в часовом поясе timezone; повторять чаще раза в час нельзя. Каналы доставки: inapp — во входящие (GET /notifications), email — письмом, webhook — событием note.reminder
+// @Tags reminders
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "ID заметки"
+// @Param input body reminders.ReminderInput true "Напоминание"
+// @Success 201 {object} model.Reminder "Созданное напоминание"
+// @Failure 400 {object} problem.Problem "Неверный запрос"
+// @Failure 403 {object} problem.Problem "Доступ запрещен"
+// @Failure 404 {object} problem.Problem "Заметка не найдена"
+// @Failure 409 {object} problem.Problem "У заметки слишком много напоминаний"
+// @Router /notes/{id}/reminders [post]
+func (h *ReminderHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, ok := httpx.UserID(w, r)
+	if !ok {
+		return
+	}
+	noteID, ok := httpx.PathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var input ReminderInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		logger.FromContext(r.Context()).WithError(err).Warn("Неверный запрос")
+		problem.Write(w, r, problem.BadRequest)
+		return
+	}
+
+	reminder, err := h.Service.Create(userID, noteID, input)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	logger.FromContext(r.Context()).WithFields(logger.Fields{
+		"user_id":     userID,
+		"note_id":     noteID,
+		"reminder_id": reminder.ID,
+	}).Info("Напоминание создано")
+	httpx.WriteJSON(w, http.StatusCreated, reminder)
+}
+
+// Delete godoc
+// @Summary Удалить напоминание
+// @Tags reminders
+// @Security ApiKeyAuth
+// @Param id path int true "ID заметки"
+// @Param reminder_id path int true "ID напоминания"
+// @Success 204 "Напоминание удалено"
+// @Failure 403 {object} problem.Problem "Доступ запрещен"
+// @Failure 404 {object} problem.Problem "Напоминание не найдено"
+// @Router /notes/{id}/reminders/{reminder_id} [delete]
+func (h *ReminderHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	userID, ok := httpx.UserID(w, r)
+	if !ok {
+		return
+	}
+	noteID, ok := httpx.PathID(w, r, "id")
+	if !ok {
+		return
+	}
+	id, ok := httpx.PathID(w, r, "reminder_id")
+	if !ok {
+		return
+	}
+
+	if err := h.Service.Delete(userID, noteID, id); err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	logger.FromContext(r.Context()).WithFields(logger.Fields{
+		"user_id":     userID,
+		"reminder_id": id,
+	}).Info("Напоминание удалено")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Notifications godoc
+// @Summary Входящие уведомления
+// @Description Уведомления от напоминаний с каналом inapp, новые сверху
+// @Tags reminders
+// @Security ApiKeyAuth
+// @Produce json
+// @Param unread query bool false "Только непрочитанные"
+// @Param limit query int false "Количество (по умолчанию 50, максимум 200)"
+// @Success 200 {array} model.Notification "Уведомления"
+// @Failure 400 {object} problem.Problem "Неверные параметры"
+// @Router /notifications [get]
+func (h *ReminderHandler) Notifications(w http.ResponseWriter, r *http.Request) {
+	userID, ok := httpx.UserID(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	unread := query.Get("unread") == "true"
+	limit := 0
+	if raw := query.Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			problem.Error(w, r, problem.Invalid(problem.Field("limit", problem.FieldInvalid)))
+			return
+		}
+		limit = n
+	}
+
+	notifications, err := h.Service.Notifications(userID, unread, limit)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, notifications)
+}
+
+// MarkRead godoc
+// @Summary Отметить уведомление прочитанным
+// @Tags reminders
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path int true "ID уведомления"
+// @Success 200 {object} model.Notification "Уведомление"
+// @Failure 404 {object} problem.Problem "Уведомление не найдено"
+// @Router /notifications/{id}/read [post]
+func (h *ReminderHandler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	userID, ok := httpx.UserID(w, r)
+	if !ok {
+		return
+	}
+	id, ok := httpx.PathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	notification, err := h.Service.MarkRead(userID, id)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, notification)
+}
@@ -0,0 +1,84 @@
+package reminders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"notes-api/logger"
+	"strings"
+	"time"
+)
+
+var errBadRecipient = errors.New("некорректный адрес получателя")
+
+// Mailer отправляет письма
+type Mailer interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+// SMTPConfig — параметры SMTP-сервера
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// SMTPMailer отправляет письма через SMTP. Если сервер поддерживает STARTTLS,
+// net/smtp включает его сам; авторизация по паролю выполняется только поверх TLS.
+type SMTPMailer struct {
+	Config SMTPConfig
+}
+
+func NewSMTPMailer(cfg SMTPConfig) *SMTPMailer {
+	return &SMTPMailer{Config: cfg}
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, to, subject, body string) error {
+	if strings.ContainsAny(to, "\r\n") {
+		return errBadRecipient
+	}
+
+	addr := net.JoinHostPort(m.Config.Host, fmt.Sprint(m.Config.Port))
+
+	var auth smtp.Auth
+	if m.Config.Username != "" {
+		auth = smtp.PlainAuth("", m.Config.Username, m.Config.Password, m.Config.Host)
+	}
+
+	msg := strings.Join([]string{
+		"From: " + m.Config.From,
+		"To: " + to,
+		"Subject: " + mime.QEncoding.Encode("utf-8", subject),
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=utf-8",
+		"Content-Transfer-Encoding: 8bit",
+		"",
+		body,
+	}, "\r\n")
+
+	// net/smtp не принимает контекст, поэтому отправка идёт в горутине
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(addr, auth, m.Config.From, []string{to}, []byte(msg))
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// LogMailer пишет письма в лог вместо отправки; используется, когда SMTP не настроен
+type LogMailer struct{}
+
+func (LogMailer) Send(ctx context.Context, to, subject, body string) error {
+	logger.Log.WithField("subject", subject).Info("Письмо не отправлено: SMTP не настроен")
+	return nil
+}
@@ -0,0 +1,105 @@
+package reminders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"notes-api/events"
+	"notes-api/model"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Message — сработавшее напоминание, которое нужно доставить пользователю
+type Message struct {
+	Reminder model.Reminder
+	Note     model.Note
+	// FiredAt — плановый момент срабатывания
+	FiredAt time.Time
+}
+
+func (m Message) Title() string {
+	title := m.noteTitle()
+	if title == "" {
+		title = fmt.Sprintf("Заметка #%d", m.Note.ID)
+	}
+	return "Напоминание: " + title
+}
+
+func (m Message) Body() string {
+	body := fmt.Sprintf("Напоминание о заметке #%d.", m.Note.ID)
+	if title := m.noteTitle(); title != "" {
+		body = fmt.Sprintf("Напоминание о заметке «%s» (#%d).", title, m.Note.ID)
+	}
+	if m.Note.DueAt != nil {
+		loc := time.UTC
+		if l, err := time.LoadLocation(m.Reminder.Timezone); err == nil {
+			loc = l
+		}
+		body += "\nСрок: " + m.Note.DueAt.In(loc).Format("02.01.2006 15:04 MST")
+	}
+	return body
+}
+
+// noteTitle возвращает заголовок заметки; заголовок зашифрованной заметки — шифротекст,
+// и в уведомление он не попадает
+func (m Message) noteTitle() string {
+	if m.Note.Encrypted {
+		return ""
+	}
+	return m.Note.Title
+}
+
+// Notifier доставляет напоминание по одному каналу
+type Notifier interface {
+	Notify(ctx context.Context, m Message) error
+}
+
+// InAppNotifier кладёт уведомление во входящие пользователя (GET /notifications).
+// Повтор задачи не создаёт дубль: уведомление уникально по напоминанию и моменту срабатывания.
+type InAppNotifier struct {
+	DB *gorm.DB
+}
+
+func (n *InAppNotifier) Notify(ctx context.Context, m Message) error {
+	return n.DB.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&model.Notification{
+		UserID:     m.Reminder.UserID,
+		NoteID:     m.Note.ID,
+		ReminderID: m.Reminder.ID,
+		FiredAt:    m.FiredAt,
+		Title:      m.Title(),
+		Body:       m.Body(),
+	}).Error
+}
+
+// EmailNotifier отправляет письмо на адрес пользователя
+type EmailNotifier struct {
+	DB     *gorm.DB
+	Mailer Mailer
+}
+
+func (n *EmailNotifier) Notify(ctx context.Context, m Message) error {
+	var user model.User
+	if err := n.DB.WithContext(ctx).Select("id", "email").First(&user, m.Reminder.UserID).Error; err != nil {
+		return err
+	}
+	if user.Email == "" {
+		return errors.New("у пользователя не указан email")
+	}
+	return n.Mailer.Send(ctx, user.Email, m.Title(), m.Body())
+}
+
+// WebhookNotifier отправляет событие note.reminder на вебхуки пользователя, подписанные
+// на него. Доставку и повторы берёт на себя сервис вебхуков.
+type WebhookNotifier struct {
+	Webhooks events.Publisher
+}
+
+func (n *WebhookNotifier) Notify(ctx context.Context, m Message) error {
+	e := events.NewNoteEvent(events.NoteReminder, m.Note)
+	e.OccurredAt = m.FiredAt.UTC()
+	n.Webhooks.Publish(e)
+	return nil
+}
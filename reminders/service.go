@@ -0,0 +1,381 @@
+package reminders
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"notes-api/events"
+	"notes-api/jobs"
+	"notes-api/logger"
+	"notes-api/model"
+	"notes-api/problem"
+	storage "notes-api/repo"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/teambition/rrule-go"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// DispatchJobType — периодическая задача, которая находит наступившие напоминания
+	DispatchJobType = "reminders.dispatch"
+	// FireJobType — доставка одного срабатывания напоминания по одному каналу
+	FireJobType = "reminders.fire"
+
+	dispatchBatch    = 500
+	fireMaxAttempts  = 5
+	maxReminders     = 50
+	maxRRuleLength   = 500
+	defaultListLimit = 50
+	maxListLimit     = 200
+
+	// minInterval — напоминание не может срабатывать чаще: иначе оно превращается в спам
+	minInterval = time.Hour
+	// intervalCheckRuns — сколько первых срабатываний правила проверяется на minInterval.
+	// Срабатывания внутри периода правила (BYHOUR, BYMINUTE, BYSECOND) повторяются
+	// от периода к периоду, поэтому частые повторы видны уже в начале.
+	intervalCheckRuns = 500
+)
+
+var (
+	ErrReminderNotFound     = errors.New("напоминание не найдено")
+	ErrNotificationNotFound = errors.New("уведомление не найдено")
+	ErrTooManyReminders     = errors.New("у заметки слишком много напоминаний")
+)
+
+// ReminderInput — данные для создания напоминания
+type ReminderInput struct {
+	// At — первое (или единственное) срабатывание
+	At time.Time `json:"at"`
+	// RRule — правило повторения RFC 5545, например FREQ=DAILY;COUNT=5; пусто для разового напоминания
+	RRule string `json:"rrule"`
+	// Timezone — часовой пояс IANA для повторений (по умолчанию UTC)
+	Timezone string `json:"timezone" example:"Europe/Moscow"`
+	// Channels — каналы доставки (по умолчанию inapp)
+	Channels []string `json:"channels" enums:"inapp,email,webhook"`
+}
+
+type firePayload struct {
+	ReminderID uint      `json:"reminder_id"`
+	FiredAt    time.Time `json:"fired_at"`
+	Channel    string    `json:"channel"`
+}
+
+type ReminderService struct {
+	DB        *gorm.DB
+	Queue     *jobs.Queue
+	Notifiers map[string]Notifier
+}
+
+func NewReminderService(db *gorm.DB, q *jobs.Queue, notifiers map[string]Notifier) *ReminderService {
+	return &ReminderService{DB: db, Queue: q, Notifiers: notifiers}
+}
+
+// List возвращает напоминания заметки
+func (s *ReminderService) List(userID, noteID uint) ([]model.Reminder, error) {
+	if err := storage.CheckNoteOwner(s.DB, userID, noteID); err != nil {
+		return nil, err
+	}
+
+	reminders := []model.Reminder{}
+	err := s.DB.Where("note_id = ?", noteID).Order("id").Find(&reminders).Error
+	return reminders, err
+}
+
+func (s *ReminderService) Create(userID, noteID uint, input ReminderInput) (model.Reminder, error) {
+	if err := storage.CheckNoteOwner(s.DB, userID, noteID); err != nil {
+		return model.Reminder{}, err
+	}
+
+	reminder := model.Reminder{
+		UserID:   userID,
+		NoteID:   noteID,
+		At:       input.At.UTC(),
+		RRule:    strings.TrimPrefix(strings.TrimSpace(input.RRule), "RRULE:"),
+		Timezone: input.Timezone,
+		Channels: input.Channels,
+	}
+	if reminder.Timezone == "" {
+		reminder.Timezone = "UTC"
+	}
+	if len(reminder.Channels) == 0 {
+		reminder.Channels = []string{model.ChannelInApp}
+	}
+	if err := s.validate(reminder); err != nil {
+		return model.Reminder{}, err
+	}
+
+	next, err := nextRun(reminder, time.Now())
+	if err != nil {
+		return model.Reminder{}, err
+	}
+	if next == nil {
+		return model.Reminder{}, problem.Invalid(problem.Field("at", problem.FieldNoOccurrences))
+	}
+	reminder.NextRunAt = next
+
+	var count int64
+	if err := s.DB.Model(&model.Reminder{}).Where("note_id = ?", noteID).Count(&count).Error; err != nil {
+		return model.Reminder{}, err
+	}
+	if count >= maxReminders {
+		return model.Reminder{}, ErrTooManyReminders
+	}
+
+	if err := s.DB.Create(&reminder).Error; err != nil {
+		return model.Reminder{}, err
+	}
+	return reminder, nil
+}
+
+func (s *ReminderService) Delete(userID, noteID, id uint) error {
+	if err := storage.CheckNoteOwner(s.DB, userID, noteID); err != nil {
+		return err
+	}
+
+	res := s.DB.Where("id = ? AND note_id = ?", id, noteID).Delete(&model.Reminder{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrReminderNotFound
+	}
+	return nil
+}
+
+// Notifications возвращает входящие уведомления пользователя, новые сверху
+func (s *ReminderService) Notifications(userID uint, unreadOnly bool, limit int) ([]model.Notification, error) {
+	if limit <= 0 {
+		limit = defaultListLimit
+	}
+	limit = min(limit, maxListLimit)
+
+	q := s.DB.Where("user_id = ?", userID)
+	if unreadOnly {
+		q = q.Where("read_at IS NULL")
+	}
+	notifications := []model.Notification{}
+	err := q.Order("id DESC").Limit(limit).Find(&notifications).Error
+	return notifications, err
+}
+
+// MarkRead отмечает уведомление прочитанным
+func (s *ReminderService) MarkRead(userID, id uint) (model.Notification, error) {
+	var notification model.Notification
+	if err := s.DB.Where("id = ? AND user_id = ?", id, userID).First(&notification).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.Notification{}, ErrNotificationNotFound
+		}
+		return model.Notification{}, err
+	}
+	if notification.ReadAt == nil {
+		now := time.Now()
+		notification.ReadAt = &now
+		if err := s.DB.Model(&notification).Update("read_at", now).Error; err != nil {
+			return model.Notification{}, err
+		}
+	}
+	return notification, nil
+}
+
+// Publish реализует events.Publisher: напоминания удалённой заметки удаляются вместе с ней
+func (s *ReminderService) Publish(e events.Event) {
+	if e.Type != events.NoteDeleted {
+		return
+	}
+	if err := s.DB.Where("note_id = ?", e.Note.ID).Delete(&model.Reminder{}).Error; err != nil {
+		logger.Log.WithError(err).WithField("note_id", e.Note.ID).Error("Не удалось удалить напоминания заметки")
+	}
+}
+
+// HandleDispatchJob ставит в очередь доставку наступивших напоминаний и переносит их
+// на следующее срабатывание. Пропущенные (например, пока сервис не работал) повторения
+// не догоняются: напоминание срабатывает один раз и переходит к ближайшему будущему.
+func (s *ReminderService) HandleDispatchJob(ctx context.Context, job *model.Job) error {
+	now := time.Now()
+	fired := 0
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var due []model.Reminder
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("next_run_at <= ?", now).
+			Order("next_run_at").
+			Limit(dispatchBatch).
+			Find(&due).Error
+		if err != nil {
+			return err
+		}
+
+		for _, reminder := range due {
+			firedAt := *reminder.NextRunAt
+			for _, channel := range reminder.Channels {
+				// Ключ не даёт дважды доставить одно срабатывание, если транзакция откатится после постановки
+				_, err := s.Queue.Enqueue(ctx, FireJobType, firePayload{ReminderID: reminder.ID, FiredAt: firedAt, Channel: channel}, jobs.Options{
+					UserID:      reminder.UserID,
+					UniqueKey:   fmt.Sprintf("%s:%d:%d:%s", FireJobType, reminder.ID, firedAt.Unix(), channel),
+					MaxAttempts: fireMaxAttempts,
+				})
+				if err != nil {
+					return err
+				}
+			}
+
+			next, err := nextRun(reminder, now)
+			if err != nil {
+				// Правило проверяется при создании, но на всякий случай не даём ему срабатывать бесконечно
+				logger.Log.WithError(err).WithField("reminder_id", reminder.ID).Warn("Некорректное правило повторения напоминания")
+				next = nil
+			}
+			err = tx.Model(&reminder).Updates(map[string]any{
+				"next_run_at":   next,
+				"last_fired_at": firedAt,
+			}).Error
+			if err != nil {
+				return err
+			}
+			fired++
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if fired > 0 {
+		logger.Log.WithField("count", fired).Info("Напоминания отправлены на доставку")
+	}
+	return nil
+}
+
+// HandleFireJob доставляет срабатывание напоминания по одному каналу
+func (s *ReminderService) HandleFireJob(ctx context.Context, job *model.Job) error {
+	var p firePayload
+	if err := json.Unmarshal(job.Payload, &p); err != nil {
+		return jobs.Permanent(err)
+	}
+
+	notifier, ok := s.Notifiers[p.Channel]
+	if !ok {
+		return jobs.Permanent(fmt.Errorf("канал доставки %q не настроен", p.Channel))
+	}
+
+	var reminder model.Reminder
+	if err := s.DB.WithContext(ctx).First(&reminder, p.ReminderID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Напоминание удалили вместе с заметкой или вручную
+			return nil
+		}
+		return err
+	}
+	var note model.Note
+	if err := s.DB.WithContext(ctx).First(&note, reminder.NoteID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	if err := notifier.Notify(ctx, Message{Reminder: reminder, Note: note, FiredAt: p.FiredAt}); err != nil {
+		return err
+	}
+
+	logger.Log.WithFields(logger.Fields{
+		"reminder_id": reminder.ID,
+		"note_id":     note.ID,
+		"channel":     p.Channel,
+	}).Info("Напоминание доставлено")
+	return nil
+}
+
+func (s *ReminderService) validate(r model.Reminder) error {
+	var fields []problem.FieldError
+	if r.At.IsZero() {
+		fields = append(fields, problem.Field("at", problem.FieldRequired))
+	}
+	if _, err := time.LoadLocation(r.Timezone); err != nil {
+		fields = append(fields, problem.Field("timezone", problem.FieldTimezone))
+	}
+	if len(r.RRule) > maxRRuleLength {
+		fields = append(fields, problem.FieldLimit("rrule", problem.FieldTooLong, maxRRuleLength))
+	}
+	fields = append(fields, s.validateChannels(r.Channels)...)
+	if len(fields) > 0 {
+		return problem.Invalid(fields...)
+	}
+	return nil
+}
+
+func (s *ReminderService) validateChannels(channels []string) []problem.FieldError {
+	// Без повторов каналов не больше, чем доставщиков; заодно не перебираем огромный список
+	if len(channels) > len(s.Notifiers) {
+		return []problem.FieldError{problem.Field("channels", problem.FieldDuplicate)}
+	}
+	for i, channel := range channels {
+		if _, ok := s.Notifiers[channel]; !ok {
+			return []problem.FieldError{problem.Field("channels", problem.FieldInvalid)}
+		}
+		if slices.Contains(channels[:i], channel) {
+			return []problem.FieldError{problem.Field("channels", problem.FieldDuplicate)}
+		}
+	}
+	return nil
+}
+
+// nextRun возвращает первое срабатывание напоминания строго после after или nil,
+// если срабатываний больше не будет
+func nextRun(r model.Reminder, after time.Time) (*time.Time, error) {
+	if r.RRule == "" {
+		if r.At.After(after) {
+			at := r.At
+			return &at, nil
+		}
+		return nil, nil
+	}
+
+	loc, err := time.LoadLocation(r.Timezone)
+	if err != nil {
+		return nil, problem.Invalid(problem.Field("timezone", problem.FieldTimezone))
+	}
+	opt, err := rrule.StrToROptionInLocation(r.RRule, loc)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", problem.Invalid(problem.Field("rrule", problem.FieldInvalidFormat)), err)
+	}
+	// Начало повторений — всегда at, DTSTART из правила не используется
+	opt.Dtstart = r.At.In(loc)
+	rule, err := rrule.NewRRule(*opt)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", problem.Invalid(problem.Field("rrule", problem.FieldInvalidFormat)), err)
+	}
+	// Частоту нельзя проверить по одному FREQ: FREQ=HOURLY;BYMINUTE=0,1,2 срабатывает каждую минуту
+	if tooFrequent(rule) {
+		return nil, problem.Invalid(problem.Field("rrule", problem.FieldTooFrequent))
+	}
+
+	next := rule.After(after, false)
+	if next.IsZero() {
+		return nil, nil
+	}
+	next = next.UTC()
+	return &next, nil
+}
+
+// tooFrequent сообщает, что между какими-то из первых intervalCheckRuns срабатываний
+// правила проходит меньше minInterval
+func tooFrequent(rule *rrule.RRule) bool {
+	next := rule.Iterator()
+	prev, ok := next()
+	for i := 1; ok && i < intervalCheckRuns; i++ {
+		var current time.Time
+		if current, ok = next(); !ok {
+			break
+		}
+		if current.Sub(prev) < minInterval {
+			return true
+		}
+		prev = current
+	}
+	return false
+}
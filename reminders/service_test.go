@@ -0,0 +1,93 @@
+package reminders
+
+import (
+	"errors"
+	"notes-api/model"
+	"notes-api/problem"
+	"testing"
+	"time"
+)
+
+// fieldError возвращает первую ошибку поля из ошибки валидации
+func fieldError(err error) (problem.FieldError, bool) {
+	var invalid *problem.ValidationError
+	if !errors.As(err, &invalid) || len(invalid.Fields) == 0 {
+		return problem.FieldError{}, false
+	}
+	return invalid.Fields[0], true
+}
+
+func TestNextRun(t *testing.T) {
+	at := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		rrule string
+		tz    string
+		after time.Time
+		want  time.Time
+	}{
+		{"разовое в будущем", "", "UTC", at.Add(-time.Minute), at},
+		{"разовое в прошлом", "", "UTC", at.Add(time.Minute), time.Time{}},
+		{"ежедневно", "FREQ=DAILY", "UTC", at, at.AddDate(0, 0, 1)},
+		{"ежечасно", "FREQ=HOURLY", "UTC", at.Add(30 * time.Minute), at.Add(time.Hour)},
+		{"раз в 90 минут", "FREQ=MINUTELY;INTERVAL=90", "UTC", at, at.Add(90 * time.Minute)},
+		{"два раза в день", "FREQ=DAILY;BYHOUR=9,18", "UTC", at, at.Add(9 * time.Hour)},
+		{"по будням в часовом поясе", "FREQ=WEEKLY;BYDAY=MO,WE", "Europe/Moscow", at, at.AddDate(0, 0, 2)},
+		{"закончилось", "FREQ=DAILY;COUNT=2", "UTC", at.AddDate(0, 0, 1), time.Time{}},
+		{"до даты UNTIL", "FREQ=DAILY;UNTIL=20260305T000000Z", "UTC", at.AddDate(0, 0, 1), at.AddDate(0, 0, 2)},
+		{"после даты UNTIL", "FREQ=DAILY;UNTIL=20260305T000000Z", "UTC", at.AddDate(0, 0, 2), time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next, err := nextRun(model.Reminder{At: at, RRule: tt.rrule, Timezone: tt.tz}, tt.after)
+			if err != nil {
+				t.Fatalf("nextRun: %v", err)
+			}
+			switch {
+			case tt.want.IsZero() && next != nil:
+				t.Errorf("ожидалось, что срабатываний больше нет, получено %v", *next)
+			case !tt.want.IsZero() && (next == nil || !next.Equal(tt.want)):
+				t.Errorf("nextRun = %v, ожидалось %v", next, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextRunRejectsFrequentRules(t *testing.T) {
+	at := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	rules := []string{
+		"FREQ=MINUTELY",
+		"FREQ=SECONDLY;INTERVAL=3599",
+		"FREQ=MINUTELY;INTERVAL=59",
+		"FREQ=HOURLY;BYMINUTE=0,1,2,3,4,5,6,7,8,9,10,11,12,13,14,15,16,17,18,19,20,21,22,23,24,25,26,27,28,29,30,31,32,33,34,35,36,37,38,39,40,41,42,43,44,45,46,47,48,49,50,51,52,53,54,55,56,57,58,59",
+		"FREQ=HOURLY;BYSECOND=0,30",
+		"FREQ=HOURLY;BYMINUTE=0,30",
+		"FREQ=DAILY;BYHOUR=9;BYMINUTE=0,59",
+		"FREQ=WEEKLY;BYDAY=FR;BYHOUR=18;BYMINUTE=0,15,30,45",
+		"FREQ=YEARLY;BYMONTH=12;BYMONTHDAY=31;BYHOUR=23;BYMINUTE=0,1",
+		"FREQ=DAILY;BYHOUR=9,10;BYMINUTE=30;BYSECOND=0,1",
+	}
+	for _, rule := range rules {
+		_, err := nextRun(model.Reminder{At: at, RRule: rule, Timezone: "UTC"}, at)
+		if f, ok := fieldError(err); !ok || f.Field != "rrule" || f.Code != problem.FieldTooFrequent {
+			t.Errorf("правило %s: %v, ожидалась ошибка rrule %s", rule, err, problem.FieldTooFrequent)
+		}
+	}
+}
+
+func TestNextRunInvalid(t *testing.T) {
+	at := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	for _, tt := range []struct {
+		reminder model.Reminder
+		want     problem.FieldError
+	}{
+		{model.Reminder{At: at, RRule: "FREQ=SOMETIMES", Timezone: "UTC"}, problem.Field("rrule", problem.FieldInvalidFormat)},
+		{model.Reminder{At: at, RRule: "BYHOUR=9", Timezone: "UTC"}, problem.Field("rrule", problem.FieldInvalidFormat)},
+		{model.Reminder{At: at, RRule: "FREQ=DAILY", Timezone: "Mars/Olympus"}, problem.Field("timezone", problem.FieldTimezone)},
+	} {
+		_, err := nextRun(tt.reminder, at)
+		if f, ok := fieldError(err); !ok || f != tt.want {
+			t.Errorf("nextRun(%q, %q): %v, ожидалась ошибка %s %s", tt.reminder.RRule, tt.reminder.Timezone, err, tt.want.Field, tt.want.Code)
+		}
+	}
+}
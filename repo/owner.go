@@ -0,0 +1,31 @@
+package storage
+
+import (
+	"errors"
+	"notes-api/model"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrNoteNotFound — заметки с таким ID нет
+	ErrNoteNotFound = errors.New("заметка не найдена")
+	// ErrForbidden — заметка принадлежит другому пользователю
+	ErrForbidden = errors.New("доступ запрещен")
+)
+
+// CheckNoteOwner проверяет, что заметка noteID существует и принадлежит пользователю userID.
+// Нужна пакетам, которые хранят данные заметки отдельно и не загружают её саму.
+func CheckNoteOwner(db *gorm.DB, userID, noteID uint) error {
+	var note model.Note
+	if err := db.Select("id", "user_id").First(&note, noteID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNoteNotFound
+		}
+		return err
+	}
+	if note.UserID != userID {
+		return ErrForbidden
+	}
+	return nil
+}
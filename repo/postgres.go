@@ -1,11 +1,21 @@
 package storage
 
 import (
+	"context"
+	"errors"
 	"notes-api/model"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ErrConflict — заметка изменилась с момента, на который рассчитывал клиент
+var ErrConflict = errors.New("заметка изменена на сервере")
+
+// ErrNoteArchived — архивную заметку нельзя закрепить, сначала её нужно вернуть из архива
+var ErrNoteArchived = errors.New("заметка в архиве")
+
 type PostgresStore struct {
 	DB *gorm.DB
 }
@@ -17,13 +27,35 @@ type NoteRepository interface {
 	Update(id int, updated model.Note) (model.Note, error)
 	Delete(id int) error
 
-	GetByUserID(userID int) ([]model.Note, error)
+	GetByUserID(userID int, includeArchived bool) ([]model.Note, error)
+	GetDueBefore(userID uint, before time.Time, includeArchived bool) ([]model.Note, error)
+	SetDueAt(id int, dueAt *time.Time) (model.Note, error)
+	SetPinned(id int, pinned bool, position *int) (model.Note, error)
+	SetArchived(id int, archived bool) (model.Note, error)
+	SetStarred(id int, starred bool) (model.Note, error)
+	EachByUserID(userID int, fn func(note model.Note) error) error
+
+	UpdateIfSeq(id int, baseSeq int64, updated model.Note) (model.Note, error)
+	DeleteIfSeq(id int, baseSeq int64) (model.NoteTombstone, error)
+	ChangesSince(userID uint, since int64, limit int) ([]model.Note, []model.NoteTombstone, error)
+	GetTombstone(userID uint, noteID uint) (model.NoteTombstone, error)
+	BackfillChangeSeq(userID uint) error
+
+	// WithContext возвращает репозиторий, выполняющий запросы в контексте ctx
+	WithContext(ctx context.Context) NoteRepository
 }
 
+// exportBatchSize — сколько заметок за раз читается из базы при потоковом обходе
+const exportBatchSize = 100
+
 func NewPostgresStore(db *gorm.DB) *PostgresStore {
 	return &PostgresStore{DB: db}
 }
 
+func (s *PostgresStore) WithContext(ctx context.Context) NoteRepository {
+	return &PostgresStore{DB: s.DB.WithContext(ctx)}
+}
+
 func (s *PostgresStore) GetAll() ([]model.Note, error) {
 	var notes []model.Note
 	if err := s.DB.Find(&notes).Error; err != nil {
@@ -41,38 +73,281 @@ func (s *PostgresStore) GetByID(id int) (model.Note, error) {
 }
 
 func (s *PostgresStore) Create(note model.Note) (model.Note, error) {
-	if err := s.DB.Create(&note).Error; err != nil {
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		seq, err := nextChangeSeq(tx, note.UserID)
+		if err != nil {
+			return err
+		}
+		note.ChangeSeq = seq
+		note.Position = 0
+		// Архив важнее закрепления, как и в SetArchived
+		if note.Archived {
+			note.Pinned = false
+		}
+		if note.Pinned {
+			if note.Position, err = nextPosition(tx, note.UserID); err != nil {
+				return err
+			}
+		}
+		return tx.Create(&note).Error
+	})
+	if err != nil {
 		return model.Note{}, err
 	}
 	return note, nil
 }
 
 func (s *PostgresStore) Update(id int, updated model.Note) (model.Note, error) {
+	return s.update(id, nil, updated)
+}
+
+// UpdateIfSeq обновляет заметку, только если её ChangeSeq всё ещё равен baseSeq
+func (s *PostgresStore) UpdateIfSeq(id int, baseSeq int64, updated model.Note) (model.Note, error) {
+	return s.update(id, &baseSeq, updated)
+}
+
+func (s *PostgresStore) update(id int, baseSeq *int64, updated model.Note) (model.Note, error) {
+	return s.modify(id, baseSeq, func(_ *gorm.DB, note *model.Note) error {
+		note.Title = updated.Title
+		note.Content = updated.Content
+		return nil
+	})
+}
+
+// SetDueAt меняет срок заметки; nil снимает срок
+func (s *PostgresStore) SetDueAt(id int, dueAt *time.Time) (model.Note, error) {
+	return s.modify(id, nil, func(_ *gorm.DB, note *model.Note) error {
+		note.DueAt = dueAt
+		return nil
+	})
+}
+
+// SetPinned закрепляет или открепляет заметку. Закреплённая без position встаёт в конец
+// списка закреплённых; уже закреплённая без position остаётся на месте. Архивную
+// заметку закрепить нельзя: ErrNoteArchived.
+func (s *PostgresStore) SetPinned(id int, pinned bool, position *int) (model.Note, error) {
+	return s.modify(id, nil, func(tx *gorm.DB, note *model.Note) error {
+		switch {
+		case pinned && note.Archived:
+			return ErrNoteArchived
+		case !pinned:
+			note.Position = 0
+		case position != nil:
+			note.Position = *position
+		case !note.Pinned:
+			next, err := nextPosition(tx, note.UserID)
+			if err != nil {
+				return err
+			}
+			note.Position = next
+		}
+		note.Pinned = pinned
+		return nil
+	})
+}
+
+// SetArchived переносит заметку в архив или возвращает из него. Архивная заметка
+// открепляется, чтобы после возврата не занимать старое место среди закреплённых.
+func (s *PostgresStore) SetArchived(id int, archived bool) (model.Note, error) {
+	return s.modify(id, nil, func(_ *gorm.DB, note *model.Note) error {
+		note.Archived = archived
+		if archived {
+			note.Pinned = false
+			note.Position = 0
+		}
+		return nil
+	})
+}
+
+func (s *PostgresStore) SetStarred(id int, starred bool) (model.Note, error) {
+	return s.modify(id, nil, func(_ *gorm.DB, note *model.Note) error {
+		note.Starred = starred
+		return nil
+	})
+}
+
+// modify меняет заметку под блокировкой строки и выдаёт ей новый номер изменения
+func (s *PostgresStore) modify(id int, baseSeq *int64, change func(tx *gorm.DB, note *model.Note) error) (model.Note, error) {
 	var note model.Note
-	if err := s.DB.Find(&note, id).Error; err != nil {
-		return model.Note{}, err
-	}
-	note.Title = updated.Title
-	note.Content = updated.Content
-	if err := s.DB.Save(&note).Error; err != nil {
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&note, id).Error; err != nil {
+			return err
+		}
+		if baseSeq != nil && note.ChangeSeq != *baseSeq {
+			return ErrConflict
+		}
+
+		seq, err := nextChangeSeq(tx, note.UserID)
+		if err != nil {
+			return err
+		}
+		if err := change(tx, &note); err != nil {
+			return err
+		}
+		note.ChangeSeq = seq
+		return tx.Save(&note).Error
+	})
+	if err != nil {
 		return model.Note{}, err
 	}
 	return note, nil
 }
 
 func (s *PostgresStore) Delete(id int) error {
-	var note model.Note
-	if err := s.DB.Find(&note, id).Error; err != nil {
-		return err
+	_, err := s.delete(id, nil)
+	return err
+}
+
+// DeleteIfSeq удаляет заметку, только если её ChangeSeq всё ещё равен baseSeq
+func (s *PostgresStore) DeleteIfSeq(id int, baseSeq int64) (model.NoteTombstone, error) {
+	return s.delete(id, &baseSeq)
+}
+
+// delete удаляет заметку и оставляет вместо неё tombstone для клиентов синхронизации
+func (s *PostgresStore) delete(id int, baseSeq *int64) (model.NoteTombstone, error) {
+	var tombstone model.NoteTombstone
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		var note model.Note
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&note, id).Error; err != nil {
+			return err
+		}
+		if baseSeq != nil && note.ChangeSeq != *baseSeq {
+			return ErrConflict
+		}
+
+		seq, err := nextChangeSeq(tx, note.UserID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Delete(&note).Error; err != nil {
+			return err
+		}
+
+		tombstone = model.NoteTombstone{
+			UserID:    note.UserID,
+			NoteID:    note.ID,
+			ChangeSeq: seq,
+			DeletedAt: time.Now(),
+		}
+		return tx.Create(&tombstone).Error
+	})
+	return tombstone, err
+}
+
+// GetByUserID возвращает заметки пользователя: сначала закреплённые по position, затем остальные
+func (s *PostgresStore) GetByUserID(userID int, includeArchived bool) ([]model.Note, error) {
+	var notes []model.Note
+	err := withArchived(s.DB.Where("user_id = ?", userID), includeArchived).
+		Order("pinned DESC, position, id").
+		Find(&notes).Error
+	return notes, err
+}
+
+// GetDueBefore возвращает заметки пользователя со сроком раньше before, ближайшие первыми
+func (s *PostgresStore) GetDueBefore(userID uint, before time.Time, includeArchived bool) ([]model.Note, error) {
+	var notes []model.Note
+	err := withArchived(s.DB.Where("user_id = ? AND due_at < ?", userID, before), includeArchived).
+		Order("due_at, id").
+		Find(&notes).Error
+	return notes, err
+}
+
+func withArchived(q *gorm.DB, includeArchived bool) *gorm.DB {
+	if includeArchived {
+		return q
 	}
-	if err := s.DB.Delete(&note).Error; err != nil {
-		return err
+	return q.Where("archived = ?", false)
+}
+
+// EachByUserID обходит заметки пользователя пачками, не загружая их все в память
+func (s *PostgresStore) EachByUserID(userID int, fn func(note model.Note) error) error {
+	var batch []model.Note
+	return s.DB.Where("user_id = ?", userID).FindInBatches(&batch, exportBatchSize, func(tx *gorm.DB, _ int) error {
+		for _, note := range batch {
+			if err := fn(note); err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
+}
+
+// nextChangeSeq выдаёт следующий номер изменения пользователя. UPDATE блокирует
+// строку пользователя до конца транзакции, поэтому изменения одного пользователя
+// фиксируются строго в порядке номеров и клиент синхронизации не пропустит ни одно.
+func nextChangeSeq(tx *gorm.DB, userID uint) (int64, error) {
+	var seq int64
+	res := tx.Raw("UPDATE users SET change_seq = change_seq + 1 WHERE id = ? RETURNING change_seq", userID).Scan(&seq)
+	if res.Error != nil {
+		return 0, res.Error
 	}
-	return nil
+	if res.RowsAffected == 0 {
+		return 0, gorm.ErrRecordNotFound
+	}
+	return seq, nil
+}
+
+// nextPosition выдаёт позицию в конце списка закреплённых заметок пользователя
+func nextPosition(tx *gorm.DB, userID uint) (int, error) {
+	var last int
+	err := tx.Model(&model.Note{}).
+		Where("user_id = ? AND pinned", userID).
+		Select("COALESCE(MAX(position), 0)").
+		Scan(&last).Error
+	return last + 1, err
 }
 
-func (s *PostgresStore) GetByUserID(userID int) ([]model.Note, error) {
+// ChangesSince возвращает заметки и tombstones пользователя с номером изменения больше since,
+// всего не больше limit записей в порядке номеров
+func (s *PostgresStore) ChangesSince(userID uint, since int64, limit int) ([]model.Note, []model.NoteTombstone, error) {
 	var notes []model.Note
-	err := s.DB.Where("user_id = ?", userID).Find(&notes).Error
-	return notes, err
+	err := s.DB.Where("user_id = ? AND change_seq > ?", userID, since).Order("change_seq").Limit(limit).Find(&notes).Error
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var tombstones []model.NoteTombstone
+	err = s.DB.Where("user_id = ? AND change_seq > ?", userID, since).Order("change_seq").Limit(limit).Find(&tombstones).Error
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Из двух отсортированных списков оставляем limit записей с наименьшими номерами
+	var n, t int
+	for n+t < limit && (n < len(notes) || t < len(tombstones)) {
+		if t >= len(tombstones) || (n < len(notes) && notes[n].ChangeSeq < tombstones[t].ChangeSeq) {
+			n++
+		} else {
+			t++
+		}
+	}
+	return notes[:n], tombstones[:t], nil
+}
+
+func (s *PostgresStore) GetTombstone(userID uint, noteID uint) (model.NoteTombstone, error) {
+	var tombstone model.NoteTombstone
+	err := s.DB.Where("user_id = ? AND note_id = ?", userID, noteID).Order("change_seq DESC").First(&tombstone).Error
+	return tombstone, err
+}
+
+// BackfillChangeSeq выдаёт номера изменений заметкам, созданным до появления синхронизации
+func (s *PostgresStore) BackfillChangeSeq(userID uint) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		var ids []uint
+		err := tx.Model(&model.Note{}).Where("user_id = ? AND change_seq = 0", userID).Order("id").Pluck("id", &ids).Error
+		if err != nil {
+			return err
+		}
+
+		for _, id := range ids {
+			seq, err := nextChangeSeq(tx, userID)
+			if err != nil {
+				return err
+			}
+			if err := tx.Model(&model.Note{}).Where("id = ?", id).UpdateColumn("change_seq", seq).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
@@ -0,0 +1,147 @@
+package storage
+
+import (
+	"errors"
+	"notes-api/db/dbtest"
+	_ "notes-api/encryption"
+	"notes-api/model"
+	"slices"
+	"testing"
+)
+
+func newStore(t *testing.T) (*PostgresStore, uint) {
+	t.Helper()
+	db := dbtest.Open(t, &model.User{}, &model.Note{}, &model.NoteTombstone{})
+	user := model.User{Email: "user@example.com"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("создать пользователя: %v", err)
+	}
+	return NewPostgresStore(db), user.ID
+}
+
+func TestCreateArchivedNoteIsNotPinned(t *testing.T) {
+	store, userID := newStore(t)
+
+	note, err := store.Create(model.Note{UserID: userID, Title: "a", Pinned: true, Archived: true})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if note.Pinned || note.Position != 0 {
+		t.Errorf("архивная заметка создана закреплённой: pinned=%v, position=%d", note.Pinned, note.Position)
+	}
+
+	stored, err := store.GetByID(int(note.ID))
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if stored.Pinned || !stored.Archived {
+		t.Errorf("в базе pinned=%v, archived=%v", stored.Pinned, stored.Archived)
+	}
+}
+
+func TestSetPinnedRejectsArchivedNote(t *testing.T) {
+	store, userID := newStore(t)
+
+	note, err := store.Create(model.Note{UserID: userID, Title: "a", Archived: true})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, err := store.SetPinned(int(note.ID), true, nil); !errors.Is(err, ErrNoteArchived) {
+		t.Fatalf("SetPinned архивной заметки: %v", err)
+	}
+	stored, err := store.GetByID(int(note.ID))
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if stored.Pinned || stored.ChangeSeq != note.ChangeSeq {
+		t.Errorf("отклонённое закрепление изменило заметку: pinned=%v, change_seq %d → %d", stored.Pinned, note.ChangeSeq, stored.ChangeSeq)
+	}
+
+	// Открепить архивную заметку можно, после возврата из архива — закрепить
+	if _, err := store.SetPinned(int(note.ID), false, nil); err != nil {
+		t.Errorf("открепление архивной заметки: %v", err)
+	}
+	if _, err := store.SetArchived(int(note.ID), false); err != nil {
+		t.Fatalf("SetArchived: %v", err)
+	}
+	pinned, err := store.SetPinned(int(note.ID), true, nil)
+	if err != nil {
+		t.Fatalf("SetPinned после возврата из архива: %v", err)
+	}
+	if !pinned.Pinned || pinned.Position != 1 {
+		t.Errorf("pinned=%v, position=%d, ожидалось закрепление на позиции 1", pinned.Pinned, pinned.Position)
+	}
+}
+
+func TestCheckNoteOwner(t *testing.T) {
+	store, userID := newStore(t)
+	note, err := store.Create(model.Note{UserID: userID, Title: "a", Content: "b"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	if err := CheckNoteOwner(store.DB, userID, note.ID); err != nil {
+		t.Errorf("владелец: %v", err)
+	}
+	if err := CheckNoteOwner(store.DB, userID+1, note.ID); !errors.Is(err, ErrForbidden) {
+		t.Errorf("чужая заметка: %v, ожидалась ErrForbidden", err)
+	}
+	if err := CheckNoteOwner(store.DB, userID, note.ID+1); !errors.Is(err, ErrNoteNotFound) {
+		t.Errorf("несуществующая заметка: %v, ожидалась ErrNoteNotFound", err)
+	}
+}
+
+func TestChangesSinceMergesNotesAndTombstones(t *testing.T) {
+	store, userID := newStore(t)
+
+	// Номера изменений: a=1, b=2, c=3, удаление b=4, изменение a=5
+	var ids []uint
+	for _, title := range []string{"a", "b", "c"} {
+		note, err := store.Create(model.Note{UserID: userID, Title: title})
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		ids = append(ids, note.ID)
+	}
+	if err := store.Delete(int(ids[1])); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := store.Update(int(ids[0]), model.Note{Title: "a2"}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		since   int64
+		limit   int
+		notes   []int64
+		deleted []int64
+	}{
+		{"всё", 0, 10, []int64{3, 5}, []int64{4}},
+		{"граница на tombstone", 0, 2, []int64{3}, []int64{4}},
+		{"только заметка", 0, 1, []int64{3}, nil},
+		{"после tombstone", 4, 10, []int64{5}, nil},
+		{"ничего нового", 5, 10, nil, nil},
+	}
+	for _, tt := range tests {
+		notes, deleted, err := store.ChangesSince(userID, tt.since, tt.limit)
+		if err != nil {
+			t.Fatalf("%s: ChangesSince: %v", tt.name, err)
+		}
+		var gotNotes, gotDeleted []int64
+		for _, n := range notes {
+			gotNotes = append(gotNotes, n.ChangeSeq)
+		}
+		for _, d := range deleted {
+			gotDeleted = append(gotDeleted, d.ChangeSeq)
+		}
+		if !slices.Equal(gotNotes, tt.notes) || !slices.Equal(gotDeleted, tt.deleted) {
+			t.Errorf("%s: заметки %v, tombstones %v, ожидались %v и %v", tt.name, gotNotes, gotDeleted, tt.notes, tt.deleted)
+		}
+	}
+
+	// Изменения другого пользователя не попадают в выборку
+	if notes, deleted, err := store.ChangesSince(userID+1, 0, 10); err != nil || len(notes) != 0 || len(deleted) != 0 {
+		t.Errorf("чужие изменения: %d заметок, %d tombstones, %v", len(notes), len(deleted), err)
+	}
+}
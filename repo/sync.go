@@ -0,0 +1,27 @@
+package storage
+
+import (
+	"notes-api/model"
+	"time"
+)
+
+type SyncMutationRepository interface {
+	GetSyncMutation(userID uint, mutationID string) (model.SyncMutation, error)
+	SaveSyncMutation(mutation *model.SyncMutation) error
+	DeleteSyncMutationsBefore(t time.Time) (int64, error)
+}
+
+func (s *PostgresStore) GetSyncMutation(userID uint, mutationID string) (model.SyncMutation, error) {
+	var mutation model.SyncMutation
+	err := s.DB.Where("user_id = ? AND mutation_id = ?", userID, mutationID).First(&mutation).Error
+	return mutation, err
+}
+
+func (s *PostgresStore) SaveSyncMutation(mutation *model.SyncMutation) error {
+	return s.DB.Create(mutation).Error
+}
+
+func (s *PostgresStore) DeleteSyncMutationsBefore(t time.Time) (int64, error) {
+	res := s.DB.Where("created_at < ?", t).Delete(&model.SyncMutation{})
+	return res.RowsAffected, res.Error
+}
@@ -0,0 +1,240 @@
+package service
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"notes-api/model"
+	"notes-api/tracing"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"go.opentelemetry.io/otel/attribute"
+	"gopkg.in/yaml.v3"
+)
+
+type ExportFormat string
+
+const (
+	ExportJSON     ExportFormat = "json"
+	ExportMarkdown ExportFormat = "markdown"
+	ExportHTML     ExportFormat = "html"
+)
+
+// ExportVersion — версия формата JSON-выгрузки
+const ExportVersion = 1
+
+// ExportedNote — заметка в том виде, в котором она попадает в выгрузку. Состояние заметки
+// (закрепление, архив, избранное, срок) пишется и в JSON, и во front matter Markdown,
+// чтобы импорт выгрузки восстановил его.
+type ExportedNote struct {
+	ID       uint       `json:"id" yaml:"id"`
+	Title    string     `json:"title" yaml:"title"`
+	Content  string     `json:"content" yaml:"-"`
+	Pinned   bool       `json:"pinned,omitempty" yaml:"pinned,omitempty"`
+	Archived bool       `json:"archived,omitempty" yaml:"archived,omitempty"`
+	Starred  bool       `json:"starred,omitempty" yaml:"starred,omitempty"`
+	DueAt    *time.Time `json:"due_at,omitempty" yaml:"due_at,omitempty"`
+	// Encrypted — заметка зашифрована на клиенте, в Title и Content лежат конверты шифротекста
+	Encrypted bool `json:"encrypted,omitempty" yaml:"-"`
+}
+
+func ParseExportFormat(s string) (ExportFormat, error) {
+	switch ExportFormat(s) {
+	case "", ExportJSON:
+		return ExportJSON, nil
+	case ExportMarkdown, "md":
+		return ExportMarkdown, nil
+	case ExportHTML:
+		return ExportHTML, nil
+	}
+	return "", fmt.Errorf("%w выгрузки", ErrUnsupportedFormat)
+}
+
+// ContentType возвращает MIME-тип результата выгрузки
+func (f ExportFormat) ContentType() string {
+	if f == ExportJSON {
+		return "application/json"
+	}
+	return "application/zip"
+}
+
+// FileName возвращает имя файла выгрузки для Content-Disposition
+func (f ExportFormat) FileName() string {
+	if f == ExportJSON {
+		return "notes.json"
+	}
+	return fmt.Sprintf("notes-%s.zip", f)
+}
+
+// ExportNotes потоково пишет все заметки пользователя в w в выбранном формате
+func (s *NoteService) ExportNotes(userID uint, format ExportFormat, w io.Writer) (err error) {
+	s, span := s.trace("ExportNotes", tracing.UserID(userID), attribute.String("export.format", string(format)))
+	defer func() { tracing.End(span, err) }()
+	switch format {
+	case ExportJSON:
+		return s.exportJSON(userID, w)
+	case ExportMarkdown, ExportHTML:
+		return s.exportZip(userID, format, w)
+	}
+	return fmt.Errorf("%w выгрузки", ErrUnsupportedFormat)
+}
+
+func (s *NoteService) exportJSON(userID uint, w io.Writer) error {
+	header := fmt.Sprintf(`{"version":%d,"exported_at":%q,"notes":[`, ExportVersion, time.Now().UTC().Format(time.RFC3339))
+	if _, err := io.WriteString(w, header); err != nil {
+		return err
+	}
+
+	first := true
+	err := s.Repo.EachByUserID(int(userID), func(note model.Note) error {
+		if !first {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+		first = false
+
+		data, err := json.Marshal(toExportedNote(note))
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, "]}\n")
+	return err
+}
+
+func (s *NoteService) exportZip(userID uint, format ExportFormat, w io.Writer) error {
+	zw := zip.NewWriter(w)
+	names := make(map[string]bool)
+
+	ext := ".md"
+	if format == ExportHTML {
+		ext = ".html"
+	}
+
+	err := s.Repo.EachByUserID(int(userID), func(note model.Note) error {
+		// Зашифрованную заметку нельзя показать в Markdown или HTML; она есть только в JSON-выгрузке
+		if note.Encrypted {
+			return nil
+		}
+		exported := toExportedNote(note)
+
+		f, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     uniqueFileName(names, exportSlug(exported), ext),
+			Method:   zip.Deflate,
+			Modified: time.Now(),
+		})
+		if err != nil {
+			return err
+		}
+
+		if format == ExportHTML {
+			return writeNoteHTML(f, exported)
+		}
+		return writeNoteMarkdown(f, exported)
+	})
+	if err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+func toExportedNote(note model.Note) ExportedNote {
+	return ExportedNote{
+		ID:        note.ID,
+		Title:     note.Title,
+		Content:   note.Content,
+		Pinned:    note.Pinned,
+		Archived:  note.Archived,
+		Starred:   note.Starred,
+		DueAt:     note.DueAt,
+		Encrypted: note.Encrypted,
+	}
+}
+
+func writeNoteMarkdown(w io.Writer, note ExportedNote) error {
+	frontMatter, err := yaml.Marshal(note)
+	if err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintf(w, "---\n%s---\n\n", frontMatter); err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, note.Content)
+	return err
+}
+
+var noteHTMLTemplate = template.Must(template.New("note").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<div style="white-space: pre-wrap">{{.Content}}</div>
+</body>
+</html>
+`))
+
+func writeNoteHTML(w io.Writer, note ExportedNote) error {
+	return noteHTMLTemplate.Execute(w, note)
+}
+
+// exportSlug строит имя файла из заголовка заметки, сохраняя буквы любых алфавитов
+func exportSlug(note ExportedNote) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(note.Title) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+		if !dash && b.Len() > 0 {
+			b.WriteRune('-')
+			dash = true
+		}
+	}
+
+	slug := strings.TrimSuffix(b.String(), "-")
+	if len(slug) > 80 {
+		slug = strings.TrimSuffix(truncateUTF8(slug, 80), "-")
+	}
+	if slug == "" {
+		slug = fmt.Sprintf("note-%d", note.ID)
+	}
+	return slug
+}
+
+func uniqueFileName(seen map[string]bool, slug, ext string) string {
+	name := slug + ext
+	for n := 2; seen[name]; n++ {
+		name = fmt.Sprintf("%s-%d%s", slug, n, ext)
+	}
+	seen[name] = true
+	return name
+}
+
+func truncateUTF8(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	for max > 0 && !utf8.RuneStart(s[max]) {
+		max--
+	}
+	return s[:max]
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"bytes"
+	"notes-api/db/dbtest"
+	_ "notes-api/encryption"
+	"notes-api/model"
+	storage "notes-api/repo"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExportImportRoundTrip(t *testing.T) {
+	db := dbtest.Open(t, &model.User{}, &model.Note{})
+	user := model.User{Email: "user@example.com"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("создать пользователя: %v", err)
+	}
+	s := NewNoteService(storage.NewPostgresStore(db))
+
+	due := time.Date(2026, 11, 1, 9, 30, 0, 0, time.UTC)
+	originals := []model.Note{
+		{Title: "Закреплённая", Content: "# Закреплённая\nтекст", Pinned: true, Starred: true, DueAt: &due},
+		{Title: "Архивная", Content: "старое", Archived: true},
+		{Title: "Обычная", Content: "---\nне front matter\n"},
+	}
+	for _, note := range originals {
+		if _, err := s.CreateNote(user.ID, note); err != nil {
+			t.Fatalf("CreateNote: %v", err)
+		}
+	}
+
+	for _, format := range []ExportFormat{ExportJSON, ExportMarkdown} {
+		var buf bytes.Buffer
+		if err := s.ExportNotes(user.ID, format, &buf); err != nil {
+			t.Fatalf("%s: ExportNotes: %v", format, err)
+		}
+		importFormat := ImportJSON
+		if format == ExportMarkdown {
+			importFormat = ImportMarkdownZip
+		}
+		items, err := ParseImportFile(importFormat, buf.Bytes())
+		if err != nil {
+			t.Fatalf("%s: ParseImportFile: %v", format, err)
+		}
+
+		byTitle := make(map[string]ImportItem)
+		for _, item := range items {
+			if item.Err != nil {
+				t.Errorf("%s: %s: %v", format, item.Source, item.Err)
+			}
+			byTitle[item.Title] = item
+		}
+		for _, want := range originals {
+			got, ok := byTitle[want.Title]
+			if !ok {
+				t.Errorf("%s: заметка %q потеряна", format, want.Title)
+				continue
+			}
+			if got.Content != want.Content || got.Pinned != want.Pinned || got.Archived != want.Archived || got.Starred != want.Starred {
+				t.Errorf("%s: %q прочитана как %+v", format, want.Title, got)
+			}
+			if (got.DueAt == nil) != (want.DueAt == nil) || (got.DueAt != nil && !got.DueAt.Equal(*want.DueAt)) {
+				t.Errorf("%s: срок %q прочитан как %v, ожидался %v", format, want.Title, got.DueAt, want.DueAt)
+			}
+		}
+	}
+}
+
+func TestExportMarkdownFrontMatter(t *testing.T) {
+	due := time.Date(2026, 11, 1, 9, 30, 0, 0, time.UTC)
+	var buf bytes.Buffer
+	if err := writeNoteMarkdown(&buf, ExportedNote{ID: 7, Title: "a", Content: "текст", Pinned: true, DueAt: &due}); err != nil {
+		t.Fatal(err)
+	}
+	want := "---\nid: 7\ntitle: a\npinned: true\ndue_at: 2026-11-01T09:30:00Z\n---\n\nтекст"
+	if buf.String() != want {
+		t.Errorf("Markdown:\n%s\nожидался:\n%s", buf.String(), want)
+	}
+
+	// Неустановленные флаги во front matter не пишутся
+	buf.Reset()
+	if err := writeNoteMarkdown(&buf, ExportedNote{ID: 8, Title: "b"}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "archived") || strings.Contains(buf.String(), "due_at") {
+		t.Errorf("пустые поля во front matter:\n%s", buf.String())
+	}
+}
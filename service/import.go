@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"notes-api/jobs"
+	"notes-api/logger"
+	"notes-api/model"
+	storage "notes-api/repo"
+)
+
+const ImportJobType = "notes.import"
+
+// importProgressEvery — как часто (в элементах) сохранять прогресс импорта
+const importProgressEvery = 20
+
+type importPayload struct {
+	Format   ImportFormat `json:"format"`
+	FileName string       `json:"file_name"`
+}
+
+type ImportService struct {
+	Notes INoteService
+	Repo  storage.NoteRepository
+	Queue *jobs.Queue
+}
+
+func NewImportService(notes INoteService, r storage.NoteRepository, q *jobs.Queue) *ImportService {
+	return &ImportService{Notes: notes, Repo: r, Queue: q}
+}
+
+// StartImport ставит импорт в очередь фоновых задач. Прогресс и отчёт
+// по элементам доступны в результате задачи через GET /jobs/{id}.
+func (s *ImportService) StartImport(ctx context.Context, userID uint, format ImportFormat, fileName string, data []byte) (model.Job, error) {
+	return s.Queue.Enqueue(ctx, ImportJobType, importPayload{
+		Format:   format,
+		FileName: fileName,
+	}, jobs.Options{
+		UserID: userID,
+		Data:   data,
+	})
+}
+
+// HandleImportJob выполняет задачу импорта. Повтор после сбоя безопасен:
+// уже импортированные заметки будут распознаны как дубликаты.
+func (s *ImportService) HandleImportJob(ctx context.Context, job *model.Job) error {
+	var payload importPayload
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return jobs.Permanent(err)
+	}
+
+	log := logger.Log.WithFields(logger.Fields{
+		"user_id": job.UserID,
+		"job_id":  job.ID,
+	})
+
+	items, err := ParseImportFile(payload.Format, job.Data)
+	if err != nil {
+		log.WithError(err).Warn("Не удалось разобрать файл импорта")
+		return jobs.Permanent(err)
+	}
+
+	seen, err := s.existingNoteHashes(job.UserID)
+	if err != nil {
+		return err
+	}
+
+	report := model.ImportReport{
+		Format:   string(payload.Format),
+		FileName: payload.FileName,
+		Total:    len(items),
+		Items:    []model.ImportItemReport{},
+	}
+	for i, item := range items {
+		s.importItem(job.UserID, &report, seen, i, item)
+		report.Processed++
+		if report.Processed%importProgressEvery == 0 {
+			s.saveProgress(ctx, job.ID, &report)
+		}
+	}
+	s.saveProgress(ctx, job.ID, &report)
+
+	log.WithFields(logger.Fields{
+		"imported":   report.Imported,
+		"duplicates": report.Duplicates,
+		"failed":     report.Failed,
+	}).Info("Импорт заметок завершён")
+	return nil
+}
+
+func (s *ImportService) importItem(userID uint, report *model.ImportReport, seen map[[sha256.Size]byte]bool, index int, item ImportItem) {
+	itemReport := model.ImportItemReport{
+		Index:  index,
+		Source: item.Source,
+	}
+
+	if item.Err != nil {
+		itemReport.Status = model.ImportItemFailed
+		itemReport.Error = item.Err.Error()
+		report.Failed++
+		report.Items = append(report.Items, itemReport)
+		return
+	}
+
+	hash := noteHash(item.Title, item.Content)
+	if seen[hash] {
+		itemReport.Status = model.ImportItemDuplicate
+		report.Duplicates++
+		report.Items = append(report.Items, itemReport)
+		return
+	}
+
+	if _, err := s.Notes.CreateNote(userID, model.Note{
+		Title:     item.Title,
+		Content:   item.Content,
+		Pinned:    item.Pinned,
+		Archived:  item.Archived,
+		Starred:   item.Starred,
+		DueAt:     item.DueAt,
+		Encrypted: item.Encrypted,
+	}); err != nil {
+		itemReport.Status = model.ImportItemFailed
+		itemReport.Error = err.Error()
+		report.Failed++
+		report.Items = append(report.Items, itemReport)
+		return
+	}
+
+	seen[hash] = true
+	report.Imported++
+}
+
+// existingNoteHashes собирает отпечатки уже существующих заметок для поиска дубликатов
+func (s *ImportService) existingNoteHashes(userID uint) (map[[sha256.Size]byte]bool, error) {
+	seen := make(map[[sha256.Size]byte]bool)
+	err := s.Repo.EachByUserID(int(userID), func(note model.Note) error {
+		seen[noteHash(note.Title, note.Content)] = true
+		return nil
+	})
+	return seen, err
+}
+
+func noteHash(title, content string) [sha256.Size]byte {
+	return sha256.Sum256([]byte(title + "\x00" + content))
+}
+
+func (s *ImportService) saveProgress(ctx context.Context, jobID uint, report *model.ImportReport) {
+	progress := 100
+	if report.Total > 0 {
+		progress = report.Processed * 100 / report.Total
+	}
+	if err := s.Queue.SetProgress(ctx, jobID, progress, report); err != nil {
+		logger.Log.WithError(err).WithField("job_id", jobID).Error("Не удалось сохранить прогресс импорта")
+	}
+}
@@ -0,0 +1,322 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+type ImportFormat string
+
+const (
+	ImportMarkdownZip ImportFormat = "markdown"
+	ImportENEX        ImportFormat = "enex"
+	ImportJSON        ImportFormat = "json"
+)
+
+// maxImportEntrySize ограничивает распакованный размер одного файла из архива
+const maxImportEntrySize = 5 << 20
+
+// ImportItem — заметка, прочитанная из импортируемого файла
+type ImportItem struct {
+	Source  string
+	Title   string
+	Content string
+	// Pinned, Archived, Starred и DueAt — состояние заметки из выгрузки сервиса
+	Pinned   bool
+	Archived bool
+	Starred  bool
+	DueAt    *time.Time
+	// Encrypted — элемент JSON-выгрузки с зашифрованной на клиенте заметкой
+	Encrypted bool
+	Err       error
+}
+
+// importItem переносит заметку из выгрузки сервиса в элемент импорта
+func importItem(source string, note ExportedNote) ImportItem {
+	return ImportItem{
+		Source:    source,
+		Title:     note.Title,
+		Content:   note.Content,
+		Pinned:    note.Pinned,
+		Archived:  note.Archived,
+		Starred:   note.Starred,
+		DueAt:     note.DueAt,
+		Encrypted: note.Encrypted,
+	}
+}
+
+// DetectImportFormat определяет формат по явному значению или расширению файла
+func DetectImportFormat(format, fileName string) (ImportFormat, error) {
+	switch strings.ToLower(format) {
+	case "markdown", "md", "zip":
+		return ImportMarkdownZip, nil
+	case "enex", "evernote":
+		return ImportENEX, nil
+	case "json":
+		return ImportJSON, nil
+	case "":
+	default:
+		return "", fmt.Errorf("%w импорта", ErrUnsupportedFormat)
+	}
+
+	switch strings.ToLower(path.Ext(fileName)) {
+	case ".zip":
+		return ImportMarkdownZip, nil
+	case ".enex":
+		return ImportENEX, nil
+	case ".json":
+		return ImportJSON, nil
+	}
+	return "", fmt.Errorf("%w: не удалось определить формат импорта по имени файла", ErrUnsupportedFormat)
+}
+
+// ParseImportFile разбирает содержимое файла импорта и возвращает найденные в нём заметки.
+// Ошибки отдельных элементов возвращаются в ImportItem.Err, ошибка функции означает,
+// что файл не удалось разобрать целиком.
+func ParseImportFile(format ImportFormat, data []byte) ([]ImportItem, error) {
+	switch format {
+	case ImportMarkdownZip:
+		return parseMarkdownZip(data)
+	case ImportENEX:
+		return parseENEX(bytes.NewReader(data))
+	case ImportJSON:
+		return parseJSONExport(bytes.NewReader(data))
+	}
+	return nil, fmt.Errorf("%w импорта", ErrUnsupportedFormat)
+}
+
+func parseMarkdownZip(data []byte) ([]ImportItem, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("не удалось открыть ZIP-архив: %w", err)
+	}
+
+	var items []ImportItem
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() || !isMarkdownFile(f.Name) {
+			continue
+		}
+
+		data, err := readZipEntry(f)
+		if err != nil {
+			items = append(items, ImportItem{Source: f.Name, Err: err})
+			continue
+		}
+
+		note, err := parseMarkdownNote(f.Name, data)
+		item := importItem(f.Name, note)
+		item.Err = err
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func isMarkdownFile(name string) bool {
+	if strings.HasPrefix(path.Base(name), ".") || strings.HasPrefix(name, "__MACOSX/") {
+		return false
+	}
+	switch strings.ToLower(path.Ext(name)) {
+	case ".md", ".markdown", ".txt":
+		return true
+	}
+	return false
+}
+
+func readZipEntry(f *zip.File) ([]byte, error) {
+	if f.UncompressedSize64 > maxImportEntrySize {
+		return nil, errors.New("файл слишком большой")
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(io.LimitReader(rc, maxImportEntrySize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxImportEntrySize {
+		return nil, errors.New("файл слишком большой")
+	}
+	return data, nil
+}
+
+// parseMarkdownNote разбирает Markdown-файл с необязательным YAML front-matter в формате
+// Markdown-выгрузки сервиса. Заголовок берётся из front-matter, затем из первого заголовка
+// первого уровня, затем из имени файла.
+func parseMarkdownNote(name string, data []byte) (ExportedNote, error) {
+	text := strings.ReplaceAll(string(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))), "\r\n", "\n")
+
+	var meta ExportedNote
+	if strings.HasPrefix(text, "---\n") {
+		// Блок начинается с перевода строки, чтобы пустой front-matter тоже находил закрывающую черту
+		block := text[len("---"):] + "\n"
+		end := strings.Index(block, "\n---\n")
+		if end < 0 {
+			return ExportedNote{}, errors.New("не закрыт блок front-matter")
+		}
+		if err := yaml.Unmarshal([]byte(block[:end]), &meta); err != nil {
+			return ExportedNote{}, fmt.Errorf("некорректный front-matter: %w", err)
+		}
+		body := strings.TrimSuffix(block[end+len("\n---\n"):], "\n")
+		text = strings.TrimPrefix(body, "\n")
+	}
+
+	meta.Title = strings.TrimSpace(meta.Title)
+	if meta.Title == "" {
+		if heading, ok := strings.CutPrefix(strings.SplitN(text, "\n", 2)[0], "# "); ok {
+			meta.Title = strings.TrimSpace(heading)
+		}
+	}
+	if meta.Title == "" {
+		meta.Title = strings.TrimSuffix(path.Base(name), path.Ext(name))
+	}
+
+	meta.Content = text
+	return meta, nil
+}
+
+type enexNote struct {
+	Title   string `xml:"title"`
+	Content string `xml:"content"`
+}
+
+func parseENEX(r io.Reader) ([]ImportItem, error) {
+	dec := xml.NewDecoder(r)
+	dec.Strict = false
+
+	var items []ImportItem
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("некорректный ENEX-файл: %w", err)
+		}
+
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "note" {
+			continue
+		}
+
+		var note enexNote
+		item := ImportItem{Source: fmt.Sprintf("note #%d", len(items)+1)}
+		if err := dec.DecodeElement(&note, &start); err != nil {
+			return nil, fmt.Errorf("некорректный ENEX-файл: %w", err)
+		}
+
+		item.Title = strings.TrimSpace(note.Title)
+		item.Content, item.Err = enmlToText(note.Content)
+		items = append(items, item)
+	}
+
+	if len(items) == 0 {
+		return nil, errors.New("в ENEX-файле нет заметок")
+	}
+	return items, nil
+}
+
+// enmlToText превращает ENML (XHTML-подмножество Evernote) в простой текст.
+// Блочные элементы разделяются переводами строк, чекбоксы en-todo становятся Markdown-задачами.
+func enmlToText(enml string) (string, error) {
+	dec := xml.NewDecoder(strings.NewReader(enml))
+	dec.Strict = false
+	dec.AutoClose = xml.HTMLAutoClose
+	dec.Entity = xml.HTMLEntity
+
+	var b strings.Builder
+	newline := func() {
+		if b.Len() > 0 && !strings.HasSuffix(b.String(), "\n") {
+			b.WriteByte('\n')
+		}
+	}
+
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("некорректное содержимое заметки: %w", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "br":
+				b.WriteByte('\n')
+			case "div", "p", "h1", "h2", "h3", "h4", "h5", "h6", "tr", "pre", "blockquote":
+				newline()
+			case "li":
+				newline()
+				b.WriteString("- ")
+			case "en-todo":
+				mark := "[ ] "
+				for _, attr := range t.Attr {
+					if attr.Name.Local == "checked" && attr.Value == "true" {
+						mark = "[x] "
+					}
+				}
+				if !strings.HasSuffix(b.String(), "- ") {
+					newline()
+					b.WriteString("- ")
+				}
+				b.WriteString(mark)
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "div", "p", "h1", "h2", "h3", "h4", "h5", "h6", "tr", "li", "pre", "blockquote":
+				newline()
+			}
+		case xml.CharData:
+			b.Write(t)
+		}
+	}
+
+	return strings.TrimSpace(b.String()), nil
+}
+
+// parseJSONExport читает собственную JSON-выгрузку сервиса или просто массив заметок
+func parseJSONExport(r io.Reader) ([]ImportItem, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var notes []ExportedNote
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(trimmed, &notes)
+	} else {
+		var export struct {
+			Version int            `json:"version"`
+			Notes   []ExportedNote `json:"notes"`
+		}
+		err = json.Unmarshal(trimmed, &export)
+		if err == nil && export.Version > ExportVersion {
+			return nil, fmt.Errorf("неподдерживаемая версия выгрузки: %d", export.Version)
+		}
+		notes = export.Notes
+	}
+	if err != nil {
+		return nil, fmt.Errorf("некорректный JSON: %w", err)
+	}
+
+	items := make([]ImportItem, 0, len(notes))
+	for i, note := range notes {
+		items = append(items, importItem(fmt.Sprintf("notes[%d]", i), note))
+	}
+	return items, nil
+}
@@ -0,0 +1,261 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestParseMarkdownNote(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		data    string
+		title   string
+		content string
+		wantErr bool
+	}{
+		{
+			name:    "front matter",
+			file:    "a.md",
+			data:    "---\ntitle: Из front matter\n---\n\n# Заголовок\nтекст",
+			title:   "Из front matter",
+			content: "# Заголовок\nтекст",
+		},
+		{
+			name:    "пустой front matter",
+			file:    "a.md",
+			data:    "---\n---\n# Заголовок\nтекст",
+			title:   "Заголовок",
+			content: "# Заголовок\nтекст",
+		},
+		{
+			name:    "без front matter",
+			file:    "a.md",
+			data:    "# Заголовок\nтекст",
+			title:   "Заголовок",
+			content: "# Заголовок\nтекст",
+		},
+		{
+			name:    "заголовок из имени файла",
+			file:    "папка/Имя файла.md",
+			data:    "## Второй уровень\nтекст",
+			title:   "Имя файла",
+			content: "## Второй уровень\nтекст",
+		},
+		{
+			name:    "BOM и CRLF",
+			file:    "a.md",
+			data:    "\xef\xbb\xbf---\r\ntitle: t\r\n---\r\nтекст\r\n",
+			title:   "t",
+			content: "текст\n",
+		},
+		{
+			name:    "не закрыт front matter",
+			file:    "a.md",
+			data:    "---\ntitle: t\nтекст",
+			wantErr: true,
+		},
+		{
+			name:    "некорректный YAML",
+			file:    "a.md",
+			data:    "---\ntitle: [не закрыт\n---\nтекст",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		note, err := parseMarkdownNote(tt.file, []byte(tt.data))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: ошибка %v", tt.name, err)
+			continue
+		}
+		if err == nil && (note.Title != tt.title || note.Content != tt.content) {
+			t.Errorf("%s: заголовок %q, текст %q, ожидались %q и %q", tt.name, note.Title, note.Content, tt.title, tt.content)
+		}
+	}
+}
+
+func zipArchive(t *testing.T, files map[string][]byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, data := range files {
+		f, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestParseMarkdownZip(t *testing.T) {
+	data := zipArchive(t, map[string][]byte{
+		"notes/a.md":          []byte("# A\nтекст"),
+		"notes/b.txt":         []byte("просто текст"),
+		"notes/.hidden.md":    []byte("скрытый"),
+		"__MACOSX/notes/a.md": []byte("служебный"),
+		"notes/image.png":     []byte("png"),
+		"notes/big.md":        bytes.Repeat([]byte("x"), maxImportEntrySize+1),
+		"notes/broken.md":     []byte("---\ntitle: t\n"),
+	})
+
+	items, err := ParseImportFile(ImportMarkdownZip, data)
+	if err != nil {
+		t.Fatalf("ParseImportFile: %v", err)
+	}
+	bySource := make(map[string]ImportItem)
+	for _, item := range items {
+		bySource[item.Source] = item
+	}
+	if len(bySource) != 4 {
+		t.Errorf("прочитаны файлы %v, ожидались только Markdown и текст", bySource)
+	}
+	if a := bySource["notes/a.md"]; a.Err != nil || a.Title != "A" || a.Content != "# A\nтекст" {
+		t.Errorf("notes/a.md: %+v", a)
+	}
+	if b := bySource["notes/b.txt"]; b.Err != nil || b.Title != "b" {
+		t.Errorf("notes/b.txt: %+v", b)
+	}
+	if big := bySource["notes/big.md"]; big.Err == nil || !strings.Contains(big.Err.Error(), "слишком большой") {
+		t.Errorf("файл больше предела: %v", big.Err)
+	}
+	if broken := bySource["notes/broken.md"]; broken.Err == nil {
+		t.Error("файл с незакрытым front matter прочитан без ошибки")
+	}
+
+	if _, err := ParseImportFile(ImportMarkdownZip, []byte("не zip")); err == nil {
+		t.Error("не ZIP-архив разобран без ошибки")
+	}
+}
+
+func TestENMLToText(t *testing.T) {
+	tests := []struct {
+		name string
+		enml string
+		want string
+	}{
+		{
+			name: "блоки и переводы строк",
+			enml: `<en-note><div>первая</div><div>вторая<br/>третья</div><p>абзац</p></en-note>`,
+			want: "первая\nвторая\nтретья\nабзац",
+		},
+		{
+			name: "задачи",
+			enml: `<en-note><div><en-todo checked="true"/>готово</div><div><en-todo checked="false"/>не готово</div><en-todo/>без блока</en-note>`,
+			want: "- [x] готово\n- [ ] не готово\n- [ ] без блока",
+		},
+		{
+			name: "задача в списке",
+			enml: `<en-note><ul><li><en-todo checked="true"/>пункт</li><li>обычный</li></ul></en-note>`,
+			want: "- [x] пункт\n- обычный",
+		},
+		{
+			name: "сущности HTML",
+			enml: `<en-note><div>a &amp; b &lt;c&gt; &mdash; d</div></en-note>`,
+			want: "a & b <c> — d",
+		},
+	}
+	for _, tt := range tests {
+		got, err := enmlToText(tt.enml)
+		if err != nil || got != tt.want {
+			t.Errorf("%s: %q, ожидалось %q: %v", tt.name, got, tt.want, err)
+		}
+	}
+}
+
+func TestParseENEX(t *testing.T) {
+	enex := `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE en-export SYSTEM "http://xml.evernote.com/pub/evernote-export3.dtd">
+<en-export>
+  <note>
+    <title> Покупки </title>
+    <content><![CDATA[<?xml version="1.0" encoding="UTF-8"?><en-note><div><en-todo checked="true"/>хлеб</div></en-note>]]></content>
+  </note>
+  <note>
+    <title>Пустая</title>
+    <content><![CDATA[<en-note></en-note>]]></content>
+  </note>
+</en-export>`
+
+	items, err := ParseImportFile(ImportENEX, []byte(enex))
+	if err != nil {
+		t.Fatalf("ParseImportFile: %v", err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("%d заметок, ожидалось 2", len(items))
+	}
+	if items[0].Title != "Покупки" || items[0].Content != "- [x] хлеб" || items[0].Source != "note #1" {
+		t.Errorf("первая заметка: %+v", items[0])
+	}
+	if items[1].Title != "Пустая" || items[1].Content != "" || items[1].Err != nil {
+		t.Errorf("вторая заметка: %+v", items[1])
+	}
+
+	if _, err := ParseImportFile(ImportENEX, []byte(`<en-export></en-export>`)); err == nil {
+		t.Error("ENEX без заметок разобран без ошибки")
+	}
+}
+
+func TestParseJSONExport(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		titles  []string
+		wantErr bool
+	}{
+		{"массив заметок", `[{"title":"a","content":"1"},{"title":"b"}]`, []string{"a", "b"}, false},
+		{"выгрузка версии 1", `{"version":1,"notes":[{"id":7,"title":"a","content":"1"}]}`, []string{"a"}, false},
+		{"выгрузка без версии", `{"notes":[{"title":"a"}]}`, []string{"a"}, false},
+		{"пустая выгрузка", `{"version":1,"notes":[]}`, nil, false},
+		{"версия новее поддерживаемой", `{"version":2,"notes":[{"title":"a"}]}`, nil, true},
+		{"некорректный JSON", `{"notes":[`, nil, true},
+	}
+	for _, tt := range tests {
+		items, err := ParseImportFile(ImportJSON, []byte(tt.data))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: ошибка %v", tt.name, err)
+			continue
+		}
+		var titles []string
+		for _, item := range items {
+			titles = append(titles, item.Title)
+		}
+		if strings.Join(titles, ",") != strings.Join(tt.titles, ",") {
+			t.Errorf("%s: заголовки %v, ожидались %v", tt.name, titles, tt.titles)
+		}
+	}
+
+	items, err := ParseImportFile(ImportJSON, []byte(`[{"title":"enc:v1:...","content":"enc:v1:...","encrypted":true}]`))
+	if err != nil || len(items) != 1 || !items[0].Encrypted || items[0].Source != "notes[0]" {
+		t.Errorf("зашифрованная заметка: %+v, %v", items, err)
+	}
+}
+
+func TestDetectImportFormat(t *testing.T) {
+	tests := []struct {
+		format, file string
+		want         ImportFormat
+		wantErr      bool
+	}{
+		{"", "notes.zip", ImportMarkdownZip, false},
+		{"", "Export.ENEX", ImportENEX, false},
+		{"", "notes.json", ImportJSON, false},
+		{"evernote", "notes.zip", ImportENEX, false},
+		{"md", "", ImportMarkdownZip, false},
+		{"", "notes.docx", "", true},
+		{"docx", "notes.json", "", true},
+	}
+	for _, tt := range tests {
+		got, err := DetectImportFormat(tt.format, tt.file)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("DetectImportFormat(%q, %q) = %q, %v", tt.format, tt.file, got, err)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"notes-api/db/dbtest"
+	_ "notes-api/encryption"
+	"notes-api/jobs"
+	"notes-api/model"
+	storage "notes-api/repo"
+	"testing"
+)
+
+func TestNoteHash(t *testing.T) {
+	if noteHash("ab", "c") == noteHash("a", "bc") {
+		t.Error("граница между заголовком и текстом не учитывается в отпечатке")
+	}
+	if noteHash("a", "b") != noteHash("a", "b") {
+		t.Error("отпечаток одной и той же заметки различается")
+	}
+}
+
+func TestImportSkipsDuplicates(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.Open(t, &model.User{}, &model.Note{}, &model.Job{})
+	user := model.User{Email: "user@example.com"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("создать пользователя: %v", err)
+	}
+	store := storage.NewPostgresStore(db)
+	s := NewImportService(NewNoteService(store), store, jobs.NewQueue(db))
+
+	if _, err := s.Notes.CreateNote(user.ID, model.Note{Title: "есть", Content: "1"}); err != nil {
+		t.Fatalf("CreateNote: %v", err)
+	}
+
+	// Дубликат уже существующей заметки, дубликат внутри файла и заметка с тем же
+	// заголовком, но другим текстом
+	data := `[
+		{"title":"есть","content":"1"},
+		{"title":"новая","content":"2"},
+		{"title":"новая","content":"2"},
+		{"title":"есть","content":"другой текст"}
+	]`
+	job, err := s.StartImport(ctx, user.ID, ImportJSON, "notes.json", []byte(data))
+	if err != nil {
+		t.Fatalf("StartImport: %v", err)
+	}
+	if err := s.HandleImportJob(ctx, &job); err != nil {
+		t.Fatalf("HandleImportJob: %v", err)
+	}
+
+	stored, err := s.Queue.Get(ctx, job.ID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	var report model.ImportReport
+	if err := json.Unmarshal(stored.Result, &report); err != nil {
+		t.Fatalf("отчёт импорта: %v", err)
+	}
+	if report.Total != 4 || report.Imported != 2 || report.Duplicates != 2 || report.Failed != 0 {
+		t.Errorf("отчёт %+v", report)
+	}
+	if len(report.Items) != 2 || report.Items[0].Index != 0 || report.Items[1].Index != 2 {
+		t.Errorf("дубликаты в отчёте: %+v", report.Items)
+	}
+
+	notes, err := store.GetByUserID(int(user.ID), true)
+	if err != nil || len(notes) != 3 {
+		t.Errorf("после импорта %d заметок: %v", len(notes), err)
+	}
+
+	// Повтор задачи после сбоя ничего не добавляет
+	if err := s.HandleImportJob(ctx, &job); err != nil {
+		t.Fatalf("повторный HandleImportJob: %v", err)
+	}
+	if notes, _ := store.GetByUserID(int(user.ID), true); len(notes) != 3 {
+		t.Errorf("повтор импорта создал заметки: %d", len(notes))
+	}
+}
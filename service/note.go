@@ -1,13 +1,32 @@
 package service
 
 import (
-	"errors"
+	"context"
+	"io"
+	"notes-api/e2e"
+	"notes-api/events"
 	"notes-api/model"
+	"notes-api/problem"
 	storage "notes-api/repo"
+	"notes-api/tracing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var tracer = otel.Tracer("notes-api/service")
+
+// ErrInvalidPosition — позиция закреплённой заметки должна быть неотрицательной
+var ErrInvalidPosition = problem.Invalid(problem.Field("position", problem.FieldNegative))
+
 type NoteService struct {
-	Repo storage.NoteRepository
+	Repo   storage.NoteRepository
+	Events events.Publisher
+
+	renderCache *renderCache
+	ctx         context.Context
 }
 
 type INoteService interface {
@@ -16,18 +35,53 @@ type INoteService interface {
 	CreateNote(userID uint, note model.Note) (model.Note, error)
 	UpdateNote(id int, updated model.Note) (model.Note, error)
 	DeleteNote(id int) error
-	GetNotesByUserID(userID int) ([]model.Note, error)
+	GetNotesByUserID(userID int, includeArchived bool) ([]model.Note, error)
+	GetNotesDueBefore(userID uint, before time.Time, includeArchived bool) ([]model.Note, error)
+	SetDueAt(id int, dueAt *time.Time) (model.Note, error)
+	SetPinned(id int, pinned bool, position *int) (model.Note, error)
+	SetArchived(id int, archived bool) (model.Note, error)
+	SetStarred(id int, starred bool) (model.Note, error)
+	ExportNotes(userID uint, format ExportFormat, w io.Writer) error
+	RenderNote(note model.Note, format RenderFormat) (RenderedNote, error)
+	WithContext(ctx context.Context) INoteService
 }
 
 func NewNoteService(r storage.NoteRepository) *NoteService {
-	return &NoteService{Repo: r}
+	return &NoteService{Repo: r, renderCache: newRenderCache(defaultRenderCacheSize)}
 }
 
-func (s *NoteService) GetAllNotes() ([]model.Note, error) {
+// WithContext возвращает сервис, методы которого выполняются в контексте ctx:
+// их спаны и запросы к базе становятся дочерними для спана запроса
+func (s *NoteService) WithContext(ctx context.Context) INoteService {
+	return s.withContext(ctx)
+}
+
+func (s *NoteService) withContext(ctx context.Context) *NoteService {
+	scoped := *s
+	scoped.ctx = ctx
+	scoped.Repo = s.Repo.WithContext(ctx)
+	return &scoped
+}
+
+// trace начинает спан метода и возвращает копию сервиса в контексте этого спана.
+// Вне трассируемого запроса (фоновые задачи, CLI) спан не создаётся.
+func (s *NoteService) trace(method string, attrs ...attribute.KeyValue) (*NoteService, trace.Span) {
+	if s.ctx == nil || !trace.SpanFromContext(s.ctx).SpanContext().IsValid() {
+		return s, trace.SpanFromContext(context.Background())
+	}
+	ctx, span := tracer.Start(s.ctx, "NoteService."+method, trace.WithAttributes(attrs...))
+	return s.withContext(ctx), span
+}
+
+func (s *NoteService) GetAllNotes() (_ []model.Note, err error) {
+	s, span := s.trace("GetAllNotes")
+	defer func() { tracing.End(span, err) }()
 	return s.Repo.GetAll()
 }
 
-func (s *NoteService) GetNoteByID(id int) (model.Note, error) {
+func (s *NoteService) GetNoteByID(id int) (_ model.Note, err error) {
+	s, span := s.trace("GetNoteByID", noteID(id))
+	defer func() { tracing.End(span, err) }()
 	note, err := s.Repo.GetByID(id)
 	if err != nil {
 		return model.Note{}, err
@@ -35,25 +89,135 @@ func (s *NoteService) GetNoteByID(id int) (model.Note, error) {
 	return note, nil
 }
 
-func (s *NoteService) CreateNote(userID uint, note model.Note) (model.Note, error) {
-	if note.Title == "" && note.Content == "" {
-		return model.Note{}, errors.New("заголовок и содержание не могут быть пустыми")
+func (s *NoteService) CreateNote(userID uint, note model.Note) (_ model.Note, err error) {
+	s, span := s.trace("CreateNote", tracing.UserID(userID))
+	defer func() { tracing.End(span, err) }()
+	if err := validateNote(note.Encrypted, note); err != nil {
+		return model.Note{}, err
 	}
 	note.UserID = userID
-	return s.Repo.Create(note)
+	created, err := s.Repo.Create(note)
+	if err != nil {
+		return model.Note{}, err
+	}
+	s.publish(events.NoteCreated, created)
+	return created, nil
 }
 
-func (s *NoteService) UpdateNote(id int, updated model.Note) (model.Note, error) {
-	if updated.Title == "" && updated.Content == "" {
-		return model.Note{}, errors.New("заголовок и содержание не могут быть пустыми")
+func (s *NoteService) UpdateNote(id int, updated model.Note) (_ model.Note, err error) {
+	s, span := s.trace("UpdateNote", noteID(id))
+	defer func() { tracing.End(span, err) }()
+	current, err := s.Repo.GetByID(id)
+	if err != nil {
+		return model.Note{}, err
+	}
+	if err := validateNote(current.Encrypted, updated); err != nil {
+		return model.Note{}, err
+	}
+	note, err := s.Repo.Update(id, updated)
+	if err != nil {
+		return model.Note{}, err
+	}
+	s.publish(events.NoteUpdated, note)
+	return note, nil
+}
+
+func (s *NoteService) DeleteNote(id int) (err error) {
+	s, span := s.trace("DeleteNote", noteID(id))
+	defer func() { tracing.End(span, err) }()
+	note, err := s.Repo.GetByID(id)
+	if err != nil {
+		return err
 	}
-	return s.Repo.Update(id, updated)
+	if err := s.Repo.Delete(id); err != nil {
+		return err
+	}
+	s.publish(events.NoteDeleted, note)
+	return nil
+}
+
+// GetNotesByUserID возвращает заметки пользователя; архивные — только при includeArchived
+func (s *NoteService) GetNotesByUserID(userID int, includeArchived bool) (_ []model.Note, err error) {
+	s, span := s.trace("GetNotesByUserID", tracing.UserID(uint(userID)))
+	defer func() { tracing.End(span, err) }()
+	return s.Repo.GetByUserID(userID, includeArchived)
 }
 
-func (s *NoteService) DeleteNote(id int) error {
-	return s.Repo.Delete(id)
+func (s *NoteService) GetNotesDueBefore(userID uint, before time.Time, includeArchived bool) (_ []model.Note, err error) {
+	s, span := s.trace("GetNotesDueBefore", tracing.UserID(userID))
+	defer func() { tracing.End(span, err) }()
+	return s.Repo.GetDueBefore(userID, before, includeArchived)
 }
 
-func (s *NoteService) GetNotesByUserID(userID int) ([]model.Note, error) {
-	return s.Repo.GetByUserID(userID)
+// SetDueAt меняет срок заметки; nil снимает срок
+func (s *NoteService) SetDueAt(id int, dueAt *time.Time) (_ model.Note, err error) {
+	s, span := s.trace("SetDueAt", noteID(id))
+	defer func() { tracing.End(span, err) }()
+	note, err := s.Repo.SetDueAt(id, dueAt)
+	if err != nil {
+		return model.Note{}, err
+	}
+	s.publish(events.NoteUpdated, note)
+	return note, nil
+}
+
+// SetPinned закрепляет или открепляет заметку; position задаёт её место среди закреплённых
+func (s *NoteService) SetPinned(id int, pinned bool, position *int) (_ model.Note, err error) {
+	s, span := s.trace("SetPinned", noteID(id))
+	defer func() { tracing.End(span, err) }()
+	if position != nil && *position < 0 {
+		return model.Note{}, ErrInvalidPosition
+	}
+	note, err := s.Repo.SetPinned(id, pinned, position)
+	if err != nil {
+		return model.Note{}, err
+	}
+	s.publish(events.NoteUpdated, note)
+	return note, nil
+}
+
+func (s *NoteService) SetArchived(id int, archived bool) (_ model.Note, err error) {
+	s, span := s.trace("SetArchived", noteID(id))
+	defer func() { tracing.End(span, err) }()
+	note, err := s.Repo.SetArchived(id, archived)
+	if err != nil {
+		return model.Note{}, err
+	}
+	s.publish(events.NoteUpdated, note)
+	return note, nil
+}
+
+func (s *NoteService) SetStarred(id int, starred bool) (_ model.Note, err error) {
+	s, span := s.trace("SetStarred", noteID(id))
+	defer func() { tracing.End(span, err) }()
+	note, err := s.Repo.SetStarred(id, starred)
+	if err != nil {
+		return model.Note{}, err
+	}
+	s.publish(events.NoteUpdated, note)
+	return note, nil
+}
+
+// validateNote проверяет новые заголовок и текст. Режим шифрования задаётся при создании
+// и не меняется, поэтому при обновлении проверка идёт по режиму сохранённой заметки.
+// У зашифрованной заметки сервер требует только корректные конверты: пустота открытого
+// текста ему не видна.
+func validateNote(encrypted bool, note model.Note) error {
+	if encrypted {
+		return e2e.ValidateNote(note.Title, note.Content)
+	}
+	if note.Title == "" && note.Content == "" {
+		return problem.Invalid(problem.Field("title", problem.FieldRequired), problem.Field("content", problem.FieldRequired))
+	}
+	return nil
+}
+
+func noteID(id int) attribute.KeyValue {
+	return attribute.Int("note.id", id)
+}
+
+func (s *NoteService) publish(eventType string, note model.Note) {
+	if s.Events != nil {
+		s.Events.Publish(events.NewNoteEvent(eventType, note))
+	}
 }
@@ -0,0 +1,221 @@
+package service
+
+import (
+	"bytes"
+	"container/list"
+	"errors"
+	"fmt"
+	"notes-api/model"
+	"notes-api/tracing"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+)
+
+type RenderFormat string
+
+const RenderHTML RenderFormat = "html"
+
+// defaultRenderCacheSize — сколько отрендеренных заметок держать в памяти
+const defaultRenderCacheSize = 1000
+
+// headingIDPrefix отделяет якоря заголовков заметки от id страницы, в которую
+// встраивается HTML, чтобы заголовок не мог подменить элемент интерфейса
+const headingIDPrefix = "user-content-"
+
+// ErrUnsupportedFormat — формат выгрузки, импорта или отображения не поддерживается
+var ErrUnsupportedFormat = errors.New("неподдерживаемый формат")
+
+var ErrUnsupportedRenderFormat = fmt.Errorf("%w отображения", ErrUnsupportedFormat)
+
+// ErrEncryptedNote — заметка зашифрована на клиенте, и сервер не может прочитать её текст
+var ErrEncryptedNote = errors.New("заметка зашифрована на клиенте")
+
+func ParseRenderFormat(s string) (RenderFormat, error) {
+	switch RenderFormat(s) {
+	case "", RenderHTML:
+		return RenderHTML, nil
+	}
+	return "", ErrUnsupportedRenderFormat
+}
+
+// ContentType возвращает MIME-тип результата отображения
+func (f RenderFormat) ContentType() string {
+	return "text/html; charset=utf-8"
+}
+
+// RenderedNote — заметка, отрендеренная в выбранный формат
+type RenderedNote struct {
+	NoteID    uint
+	ChangeSeq int64
+	Format    RenderFormat
+	Body      []byte
+}
+
+// ETag — версия результата: заметка меняет ChangeSeq при каждом изменении
+func (r RenderedNote) ETag() string {
+	return fmt.Sprintf(`"%d-%d-%s"`, r.NoteID, r.ChangeSeq, r.Format)
+}
+
+// markdown — CommonMark с расширениями GFM. Сырой HTML из заметки не выводится,
+// выравнивание в таблицах задаётся атрибутом align, а не style, который вырезает санитайзер.
+var markdown = goldmark.New(
+	goldmark.WithExtensions(
+		extension.NewTable(extension.WithTableCellAlignMethod(extension.TableCellAlignAttribute)),
+		extension.Strikethrough,
+		extension.Linkify,
+		extension.TaskList,
+	),
+	goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+)
+
+// renderPolicy — разрешённый набор HTML поверх политики для пользовательского контента:
+// якоря заголовков, флажки списков задач и язык блоков кода
+var renderPolicy = func() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowAttrs("id").Matching(regexp.MustCompile(`^[\p{L}\p{N}_-]+$`)).OnElements("h1", "h2", "h3", "h4", "h5", "h6")
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").Matching(regexp.MustCompile(`^$`)).OnElements("input")
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+#.-]+$`)).OnElements("code")
+	return p
+}()
+
+// RenderNote рендерит Markdown заметки в HTML. Результат кешируется по (ID, ChangeSeq),
+// поэтому любое изменение заметки автоматически даёт новую запись в кеше.
+func (s *NoteService) RenderNote(note model.Note, format RenderFormat) (_ RenderedNote, err error) {
+	s, span := s.trace("RenderNote", noteID(int(note.ID)))
+	defer func() { tracing.End(span, err) }()
+	if format != RenderHTML {
+		return RenderedNote{}, ErrUnsupportedRenderFormat
+	}
+	if note.Encrypted {
+		return RenderedNote{}, ErrEncryptedNote
+	}
+
+	key := renderKey{noteID: note.ID, changeSeq: note.ChangeSeq, format: format}
+	if body, ok := s.renderCache.get(key); ok {
+		return RenderedNote{NoteID: note.ID, ChangeSeq: note.ChangeSeq, Format: format, Body: body}, nil
+	}
+
+	body, err := renderMarkdown(note.Content)
+	if err != nil {
+		return RenderedNote{}, err
+	}
+	s.renderCache.put(key, body)
+	return RenderedNote{NoteID: note.ID, ChangeSeq: note.ChangeSeq, Format: format, Body: body}, nil
+}
+
+func renderMarkdown(content string) ([]byte, error) {
+	var buf bytes.Buffer
+	ctx := parser.NewContext(parser.WithIDs(&headingIDs{used: make(map[string]bool)}))
+	if err := markdown.Convert([]byte(content), &buf, parser.WithContext(ctx)); err != nil {
+		return nil, err
+	}
+	return renderPolicy.SanitizeBytes(buf.Bytes()), nil
+}
+
+// headingIDs строит якоря заголовков с префиксом headingIDPrefix. Стандартный генератор
+// goldmark выбрасывает всё, кроме ASCII, и у русских заголовков остаётся только "heading".
+type headingIDs struct {
+	used map[string]bool
+}
+
+func (h *headingIDs) Generate(value []byte, kind ast.NodeKind) []byte {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(strings.TrimSpace(string(value))) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_':
+			b.WriteRune(r)
+			dash = false
+		case unicode.IsSpace(r) || r == '-':
+			if !dash && b.Len() > 0 {
+				b.WriteRune('-')
+				dash = true
+			}
+		}
+	}
+
+	id := strings.TrimSuffix(b.String(), "-")
+	if id == "" {
+		id = "heading"
+		if kind != ast.KindHeading {
+			id = "id"
+		}
+	}
+	result := id
+	for i := 1; h.used[result]; i++ {
+		result = fmt.Sprintf("%s-%d", id, i)
+	}
+	h.used[result] = true
+	return []byte(headingIDPrefix + result)
+}
+
+func (h *headingIDs) Put(value []byte) {
+	h.used[strings.TrimPrefix(string(value), headingIDPrefix)] = true
+}
+
+type renderKey struct {
+	noteID    uint
+	changeSeq int64
+	format    RenderFormat
+}
+
+type renderEntry struct {
+	key  renderKey
+	body []byte
+}
+
+// renderCache — LRU-кеш отрендеренных заметок. Устаревшие версии не удаляются явно,
+// а вытесняются свежими.
+type renderCache struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List
+	entries map[renderKey]*list.Element
+}
+
+func newRenderCache(size int) *renderCache {
+	return &renderCache{size: size, order: list.New(), entries: make(map[renderKey]*list.Element)}
+}
+
+func (c *renderCache) get(key renderKey) ([]byte, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*renderEntry).body, true
+}
+
+func (c *renderCache) put(key renderKey, body []byte) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[key]; ok {
+		c.order.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&renderEntry{key: key, body: body})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*renderEntry).key)
+	}
+}
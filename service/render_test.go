@@ -0,0 +1,118 @@
+package service
+
+import (
+	"errors"
+	"notes-api/model"
+	"strings"
+	"testing"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		contains []string
+		absent   []string
+	}{
+		{
+			name:    "script",
+			content: "текст\n\n<script>alert(1)</script>",
+			absent:  []string{"<script", "alert(1)"},
+		},
+		{
+			name:     "javascript в ссылке",
+			content:  "[ссылка](javascript:alert(1)) и <javascript:alert(2)>",
+			contains: []string{"ссылка"},
+			absent:   []string{"href", "<a"},
+		},
+		{
+			name:    "сырой HTML",
+			content: `<b onclick="steal()">жирный</b> <img src="x" onerror="steal()"> <iframe src="https://example.com"></iframe>`,
+			absent:  []string{"onclick", "onerror", "<b", "<img", "<iframe"},
+		},
+		{
+			name:     "обычная разметка",
+			content:  "**жирный** [ссылка](https://example.com)\n\n- [x] готово",
+			contains: []string{"<strong>жирный</strong>", `href="https://example.com"`, `type="checkbox"`},
+		},
+		{
+			name:     "повторяющиеся заголовки",
+			content:  "# Заголовок\n\n## Заголовок\n\n# Заголовок",
+			contains: []string{`id="user-content-заголовок"`, `id="user-content-заголовок-1"`, `id="user-content-заголовок-2"`},
+		},
+		{
+			name:     "заголовок без букв",
+			content:  "# !!!",
+			contains: []string{`id="user-content-heading"`},
+		},
+	}
+	for _, tt := range tests {
+		body, err := renderMarkdown(tt.content)
+		if err != nil {
+			t.Fatalf("%s: renderMarkdown: %v", tt.name, err)
+		}
+		html := string(body)
+		for _, s := range tt.contains {
+			if !strings.Contains(html, s) {
+				t.Errorf("%s: нет %q в %s", tt.name, s, html)
+			}
+		}
+		for _, s := range tt.absent {
+			if strings.Contains(html, s) {
+				t.Errorf("%s: %q не вырезан из %s", tt.name, s, html)
+			}
+		}
+	}
+}
+
+func TestRenderNoteCacheFollowsChangeSeq(t *testing.T) {
+	s := NewNoteService(nil)
+	note := model.Note{ID: 1, ChangeSeq: 1, Content: "первая"}
+
+	first, err := s.RenderNote(note, RenderHTML)
+	if err != nil {
+		t.Fatalf("RenderNote: %v", err)
+	}
+
+	// Та же версия берётся из кеша, даже если текст передан другой
+	note.Content = "вторая"
+	cached, err := s.RenderNote(note, RenderHTML)
+	if err != nil || string(cached.Body) != string(first.Body) || cached.ETag() != first.ETag() {
+		t.Errorf("та же версия отрендерена заново: %s, %v", cached.Body, err)
+	}
+
+	// Новая версия вытесняет старую запись
+	note.ChangeSeq = 2
+	fresh, err := s.RenderNote(note, RenderHTML)
+	if err != nil || !strings.Contains(string(fresh.Body), "вторая") || fresh.ETag() == first.ETag() {
+		t.Errorf("новая версия: %s, ETag %s, %v", fresh.Body, fresh.ETag(), err)
+	}
+}
+
+func TestRenderCacheEvictsOldest(t *testing.T) {
+	c := newRenderCache(2)
+	keys := []renderKey{{noteID: 1, changeSeq: 1}, {noteID: 2, changeSeq: 1}, {noteID: 3, changeSeq: 1}}
+	c.put(keys[0], []byte("1"))
+	c.put(keys[1], []byte("2"))
+	c.get(keys[0])
+	c.put(keys[2], []byte("3"))
+
+	if _, ok := c.get(keys[1]); ok {
+		t.Error("давно не использованная запись не вытеснена")
+	}
+	for _, key := range []renderKey{keys[0], keys[2]} {
+		if _, ok := c.get(key); !ok {
+			t.Errorf("запись %v вытеснена", key)
+		}
+	}
+}
+
+func TestRenderNoteRejects(t *testing.T) {
+	s := NewNoteService(nil)
+	if _, err := s.RenderNote(model.Note{ID: 1, Encrypted: true}, RenderHTML); !errors.Is(err, ErrEncryptedNote) {
+		t.Errorf("зашифрованная заметка: %v", err)
+	}
+	if _, err := s.RenderNote(model.Note{ID: 1}, "pdf"); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("неизвестный формат: %v", err)
+	}
+}
@@ -0,0 +1,349 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"notes-api/events"
+	"notes-api/logger"
+	"notes-api/model"
+	"notes-api/problem"
+	storage "notes-api/repo"
+	"notes-api/tracing"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	SyncCleanupJobType = "sync.cleanup"
+
+	DefaultSyncLimit = 200
+	MaxSyncLimit     = 1000
+	MaxSyncBatch     = 500
+
+	// syncMutationRetention — сколько помнить применённые мутации для защиты от повторной отправки
+	syncMutationRetention = 30 * 24 * time.Hour
+)
+
+const (
+	SyncOpCreate = "create"
+	SyncOpUpdate = "update"
+	SyncOpDelete = "delete"
+)
+
+// Статусы результата мутации.
+//
+// Разрешение конфликтов: сервер всегда прав. Мутация update или delete применяется,
+// только если base_seq совпадает с текущим change_seq заметки. Иначе возвращается
+// conflict вместе с актуальной версией заметки (note) или tombstone (deleted), если
+// заметку уже удалили. Клиент должен слить свои изменения с серверной версией и
+// отправить новую мутацию с base_seq, равным change_seq серверной версии.
+const (
+	SyncApplied  = "applied"
+	SyncConflict = "conflict"
+	SyncNotFound = "not_found"
+	SyncInvalid  = "invalid"
+)
+
+var ErrInvalidSyncToken = errors.New("некорректный sync_token")
+
+// SyncChanges — изменения с момента sync_token
+type SyncChanges struct {
+	Notes     []model.Note          `json:"notes"`
+	Deleted   []model.NoteTombstone `json:"deleted"`
+	SyncToken string                `json:"sync_token"`
+	HasMore   bool                  `json:"has_more"`
+}
+
+// SyncMutationInput — одна изменённая на клиенте заметка
+type SyncMutationInput struct {
+	// MutationID — уникальный в пределах пользователя идентификатор, по которому
+	// повторно отправленная мутация распознаётся и не применяется второй раз
+	MutationID string `json:"mutation_id"`
+	Op         string `json:"op" enums:"create,update,delete"`
+	NoteID     uint   `json:"note_id"`
+	// BaseSeq — change_seq версии заметки, от которой клиент вносил изменения (обязателен для update и delete)
+	BaseSeq *int64 `json:"base_seq"`
+	Title   string `json:"title"`
+	Content string `json:"content"`
+	// Encrypted создаёт заметку, зашифрованную на клиенте (только для create); у update режим берётся из заметки
+	Encrypted bool `json:"encrypted"`
+}
+
+type SyncMutationResult struct {
+	MutationID string               `json:"mutation_id"`
+	Op         string               `json:"op"`
+	Status     string               `json:"status" enums:"applied,conflict,not_found,invalid"`
+	Note       *model.Note          `json:"note,omitempty"`
+	Deleted    *model.NoteTombstone `json:"deleted,omitempty"`
+	Error      string               `json:"error,omitempty"`
+}
+
+type SyncService struct {
+	Notes     *NoteService
+	Repo      storage.NoteRepository
+	Mutations storage.SyncMutationRepository
+}
+
+func NewSyncService(notes *NoteService, r storage.NoteRepository, m storage.SyncMutationRepository) *SyncService {
+	return &SyncService{Notes: notes, Repo: r, Mutations: m}
+}
+
+// Changes возвращает изменения после token. Пустой token означает первую синхронизацию:
+// клиент получает все заметки. Если has_more, нужно сразу запросить следующую страницу.
+func (s *SyncService) Changes(userID uint, token string, limit int) (SyncChanges, error) {
+	since, err := decodeSyncToken(token)
+	if err != nil {
+		return SyncChanges{}, err
+	}
+	if limit <= 0 {
+		limit = DefaultSyncLimit
+	}
+	limit = min(limit, MaxSyncLimit)
+
+	if since < 0 {
+		if err := s.Repo.BackfillChangeSeq(userID); err != nil {
+			return SyncChanges{}, err
+		}
+	}
+
+	notes, deleted, err := s.Repo.ChangesSince(userID, since, limit+1)
+	if err != nil {
+		return SyncChanges{}, err
+	}
+
+	changes := SyncChanges{Notes: notes, Deleted: deleted}
+	if len(notes)+len(deleted) > limit {
+		changes.HasMore = true
+		if len(deleted) == 0 || (len(notes) > 0 && notes[len(notes)-1].ChangeSeq > deleted[len(deleted)-1].ChangeSeq) {
+			changes.Notes = notes[:len(notes)-1]
+		} else {
+			changes.Deleted = deleted[:len(deleted)-1]
+		}
+	}
+
+	// Токен — наибольший отданный номер: всё, что зафиксируется позже, получит номер больше
+	next := max(since, 0)
+	if n := len(changes.Notes); n > 0 {
+		next = max(next, changes.Notes[n-1].ChangeSeq)
+	}
+	if n := len(changes.Deleted); n > 0 {
+		next = max(next, changes.Deleted[n-1].ChangeSeq)
+	}
+	changes.SyncToken = encodeSyncToken(next)
+
+	if changes.Notes == nil {
+		changes.Notes = []model.Note{}
+	}
+	if changes.Deleted == nil {
+		changes.Deleted = []model.NoteTombstone{}
+	}
+	return changes, nil
+}
+
+// Apply применяет пакет мутаций по порядку. Ошибка одной мутации не прерывает остальные.
+func (s *SyncService) Apply(userID uint, mutations []SyncMutationInput) ([]SyncMutationResult, error) {
+	if len(mutations) > MaxSyncBatch {
+		return nil, problem.Invalid(problem.FieldLimit("mutations", problem.FieldTooMany, MaxSyncBatch))
+	}
+
+	results := make([]SyncMutationResult, 0, len(mutations))
+	for _, m := range mutations {
+		if m.MutationID != "" {
+			if previous, ok := s.previousResult(userID, m.MutationID); ok {
+				results = append(results, previous)
+				continue
+			}
+		}
+
+		result := s.apply(userID, m)
+		if m.MutationID != "" && result.Status != SyncInvalid {
+			s.rememberResult(userID, result)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func (s *SyncService) apply(userID uint, m SyncMutationInput) SyncMutationResult {
+	result := SyncMutationResult{MutationID: m.MutationID, Op: m.Op}
+
+	switch m.Op {
+	case SyncOpCreate:
+		note, err := s.Notes.CreateNote(userID, model.Note{Title: m.Title, Content: m.Content, Encrypted: m.Encrypted})
+		if err != nil {
+			return invalidResult(result, err)
+		}
+		result.Status = SyncApplied
+		result.Note = &note
+		return result
+
+	case SyncOpUpdate, SyncOpDelete:
+		if m.NoteID == 0 || m.BaseSeq == nil {
+			return invalidResult(result, errors.New("для update и delete нужны note_id и base_seq"))
+		}
+	default:
+		return invalidResult(result, fmt.Errorf("неизвестная операция %q", m.Op))
+	}
+
+	current, err := s.Repo.GetByID(int(m.NoteID))
+	if err != nil || current.UserID != userID {
+		return s.missingResult(userID, result, m)
+	}
+
+	if m.Op == SyncOpUpdate {
+		note, err := s.Notes.UpdateNoteIfSeq(int(m.NoteID), *m.BaseSeq, model.Note{Title: m.Title, Content: m.Content})
+		return s.writeResult(userID, result, m, note, nil, err)
+	}
+
+	tombstone, err := s.Notes.DeleteNoteIfSeq(int(m.NoteID), *m.BaseSeq)
+	return s.writeResult(userID, result, m, model.Note{}, &tombstone, err)
+}
+
+func (s *SyncService) writeResult(userID uint, result SyncMutationResult, m SyncMutationInput, note model.Note, tombstone *model.NoteTombstone, err error) SyncMutationResult {
+	switch {
+	case err == nil:
+		result.Status = SyncApplied
+		if tombstone != nil {
+			result.Deleted = tombstone
+		} else {
+			result.Note = &note
+		}
+	case errors.Is(err, storage.ErrConflict):
+		result.Status = SyncConflict
+		if current, getErr := s.Repo.GetByID(int(m.NoteID)); getErr == nil {
+			result.Note = &current
+		}
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return s.missingResult(userID, result, m)
+	default:
+		return invalidResult(result, err)
+	}
+	return result
+}
+
+// missingResult разбирает мутацию заметки, которой нет: если заметку удалили, это
+// конфликт (или успех для повторного delete), иначе заметка клиенту не принадлежит
+func (s *SyncService) missingResult(userID uint, result SyncMutationResult, m SyncMutationInput) SyncMutationResult {
+	tombstone, err := s.Repo.GetTombstone(userID, m.NoteID)
+	if err != nil {
+		result.Status = SyncNotFound
+		return result
+	}
+
+	result.Deleted = &tombstone
+	if m.Op == SyncOpDelete {
+		result.Status = SyncApplied
+	} else {
+		result.Status = SyncConflict
+	}
+	return result
+}
+
+func invalidResult(result SyncMutationResult, err error) SyncMutationResult {
+	result.Status = SyncInvalid
+	result.Error = err.Error()
+	return result
+}
+
+func (s *SyncService) previousResult(userID uint, mutationID string) (SyncMutationResult, bool) {
+	stored, err := s.Mutations.GetSyncMutation(userID, mutationID)
+	if err != nil {
+		return SyncMutationResult{}, false
+	}
+
+	var result SyncMutationResult
+	if err := json.Unmarshal(stored.Result, &result); err != nil {
+		return SyncMutationResult{}, false
+	}
+	return result, true
+}
+
+func (s *SyncService) rememberResult(userID uint, result SyncMutationResult) {
+	data, err := json.Marshal(result)
+	if err == nil {
+		err = s.Mutations.SaveSyncMutation(&model.SyncMutation{
+			UserID:     userID,
+			MutationID: result.MutationID,
+			Result:     data,
+		})
+	}
+	if err != nil {
+		logger.Log.WithError(err).WithField("mutation_id", result.MutationID).Warn("Не удалось сохранить результат мутации")
+	}
+}
+
+// HandleCleanup забывает старые мутации синхронизации
+func (s *SyncService) HandleCleanup(ctx context.Context, job *model.Job) error {
+	n, err := s.Mutations.DeleteSyncMutationsBefore(time.Now().Add(-syncMutationRetention))
+	if err != nil {
+		return err
+	}
+	logger.Log.WithField("count", n).Info("Старые мутации синхронизации удалены")
+	return nil
+}
+
+// UpdateNoteIfSeq обновляет заметку, если её не меняли после версии baseSeq
+func (s *NoteService) UpdateNoteIfSeq(id int, baseSeq int64, updated model.Note) (_ model.Note, err error) {
+	s, span := s.trace("UpdateNoteIfSeq", noteID(id))
+	defer func() { tracing.End(span, err) }()
+	current, err := s.Repo.GetByID(id)
+	if err != nil {
+		return model.Note{}, err
+	}
+	if err := validateNote(current.Encrypted, updated); err != nil {
+		return model.Note{}, err
+	}
+	note, err := s.Repo.UpdateIfSeq(id, baseSeq, updated)
+	if err != nil {
+		return model.Note{}, err
+	}
+	s.publish(events.NoteUpdated, note)
+	return note, nil
+}
+
+// DeleteNoteIfSeq удаляет заметку, если её не меняли после версии baseSeq
+func (s *NoteService) DeleteNoteIfSeq(id int, baseSeq int64) (_ model.NoteTombstone, err error) {
+	s, span := s.trace("DeleteNoteIfSeq", noteID(id))
+	defer func() { tracing.End(span, err) }()
+	note, err := s.Repo.GetByID(id)
+	if err != nil {
+		return model.NoteTombstone{}, err
+	}
+	tombstone, err := s.Repo.DeleteIfSeq(id, baseSeq)
+	if err != nil {
+		return model.NoteTombstone{}, err
+	}
+	s.publish(events.NoteDeleted, note)
+	return tombstone, nil
+}
+
+// Токен синхронизации непрозрачен для клиента: "v1:<change_seq>" в base64url
+func encodeSyncToken(seq int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("v1:" + strconv.FormatInt(seq, 10)))
+}
+
+func decodeSyncToken(token string) (int64, error) {
+	if token == "" {
+		return -1, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, ErrInvalidSyncToken
+	}
+	value, ok := strings.CutPrefix(string(raw), "v1:")
+	if !ok {
+		return 0, ErrInvalidSyncToken
+	}
+	seq, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || seq < 0 {
+		return 0, ErrInvalidSyncToken
+	}
+	return seq, nil
+}
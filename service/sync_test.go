@@ -0,0 +1,203 @@
+package service
+
+import (
+	"encoding/base64"
+	"errors"
+	"notes-api/db/dbtest"
+	_ "notes-api/encryption"
+	"notes-api/model"
+	storage "notes-api/repo"
+	"slices"
+	"testing"
+)
+
+func newSyncService(t *testing.T) (*SyncService, uint) {
+	t.Helper()
+	db := dbtest.Open(t, &model.User{}, &model.Note{}, &model.NoteTombstone{}, &model.SyncMutation{})
+	user := model.User{Email: "user@example.com"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("создать пользователя: %v", err)
+	}
+	store := storage.NewPostgresStore(db)
+	return NewSyncService(NewNoteService(store), store, store), user.ID
+}
+
+func seq(v int64) *int64 { return &v }
+
+func applyOne(t *testing.T, s *SyncService, userID uint, m SyncMutationInput) SyncMutationResult {
+	t.Helper()
+	results, err := s.Apply(userID, []SyncMutationInput{m})
+	if err != nil || len(results) != 1 {
+		t.Fatalf("Apply %s: %v, %v", m.Op, results, err)
+	}
+	return results[0]
+}
+
+func TestSyncTokenRoundTrip(t *testing.T) {
+	for _, v := range []int64{0, 1, 42, 1 << 40} {
+		got, err := decodeSyncToken(encodeSyncToken(v))
+		if err != nil || got != v {
+			t.Errorf("токен %d прочитан как %d: %v", v, got, err)
+		}
+	}
+
+	if got, err := decodeSyncToken(""); err != nil || got != -1 {
+		t.Errorf("пустой токен прочитан как %d: %v", got, err)
+	}
+
+	encode := func(raw string) string { return base64.RawURLEncoding.EncodeToString([]byte(raw)) }
+	for _, token := range []string{"!!!", encode("v2:1"), encode("v1:"), encode("v1:x"), encode("v1:-1")} {
+		if _, err := decodeSyncToken(token); !errors.Is(err, ErrInvalidSyncToken) {
+			t.Errorf("токен %q принят: %v", token, err)
+		}
+	}
+}
+
+func TestChangesPagesAcrossTombstones(t *testing.T) {
+	s, userID := newSyncService(t)
+
+	// Номера изменений: a=1, b=2, c=3, удаление b=4
+	var notes []model.Note
+	for _, title := range []string{"a", "b", "c"} {
+		note, err := s.Notes.CreateNote(userID, model.Note{Title: title})
+		if err != nil {
+			t.Fatalf("CreateNote: %v", err)
+		}
+		notes = append(notes, note)
+	}
+	if err := s.Notes.DeleteNote(int(notes[1].ID)); err != nil {
+		t.Fatalf("DeleteNote: %v", err)
+	}
+
+	// Страницы по одной записи: заметки и tombstones идут вперемешку в порядке номеров
+	var got []int64
+	token := ""
+	for page := 0; ; page++ {
+		if page > 5 {
+			t.Fatal("has_more не сбрасывается")
+		}
+		changes, err := s.Changes(userID, token, 1)
+		if err != nil {
+			t.Fatalf("Changes: %v", err)
+		}
+		if n := len(changes.Notes) + len(changes.Deleted); n != 1 {
+			t.Fatalf("страница %d: %d записей", page, n)
+		}
+		for _, n := range changes.Notes {
+			got = append(got, n.ChangeSeq)
+		}
+		for _, d := range changes.Deleted {
+			if d.NoteID != notes[1].ID {
+				t.Errorf("tombstone заметки %d, ожидалась %d", d.NoteID, notes[1].ID)
+			}
+			got = append(got, -d.ChangeSeq)
+		}
+		token = changes.SyncToken
+		if !changes.HasMore {
+			break
+		}
+	}
+	if want := []int64{1, 3, -4}; !slices.Equal(got, want) {
+		t.Errorf("записи по страницам %v, ожидались %v (tombstones со знаком минус)", got, want)
+	}
+
+	// С последним токеном новых изменений нет, токен не меняется
+	changes, err := s.Changes(userID, token, 10)
+	if err != nil || len(changes.Notes) != 0 || len(changes.Deleted) != 0 || changes.HasMore || changes.SyncToken != token {
+		t.Errorf("после последней страницы: %+v, %v", changes, err)
+	}
+
+	if _, err := s.Changes(userID, "мусор", 10); !errors.Is(err, ErrInvalidSyncToken) {
+		t.Errorf("некорректный токен принят: %v", err)
+	}
+}
+
+func TestApplyConflictOnStaleSeq(t *testing.T) {
+	s, userID := newSyncService(t)
+
+	created := applyOne(t, s, userID, SyncMutationInput{Op: SyncOpCreate, Title: "a", Content: "1"})
+	if created.Status != SyncApplied || created.Note == nil {
+		t.Fatalf("create: %+v", created)
+	}
+	base := created.Note.ChangeSeq
+
+	updated := applyOne(t, s, userID, SyncMutationInput{Op: SyncOpUpdate, NoteID: created.Note.ID, BaseSeq: seq(base), Title: "a", Content: "2"})
+	if updated.Status != SyncApplied || updated.Note == nil || updated.Note.ChangeSeq <= base {
+		t.Fatalf("update: %+v", updated)
+	}
+
+	// Второй клиент правит ту же исходную версию и получает серверную
+	for _, op := range []string{SyncOpUpdate, SyncOpDelete} {
+		stale := applyOne(t, s, userID, SyncMutationInput{Op: op, NoteID: created.Note.ID, BaseSeq: seq(base), Title: "a", Content: "3"})
+		if stale.Status != SyncConflict || stale.Note == nil || stale.Note.Content != "2" || stale.Note.ChangeSeq != updated.Note.ChangeSeq {
+			t.Errorf("%s с устаревшим base_seq: %+v", op, stale)
+		}
+	}
+
+	// Без base_seq мутация некорректна
+	if invalid := applyOne(t, s, userID, SyncMutationInput{Op: SyncOpUpdate, NoteID: created.Note.ID}); invalid.Status != SyncInvalid {
+		t.Errorf("update без base_seq: %+v", invalid)
+	}
+}
+
+func TestApplyDeleteLeavesTombstone(t *testing.T) {
+	s, userID := newSyncService(t)
+
+	created := applyOne(t, s, userID, SyncMutationInput{Op: SyncOpCreate, Title: "a"})
+	before, err := s.Changes(userID, "", 10)
+	if err != nil {
+		t.Fatalf("Changes: %v", err)
+	}
+
+	deleted := applyOne(t, s, userID, SyncMutationInput{Op: SyncOpDelete, NoteID: created.Note.ID, BaseSeq: seq(created.Note.ChangeSeq)})
+	if deleted.Status != SyncApplied || deleted.Deleted == nil || deleted.Deleted.NoteID != created.Note.ID {
+		t.Fatalf("delete: %+v", deleted)
+	}
+
+	changes, err := s.Changes(userID, before.SyncToken, 10)
+	if err != nil {
+		t.Fatalf("Changes: %v", err)
+	}
+	if len(changes.Notes) != 0 || len(changes.Deleted) != 1 || changes.Deleted[0].NoteID != created.Note.ID {
+		t.Errorf("после удаления: заметки %v, tombstones %v", changes.Notes, changes.Deleted)
+	}
+
+	// Изменение удалённой заметки — конфликт с tombstone, повторное удаление — успех
+	update := applyOne(t, s, userID, SyncMutationInput{Op: SyncOpUpdate, NoteID: created.Note.ID, BaseSeq: seq(created.Note.ChangeSeq), Title: "b"})
+	if update.Status != SyncConflict || update.Deleted == nil {
+		t.Errorf("update удалённой заметки: %+v", update)
+	}
+	again := applyOne(t, s, userID, SyncMutationInput{Op: SyncOpDelete, NoteID: created.Note.ID, BaseSeq: seq(created.Note.ChangeSeq)})
+	if again.Status != SyncApplied || again.Deleted == nil {
+		t.Errorf("повторный delete: %+v", again)
+	}
+
+	// Чужая заметка не находится
+	other := applyOne(t, s, userID+1, SyncMutationInput{Op: SyncOpDelete, NoteID: created.Note.ID, BaseSeq: seq(0)})
+	if other.Status != SyncNotFound {
+		t.Errorf("delete чужой заметки: %+v", other)
+	}
+}
+
+func TestApplyReplaysMutation(t *testing.T) {
+	s, userID := newSyncService(t)
+
+	m := SyncMutationInput{MutationID: "m1", Op: SyncOpCreate, Title: "a"}
+	first := applyOne(t, s, userID, m)
+	second := applyOne(t, s, userID, m)
+	if first.Status != SyncApplied || second.Status != SyncApplied || second.Note == nil || second.Note.ID != first.Note.ID {
+		t.Errorf("повтор мутации: %+v, затем %+v", first, second)
+	}
+
+	notes, err := s.Repo.GetByUserID(int(userID), true)
+	if err != nil || len(notes) != 1 {
+		t.Errorf("после повтора %d заметок: %v", len(notes), err)
+	}
+
+	// Некорректная мутация не запоминается: исправленная с тем же mutation_id применяется
+	invalid := applyOne(t, s, userID, SyncMutationInput{MutationID: "m2", Op: SyncOpUpdate})
+	fixed := applyOne(t, s, userID, SyncMutationInput{MutationID: "m2", Op: SyncOpCreate, Title: "b"})
+	if invalid.Status != SyncInvalid || fixed.Status != SyncApplied {
+		t.Errorf("исправленная мутация: %+v, затем %+v", invalid, fixed)
+	}
+}
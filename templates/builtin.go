@@ -0,0 +1,55 @@
+package templates
+
+import "notes-api/model"
+
+// builtinTemplates — общие шаблоны, которые создаются при запуске сервиса
+var builtinTemplates = []model.Template{
+	{
+		Name:        "Встреча",
+		Description: "Протокол встречи. Переменные: topic — тема, participants — участники",
+		Title:       `Встреча{{with .topic}}: {{.}}{{end}} — {{date "02.01.2006"}}`,
+		Content: `# {{default "Встреча" .topic}}
+
+**Дата:** {{date "02.01.2006"}} {{time}}
+**Ведущий:** {{user.email}}
+**Участники:** {{default "—" .participants}}
+
+## Повестка
+
+-
+
+## Решения
+
+-
+
+## Задачи
+
+- [ ]
+`,
+	},
+	{
+		Name:        "Инцидент",
+		Description: "Разбор инцидента. Переменные: service — затронутый сервис, severity — критичность",
+		Title:       `Инцидент{{with .service}} в {{.}}{{end}} — {{date}}`,
+		Content: `# Инцидент{{with .service}}: {{.}}{{end}}
+
+**Обнаружен:** {{date}} {{time}}
+**Критичность:** {{default "не определена" .severity}}
+**Ответственный:** {{user.email}}
+
+## Что произошло
+
+## Влияние на пользователей
+
+## Хронология
+
+- {{time}} — инцидент обнаружен
+
+## Причина
+
+## Что сделать, чтобы не повторилось
+
+- [ ]
+`,
+	},
+}
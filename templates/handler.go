@@ -0,0 +1,220 @@
+package templates
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"notes-api/httpx"
+	"notes-api/logger"
+	"notes-api/problem"
+	"strconv"
+)
+
+// maxRequestBody — предел тела запроса с шаблоном или переменными
+const maxRequestBody = 2 << 20
+
+type TemplateHandler struct {
+	Service *TemplateService
+}
+
+// List godoc
+// @Summary Получить шаблоны заметок
+// @Description Общие шаблоны (user_id = 0) и шаблоны текущего пользователя
+// @Tags templates
+// @Security ApiKeyAuth
+// @Produce json
+// @Success 200 {array} model.Template "Шаблоны"
+// @Failure 401 {object} problem.Problem "Пользователь не аутентифицирован"
+// @Router /templates [get]
+func (h *TemplateHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := httpx.UserID(w, r)
+	if !ok {
+		return
+	}
+
+	templates, err := h.Service.List(userID)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, templates)
+}
+
+// Create godoc
+// @Summary Создать шаблон заметки
+// @Description Заголовок и текст — шаблоны Go text/template. Доступны {{date}} и {{time}} (с необязательным форматом Go, например {{date "02.01.2006"}}), {{now}}, {{user.email}}, {{user.id}}, переменные из запроса {{.имя}}, а также default, upper, lower и trim. Циклы, define и template не поддерживаются
+// @Tags templates
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param input body templates.TemplateInput true "Шаблон"
+// @Success 201 {object} model.Template "Созданный шаблон"
+// @Failure 400 {object} problem.Problem "Неверный запрос или ошибка в шаблоне"
+// @Router /templates [post]
+func (h *TemplateHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, ok := httpx.UserID(w, r)
+	if !ok {
+		return
+	}
+
+	var input TemplateInput
+	if !decode(w, r, &input) {
+		return
+	}
+
+	t, err := h.Service.Create(userID, input)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	httpx.WriteJSON(w, http.StatusCreated, t)
+	logger.FromContext(r.Context()).WithFields(logger.Fields{
+		"user_id":     userID,
+		"template_id": t.ID,
+	}).Info("Шаблон создан")
+}
+
+// Get godoc
+// @Summary Получить шаблон по ID
+// @Tags templates
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path int true "ID шаблона"
+// @Success 200 {object} model.Template "Шаблон"
+// @Failure 404 {object} problem.Problem "Шаблон не найден"
+// @Router /templates/{id} [get]
+func (h *TemplateHandler) Get(w http.ResponseWriter, r *http.Request) {
+	userID, ok := httpx.UserID(w, r)
+	if !ok {
+		return
+	}
+	id, ok := httpx.PathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	t, err := h.Service.Get(userID, id)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, t)
+}
+
+// Update godoc
+// @Summary Изменить шаблон
+// @Tags templates
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "ID шаблона"
+// @Param input body templates.TemplateInput true "Шаблон"
+// @Success 200 {object} model.Template "Изменённый шаблон"
+// @Failure 400 {object} problem.Problem "Неверный запрос или ошибка в шаблоне"
+// @Failure 403 {object} problem.Problem "Общие шаблоны нельзя изменять"
+// @Failure 404 {object} problem.Problem "Шаблон не найден"
+// @Router /templates/{id} [put]
+func (h *TemplateHandler) Update(w http.ResponseWriter, r *http.Request) {
+	userID, ok := httpx.UserID(w, r)
+	if !ok {
+		return
+	}
+	id, ok := httpx.PathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var input TemplateInput
+	if !decode(w, r, &input) {
+		return
+	}
+
+	t, err := h.Service.Update(userID, id, input)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, t)
+}
+
+// Delete godoc
+// @Summary Удалить шаблон
+// @Tags templates
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path int true "ID шаблона"
+// @Success 200 {object} map[string]string "Сообщение об удалении"
+// @Failure 403 {object} problem.Problem "Общие шаблоны нельзя изменять"
+// @Failure 404 {object} problem.Problem "Шаблон не найден"
+// @Router /templates/{id} [delete]
+func (h *TemplateHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	userID, ok := httpx.UserID(w, r)
+	if !ok {
+		return
+	}
+	id, ok := httpx.PathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.Service.Delete(userID, id); err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, map[string]string{"message": "Шаблон удалён"})
+	logger.FromContext(r.Context()).WithFields(logger.Fields{
+		"user_id":     userID,
+		"template_id": id,
+	}).Info("Шаблон удалён")
+}
+
+// Instantiate обслуживает POST /notes?template_id=: тело запроса содержит не заметку,
+// а переменные шаблона. В swagger описан вместе с обычным созданием заметки.
+func (h *TemplateHandler) Instantiate(w http.ResponseWriter, r *http.Request) {
+	userID, ok := httpx.UserID(w, r)
+	if !ok {
+		return
+	}
+	templateID, err := strconv.Atoi(r.URL.Query().Get("template_id"))
+	if err != nil || templateID <= 0 {
+		problem.Error(w, r, problem.Invalid(problem.Field("template_id", problem.FieldInvalidFormat)))
+		return
+	}
+
+	var input InstantiateInput
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBody)
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil && !errors.Is(err, io.EOF) {
+		logger.FromContext(r.Context()).WithError(err).Warn("Неверный запрос")
+		problem.Write(w, r, problem.BadRequest)
+		return
+	}
+
+	note, err := h.Service.Instantiate(userID, uint(templateID), input)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	httpx.WriteJSON(w, http.StatusCreated, note)
+	logger.FromContext(r.Context()).WithFields(logger.Fields{
+		"user_id":     userID,
+		"template_id": templateID,
+		"note_id":     note.ID,
+	}).Info("Заметка создана из шаблона")
+}
+
+func decode(w http.ResponseWriter, r *http.Request, v any) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBody)
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		logger.FromContext(r.Context()).WithError(err).Warn("Неверный запрос")
+		problem.Write(w, r, problem.BadRequest)
+		return false
+	}
+	return true
+}
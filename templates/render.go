@@ -0,0 +1,160 @@
+package templates
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"notes-api/problem"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+)
+
+const (
+	// maxTemplateLength — наибольший размер заголовка или текста шаблона
+	maxTemplateLength = 64 << 10
+	// maxOutputLength — наибольший размер результата; совпадает с пределом текста заметки в совместном редактировании
+	maxOutputLength = 1 << 20
+
+	defaultDateLayout = "2006-01-02"
+	defaultTimeLayout = "15:04"
+)
+
+var (
+	errOutputTooLarge = errors.New("результат шаблона слишком большой")
+	errFuncDisabled   = errors.New("функция недоступна в шаблонах")
+)
+
+// Context — данные, доступные шаблону при создании заметки
+type Context struct {
+	Now       time.Time
+	UserID    uint
+	UserEmail string
+	// Vars — переменные из запроса, доступны как {{.имя}}
+	Vars map[string]string
+}
+
+// funcs — функции шаблонов. Встроенные printf, print и println перекрыты: ширина поля
+// в printf позволяет одним коротким шаблоном выделить гигабайты памяти.
+func funcs(c Context) template.FuncMap {
+	return template.FuncMap{
+		"date": func(layout ...string) string { return c.Now.Format(firstOr(layout, defaultDateLayout)) },
+		"time": func(layout ...string) string { return c.Now.Format(firstOr(layout, defaultTimeLayout)) },
+		"now":  func() time.Time { return c.Now },
+		"user": func() map[string]any {
+			return map[string]any{"id": c.UserID, "email": c.UserEmail}
+		},
+		"default": func(fallback, value string) string {
+			if value == "" {
+				return fallback
+			}
+			return value
+		},
+		"upper":   strings.ToUpper,
+		"lower":   strings.ToLower,
+		"trim":    strings.TrimSpace,
+		"printf":  disabled,
+		"print":   disabled,
+		"println": disabled,
+	}
+}
+
+func disabled(...any) (string, error) {
+	return "", errFuncDisabled
+}
+
+func firstOr(values []string, fallback string) string {
+	if len(values) > 0 && values[0] != "" {
+		return values[0]
+	}
+	return fallback
+}
+
+// compile разбирает шаблон и проверяет, что в нём нет конструкций, которыми можно
+// нагрузить сервер: циклов (range по числу крутится без вывода) и вызовов шаблонов
+// (define/template позволяют экспоненциальную рекурсию)
+func compile(name, text string, c Context) (*template.Template, error) {
+	if len(text) > maxTemplateLength {
+		return nil, problem.Invalid(problem.FieldLimit(name, problem.FieldTooLong, maxTemplateLength))
+	}
+
+	// Текст ошибки разбора с номером строки остаётся в логе, клиент получает код поля
+	t, err := template.New(name).Option("missingkey=zero").Funcs(funcs(c)).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", problem.Invalid(problem.Field(name, problem.FieldTemplate)), err)
+	}
+	for _, tree := range t.Templates() {
+		// define и block добавляют шаблоны с другими именами
+		if tree.Name() != name || !allowed(tree.Tree.Root) {
+			return nil, problem.Invalid(problem.Field(name, problem.FieldTemplateAction))
+		}
+	}
+	return t, nil
+}
+
+// allowed сообщает, что в дереве нет range и template
+func allowed(node parse.Node) bool {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return true
+		}
+		for _, child := range n.Nodes {
+			if !allowed(child) {
+				return false
+			}
+		}
+	case *parse.RangeNode, *parse.TemplateNode:
+		return false
+	case *parse.IfNode:
+		return allowedBranch(&n.BranchNode)
+	case *parse.WithNode:
+		return allowedBranch(&n.BranchNode)
+	}
+	return true
+}
+
+func allowedBranch(n *parse.BranchNode) bool {
+	return allowed(n.List) && allowed(n.ElseList)
+}
+
+// execute выполняет шаблон, обрывая его, когда результат превышает maxOutputLength
+func execute(name, text string, c Context) (string, error) {
+	t, err := compile(name, text, c)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	vars := c.Vars
+	if vars == nil {
+		vars = map[string]string{}
+	}
+	if err := t.Execute(&limitedWriter{w: &b, remaining: maxOutputLength}, vars); err != nil {
+		if errors.Is(err, errOutputTooLarge) {
+			return "", problem.Invalid(problem.FieldLimit(name, problem.FieldOutputTooLong, maxOutputLength))
+		}
+		return "", fmt.Errorf("%w: %v", problem.Invalid(problem.Field(name, problem.FieldTemplate)), err)
+	}
+	return b.String(), nil
+}
+
+// validate проверяет шаблон без выполнения
+func validate(name, text string) error {
+	_, err := compile(name, text, Context{})
+	return err
+}
+
+type limitedWriter struct {
+	w         io.Writer
+	remaining int
+}
+
+func (l *limitedWriter) Write(p []byte) (int, error) {
+	if len(p) > l.remaining {
+		return 0, errOutputTooLarge
+	}
+	l.remaining -= len(p)
+	return l.w.Write(p)
+}
@@ -0,0 +1,92 @@
+package templates
+
+import (
+	"errors"
+	"notes-api/problem"
+	"strings"
+	"testing"
+	"time"
+)
+
+func fieldError(err error) (problem.FieldError, bool) {
+	var invalid *problem.ValidationError
+	if !errors.As(err, &invalid) || len(invalid.Fields) != 1 {
+		return problem.FieldError{}, false
+	}
+	return invalid.Fields[0], true
+}
+
+func TestExecute(t *testing.T) {
+	c := Context{
+		Now:       time.Date(2026, 3, 2, 9, 5, 0, 0, time.UTC),
+		UserID:    7,
+		UserEmail: "user@example.com",
+		Vars:      map[string]string{"project": "notes"},
+	}
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"Встреча {{date}} в {{time}}", "Встреча 2026-03-02 в 09:05"},
+		{`{{date "02.01.2006"}}`, "02.03.2026"},
+		{"{{.project | upper}} / {{.missing}}", "NOTES / "},
+		{`{{default "без проекта" .missing}} {{default "x" .project}}`, "без проекта notes"},
+		{"{{(user).email}} {{(user).id}}", "user@example.com 7"},
+		{`{{if .project}}да{{else}}нет{{end}} {{with .missing}}{{.}}{{else}}пусто{{end}}`, "да пусто"},
+		{`{{trim "  a  " | lower}}`, "a"},
+	}
+	for _, tt := range tests {
+		got, err := execute("content", tt.text, c)
+		if err != nil || got != tt.want {
+			t.Errorf("execute(%q) = %q, %v, ожидалось %q", tt.text, got, err, tt.want)
+		}
+	}
+}
+
+func TestSandboxRejectsActions(t *testing.T) {
+	for _, text := range []string{
+		`{{range .}}x{{end}}`,
+		`{{if true}}{{range .}}x{{end}}{{end}}`,
+		`{{with .project}}{{else}}{{range .}}{{end}}{{end}}`,
+		`{{define "t"}}x{{end}}`,
+		`{{block "t" .}}x{{end}}`,
+		`{{template "content"}}`,
+	} {
+		err := validate("content", text)
+		if f, ok := fieldError(err); !ok || f != problem.Field("content", problem.FieldTemplateAction) {
+			t.Errorf("validate(%q): %v, ожидалась ошибка %s", text, err, problem.FieldTemplateAction)
+		}
+	}
+}
+
+func TestSandboxDisablesPrint(t *testing.T) {
+	for _, text := range []string{`{{printf "%9999999d" 1}}`, `{{print 1}}`, `{{println 1}}`} {
+		_, err := execute("content", text, Context{})
+		if f, ok := fieldError(err); !ok || f != problem.Field("content", problem.FieldTemplate) {
+			t.Errorf("execute(%q): %v, ожидалась ошибка %s", text, err, problem.FieldTemplate)
+		}
+	}
+}
+
+func TestSandboxLimits(t *testing.T) {
+	_, err := execute("title", strings.Repeat("a", maxTemplateLength+1), Context{})
+	if f, ok := fieldError(err); !ok || f != problem.FieldLimit("title", problem.FieldTooLong, maxTemplateLength) {
+		t.Errorf("длинный шаблон: %v", err)
+	}
+
+	big := Context{Vars: map[string]string{"big": strings.Repeat("a", maxOutputLength/2+1)}}
+	if _, err := execute("content", "{{.big}}", big); err != nil {
+		t.Errorf("результат в пределах лимита: %v", err)
+	}
+	_, err = execute("content", "{{.big}}{{.big}}", big)
+	if f, ok := fieldError(err); !ok || f != problem.FieldLimit("content", problem.FieldOutputTooLong, maxOutputLength) {
+		t.Errorf("слишком большой результат: %v", err)
+	}
+}
+
+func TestSyntaxError(t *testing.T) {
+	err := validate("title", "{{.project")
+	if f, ok := fieldError(err); !ok || f != problem.Field("title", problem.FieldTemplate) {
+		t.Errorf("синтаксическая ошибка: %v", err)
+	}
+}
@@ -0,0 +1,200 @@
+package templates
+
+import (
+	"errors"
+	"notes-api/model"
+	"notes-api/problem"
+	"notes-api/service"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+const (
+	maxNameLength = 200
+	// maxVars — сколько переменных можно передать при создании заметки из шаблона
+	maxVars = 100
+)
+
+var (
+	ErrTemplateNotFound = errors.New("шаблон не найден")
+	ErrForbidden        = errors.New("общие шаблоны нельзя изменять")
+)
+
+// TemplateInput — данные для создания и изменения шаблона
+type TemplateInput struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// Title и Content — шаблоны text/template, например "Встреча {{date}}"
+	Title   string `json:"title"`
+	Content string `json:"content"`
+}
+
+// InstantiateInput — параметры создания заметки из шаблона
+type InstantiateInput struct {
+	// Variables — пользовательские переменные, в шаблоне доступны как {{.имя}}
+	Variables map[string]string `json:"variables"`
+	// Timezone — часовой пояс IANA для date, time и now (по умолчанию UTC)
+	Timezone string `json:"timezone" example:"Europe/Moscow"`
+}
+
+type TemplateService struct {
+	DB    *gorm.DB
+	Notes *service.NoteService
+}
+
+func NewTemplateService(db *gorm.DB, notes *service.NoteService) *TemplateService {
+	return &TemplateService{DB: db, Notes: notes}
+}
+
+// List возвращает общие шаблоны и шаблоны пользователя
+func (s *TemplateService) List(userID uint) ([]model.Template, error) {
+	templates := []model.Template{}
+	err := s.DB.Where("user_id IN ?", []uint{0, userID}).Order("user_id, name, id").Find(&templates).Error
+	return templates, err
+}
+
+// Get возвращает шаблон пользователя или общий шаблон
+func (s *TemplateService) Get(userID, id uint) (model.Template, error) {
+	var t model.Template
+	if err := s.DB.Where("id = ? AND user_id IN ?", id, []uint{0, userID}).First(&t).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.Template{}, ErrTemplateNotFound
+		}
+		return model.Template{}, err
+	}
+	return t, nil
+}
+
+func (s *TemplateService) Create(userID uint, input TemplateInput) (model.Template, error) {
+	if err := validateInput(input); err != nil {
+		return model.Template{}, err
+	}
+
+	t := model.Template{
+		UserID:      userID,
+		Name:        strings.TrimSpace(input.Name),
+		Description: input.Description,
+		Title:       input.Title,
+		Content:     input.Content,
+	}
+	if err := s.DB.Create(&t).Error; err != nil {
+		return model.Template{}, err
+	}
+	return t, nil
+}
+
+func (s *TemplateService) Update(userID, id uint, input TemplateInput) (model.Template, error) {
+	t, err := s.Get(userID, id)
+	if err != nil {
+		return model.Template{}, err
+	}
+	if t.Global() {
+		return model.Template{}, ErrForbidden
+	}
+	if err := validateInput(input); err != nil {
+		return model.Template{}, err
+	}
+
+	t.Name = strings.TrimSpace(input.Name)
+	t.Description = input.Description
+	t.Title = input.Title
+	t.Content = input.Content
+	if err := s.DB.Save(&t).Error; err != nil {
+		return model.Template{}, err
+	}
+	return t, nil
+}
+
+func (s *TemplateService) Delete(userID, id uint) error {
+	t, err := s.Get(userID, id)
+	if err != nil {
+		return err
+	}
+	if t.Global() {
+		return ErrForbidden
+	}
+	return s.DB.Delete(&t).Error
+}
+
+// Instantiate создаёт заметку пользователя из шаблона
+func (s *TemplateService) Instantiate(userID, templateID uint, input InstantiateInput) (model.Note, error) {
+	t, err := s.Get(userID, templateID)
+	if err != nil {
+		return model.Note{}, err
+	}
+	if len(input.Variables) > maxVars {
+		return model.Note{}, problem.Invalid(problem.FieldLimit("variables", problem.FieldTooMany, maxVars))
+	}
+
+	loc := time.UTC
+	if input.Timezone != "" {
+		if loc, err = time.LoadLocation(input.Timezone); err != nil {
+			return model.Note{}, problem.Invalid(problem.Field("timezone", problem.FieldTimezone))
+		}
+	}
+
+	var user model.User
+	if err := s.DB.Select("id", "email").First(&user, userID).Error; err != nil {
+		return model.Note{}, err
+	}
+
+	c := Context{
+		Now:       time.Now().In(loc),
+		UserID:    user.ID,
+		UserEmail: user.Email,
+		Vars:      input.Variables,
+	}
+	title, err := execute("title", t.Title, c)
+	if err != nil {
+		return model.Note{}, err
+	}
+	content, err := execute("content", t.Content, c)
+	if err != nil {
+		return model.Note{}, err
+	}
+
+	if strings.TrimSpace(title) == "" && strings.TrimSpace(content) == "" {
+		return model.Note{}, problem.Invalid(problem.Field("title", problem.FieldRequired), problem.Field("content", problem.FieldRequired))
+	}
+	return s.Notes.CreateNote(userID, model.Note{Title: title, Content: content})
+}
+
+// SeedGlobal создаёт или обновляет встроенные общие шаблоны
+func (s *TemplateService) SeedGlobal() error {
+	for _, builtin := range builtinTemplates {
+		var t model.Template
+		// Условие строкой: в структуре нулевой UserID был бы пропущен
+		err := s.DB.Where("user_id = 0 AND name = ?", builtin.Name).
+			Attrs(model.Template{Name: builtin.Name}).
+			Assign(model.Template{Description: builtin.Description, Title: builtin.Title, Content: builtin.Content}).
+			FirstOrCreate(&t).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateInput(input TemplateInput) error {
+	var fields []problem.FieldError
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		fields = append(fields, problem.Field("name", problem.FieldRequired))
+	}
+	if utf8.RuneCountInString(name) > maxNameLength {
+		fields = append(fields, problem.FieldLimit("name", problem.FieldTooLong, maxNameLength))
+	}
+	if input.Title == "" && input.Content == "" {
+		fields = append(fields, problem.Field("title", problem.FieldRequired), problem.Field("content", problem.FieldRequired))
+	}
+	if len(fields) > 0 {
+		return problem.Invalid(fields...)
+	}
+	if err := validate("title", input.Title); err != nil {
+		return err
+	}
+	return validate("content", input.Content)
+}
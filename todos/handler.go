@@ -0,0 +1,127 @@
+package todos
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"notes-api/httpx"
+	"notes-api/logger"
+	"notes-api/problem"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type TodoHandler struct {
+	Service *TodoService
+}
+
+// List godoc
+// @Summary Пункты списков задач из всех заметок
+// @Description Пункты "- [ ] ..." извлекаются из текста заметок при сохранении. По умолчанию возвращаются только открытые, сначала из недавно изменённых заметок
+// @Tags todos
+// @Security ApiKeyAuth
+// @Produce json
+// @Param status query string false "Состояние пунктов (по умолчанию open)" Enums(open, done, all)
+// @Param note_id query int false "Только пункты этой заметки"
+// @Param q query string false "Подстрока текста пункта"
+// @Param limit query int false "Сколько пунктов вернуть (по умолчанию 100, максимум 500)"
+// @Param offset query int false "Сколько пунктов пропустить"
+// @Success 200 {array} model.NoteTodo "Пункты списков задач"
+// @Failure 400 {object} problem.Problem "Неверные параметры фильтра"
+// @Failure 401 {object} problem.Problem "Пользователь не аутентифицирован"
+// @Router /todos [get]
+func (h *TodoHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := httpx.UserID(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	filter := Filter{
+		Status: query.Get("status"),
+		Query:  query.Get("q"),
+	}
+	for name, dst := range map[string]*int{"limit": &filter.Limit, "offset": &filter.Offset} {
+		if raw := query.Get(name); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil || n < 0 {
+				problem.Error(w, r, ErrInvalidQuery)
+				return
+			}
+			*dst = n
+		}
+	}
+	if raw := query.Get("note_id"); raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 0)
+		if err != nil {
+			problem.Error(w, r, ErrInvalidQuery)
+			return
+		}
+		filter.NoteID = uint(id)
+	}
+
+	todos, err := h.Service.List(userID, filter)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, todos)
+}
+
+// Toggle godoc
+// @Summary Переключить пункт списка задач
+// @Description Меняет флажок пункта прямо в тексте заметки. Если передать change_seq и text пункта, который видел клиент, пункт будет найден по тексту даже после параллельных правок заметки; если его больше нет, вернётся 409
+// @Tags todos
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "ID заметки"
+// @Param index path int true "Номер пункта в заметке, начиная с 0"
+// @Param input body todos.ToggleInput false "Версия заметки и текст пункта, которые видел клиент"
+// @Success 200 {object} todos.ToggleResult "Переключённый пункт и обновлённая заметка"
+// @Failure 400 {object} problem.Problem "Неверный запрос"
+// @Failure 403 {object} problem.Problem "Доступ запрещен"
+// @Failure 404 {object} problem.Problem "Заметка или пункт не найдены"
+// @Failure 409 {object} problem.Problem "Пункт изменился"
+// @Router /notes/{id}/todos/{index}/toggle [post]
+func (h *TodoHandler) Toggle(w http.ResponseWriter, r *http.Request) {
+	userID, ok := httpx.UserID(w, r)
+	if !ok {
+		return
+	}
+	noteID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || noteID <= 0 {
+		logger.FromContext(r.Context()).WithError(err).Warn("Неверный ID")
+		problem.Write(w, r, problem.InvalidID)
+		return
+	}
+	index, err := strconv.Atoi(mux.Vars(r)["index"])
+	if err != nil || index < 0 {
+		problem.Error(w, r, problem.Invalid(problem.Field("index", problem.FieldInvalidFormat)))
+		return
+	}
+
+	var in ToggleInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil && !errors.Is(err, io.EOF) {
+		logger.FromContext(r.Context()).WithError(err).Warn("Неверный запрос")
+		problem.Write(w, r, problem.BadRequest)
+		return
+	}
+
+	result, err := h.Service.Toggle(userID, uint(noteID), index, in)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, result)
+	logger.FromContext(r.Context()).WithFields(logger.Fields{
+		"user_id": userID,
+		"note_id": noteID,
+		"index":   result.Todo.Index,
+		"done":    result.Todo.Done,
+	}).Info("Пункт списка задач переключён")
+}
@@ -0,0 +1,96 @@
+package todos
+
+import (
+	"regexp"
+	"strings"
+)
+
+// taskLine — пункт списка задач GFM: маркер списка, флажок и текст.
+// Группа 1 — всё до флажка, группа 2 — его состояние, группа 3 — текст.
+var taskLine = regexp.MustCompile(`^(\s*(?:>\s*)*(?:[-*+]|\d{1,9}[.)])\s+)\[([ xX])\]\s+(\S.*)$`)
+
+// fence — начало или конец блока кода; внутри блоков флажки не считаются задачами
+var fence = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})")
+
+// Task — пункт списка задач, найденный в тексте заметки
+type Task struct {
+	Index int
+	Line  int
+	Text  string
+	Done  bool
+	// offset — позиция символа внутри [ ] в тексте заметки
+	offset int
+}
+
+// ParseTasks находит пункты списков задач вне блоков кода
+func ParseTasks(content string) []Task {
+	var tasks []Task
+	openFence := ""
+	offset := 0
+	for i, line := range strings.SplitAfter(content, "\n") {
+		start := offset
+		offset += len(line)
+		line = strings.TrimRight(line, "\r\n")
+
+		if m := fence.FindStringSubmatch(line); m != nil {
+			switch {
+			case openFence == "":
+				openFence = m[1]
+			case m[1][0] == openFence[0] && len(m[1]) >= len(openFence) && strings.TrimSpace(line[len(m[0]):]) == "":
+				openFence = ""
+			}
+			continue
+		}
+		if openFence != "" {
+			continue
+		}
+
+		m := taskLine.FindStringSubmatchIndex(line)
+		if m == nil {
+			continue
+		}
+		tasks = append(tasks, Task{
+			Index:  len(tasks),
+			Line:   i + 1,
+			Text:   strings.TrimSpace(line[m[6]:m[7]]),
+			Done:   line[m[4]] != ' ',
+			offset: start + m[4],
+		})
+	}
+	return tasks
+}
+
+// setDone возвращает текст заметки с изменённым состоянием флажка задачи
+func setDone(content string, task Task, done bool) string {
+	mark := " "
+	if done {
+		mark = "x"
+	}
+	return content[:task.offset] + mark + content[task.offset+1:]
+}
+
+// locate ищет в новой версии заметки пункт, который в старой был task: сначала на
+// прежнем месте, затем ближайший по номеру пункт с тем же текстом
+func locate(tasks []Task, task Task) (Task, bool) {
+	if task.Index < len(tasks) && tasks[task.Index].Text == task.Text {
+		return tasks[task.Index], true
+	}
+
+	best, found := Task{}, false
+	for _, candidate := range tasks {
+		if candidate.Text != task.Text {
+			continue
+		}
+		if !found || abs(candidate.Index-task.Index) < abs(best.Index-task.Index) {
+			best, found = candidate, true
+		}
+	}
+	return best, found
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,87 @@
+package todos
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseTasks(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []Task
+	}{
+		{"пусто", "", nil},
+		{"маркеры списков", "- [ ] a\n* [x] b\n+ [X] c\n1. [ ] d\n2) [ ] e", []Task{
+			{Index: 0, Line: 1, Text: "a"},
+			{Index: 1, Line: 2, Text: "b", Done: true},
+			{Index: 2, Line: 3, Text: "c", Done: true},
+			{Index: 3, Line: 4, Text: "d"},
+			{Index: 4, Line: 5, Text: "e"},
+		}},
+		{"вложенность и цитаты", "  - [ ] вложенный\n> - [x] в цитате", []Task{
+			{Index: 0, Line: 1, Text: "вложенный"},
+			{Index: 1, Line: 2, Text: "в цитате", Done: true},
+		}},
+		{"не задачи", "[ ] без маркера\n- [] пустые скобки\n- [ ]\n- [y] не флажок\n-[ ] без пробела", nil},
+		{"блоки кода", "```\n- [ ] в коде\n```\n- [ ] после\n~~~~\n- [ ] в тильдах\n~~~\n- [ ] всё ещё в коде\n~~~~\n- [x] снаружи", []Task{
+			{Index: 0, Line: 4, Text: "после"},
+			{Index: 1, Line: 10, Text: "снаружи", Done: true},
+		}},
+		{"другой забор не закрывает блок", "```\n~~~\n- [ ] в коде\n```go\n- [ ] в коде\n```\n- [ ] после", []Task{
+			{Index: 0, Line: 7, Text: "после"},
+		}},
+		{"CRLF и пробелы в тексте", "- [ ] задача  \r\n- [x] вторая\r\n", []Task{
+			{Index: 0, Line: 1, Text: "задача"},
+			{Index: 1, Line: 2, Text: "вторая", Done: true},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseTasks(tt.content)
+			for i := range got {
+				got[i].offset = 0
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ParseTasks(%q) = %+v, ожидалось %+v", tt.content, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetDone(t *testing.T) {
+	content := "текст\n- [ ] первая\n  1. [x] вторая\n"
+	tasks := ParseTasks(content)
+	if len(tasks) != 2 {
+		t.Fatalf("найдено %d задач, ожидалось 2", len(tasks))
+	}
+
+	content = setDone(content, tasks[0], true)
+	content = setDone(content, tasks[1], false)
+	if want := "текст\n- [x] первая\n  1. [ ] вторая\n"; content != want {
+		t.Errorf("setDone: %q, ожидалось %q", content, want)
+	}
+}
+
+func TestLocate(t *testing.T) {
+	tasks := ParseTasks("- [ ] a\n- [ ] b\n- [ ] a\n- [ ] c")
+
+	tests := []struct {
+		task      Task
+		wantIndex int
+		wantFound bool
+	}{
+		{Task{Index: 1, Text: "b"}, 1, true},
+		{Task{Index: 0, Text: "b"}, 1, true},
+		{Task{Index: 3, Text: "a"}, 2, true},
+		{Task{Index: 0, Text: "a"}, 0, true},
+		{Task{Index: 9, Text: "c"}, 3, true},
+		{Task{Index: 0, Text: "нет"}, 0, false},
+	}
+	for _, tt := range tests {
+		got, found := locate(tasks, tt.task)
+		if found != tt.wantFound || (found && got.Index != tt.wantIndex) {
+			t.Errorf("locate(%q, %d) = %d, %v, ожидалось %d, %v", tt.task.Text, tt.task.Index, got.Index, found, tt.wantIndex, tt.wantFound)
+		}
+	}
+}
@@ -0,0 +1,303 @@
+package todos
+
+import (
+	"context"
+	"errors"
+	"notes-api/encryption"
+	"notes-api/events"
+	"notes-api/logger"
+	"notes-api/model"
+	storage "notes-api/repo"
+	"notes-api/service"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// ReindexJobType — задача индексации заметок, сохранённых до появления списка задач
+	ReindexJobType = "todos.reindex"
+
+	DefaultLimit = 100
+	MaxLimit     = 500
+
+	// toggleAttempts — сколько раз переприменять переключение, если заметку меняют параллельно
+	toggleAttempts = 3
+	reindexBatch   = 100
+)
+
+// Фильтр по состоянию пунктов
+const (
+	StatusOpen = "open"
+	StatusDone = "done"
+	StatusAll  = "all"
+)
+
+var (
+	ErrTodoNotFound = errors.New("пункт списка задач не найден")
+	ErrTodoChanged  = errors.New("пункт списка задач изменился, обновите заметку")
+	ErrInvalidQuery = errors.New("неверные параметры фильтра")
+)
+
+// Filter — параметры выборки пунктов
+type Filter struct {
+	Status string
+	NoteID uint
+	// Query — подстрока текста пункта без учёта регистра
+	Query  string
+	Limit  int
+	Offset int
+}
+
+// ToggleInput — необязательное уточнение, какой пункт клиент видел. Если заметка
+// изменилась после change_seq, пункт ищется в новой версии по тексту.
+type ToggleInput struct {
+	ChangeSeq *int64 `json:"change_seq"`
+	Text      string `json:"text"`
+}
+
+// ToggleResult — переключённый пункт и новая версия заметки
+type ToggleResult struct {
+	Todo model.NoteTodo `json:"todo"`
+	Note model.Note     `json:"note"`
+}
+
+type TodoService struct {
+	DB    *gorm.DB
+	Notes *service.NoteService
+}
+
+func NewTodoService(db *gorm.DB, notes *service.NoteService) *TodoService {
+	return &TodoService{DB: db, Notes: notes}
+}
+
+// List возвращает пункты всех заметок пользователя, по умолчанию только открытые
+func (s *TodoService) List(userID uint, f Filter) ([]model.NoteTodo, error) {
+	if f.Limit <= 0 {
+		f.Limit = DefaultLimit
+	}
+	f.Limit = min(f.Limit, MaxLimit)
+
+	q := s.DB.Table("note_todos").
+		Select("note_todos.*, notes.title AS note_title").
+		Joins("JOIN notes ON notes.id = note_todos.note_id").
+		Where("note_todos.user_id = ?", userID)
+
+	switch f.Status {
+	case "", StatusOpen:
+		q = q.Where("note_todos.done = ?", false)
+	case StatusDone:
+		q = q.Where("note_todos.done = ?", true)
+	case StatusAll:
+	default:
+		return nil, ErrInvalidQuery
+	}
+	if f.NoteID != 0 {
+		q = q.Where("note_todos.note_id = ?", f.NoteID)
+	}
+	q = q.Order("notes.updated_at DESC, note_todos.note_id, note_todos.position")
+
+	todos := []model.NoteTodo{}
+	if f.Query == "" {
+		err := q.Limit(f.Limit).Offset(f.Offset).Find(&todos).Error
+		return todos, err
+	}
+
+	// Текст пунктов зашифрован, поэтому поиск по подстроке идёт в Go, а страница
+	// отсчитывается уже среди найденного
+	var all []model.NoteTodo
+	if err := q.Find(&all).Error; err != nil {
+		return nil, err
+	}
+	query := strings.ToLower(f.Query)
+	skipped := 0
+	for _, todo := range all {
+		if !strings.Contains(strings.ToLower(todo.Text), query) {
+			continue
+		}
+		if skipped < f.Offset {
+			skipped++
+			continue
+		}
+		todos = append(todos, todo)
+		if len(todos) == f.Limit {
+			break
+		}
+	}
+	return todos, nil
+}
+
+// Toggle переключает флажок пункта index прямо в тексте заметки. Запись идёт условным
+// обновлением по change_seq: если заметку успели изменить, пункт ищется заново по тексту
+// и переключение повторяется на свежей версии.
+func (s *TodoService) Toggle(userID, noteID uint, index int, in ToggleInput) (ToggleResult, error) {
+	// target — пункт, выбранный при первой попытке; повторные попытки ищут его по тексту,
+	// даже если его сдвинули
+	var target *Task
+
+	for attempt := 0; attempt < toggleAttempts; attempt++ {
+		note, err := s.Notes.GetNoteByID(int(noteID))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ToggleResult{}, storage.ErrNoteNotFound
+		}
+		if err != nil {
+			return ToggleResult{}, err
+		}
+		if note.UserID != userID {
+			return ToggleResult{}, storage.ErrForbidden
+		}
+		// Текст зашифрованной заметки серверу не виден, флажки в ней переключает клиент
+		if note.Encrypted {
+			return ToggleResult{}, ErrTodoNotFound
+		}
+
+		tasks := ParseTasks(note.Content)
+		var task Task
+		if target != nil {
+			found, ok := locate(tasks, *target)
+			if !ok {
+				return ToggleResult{}, ErrTodoChanged
+			}
+			task = found
+		} else {
+			task, err = resolve(tasks, index, in, note.ChangeSeq)
+			if err != nil {
+				return ToggleResult{}, err
+			}
+		}
+		target = &Task{Index: task.Index, Text: task.Text}
+
+		done := !task.Done
+		saved, err := s.Notes.UpdateNoteIfSeq(int(noteID), note.ChangeSeq, model.Note{
+			Title:   note.Title,
+			Content: setDone(note.Content, task, done),
+		})
+		if errors.Is(err, storage.ErrConflict) || errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return ToggleResult{}, err
+		}
+
+		return ToggleResult{
+			Todo: model.NoteTodo{
+				NoteID:    saved.ID,
+				NoteTitle: saved.Title,
+				Index:     task.Index,
+				Line:      task.Line,
+				Text:      task.Text,
+				Done:      done,
+				ChangeSeq: saved.ChangeSeq,
+				UpdatedAt: saved.UpdatedAt,
+			},
+			Note: saved,
+		}, nil
+	}
+	return ToggleResult{}, ErrTodoChanged
+}
+
+// resolve выбирает пункт, который имел в виду клиент. Без текста номер пункта
+// верен только для той версии заметки, которую клиент видел.
+func resolve(tasks []Task, index int, in ToggleInput, changeSeq int64) (Task, error) {
+	if text := strings.TrimSpace(in.Text); text != "" {
+		task, ok := locate(tasks, Task{Index: index, Text: text})
+		if !ok {
+			return Task{}, ErrTodoChanged
+		}
+		return task, nil
+	}
+	if in.ChangeSeq != nil && *in.ChangeSeq != changeSeq {
+		return Task{}, ErrTodoChanged
+	}
+	if index < 0 || index >= len(tasks) {
+		return Task{}, ErrTodoNotFound
+	}
+	return tasks[index], nil
+}
+
+// Publish реализует events.Publisher: пункты пересобираются при каждом сохранении заметки
+func (s *TodoService) Publish(e events.Event) {
+	var err error
+	switch e.Type {
+	case events.NoteCreated, events.NoteUpdated:
+		err = s.index(context.Background(), e.Note.ID)
+	case events.NoteDeleted:
+		err = s.DB.Where("note_id = ?", e.Note.ID).Delete(&model.NoteTodo{}).Error
+	}
+	if err != nil {
+		logger.Log.WithError(err).WithField("note_id", e.Note.ID).Error("Не удалось обновить список задач заметки")
+	}
+}
+
+// index пересобирает пункты заметки по её текущему тексту. Строка заметки блокируется,
+// чтобы параллельные сохранения не записали пункты устаревшей версии поверх свежей.
+func (s *TodoService) index(ctx context.Context, noteID uint) error {
+	return s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var note model.Note
+		err := tx.Clauses(clause.Locking{Strength: "SHARE"}).
+			Select("id", "user_id", "content", "change_seq", "encrypted").
+			First(&note, noteID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return tx.Where("note_id = ?", noteID).Delete(&model.NoteTodo{}).Error
+		}
+		if err != nil {
+			return err
+		}
+		return replaceTodos(tx, note)
+	})
+}
+
+func replaceTodos(tx *gorm.DB, note model.Note) error {
+	if err := tx.Where("note_id = ?", note.ID).Delete(&model.NoteTodo{}).Error; err != nil {
+		return err
+	}
+	if note.Encrypted {
+		return nil
+	}
+
+	tasks := ParseTasks(note.Content)
+	if len(tasks) == 0 {
+		return nil
+	}
+	todos := make([]model.NoteTodo, len(tasks))
+	for i, task := range tasks {
+		todos[i] = model.NoteTodo{
+			UserID:    note.UserID,
+			NoteID:    note.ID,
+			Index:     task.Index,
+			Line:      task.Line,
+			Text:      task.Text,
+			Done:      task.Done,
+			ChangeSeq: note.ChangeSeq,
+		}
+	}
+	return tx.Create(&todos).Error
+}
+
+// HandleReindexJob индексирует заметки с флажками, у которых ещё нет пунктов в индексе.
+// Повторный запуск безопасен: уже проиндексированные заметки пропускаются.
+func (s *TodoService) HandleReindexJob(ctx context.Context, job *model.Job) error {
+	var notes []model.Note
+	indexed := 0
+	err := s.DB.WithContext(ctx).
+		Select("id").
+		// Зашифрованный в базе текст проверить в SQL нельзя, такие заметки разбираются все
+		Where("content LIKE ? OR content ILIKE ? OR content LIKE ?", "%[ ]%", "%[x]%", encryption.Prefix+"%").
+		Where("NOT EXISTS (SELECT 1 FROM note_todos WHERE note_todos.note_id = notes.id)").
+		FindInBatches(&notes, reindexBatch, func(tx *gorm.DB, batch int) error {
+			for _, note := range notes {
+				if err := s.index(ctx, note.ID); err != nil {
+					return err
+				}
+				indexed++
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return err
+	}
+
+	logger.Log.WithField("count", indexed).Info("Списки задач заметок проиндексированы")
+	return nil
+}
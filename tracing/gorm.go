@@ -0,0 +1,62 @@
+package tracing
+
+import (
+	"notes-api/db/dbhooks"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const spanKey = "tracing:span"
+
+// GormPlugin создаёт спан на каждый запрос GORM. Родитель берётся из контекста запроса,
+// поэтому спаны появляются у запросов, выполненных через DB.WithContext.
+type GormPlugin struct{}
+
+func (GormPlugin) Name() string {
+	return "tracing"
+}
+
+func (GormPlugin) Initialize(db *gorm.DB) error {
+	return dbhooks.Register(db, "tracing", startQuery, dbhooks.Each(endQuery))
+}
+
+func startQuery(operation string) func(*gorm.DB) {
+	tracer := otel.Tracer("notes-api/db")
+	return func(db *gorm.DB) {
+		ctx := db.Statement.Context
+		// Запросы вне трассируемого запроса (воркеры, миграции) не порождают корневых спанов
+		if ctx == nil || !trace.SpanFromContext(ctx).SpanContext().IsValid() {
+			return
+		}
+		name := operation
+		if db.Statement.Table != "" {
+			name += " " + db.Statement.Table
+		}
+		_, span := tracer.Start(ctx, name,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				semconv.DBSystemPostgreSQL,
+				semconv.DBOperationName(operation),
+				semconv.DBCollectionName(db.Statement.Table),
+			))
+		db.InstanceSet(spanKey, span)
+	}
+}
+
+func endQuery(db *gorm.DB) {
+	value, ok := db.InstanceGet(spanKey)
+	if !ok {
+		return
+	}
+	span, ok := value.(trace.Span)
+	if !ok {
+		return
+	}
+	// Текст запроса с плейсхолдерами, без значений: в них содержимое заметок и хеши паролей
+	span.SetAttributes(semconv.DBQueryText(db.Statement.SQL.String()), attribute.Int64("db.rows_affected", db.RowsAffected))
+	EndIgnoring(span, db.Error, gorm.ErrRecordNotFound)
+}
@@ -0,0 +1,18 @@
+package tracing
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux"
+)
+
+// Middleware создаёт серверный спан на каждый запрос с именем «МЕТОД шаблон-маршрута»
+// и продолжает трассу из заголовка traceparent. Подключается через Router.Use.
+func Middleware(service string) mux.MiddlewareFunc {
+	return otelmux.Middleware(service,
+		otelmux.WithSpanNameFormatter(func(route string, r *http.Request) string {
+			return r.Method + " " + route
+		}),
+	)
+}
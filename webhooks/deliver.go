@@ -0,0 +1,164 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"notes-api/jobs"
+	"notes-api/logger"
+	"notes-api/model"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	deliveryTimeout = 10 * time.Second
+	// maxDrainBody — сколько байт ответа дочитывать, чтобы соединение вернулось в пул.
+	// Само тело ответа не сохраняется: в журнале доставок достаточно кода статуса.
+	maxDrainBody = 4 << 10
+
+	SignatureHeader = "X-Webhook-Signature"
+	TimestampHeader = "X-Webhook-Timestamp"
+	EventHeader     = "X-Webhook-Event"
+	DeliveryHeader  = "X-Webhook-Delivery"
+)
+
+// Sign возвращает подпись тела запроса: hex(HMAC-SHA256(secret, timestamp + "." + body)).
+// Получатель должен вычислить её сам и сравнить со значением заголовка X-Webhook-Signature
+// (без префикса "sha256="), а также проверить, что X-Webhook-Timestamp не слишком старый.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// HandleDeliverJob отправляет одну доставку. Ошибка возвращается в очередь задач,
+// которая повторит попытку с экспоненциальной задержкой.
+func (s *WebhookService) HandleDeliverJob(ctx context.Context, job *model.Job) error {
+	var p deliverPayload
+	if err := json.Unmarshal(job.Payload, &p); err != nil {
+		return jobs.Permanent(err)
+	}
+
+	var delivery model.WebhookDelivery
+	if err := s.DB.WithContext(ctx).First(&delivery, p.DeliveryID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return jobs.Permanent(ErrDeliveryNotFound)
+		}
+		return err
+	}
+
+	var hook model.Webhook
+	if err := s.DB.WithContext(ctx).First(&hook, delivery.WebhookID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return jobs.Permanent(ErrWebhookNotFound)
+		}
+		return err
+	}
+	if !hook.Active {
+		s.finishDelivery(ctx, &delivery, model.DeliveryFailed)
+		return jobs.Permanent(errors.New("вебхук отключён"))
+	}
+
+	delivery.Attempts++
+	sendErr := s.send(ctx, hook, &delivery)
+	if sendErr == nil {
+		s.finishDelivery(ctx, &delivery, model.DeliverySucceeded)
+		s.recordSuccess(ctx, &hook)
+		return nil
+	}
+
+	delivery.Error = sendErr.Error()
+	status := model.DeliveryPending
+	if job.Attempts >= job.MaxAttempts || jobs.IsPermanent(sendErr) {
+		status = model.DeliveryFailed
+	}
+	s.finishDelivery(ctx, &delivery, status)
+	s.recordFailure(ctx, &hook)
+	return sendErr
+}
+
+func (s *WebhookService) send(ctx context.Context, hook model.Webhook, delivery *model.WebhookDelivery) error {
+	timestamp := time.Now().Unix()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return jobs.Permanent(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "notes-api-webhooks/1.0")
+	req.Header.Set(EventHeader, delivery.Event)
+	req.Header.Set(DeliveryHeader, delivery.EventID)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, "sha256="+Sign(hook.Secret, timestamp, delivery.Payload))
+
+	start := time.Now()
+	resp, err := s.Client.Do(req)
+	delivery.DurationMs = time.Since(start).Milliseconds()
+	if errors.Is(err, ErrForbiddenDestination) {
+		return jobs.Permanent(err)
+	}
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxDrainBody))
+	delivery.ResponseStatus = resp.StatusCode
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("подписчик ответил статусом %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (s *WebhookService) finishDelivery(ctx context.Context, delivery *model.WebhookDelivery, status string) {
+	delivery.Status = status
+	if status == model.DeliverySucceeded {
+		now := time.Now()
+		delivery.DeliveredAt = &now
+		delivery.Error = ""
+	}
+	if err := s.DB.WithContext(ctx).Save(delivery).Error; err != nil {
+		logger.Log.WithError(err).WithField("delivery_id", delivery.ID).Error("Не удалось сохранить результат доставки")
+	}
+}
+
+func (s *WebhookService) recordSuccess(ctx context.Context, hook *model.Webhook) {
+	if hook.ConsecutiveFailures == 0 {
+		return
+	}
+	err := s.DB.WithContext(ctx).Model(hook).Update("consecutive_failures", 0).Error
+	if err != nil {
+		logger.Log.WithError(err).WithField("webhook_id", hook.ID).Error("Не удалось сбросить счётчик неудач вебхука")
+	}
+}
+
+// recordFailure увеличивает счётчик неудач и отключает вебхук, если подписчик долго недоступен
+func (s *WebhookService) recordFailure(ctx context.Context, hook *model.Webhook) {
+	updates := map[string]any{
+		"consecutive_failures": gorm.Expr("consecutive_failures + 1"),
+	}
+	if hook.ConsecutiveFailures+1 >= maxConsecutiveFailures {
+		updates["active"] = false
+		updates["disabled_at"] = time.Now()
+		logger.Log.WithFields(logger.Fields{
+			"webhook_id": hook.ID,
+			"user_id":    hook.UserID,
+		}).Warn("Вебхук отключён после серии неудачных доставок")
+	}
+
+	if err := s.DB.WithContext(ctx).Model(hook).Updates(updates).Error; err != nil {
+		logger.Log.WithError(err).WithField("webhook_id", hook.ID).Error("Не удалось обновить счётчик неудач вебхука")
+	}
+}
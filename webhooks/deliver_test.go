@@ -0,0 +1,355 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"notes-api/db/dbtest"
+	_ "notes-api/encryption"
+	"notes-api/events"
+	"notes-api/jobs"
+	"notes-api/model"
+	"notes-api/problem"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testSecret = "s3cret"
+
+// receiver — подписчик вебхука: проверяет подпись и отвечает статусом из status
+type receiver struct {
+	*httptest.Server
+	status   atomic.Int32
+	delay    atomic.Int64
+	requests atomic.Int32
+	// badSignatures — запросы, подпись которых не сошлась
+	badSignatures atomic.Int32
+	lastEvent     atomic.Value
+}
+
+func newReceiver(t *testing.T) *receiver {
+	rc := &receiver{}
+	rc.status.Store(http.StatusOK)
+	rc.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rc.requests.Add(1)
+		body, _ := io.ReadAll(r.Body)
+		if !verify(r.Header, body) {
+			rc.badSignatures.Add(1)
+		}
+		rc.lastEvent.Store(r.Header.Get(EventHeader))
+
+		if d := time.Duration(rc.delay.Load()); d > 0 {
+			select {
+			case <-time.After(d):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		w.WriteHeader(int(rc.status.Load()))
+		io.WriteString(w, "internal details that must not be stored")
+	}))
+	t.Cleanup(rc.Close)
+	return rc
+}
+
+// verify проверяет подпись так, как это описано для получателей в документации Sign
+func verify(h http.Header, body []byte) bool {
+	timestamp, err := strconv.ParseInt(h.Get(TimestampHeader), 10, 64)
+	if err != nil || time.Since(time.Unix(timestamp, 0)) > 5*time.Minute {
+		return false
+	}
+	signature, ok := strings.CutPrefix(h.Get(SignatureHeader), "sha256=")
+	return ok && hmac.Equal([]byte(signature), []byte(Sign(testSecret, timestamp, body)))
+}
+
+func newTestService(t *testing.T) *WebhookService {
+	db := dbtest.Open(t, &model.Webhook{}, &model.WebhookDelivery{}, &model.Job{})
+	// httptest слушает на 127.0.0.1, поэтому внутренняя сеть разрешена
+	return NewWebhookService(db, jobs.NewQueue(db), true)
+}
+
+func createHook(t *testing.T, s *WebhookService, url string) model.Webhook {
+	t.Helper()
+	hook, err := s.Create(1, WebhookInput{URL: url, Events: []string{events.NoteCreated}, Secret: testSecret})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	return hook
+}
+
+// publish отправляет событие note.created и возвращает задачу доставки из очереди
+func publish(t *testing.T, s *WebhookService) *model.Job {
+	t.Helper()
+	s.Publish(events.NewNoteEvent(events.NoteCreated, model.Note{ID: 7, UserID: 1, Title: "Заметка"}))
+	return lastJob(t, s)
+}
+
+func lastJob(t *testing.T, s *WebhookService) *model.Job {
+	t.Helper()
+	var job model.Job
+	if err := s.DB.Where("type = ?", DeliverJobType).Order("id DESC").First(&job).Error; err != nil {
+		t.Fatalf("задача доставки не поставлена: %v", err)
+	}
+	return &job
+}
+
+func lastDelivery(t *testing.T, s *WebhookService) model.WebhookDelivery {
+	t.Helper()
+	var delivery model.WebhookDelivery
+	if err := s.DB.Order("id DESC").First(&delivery).Error; err != nil {
+		t.Fatalf("доставка не найдена: %v", err)
+	}
+	return delivery
+}
+
+func reloadHook(t *testing.T, s *WebhookService, id uint) model.Webhook {
+	t.Helper()
+	var hook model.Webhook
+	if err := s.DB.First(&hook, id).Error; err != nil {
+		t.Fatalf("вебхук не найден: %v", err)
+	}
+	return hook
+}
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"id":"1"}`)
+	sig := Sign(testSecret, 1700000000, body)
+
+	if len(sig) != 64 {
+		t.Fatalf("подпись должна быть hex SHA-256, получено %q", sig)
+	}
+	if sig != Sign(testSecret, 1700000000, body) {
+		t.Fatal("подпись недетерминирована")
+	}
+	for name, other := range map[string]string{
+		"другой секрет":       Sign("other", 1700000000, body),
+		"другое время":        Sign(testSecret, 1700000001, body),
+		"другое тело":         Sign(testSecret, 1700000000, []byte(`{"id":"2"}`)),
+		"время слито с телом": Sign(testSecret, 170000000, append([]byte("0."), body...)),
+	} {
+		if other == sig {
+			t.Errorf("%s: подпись совпала", name)
+		}
+	}
+}
+
+func TestDeliverSuccess(t *testing.T) {
+	s := newTestService(t)
+	rc := newReceiver(t)
+	hook := createHook(t, s, rc.URL)
+	job := publish(t, s)
+	job.Attempts = 1
+
+	if err := s.HandleDeliverJob(context.Background(), job); err != nil {
+		t.Fatalf("HandleDeliverJob: %v", err)
+	}
+
+	if rc.requests.Load() != 1 || rc.badSignatures.Load() != 0 {
+		t.Fatalf("запросов %d, с неверной подписью %d", rc.requests.Load(), rc.badSignatures.Load())
+	}
+	if got := rc.lastEvent.Load(); got != events.NoteCreated {
+		t.Errorf("%s = %v", EventHeader, got)
+	}
+	delivery := lastDelivery(t, s)
+	if delivery.Status != model.DeliverySucceeded || delivery.ResponseStatus != http.StatusOK || delivery.DeliveredAt == nil {
+		t.Errorf("доставка: статус %q, ответ %d, delivered_at %v", delivery.Status, delivery.ResponseStatus, delivery.DeliveredAt)
+	}
+	if raw, _ := json.Marshal(delivery); strings.Contains(string(raw), "internal details") {
+		t.Error("тело ответа подписчика попало в журнал доставок")
+	}
+	if reloadHook(t, s, hook.ID).ConsecutiveFailures != 0 {
+		t.Error("счётчик неудач после успешной доставки не нулевой")
+	}
+}
+
+func TestDeliverRetries(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(s *WebhookService, rc *receiver)
+	}{
+		{"статус 500", func(s *WebhookService, rc *receiver) {
+			rc.status.Store(http.StatusInternalServerError)
+		}},
+		{"перенаправление без Location", func(s *WebhookService, rc *receiver) {
+			rc.status.Store(http.StatusFound)
+		}},
+		{"таймаут", func(s *WebhookService, rc *receiver) {
+			rc.delay.Store(int64(time.Second))
+			s.Client.Timeout = 50 * time.Millisecond
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t)
+			rc := newReceiver(t)
+			tt.setup(s, rc)
+			hook := createHook(t, s, rc.URL)
+			job := publish(t, s)
+
+			job.Attempts = 1
+			err := s.HandleDeliverJob(context.Background(), job)
+			if err == nil || jobs.IsPermanent(err) {
+				t.Fatalf("ожидалась ошибка для повтора, получено %v", err)
+			}
+			if d := lastDelivery(t, s); d.Status != model.DeliveryPending || d.Attempts != 1 || d.Error == "" {
+				t.Errorf("после первой попытки: статус %q, попыток %d, ошибка %q", d.Status, d.Attempts, d.Error)
+			}
+
+			job.Attempts = job.MaxAttempts
+			if err := s.HandleDeliverJob(context.Background(), job); err == nil {
+				t.Fatal("последняя попытка должна вернуть ошибку")
+			}
+			if d := lastDelivery(t, s); d.Status != model.DeliveryFailed || d.Attempts != 2 {
+				t.Errorf("после последней попытки: статус %q, попыток %d", d.Status, d.Attempts)
+			}
+			if got := reloadHook(t, s, hook.ID).ConsecutiveFailures; got != 2 {
+				t.Errorf("consecutive_failures = %d, ожидалось 2", got)
+			}
+		})
+	}
+}
+
+func TestDeliverDisablesAfterRepeatedFailures(t *testing.T) {
+	s := newTestService(t)
+	rc := newReceiver(t)
+	rc.status.Store(http.StatusServiceUnavailable)
+	hook := createHook(t, s, rc.URL)
+	s.DB.Model(&hook).Update("consecutive_failures", maxConsecutiveFailures-2)
+
+	job := publish(t, s)
+	job.Attempts = 1
+	s.HandleDeliverJob(context.Background(), job)
+	if !reloadHook(t, s, hook.ID).Active {
+		t.Fatal("вебхук отключён раньше порога")
+	}
+
+	job.Attempts = 2
+	s.HandleDeliverJob(context.Background(), job)
+	hook = reloadHook(t, s, hook.ID)
+	if hook.Active || hook.DisabledAt == nil {
+		t.Fatalf("вебхук не отключён после %d неудач подряд", hook.ConsecutiveFailures)
+	}
+
+	// Отключённому вебхуку доставка больше не отправляется
+	requests := rc.requests.Load()
+	job.Attempts = 3
+	if err := s.HandleDeliverJob(context.Background(), job); !jobs.IsPermanent(err) {
+		t.Errorf("доставка на отключённый вебхук: %v", err)
+	}
+	if rc.requests.Load() != requests {
+		t.Error("запрос отправлен на отключённый вебхук")
+	}
+
+	// Повторное включение сбрасывает счётчик
+	active := true
+	hook, err := s.Update(1, hook.ID, WebhookInput{URL: rc.URL, Events: hook.Events, Active: &active})
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if !hook.Active || hook.ConsecutiveFailures != 0 || hook.DisabledAt != nil {
+		t.Errorf("после включения: active %v, неудач %d", hook.Active, hook.ConsecutiveFailures)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	s := newTestService(t)
+	rc := newReceiver(t)
+	hook := createHook(t, s, rc.URL)
+	job := publish(t, s)
+	job.Attempts = 1
+	if err := s.HandleDeliverJob(context.Background(), job); err != nil {
+		t.Fatalf("HandleDeliverJob: %v", err)
+	}
+	original := lastDelivery(t, s)
+
+	replay, err := s.Replay(1, hook.ID, original.ID)
+	if err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+	if replay.ID == original.ID || replay.ReplayOf == nil || *replay.ReplayOf != original.ID {
+		t.Fatalf("повтор должен быть новой доставкой со ссылкой на исходную: %+v", replay)
+	}
+	if replay.EventID != original.EventID || string(replay.Payload) != string(original.Payload) {
+		t.Error("повтор отправляет другое событие")
+	}
+
+	job = lastJob(t, s)
+	var p deliverPayload
+	json.Unmarshal(job.Payload, &p)
+	if p.DeliveryID != replay.ID {
+		t.Fatalf("задача повтора указывает на доставку %d, ожидалась %d", p.DeliveryID, replay.ID)
+	}
+}
+
+func TestReplayOtherUser(t *testing.T) {
+	s := newTestService(t)
+	rc := newReceiver(t)
+	hook := createHook(t, s, rc.URL)
+	publish(t, s)
+	delivery := lastDelivery(t, s)
+
+	if _, err := s.Replay(2, hook.ID, delivery.ID); !errors.Is(err, ErrWebhookNotFound) {
+		t.Errorf("повтор чужой доставки: %v", err)
+	}
+	if _, err := s.Replay(1, hook.ID, delivery.ID+100); !errors.Is(err, ErrDeliveryNotFound) {
+		t.Errorf("повтор несуществующей доставки: %v", err)
+	}
+}
+
+func TestDeliverRefusesInternalNetwork(t *testing.T) {
+	s := newTestService(t)
+	rc := newReceiver(t)
+	hook := createHook(t, s, rc.URL)
+
+	// Вебхук сохранён, когда внутренняя сеть была разрешена, или имя хоста потом стало указывать на 127.0.0.1
+	s.AllowPrivateNetworks = false
+	s.Client = newClient(false)
+	_, err := s.Update(1, hook.ID, WebhookInput{URL: rc.URL, Events: hook.Events})
+	var invalid *problem.ValidationError
+	if !errors.As(err, &invalid) || len(invalid.Fields) != 1 || invalid.Fields[0] != problem.Field("url", problem.FieldPrivateURL) {
+		t.Errorf("сохранение адреса во внутренней сети: %v", err)
+	}
+
+	job := publish(t, s)
+	job.Attempts = 1
+	err = s.HandleDeliverJob(context.Background(), job)
+	if !errors.Is(err, ErrForbiddenDestination) || !jobs.IsPermanent(err) {
+		t.Fatalf("ожидался отказ без повтора, получено %v", err)
+	}
+	if rc.requests.Load() != 0 {
+		t.Error("запрос дошёл до адреса во внутренней сети")
+	}
+}
+
+func TestForbiddenHost(t *testing.T) {
+	tests := map[string]bool{
+		"localhost":        true,
+		"api.localhost":    true,
+		"127.0.0.1":        true,
+		"10.0.0.5":         true,
+		"172.16.3.4":       true,
+		"192.168.1.1":      true,
+		"169.254.169.254":  true,
+		"100.64.0.1":       true,
+		"0.0.0.0":          true,
+		"::1":              true,
+		"fd00::1":          true,
+		"fe80::1":          true,
+		"::ffff:127.0.0.1": true,
+		"example.com":      false,
+		"8.8.8.8":          false,
+		"2001:4860::8888":  false,
+	}
+	for host, want := range tests {
+		if got := forbiddenHost(host); got != want {
+			t.Errorf("forbiddenHost(%q) = %v, ожидалось %v", host, got, want)
+		}
+	}
+}
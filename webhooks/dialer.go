@@ -0,0 +1,76 @@
+package webhooks
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenDestination — адрес подписчика ведёт во внутреннюю сеть сервиса
+var ErrForbiddenDestination = errors.New("адрес вебхука ведёт во внутреннюю сеть")
+
+// sharedAddressSpace — 100.64.0.0/10 (RFC 6598), адреса внутри сетей провайдеров и облаков
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// forbiddenIP сообщает, что адрес не должен быть получателем вебхука: петля, частные сети
+// RFC 1918 и ULA, link-local (в том числе 169.254.169.254 — метаданные облака), multicast
+func forbiddenIP(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return !ip.IsValid() ||
+		ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() ||
+		ip.IsUnspecified() ||
+		sharedAddressSpace.Contains(ip)
+}
+
+// forbiddenHost проверяет адрес из URL вебхука до его сохранения. Имена хостов здесь
+// не разрешаются: DNS может поменяться, окончательную проверку делает denyPrivate при подключении.
+func forbiddenHost(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+	ip, err := netip.ParseAddr(host)
+	return err == nil && forbiddenIP(ip)
+}
+
+// denyPrivate вызывается для каждого соединения после разрешения имени, поэтому
+// закрывает и подмену DNS после проверки URL, и перенаправления во внутреннюю сеть
+func denyPrivate(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil || forbiddenIP(ip) {
+		return fmt.Errorf("%w: %s", ErrForbiddenDestination, host)
+	}
+	return nil
+}
+
+// newClient возвращает HTTP-клиент для доставки. Без allowPrivate соединения во внутреннюю
+// сеть запрещены; прокси из окружения не используется, иначе проверялся бы адрес прокси.
+func newClient(allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: deliveryTimeout, KeepAlive: 30 * time.Second}
+	if !allowPrivate {
+		dialer.Control = denyPrivate
+	}
+	return &http.Client{
+		Timeout: deliveryTimeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: deliveryTimeout,
+			MaxIdleConnsPerHost: 2,
+			IdleConnTimeout:     90 * time.Second,
+		},
+	}
+}
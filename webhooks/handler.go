@@ -0,0 +1,248 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"net/http"
+	"notes-api/httpx"
+	"notes-api/logger"
+	"notes-api/problem"
+	"strconv"
+)
+
+const (
+	defaultDeliveriesLimit = 50
+	maxDeliveriesLimit     = 200
+)
+
+type WebhookHandler struct {
+	Service *WebhookService
+}
+
+// List godoc
+// @Summary Получить вебхуки текущего пользователя
+// @Tags webhooks
+// @Security ApiKeyAuth
+// @Produce json
+// @Success 200 {array} model.Webhook "Список вебхуков"
+// @Failure 401 {object} problem.Problem "Пользователь не аутентифицирован"
+// @Router /webhooks [get]
+func (h *WebhookHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := httpx.UserID(w, r)
+	if !ok {
+		return
+	}
+
+	hooks, err := h.Service.List(userID)
+	if err != nil {
+		logger.FromContext(r.Context()).WithError(err).WithField("user_id", userID).Error("Ошибка при получении вебхуков")
+		problem.Write(w, r, problem.Internal)
+		return
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, hooks)
+}
+
+// Create godoc
+// @Summary Создать вебхук
+// @Description Подписывает URL на события заметок. Запросы подписываются HMAC-SHA256 с секретом вебхука, подпись передаётся в заголовке X-Webhook-Signature
+// @Tags webhooks
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param input body webhooks.WebhookInput true "Параметры вебхука"
+// @Success 201 {object} model.Webhook "Созданный вебхук вместе с секретом"
+// @Failure 400 {object} problem.Problem "Неверный запрос или ошибка валидации"
+// @Router /webhooks [post]
+func (h *WebhookHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, ok := httpx.UserID(w, r)
+	if !ok {
+		return
+	}
+
+	var input WebhookInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		logger.FromContext(r.Context()).WithError(err).Warn("Неверный запрос")
+		problem.Write(w, r, problem.BadRequest)
+		return
+	}
+
+	hook, err := h.Service.Create(userID, input)
+	if err != nil {
+		logger.FromContext(r.Context()).WithError(err).WithField("user_id", userID).Warn("Ошибка при создании вебхука")
+		problem.Error(w, r, err)
+		return
+	}
+
+	httpx.WriteJSON(w, http.StatusCreated, hook)
+	logger.FromContext(r.Context()).WithFields(logger.Fields{
+		"user_id":    userID,
+		"webhook_id": hook.ID,
+	}).Info("Вебхук создан")
+}
+
+// Get godoc
+// @Summary Получить вебхук по ID
+// @Tags webhooks
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path int true "ID вебхука"
+// @Success 200 {object} model.Webhook "Вебхук"
+// @Failure 404 {object} problem.Problem "Вебхук не найден"
+// @Router /webhooks/{id} [get]
+func (h *WebhookHandler) Get(w http.ResponseWriter, r *http.Request) {
+	userID, ok := httpx.UserID(w, r)
+	if !ok {
+		return
+	}
+	id, ok := httpx.PathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	hook, err := h.Service.Get(userID, id)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	hook.Secret = ""
+	httpx.WriteJSON(w, http.StatusOK, hook)
+}
+
+// Update godoc
+// @Summary Изменить вебхук
+// @Description Передача active=true для отключённого вебхука включает его снова и сбрасывает счётчик неудач
+// @Tags webhooks
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "ID вебхука"
+// @Param input body webhooks.WebhookInput true "Параметры вебхука"
+// @Success 200 {object} model.Webhook "Обновлённый вебхук"
+// @Failure 400 {object} problem.Problem "Неверный запрос или ошибка валидации"
+// @Failure 404 {object} problem.Problem "Вебхук не найден"
+// @Router /webhooks/{id} [put]
+func (h *WebhookHandler) Update(w http.ResponseWriter, r *http.Request) {
+	userID, ok := httpx.UserID(w, r)
+	if !ok {
+		return
+	}
+	id, ok := httpx.PathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var input WebhookInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		logger.FromContext(r.Context()).WithError(err).Warn("Неверный запрос")
+		problem.Write(w, r, problem.BadRequest)
+		return
+	}
+
+	hook, err := h.Service.Update(userID, id, input)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, hook)
+}
+
+// Delete godoc
+// @Summary Удалить вебхук
+// @Tags webhooks
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path int true "ID вебхука"
+// @Success 200 {object} map[string]string "Сообщение об удалении"
+// @Failure 404 {object} problem.Problem "Вебхук не найден"
+// @Router /webhooks/{id} [delete]
+func (h *WebhookHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	userID, ok := httpx.UserID(w, r)
+	if !ok {
+		return
+	}
+	id, ok := httpx.PathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.Service.Delete(userID, id); err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, map[string]string{"message": "Вебхук удалён"})
+}
+
+// Deliveries godoc
+// @Summary Журнал доставок вебхука
+// @Tags webhooks
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path int true "ID вебхука"
+// @Param limit query int false "Сколько последних доставок вернуть (по умолчанию 50, максимум 200)"
+// @Success 200 {array} model.WebhookDelivery "Доставки, новые сверху"
+// @Failure 404 {object} problem.Problem "Вебхук не найден"
+// @Router /webhooks/{id}/deliveries [get]
+func (h *WebhookHandler) Deliveries(w http.ResponseWriter, r *http.Request) {
+	userID, ok := httpx.UserID(w, r)
+	if !ok {
+		return
+	}
+	id, ok := httpx.PathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	limit := defaultDeliveriesLimit
+	if n, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && n > 0 {
+		limit = min(n, maxDeliveriesLimit)
+	}
+
+	deliveries, err := h.Service.Deliveries(userID, id, limit)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, deliveries)
+}
+
+// Replay godoc
+// @Summary Повторно отправить доставку
+// @Tags webhooks
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path int true "ID вебхука"
+// @Param delivery_id path int true "ID доставки"
+// @Success 202 {object} model.WebhookDelivery "Новая доставка"
+// @Failure 404 {object} problem.Problem "Вебхук или доставка не найдены"
+// @Router /webhooks/{id}/deliveries/{delivery_id}/replay [post]
+func (h *WebhookHandler) Replay(w http.ResponseWriter, r *http.Request) {
+	userID, ok := httpx.UserID(w, r)
+	if !ok {
+		return
+	}
+	id, ok := httpx.PathID(w, r, "id")
+	if !ok {
+		return
+	}
+	deliveryID, ok := httpx.PathID(w, r, "delivery_id")
+	if !ok {
+		return
+	}
+
+	delivery, err := h.Service.Replay(userID, id, deliveryID)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	httpx.WriteJSON(w, http.StatusAccepted, delivery)
+	logger.FromContext(r.Context()).WithFields(logger.Fields{
+		"user_id":     userID,
+		"webhook_id":  id,
+		"delivery_id": deliveryID,
+	}).Info("Доставка вебхука поставлена на повтор")
+}
@@ -0,0 +1,296 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"notes-api/events"
+	"notes-api/jobs"
+	"notes-api/logger"
+	"notes-api/model"
+	"notes-api/problem"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	DeliverJobType = "webhooks.deliver"
+
+	// deliveryMaxAttempts — сколько раз пытаться доставить одно событие
+	deliveryMaxAttempts = 8
+	// maxConsecutiveFailures — после стольких неудачных попыток подряд вебхук отключается
+	maxConsecutiveFailures = 20
+)
+
+var (
+	ErrWebhookNotFound  = errors.New("вебхук не найден")
+	ErrDeliveryNotFound = errors.New("доставка не найдена")
+)
+
+type WebhookService struct {
+	DB     *gorm.DB
+	Queue  *jobs.Queue
+	Client *http.Client
+	// AllowPrivateNetworks разрешает вебхуки на адреса внутренней сети (петля, RFC 1918,
+	// link-local). По умолчанию запрещено: иначе через вебхук можно обращаться к внутренним сервисам.
+	AllowPrivateNetworks bool
+}
+
+func NewWebhookService(db *gorm.DB, q *jobs.Queue, allowPrivateNetworks bool) *WebhookService {
+	return &WebhookService{
+		DB:                   db,
+		Queue:                q,
+		Client:               newClient(allowPrivateNetworks),
+		AllowPrivateNetworks: allowPrivateNetworks,
+	}
+}
+
+// WebhookInput — данные для создания и изменения вебхука
+type WebhookInput struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	Secret string   `json:"secret"`
+	Active *bool    `json:"active"`
+}
+
+// payload — тело запроса, которое получает подписчик
+type payload struct {
+	ID         string          `json:"id"`
+	Type       string          `json:"type"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Data       events.NoteData `json:"data"`
+}
+
+type deliverPayload struct {
+	DeliveryID uint `json:"delivery_id"`
+}
+
+func (s *WebhookService) List(userID uint) ([]model.Webhook, error) {
+	var hooks []model.Webhook
+	if err := s.DB.Where("user_id = ?", userID).Order("id").Find(&hooks).Error; err != nil {
+		return nil, err
+	}
+	for i := range hooks {
+		hooks[i].Secret = ""
+	}
+	return hooks, nil
+}
+
+func (s *WebhookService) Get(userID, id uint) (model.Webhook, error) {
+	var hook model.Webhook
+	if err := s.DB.Where("id = ? AND user_id = ?", id, userID).First(&hook).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.Webhook{}, ErrWebhookNotFound
+		}
+		return model.Webhook{}, err
+	}
+	return hook, nil
+}
+
+// Create создаёт вебхук. Если секрет не передан, он генерируется;
+// секрет возвращается только в ответе на создание.
+func (s *WebhookService) Create(userID uint, input WebhookInput) (model.Webhook, error) {
+	if err := s.validateInput(input); err != nil {
+		return model.Webhook{}, err
+	}
+
+	secret := input.Secret
+	if secret == "" {
+		secret = randomHex(32)
+	}
+
+	hook := model.Webhook{
+		UserID: userID,
+		URL:    input.URL,
+		Events: input.Events,
+		Secret: secret,
+		Active: input.Active == nil || *input.Active,
+	}
+	if err := s.DB.Create(&hook).Error; err != nil {
+		return model.Webhook{}, err
+	}
+	return hook, nil
+}
+
+// Update меняет вебхук. Повторное включение сбрасывает счётчик неудач.
+func (s *WebhookService) Update(userID, id uint, input WebhookInput) (model.Webhook, error) {
+	hook, err := s.Get(userID, id)
+	if err != nil {
+		return model.Webhook{}, err
+	}
+	if err := s.validateInput(input); err != nil {
+		return model.Webhook{}, err
+	}
+
+	hook.URL = input.URL
+	hook.Events = input.Events
+	if input.Secret != "" {
+		hook.Secret = input.Secret
+	}
+	if input.Active != nil {
+		if *input.Active && !hook.Active {
+			hook.ConsecutiveFailures = 0
+			hook.DisabledAt = nil
+		}
+		hook.Active = *input.Active
+	}
+
+	if err := s.DB.Save(&hook).Error; err != nil {
+		return model.Webhook{}, err
+	}
+	hook.Secret = ""
+	return hook, nil
+}
+
+func (s *WebhookService) Delete(userID, id uint) error {
+	res := s.DB.Where("id = ? AND user_id = ?", id, userID).Delete(&model.Webhook{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrWebhookNotFound
+	}
+	return s.DB.Where("webhook_id = ?", id).Delete(&model.WebhookDelivery{}).Error
+}
+
+// Deliveries возвращает журнал доставок вебхука, новые сверху
+func (s *WebhookService) Deliveries(userID, webhookID uint, limit int) ([]model.WebhookDelivery, error) {
+	if _, err := s.Get(userID, webhookID); err != nil {
+		return nil, err
+	}
+
+	var deliveries []model.WebhookDelivery
+	err := s.DB.Where("webhook_id = ?", webhookID).Order("id DESC").Limit(limit).Find(&deliveries).Error
+	return deliveries, err
+}
+
+// Replay повторно отправляет сохранённое событие как новую доставку
+func (s *WebhookService) Replay(userID, webhookID, deliveryID uint) (model.WebhookDelivery, error) {
+	if _, err := s.Get(userID, webhookID); err != nil {
+		return model.WebhookDelivery{}, err
+	}
+
+	var original model.WebhookDelivery
+	if err := s.DB.Where("id = ? AND webhook_id = ?", deliveryID, webhookID).First(&original).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.WebhookDelivery{}, ErrDeliveryNotFound
+		}
+		return model.WebhookDelivery{}, err
+	}
+
+	replay := model.WebhookDelivery{
+		WebhookID: original.WebhookID,
+		UserID:    original.UserID,
+		EventID:   original.EventID,
+		Event:     original.Event,
+		Payload:   original.Payload,
+		Status:    model.DeliveryPending,
+		ReplayOf:  &original.ID,
+	}
+	if err := s.enqueue(context.Background(), &replay); err != nil {
+		return model.WebhookDelivery{}, err
+	}
+	return replay, nil
+}
+
+// Publish реализует events.Publisher: создаёт доставку для каждого
+// активного вебхука пользователя, подписанного на тип события
+func (s *WebhookService) Publish(e events.Event) {
+	log := logger.Log.WithFields(logger.Fields{
+		"user_id": e.UserID,
+		"event":   e.Type,
+	})
+
+	var hooks []model.Webhook
+	if err := s.DB.Where("user_id = ? AND active", e.UserID).Find(&hooks).Error; err != nil {
+		log.WithError(err).Error("Не удалось получить вебхуки пользователя")
+		return
+	}
+
+	eventID := randomHex(16)
+	body, err := json.Marshal(payload{
+		ID:         eventID,
+		Type:       e.Type,
+		OccurredAt: e.OccurredAt,
+		Data:       e.Note,
+	})
+	if err != nil {
+		log.WithError(err).Error("Не удалось сериализовать событие")
+		return
+	}
+
+	for _, hook := range hooks {
+		if !subscribed(hook, e.Type) {
+			continue
+		}
+
+		delivery := model.WebhookDelivery{
+			WebhookID: hook.ID,
+			UserID:    hook.UserID,
+			EventID:   eventID,
+			Event:     e.Type,
+			Payload:   body,
+			Status:    model.DeliveryPending,
+		}
+		if err := s.enqueue(context.Background(), &delivery); err != nil {
+			log.WithError(err).WithField("webhook_id", hook.ID).Error("Не удалось поставить доставку вебхука в очередь")
+		}
+	}
+}
+
+func (s *WebhookService) enqueue(ctx context.Context, delivery *model.WebhookDelivery) error {
+	if err := s.DB.WithContext(ctx).Create(delivery).Error; err != nil {
+		return err
+	}
+	_, err := s.Queue.Enqueue(ctx, DeliverJobType, deliverPayload{DeliveryID: delivery.ID}, jobs.Options{
+		UserID:      delivery.UserID,
+		MaxAttempts: deliveryMaxAttempts,
+	})
+	return err
+}
+
+func subscribed(hook model.Webhook, eventType string) bool {
+	for _, e := range hook.Events {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *WebhookService) validateInput(input WebhookInput) error {
+	var fields []problem.FieldError
+	u, err := url.Parse(input.URL)
+	switch {
+	case err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "":
+		fields = append(fields, problem.Field("url", problem.FieldInvalidURL))
+	case !s.AllowPrivateNetworks && forbiddenHost(u.Hostname()):
+		fields = append(fields, problem.Field("url", problem.FieldPrivateURL))
+	}
+
+	if len(input.Events) == 0 {
+		fields = append(fields, problem.Field("events", problem.FieldRequired))
+	}
+	for _, e := range input.Events {
+		if !events.IsNoteType(e) {
+			fields = append(fields, problem.Field("events", problem.FieldInvalid))
+			break
+		}
+	}
+
+	if len(fields) > 0 {
+		return problem.Invalid(fields...)
+	}
+	return nil
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}